@@ -0,0 +1,112 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// NewAgent creates an agent that executes its task with the given LLM
+func NewAgent(llm LlmInterface) AgentInterface {
+	return &agentImplementation{llm: llm}
+}
+
+// agentImplementation implements AgentInterface on top of an LlmInterface.
+// It keeps the conversation history, so consecutive executions build on
+// each other.
+type agentImplementation struct {
+	mu       sync.Mutex
+	llm      LlmInterface
+	role     string
+	task     string
+	messages []Message
+}
+
+// SetRole implements AgentInterface
+func (a *agentImplementation) SetRole(role string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.role = role
+}
+
+// GetRole implements AgentInterface
+func (a *agentImplementation) GetRole() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.role
+}
+
+// SetTask implements AgentInterface
+func (a *agentImplementation) SetTask(task string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.task = task
+}
+
+// GetTask implements AgentInterface
+func (a *agentImplementation) GetTask() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.task
+}
+
+// AddMessage implements AgentInterface
+func (a *agentImplementation) AddMessage(role string, content string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.messages = append(a.messages, Message{Role: role, Content: content})
+}
+
+// GetMessages implements AgentInterface
+func (a *agentImplementation) GetMessages() []Message {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]Message(nil), a.messages...)
+}
+
+// Execute implements AgentInterface
+func (a *agentImplementation) Execute() (string, error) {
+	return a.ExecuteContext(context.Background())
+}
+
+// ExecuteContext implements AgentInterface
+func (a *agentImplementation) ExecuteContext(ctx context.Context) (string, error) {
+	if a.llm == nil {
+		return "", fmt.Errorf("agent has no llm")
+	}
+
+	a.mu.Lock()
+	task := a.task
+	messages := a.chatMessages()
+	a.mu.Unlock()
+
+	response, err := GenerateChat(a.llm, messages, LlmOptions{Context: ctx})
+	if err != nil {
+		return "", err
+	}
+
+	a.mu.Lock()
+	if strings.TrimSpace(task) != "" {
+		a.messages = append(a.messages, Message{Role: MessageRoleUser, Content: task})
+	}
+	a.messages = append(a.messages, Message{Role: MessageRoleAssistant, Content: response})
+	a.mu.Unlock()
+
+	return response, nil
+}
+
+// chatMessages assembles the messages for the next execution: the role as
+// system message, the history, and the task as the latest user message.
+// The caller must hold a.mu.
+func (a *agentImplementation) chatMessages() []Message {
+	messages := make([]Message, 0, len(a.messages)+2)
+	if strings.TrimSpace(a.role) != "" {
+		messages = append(messages, Message{Role: MessageRoleSystem, Content: a.role})
+	}
+	messages = append(messages, a.messages...)
+	if strings.TrimSpace(a.task) != "" {
+		messages = append(messages, Message{Role: MessageRoleUser, Content: a.task})
+	}
+	return messages
+}
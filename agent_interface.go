@@ -1,5 +1,7 @@
 package llm
 
+import "context"
+
 // AgentInterface defines the core interface that all agents must implement
 type AgentInterface interface {
 	// SetRole sets the role of the agent
@@ -16,6 +18,18 @@ type AgentInterface interface {
 	// GetTask returns the task of the agent
 	GetTask() string
 
-	// Execute runs the agent and returns the response
+	// AddMessage appends a message to the conversation history
+	// i.e. AddMessage(MessageRoleUser, "My name is John")
+	AddMessage(role string, content string)
+
+	// GetMessages returns a copy of the conversation history
+	GetMessages() []Message
+
+	// Execute runs the agent and returns the response. The history is sent
+	// along with the task, and the task and response are appended to it.
 	Execute() (response string, err error)
+
+	// ExecuteContext runs the agent like Execute, cancelling the request
+	// when ctx is done
+	ExecuteContext(ctx context.Context) (response string, err error)
 }
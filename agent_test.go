@@ -0,0 +1,132 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAgentExecuteIncludesHistory(t *testing.T) {
+	var received [][]Message
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []Message `json:"messages"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		received = append(received, body.Messages)
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"Nice to meet you, John"}}]}`))
+	}))
+	defer server.Close()
+
+	llm, err := NewLLM(LlmOptions{
+		Provider:        ProviderCustom,
+		ProviderOptions: map[string]any{"url": server.URL},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create custom LLM: %v", err)
+	}
+
+	agent := NewAgent(llm)
+	agent.SetRole("You are a helpful assistant")
+	agent.AddMessage(MessageRoleUser, "My name is John")
+	agent.AddMessage(MessageRoleAssistant, "Hello John")
+	agent.SetTask("What is my name?")
+
+	if _, err := agent.Execute(); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	want := []Message{
+		{Role: MessageRoleSystem, Content: "You are a helpful assistant"},
+		{Role: MessageRoleUser, Content: "My name is John"},
+		{Role: MessageRoleAssistant, Content: "Hello John"},
+		{Role: MessageRoleUser, Content: "What is my name?"},
+	}
+	if len(received) != 1 || !reflect.DeepEqual(received[0], want) {
+		t.Fatalf("unexpected messages sent:\n got: %+v\nwant: %+v", received, want)
+	}
+
+	// The executed turn becomes part of the history
+	agent.SetTask("Say it again")
+	if _, err := agent.Execute(); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if len(received[1]) != 6 {
+		t.Fatalf("expected 6 messages in the second turn, got %+v", received[1])
+	}
+	if !reflect.DeepEqual(received[1][4], Message{Role: MessageRoleAssistant, Content: "Nice to meet you, John"}) {
+		t.Errorf("expected previous response in history, got %+v", received[1][4])
+	}
+	if len(agent.GetMessages()) != 6 {
+		t.Errorf("expected 6 messages in history, got %d", len(agent.GetMessages()))
+	}
+}
+
+func TestFlattenMessages(t *testing.T) {
+	system, user := flattenMessages([]Message{
+		{Role: MessageRoleSystem, Content: "Be brief"},
+		{Role: MessageRoleUser, Content: "Hi"},
+	})
+	if system != "Be brief" || user != "Hi" {
+		t.Errorf("unexpected single turn flattening: %q, %q", system, user)
+	}
+
+	system, user = flattenMessages([]Message{
+		{Role: MessageRoleSystem, Content: "Be brief"},
+		{Role: MessageRoleUser, Content: "My name is John"},
+		{Role: MessageRoleAssistant, Content: "Hello John"},
+		{Role: MessageRoleUser, Content: "What is my name?"},
+	})
+	if system != "Be brief" {
+		t.Errorf("unexpected system prompt: %q", system)
+	}
+	if user != "User: My name is John\n\nAssistant: Hello John\n\nUser: What is my name?" {
+		t.Errorf("unexpected transcript: %q", user)
+	}
+}
+
+func TestAgentExecuteContextCancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Read the body so the server notices when the client goes away
+		io.ReadAll(r.Body)
+		select {
+		case <-time.After(5 * time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	llm, err := NewLLM(LlmOptions{
+		Provider:        ProviderCustom,
+		ProviderOptions: map[string]any{"url": server.URL},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create custom LLM: %v", err)
+	}
+
+	agent := NewAgent(llm)
+	agent.SetTask("Write a long story")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err = agent.ExecuteContext(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("ExecuteContext did not return promptly after cancel, took %v", elapsed)
+	}
+	if len(agent.GetMessages()) != 0 {
+		t.Errorf("expected failed execution to leave history unchanged, got %+v", agent.GetMessages())
+	}
+}
@@ -3,10 +3,6 @@ package llm
 import (
 	"bytes"
 	"context"
-	"crypto/sha256"
-	"crypto/subtle"
-	"crypto/tls"
-	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
@@ -16,9 +12,16 @@ import (
 	"net/http"
 	"os"
 	"strings"
-	"time"
 )
 
+// anthropicAPIURL is the base URL of the Anthropic API. Tests point it at
+// a local server.
+var anthropicAPIURL = "https://api.anthropic.com/v1"
+
+// anthropicDefaultMaxTokens is sent when MaxTokens is not positive, as the
+// Anthropic API requires max_tokens
+const anthropicDefaultMaxTokens = 4096
+
 // anthropicImplementation implements LlmInterface for Anthropic
 type anthropicImplementation struct {
 	apiKey          string
@@ -29,6 +32,8 @@ type anthropicImplementation struct {
 	logger          *slog.Logger
 	providerOptions map[string]any
 	httpClient      *http.Client
+	options         LlmOptions
+	*lastResponseRecorder
 }
 
 func mergeProviderOptions(base map[string]any, override map[string]any) map[string]any {
@@ -49,115 +54,24 @@ func mergeProviderOptions(base map[string]any, override map[string]any) map[stri
 	return merged
 }
 
-func buildAnthropicHTTPClient(providerOptions map[string]any) (*http.Client, error) {
-	tlsConfig := &tls.Config{
-		MinVersion: tls.VersionTLS12,
-	}
-
-	rootCAFile := valueFromProviderOrEnv(providerOptions, "anthropic_root_ca_file", "ANTHROPIC_ROOT_CA_FILE")
-	rootCAPEM := valueFromProviderOrEnv(providerOptions, "anthropic_root_ca_pem", "ANTHROPIC_ROOT_CA_PEM")
-	spkiHash := valueFromProviderOrEnv(providerOptions, "anthropic_spki_hash", "ANTHROPIC_EXPECTED_SPKI_HASH")
-
-	customRootCA := false
-	if rootCAFile != "" || rootCAPEM != "" {
-		rootPool, err := x509.SystemCertPool()
-		if err != nil || rootPool == nil {
-			rootPool = x509.NewCertPool()
-		}
-
-		if rootCAPEM != "" {
-			if ok := rootPool.AppendCertsFromPEM([]byte(rootCAPEM)); !ok {
-				return nil, fmt.Errorf("anthropic: invalid root CA PEM")
-			}
-			customRootCA = true
-		}
-
-		if rootCAFile != "" {
-			pemBytes, err := os.ReadFile(rootCAFile)
-			if err != nil {
-				return nil, fmt.Errorf("anthropic: unable to read root CA file %s: %w", rootCAFile, err)
-			}
-			if ok := rootPool.AppendCertsFromPEM(pemBytes); !ok {
-				return nil, fmt.Errorf("anthropic: invalid root CA file %s", rootCAFile)
-			}
-			customRootCA = true
-		}
-
-		if customRootCA {
-			tlsConfig.RootCAs = rootPool
-		}
-	}
-
-	spkiHash = strings.TrimSpace(spkiHash)
-	spkiHash = strings.TrimPrefix(spkiHash, "sha256/")
-
-	if spkiHash != "" {
-		expectedPin, err := base64.StdEncoding.DecodeString(spkiHash)
-		if err != nil {
-			return nil, fmt.Errorf("anthropic: invalid SPKI hash: %w", err)
-		}
-
-		tlsConfig.VerifyConnection = func(state tls.ConnectionState) error {
-			if len(state.PeerCertificates) == 0 {
-				return fmt.Errorf("anthropic: no peer certificates for pinning")
-			}
-
-			leaf := state.PeerCertificates[0]
-			hash := sha256.Sum256(leaf.RawSubjectPublicKeyInfo)
-			if subtle.ConstantTimeCompare(hash[:], expectedPin) != 1 {
-				return fmt.Errorf("anthropic: certificate pin mismatch")
-			}
-
-			return nil
-		}
-	}
-
-	transport := &http.Transport{
-		Proxy:               http.ProxyFromEnvironment,
-		TLSClientConfig:     tlsConfig,
-		ForceAttemptHTTP2:   true,
-		TLSHandshakeTimeout: 10 * time.Second,
-	}
-
-	return &http.Client{
-		Timeout:   30 * time.Second,
-		Transport: transport,
-	}, nil
-}
-
-func valueFromProviderOrEnv(providerOptions map[string]any, key string, envKey string) string {
-	if providerOptions != nil {
-		if raw, ok := providerOptions[key]; ok {
-			switch v := raw.(type) {
-			case string:
-				if trimmed := strings.TrimSpace(v); trimmed != "" {
-					return trimmed
-				}
-			case []byte:
-				if trimmed := strings.TrimSpace(string(v)); trimmed != "" {
-					return trimmed
-				}
-			}
-		}
-	}
-
-	return strings.TrimSpace(os.Getenv(envKey))
-}
-
 // newAnthropicImplementation creates a new Anthropic provider implementation
 func newAnthropicImplementation(options LlmOptions) (LlmInterface, error) {
 	model := options.Model
 	if model == "" {
-		model = "claude-3-opus-20240229" // Default to Claude 3 Opus
+		model = DefaultModel(ProviderAnthropic)
 	}
 
-	client, err := buildAnthropicHTTPClient(options.ProviderOptions)
+	client, err := buildSecureHTTPClient(ProviderAnthropic, options.ProviderOptions, httpTimeout(options))
 	if err != nil {
 		return nil, fmt.Errorf("failed to configure anthropic http client: %w", err)
 	}
 
+	client = apiKeyHTTPClientWith(client, options, func(req *http.Request, key string) {
+		req.Header.Set("x-api-key", key)
+	})
+
 	return &anthropicImplementation{
-		apiKey:          options.ApiKey,
+		apiKey:          providerAPIKey(options),
 		model:           model,
 		maxTokens:       options.MaxTokens,
 		temperature:     derefFloat64(options.Temperature, 0.7),
@@ -165,52 +79,191 @@ func newAnthropicImplementation(options LlmOptions) (LlmInterface, error) {
 		logger:          options.Logger,
 		providerOptions: options.ProviderOptions,
 		httpClient:      client,
+		options:         options,
+
+		lastResponseRecorder: newLastResponseRecorder(options.ProviderOptions),
 	}, nil
 }
 
-// baseOptions returns the base LlmOptions from the struct fields for merging.
+// baseOptions returns the construction options, with the struct fields
+// applied on top, as the base for merging per-call options.
 func (a *anthropicImplementation) baseOptions() LlmOptions {
-	return LlmOptions{
-		Model:           a.model,
-		MaxTokens:       a.maxTokens,
-		Temperature:     &a.temperature,
-		Verbose:         a.verbose,
-		Logger:          a.logger,
-		ProviderOptions: a.providerOptions,
-	}
+	options := a.options
+	options.Model = a.model
+	options.MaxTokens = a.maxTokens
+	options.Temperature = &a.temperature
+	options.Verbose = a.verbose
+	options.Logger = a.logger
+	options.ProviderOptions = a.providerOptions
+	return options
 }
 
 // Generate implements LlmInterface
 func (a *anthropicImplementation) Generate(systemPrompt string, userMessage string, opts ...LlmOptions) (string, error) {
+	return a.GenerateChat(promptMessages(systemPrompt, userMessage), opts...)
+}
+
+// EffectiveOptions implements EffectiveOptionsInterface
+func (a *anthropicImplementation) EffectiveOptions(opts ...LlmOptions) LlmOptions {
+	return mergeOptions(a.baseOptions(), firstOptions(opts))
+}
+
+// DebugMessages implements DebugMessagesInterface. The system message is
+// sent as Anthropic's top-level system prompt.
+func (a *anthropicImplementation) DebugMessages(systemPrompt string, userMessage string, opts ...LlmOptions) []Message {
+	perCall := LlmOptions{}
+	if len(opts) > 0 {
+		perCall = opts[0]
+	}
+	merged := mergeOptions(a.baseOptions(), perCall)
+
+	return promptMessages(formatSystemPrompt(systemPrompt, merged), userMessage)
+}
+
+// GenerateChat implements ChatInterface. System messages are joined into
+// Anthropic's top-level system prompt.
+func (a *anthropicImplementation) GenerateChat(messages []Message, opts ...LlmOptions) (string, error) {
+	perCall := LlmOptions{}
+	if len(opts) > 0 {
+		perCall = opts[0]
+	}
+	merged := mergeOptions(a.baseOptions(), perCall)
+
+	if override, err := overrideProvider(a.options.Provider, merged, perCall); err != nil {
+		return "", err
+	} else if override != nil {
+		defer Close(override)
+		return GenerateChat(override, messages, perCall)
+	}
+
+	content, stopReason, err := a.createMessage(messages, nil, merged)
+	if err != nil {
+		return "", err
+	}
+
+	// Get text from first content item
+	firstContent, ok := content[0].(map[string]interface{})
+	if !ok {
+		return "", fmt.Errorf("invalid content format")
+	}
+
+	text, ok := firstContent["text"].(string)
+	if !ok {
+		return "", fmt.Errorf("invalid text format")
+	}
+
+	// Return the truncated text along with ErrMaxTokensReached
+	if stopReason == anthropicStopMaxTokens {
+		return strings.TrimSpace(text), fmt.Errorf("%w: anthropic stop reason %s", ErrMaxTokensReached, stopReason)
+	}
+
+	if strings.TrimSpace(text) == "" {
+		return "", fmt.Errorf("%w: anthropic returned empty content", ErrEmptyResponse)
+	}
+
+	return strings.TrimSpace(text), nil
+}
+
+// GenerateWithTools implements ToolInterface. Text and tool_use content
+// blocks are returned as the result's text and tool calls.
+func (a *anthropicImplementation) GenerateWithTools(systemPrompt string, userPrompt string, tools []ToolDefinition, opts ...LlmOptions) (ToolResult, error) {
 	perCall := LlmOptions{}
 	if len(opts) > 0 {
 		perCall = opts[0]
 	}
 	merged := mergeOptions(a.baseOptions(), perCall)
 
+	if override, err := overrideProvider(a.options.Provider, merged, perCall); err != nil {
+		return ToolResult{}, err
+	} else if override != nil {
+		defer Close(override)
+		return GenerateWithTools(override, systemPrompt, userPrompt, tools, perCall)
+	}
+
+	content, _, err := a.createMessage([]Message{
+		{Role: MessageRoleSystem, Content: systemPrompt},
+		{Role: MessageRoleUser, Content: userPrompt},
+	}, tools, merged)
+	if err != nil {
+		return ToolResult{}, err
+	}
+
+	result := ToolResult{}
+	var text strings.Builder
+	for _, item := range content {
+		block, ok := item.(map[string]interface{})
+		if !ok {
+			return ToolResult{}, fmt.Errorf("invalid content format")
+		}
+		switch block["type"] {
+		case "text":
+			blockText, _ := block["text"].(string)
+			text.WriteString(blockText)
+		case "tool_use":
+			arguments, err := json.Marshal(block["input"])
+			if err != nil {
+				return ToolResult{}, fmt.Errorf("failed to marshal tool input: %v", err)
+			}
+			id, _ := block["id"].(string)
+			name, _ := block["name"].(string)
+			result.ToolCalls = append(result.ToolCalls, ToolCall{ID: id, Name: name, Arguments: string(arguments)})
+		}
+	}
+	result.Text = strings.TrimSpace(text.String())
+
+	return result, nil
+}
+
+// createMessage sends the messages, and the tools if any, to the messages
+// API and returns the content blocks and the stop_reason of the response
+func (a *anthropicImplementation) createMessage(messages []Message, tools []ToolDefinition, merged LlmOptions) ([]interface{}, string, error) {
+	if err := checkCostBudget(merged, "", messagesContent(messages)); err != nil {
+		return nil, "", err
+	}
+
 	// Validate API key
 	if a.apiKey == "" {
-		return "", fmt.Errorf("anthropic api key not provided")
+		return nil, "", fmt.Errorf("anthropic api key not provided")
 	}
 
-	ctx := context.Background()
+	ctx, cancel := requestContext(merged)
+	defer cancel()
 
 	model := merged.Model
-	maxTokens := merged.MaxTokens
+	maxTokens := requestMaxTokens(merged)
+	if maxTokens == 0 {
+		maxTokens = anthropicDefaultMaxTokens
+	}
 	temperature := derefFloat64(merged.Temperature, a.temperature)
 
+	systemPrompt, conversation := splitSystemMessages(messages)
+	systemPrompt = formatSystemPrompt(systemPrompt, merged)
+	anthropicConversation, err := anthropicMessages(conversation)
+	if err != nil {
+		return nil, "", err
+	}
+
 	// Prepare request body
 	requestBody := map[string]interface{}{
 		"model":       model,
 		"max_tokens":  maxTokens,
 		"temperature": temperature,
 		"system":      systemPrompt,
-		"messages": []map[string]string{
-			{
-				"role":    "user",
-				"content": userMessage,
-			},
-		},
+		"messages":    anthropicConversation,
+	}
+	if cache, _ := providerOptionBool(merged.ProviderOptions, "enable_prompt_cache"); cache {
+		if systemPrompt != "" {
+			requestBody["system"] = anthropicCachedSystem(systemPrompt)
+		}
+		if cacheMessages, _ := providerOptionBool(merged.ProviderOptions, "prompt_cache_messages"); cacheMessages {
+			anthropicCacheLastMessage(anthropicConversation)
+		}
+	}
+	if len(merged.Stop) > 0 {
+		requestBody["stop_sequences"] = merged.Stop
+	}
+	if len(tools) > 0 {
+		requestBody["tools"] = anthropicTools(tools)
 	}
 
 	// Add response format if JSON is requested
@@ -223,13 +276,62 @@ func (a *anthropicImplementation) Generate(systemPrompt string, userMessage stri
 	// Convert request body to JSON
 	jsonBody, err := json.Marshal(requestBody)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request body: %v", err)
+		return nil, "", fmt.Errorf("failed to marshal request body: %v", err)
+	}
+
+	var body []byte
+	err = withRetry(ctx, merged, func() error {
+		var err error
+		body, err = a.send(ctx, jsonBody)
+		return err
+	})
+	if err != nil {
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && anthropicModelNotFound(apiErr.Body) {
+			return nil, "", &ModelNotFoundError{Provider: ProviderAnthropic, Model: model, Err: err}
+		}
+		return nil, "", err
 	}
 
+	// Parse response
+	var responseData map[string]interface{}
+	if err := json.Unmarshal(body, &responseData); err != nil {
+		return nil, "", fmt.Errorf("failed to parse response: %v", err)
+	}
+
+	// Extract content from response
+	content, ok := responseData["content"].([]interface{})
+	if !ok {
+		return nil, "", fmt.Errorf("invalid response format")
+	}
+	if len(content) == 0 {
+		return nil, "", fmt.Errorf("%w: no content blocks from anthropic", ErrEmptyResponse)
+	}
+
+	stopReason, _ := responseData["stop_reason"].(string)
+	return content, stopReason, nil
+}
+
+// anthropicTools converts tool definitions to the messages API tools
+func anthropicTools(tools []ToolDefinition) []map[string]any {
+	anthropicTools := make([]map[string]any, len(tools))
+	for i, tool := range tools {
+		anthropicTools[i] = map[string]any{
+			"name":         tool.Name,
+			"description":  tool.Description,
+			"input_schema": toolParameters(tool),
+		}
+	}
+	return anthropicTools
+}
+
+// send posts the request body to the messages API and returns the response
+// body. Error responses are returned as an *APIError.
+func (a *anthropicImplementation) send(ctx context.Context, jsonBody []byte) ([]byte, error) {
 	// Create HTTP request
-	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.anthropic.com/v1/messages", bytes.NewBuffer(jsonBody))
+	req, err := http.NewRequestWithContext(ctx, "POST", anthropicAPIURL+"/messages", bytes.NewBuffer(jsonBody))
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %v", err)
+		return nil, fmt.Errorf("failed to create request: %v", err)
 	}
 
 	// Set headers
@@ -240,10 +342,10 @@ func (a *anthropicImplementation) Generate(systemPrompt string, userMessage stri
 	// Send request
 	resp, err := a.httpClient.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to send request: %v", err)
+		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
 	if resp == nil {
-		return "", fmt.Errorf("failed to send request: received nil response")
+		return nil, fmt.Errorf("failed to send request: received nil response")
 	}
 	defer func() {
 		if cerr := resp.Body.Close(); cerr != nil {
@@ -259,38 +361,16 @@ func (a *anthropicImplementation) Generate(systemPrompt string, userMessage stri
 	// Read response body (limit to 10 MB to prevent memory exhaustion)
 	body, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
 	if err != nil {
-		return "", fmt.Errorf("failed to read response body: %v", err)
+		return nil, fmt.Errorf("failed to read response body: %v", err)
 	}
+	a.recordHTTP(ProviderAnthropic, resp, body)
 
 	// Check for error response
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API returned error: %s", string(body))
-	}
-
-	// Parse response
-	var responseData map[string]interface{}
-	if err := json.Unmarshal(body, &responseData); err != nil {
-		return "", fmt.Errorf("failed to parse response: %v", err)
-	}
-
-	// Extract content from response
-	content, ok := responseData["content"].([]interface{})
-	if !ok || len(content) == 0 {
-		return "", fmt.Errorf("invalid response format")
-	}
-
-	// Get text from first content item
-	firstContent, ok := content[0].(map[string]interface{})
-	if !ok {
-		return "", fmt.Errorf("invalid content format")
-	}
-
-	text, ok := firstContent["text"].(string)
-	if !ok {
-		return "", fmt.Errorf("invalid text format")
+		return nil, newAPIError(ProviderAnthropic, resp.StatusCode, body)
 	}
 
-	return strings.TrimSpace(text), nil
+	return body, nil
 }
 
 // GenerateText implements LlmInterface
@@ -310,8 +390,26 @@ func (a *anthropicImplementation) GenerateJSON(systemPrompt string, userPrompt s
 		perCall = opts[0]
 	}
 	perCall.OutputFormat = OutputFormatJSON
-	systemPrompt += "\nYou must respond with valid JSON only. Do not include any text outside the JSON."
-	return a.Generate(systemPrompt, userPrompt, perCall)
+	response, err := a.Generate(systemPrompt, userPrompt, perCall)
+	if err != nil {
+		return "", err
+	}
+	return sanitizeJSONResponse(response), nil
+}
+
+// GenerateXML implements LlmInterface
+func (a *anthropicImplementation) GenerateXML(systemPrompt string, userPrompt string, opts ...LlmOptions) (string, error) {
+	return generateMarkup(a, OutputFormatXML, systemPrompt, userPrompt, opts...)
+}
+
+// GenerateYAML implements LlmInterface
+func (a *anthropicImplementation) GenerateYAML(systemPrompt string, userPrompt string, opts ...LlmOptions) (string, error) {
+	return generateMarkup(a, OutputFormatYAML, systemPrompt, userPrompt, opts...)
+}
+
+// GenerateWithImages implements VisionInterface
+func (a *anthropicImplementation) GenerateWithImages(systemPrompt string, userPrompt string, images [][]byte, opts ...LlmOptions) (string, error) {
+	return a.GenerateChat(visionMessages(systemPrompt, userPrompt, images), opts...)
 }
 
 // GenerateImage implements LlmInterface
@@ -331,5 +429,189 @@ func (a *anthropicImplementation) GenerateImage(prompt string, opts ...LlmOption
 
 // GenerateEmbedding implements LlmInterface
 func (a *anthropicImplementation) GenerateEmbedding(text string) ([]float32, error) {
+	if a.options.EmbeddingLlm != nil {
+		return a.options.EmbeddingLlm.GenerateEmbedding(text)
+	}
+
 	return nil, errors.New("not supported. change to openrouter")
 }
+
+// anthropicCacheControl marks a content block as a prompt cache breakpoint
+var anthropicCacheControl = map[string]string{"type": "ephemeral"}
+
+// anthropicCachedSystem returns the system prompt as a text content block
+// marked as a prompt cache breakpoint, so the system prompt is cached
+// across calls
+func anthropicCachedSystem(systemPrompt string) []map[string]any {
+	return []map[string]any{{
+		"type":          "text",
+		"text":          systemPrompt,
+		"cache_control": anthropicCacheControl,
+	}}
+}
+
+// anthropicCacheLastMessage marks the last content block of the last
+// message as a prompt cache breakpoint, so the whole conversation is
+// cached for the next turn
+func anthropicCacheLastMessage(messages []map[string]any) {
+	if len(messages) == 0 {
+		return
+	}
+
+	last := messages[len(messages)-1]
+	switch content := last["content"].(type) {
+	case string:
+		if content != "" {
+			last["content"] = []map[string]any{{
+				"type":          "text",
+				"text":          content,
+				"cache_control": anthropicCacheControl,
+			}}
+		}
+	case []map[string]any:
+		if len(content) > 0 {
+			content[len(content)-1]["cache_control"] = anthropicCacheControl
+		}
+	}
+}
+
+// anthropicMessages converts messages to the Anthropic messages format.
+// Messages with images are sent as base64 image content blocks followed by
+// the text block.
+func anthropicMessages(messages []Message) ([]map[string]any, error) {
+	result := make([]map[string]any, len(messages))
+	for i, message := range messages {
+		if len(message.Images) == 0 {
+			result[i] = map[string]any{
+				"role":    message.Role,
+				"content": message.Content,
+			}
+			continue
+		}
+
+		blocks := []map[string]any{}
+		for _, image := range message.Images {
+			mediaType, err := imageMediaType(image)
+			if err != nil {
+				return nil, err
+			}
+			blocks = append(blocks, map[string]any{
+				"type": "image",
+				"source": map[string]any{
+					"type":       "base64",
+					"media_type": mediaType,
+					"data":       base64.StdEncoding.EncodeToString(image),
+				},
+			})
+		}
+		if message.Content != "" {
+			blocks = append(blocks, map[string]any{
+				"type": "text",
+				"text": message.Content,
+			})
+		}
+
+		result[i] = map[string]any{
+			"role":    message.Role,
+			"content": blocks,
+		}
+	}
+	return result, nil
+}
+
+// anthropicModelNotFound reports whether an Anthropic error response has
+// the not_found_error type, which the messages API returns for an unknown
+// model
+func anthropicModelNotFound(body []byte) bool {
+	var errorResponse struct {
+		Error struct {
+			Type string `json:"type"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &errorResponse); err != nil {
+		return false
+	}
+	return errorResponse.Error.Type == "not_found_error"
+}
+
+// CountTokensAnthropic counts the input tokens of text as a user message to
+// the Claude model, using Anthropic's count_tokens API. Unlike CountTokens,
+// which estimates with an OpenAI tokenizer, the count is exact, so it can
+// be used to split documents to fit the context window. The API key is
+// taken from the options or the ANTHROPIC_API_KEY environment variable.
+// A non-2xx response returns an *APIError.
+func CountTokensAnthropic(text string, model string, opts ...LlmOptions) (int, error) {
+	options := LlmOptions{}
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+
+	apiKey := strings.TrimSpace(options.ApiKey)
+	if apiKey == "" {
+		apiKey = strings.TrimSpace(os.Getenv("ANTHROPIC_API_KEY"))
+	}
+	if apiKey == "" {
+		return 0, fmt.Errorf("anthropic api key is required")
+	}
+
+	client, err := buildSecureHTTPClient(ProviderAnthropic, options.ProviderOptions, httpTimeout(options))
+	if err != nil {
+		return 0, fmt.Errorf("failed to configure anthropic http client: %w", err)
+	}
+
+	jsonBody, err := json.Marshal(map[string]any{
+		"model": model,
+		"messages": []map[string]any{
+			{"role": MessageRoleUser, "content": text},
+		},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	ctx, cancel := requestContext(options)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", anthropicAPIURL+"/messages/count_tokens", bytes.NewReader(jsonBody))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", apiKey)
+	req.Header.Set("anthropic-version", "2023-06-01")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		err := newAPIError(ProviderAnthropic, resp.StatusCode, body)
+		if anthropicModelNotFound(body) {
+			return 0, &ModelNotFoundError{Provider: ProviderAnthropic, Model: model, Err: err}
+		}
+		return 0, err
+	}
+
+	var result struct {
+		InputTokens int `json:"input_tokens"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return 0, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return result.InputTokens, nil
+}
+
+// Close implements io.Closer. It closes the idle connections of the
+// HTTP client.
+func (a *anthropicImplementation) Close() error {
+	closeIdleConnections(a.httpClient)
+	return nil
+}
@@ -0,0 +1,97 @@
+package llm
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestAnthropicPromptCache(t *testing.T) {
+	var captured map[string]any
+	server := captureServer(t, `{"content":[{"type":"text","text":"hi"}],"usage":{
+		"input_tokens":12,"output_tokens":5,"cache_creation_input_tokens":0,"cache_read_input_tokens":2048}}`, &captured)
+	defer server.Close()
+	target, _ := url.Parse(server.URL)
+
+	engine, err := NewLLM(LlmOptions{
+		Provider:        ProviderAnthropic,
+		ApiKey:          "test-key",
+		Model:           "claude-sonnet-4",
+		ProviderOptions: map[string]any{"enable_prompt_cache": true},
+	})
+	if err != nil {
+		t.Fatalf("failed to create Anthropic LLM: %v", err)
+	}
+	engine.(*anthropicImplementation).httpClient = &http.Client{Transport: redirectTransport{target: target}}
+
+	_, raw, err := GenerateRaw(engine, "long shared context", "question")
+	if err != nil {
+		t.Fatalf("GenerateRaw failed: %v", err)
+	}
+
+	system, _ := captured["system"].([]any)
+	if len(system) != 1 {
+		t.Fatalf("expected the system prompt as one content block, got %v", captured["system"])
+	}
+	block, _ := system[0].(map[string]any)
+	cacheControl, _ := block["cache_control"].(map[string]any)
+	if block["text"] != "long shared context" || cacheControl["type"] != "ephemeral" {
+		t.Errorf("expected an ephemeral cache breakpoint on the system prompt, got %v", block)
+	}
+	messages, _ := captured["messages"].([]any)
+	if content, _ := messages[0].(map[string]any)["content"].(string); content != "question" {
+		t.Errorf("expected the user message untouched, got %v", messages[0])
+	}
+
+	usage, ok := raw.Usage()
+	if !ok {
+		t.Fatal("expected usage in the response")
+	}
+	if usage.PromptTokens != 12 || usage.CompletionTokens != 5 || usage.CacheReadTokens != 2048 || usage.TotalTokens != 2065 {
+		t.Errorf("unexpected usage: %+v", usage)
+	}
+
+	// Caching the conversation marks the last message too
+	captured = nil
+	_, err = engine.GenerateText("long shared context", "question", LlmOptions{
+		ProviderOptions: map[string]any{"enable_prompt_cache": true, "prompt_cache_messages": true},
+	})
+	if err != nil {
+		t.Fatalf("GenerateText failed: %v", err)
+	}
+	messages, _ = captured["messages"].([]any)
+	blocks, _ := messages[0].(map[string]any)["content"].([]any)
+	if len(blocks) != 1 || blocks[0].(map[string]any)["cache_control"] == nil {
+		t.Errorf("expected a cache breakpoint on the last message, got %v", messages[0])
+	}
+}
+
+func TestAnthropicMaxTokensStopReason(t *testing.T) {
+	var captured map[string]any
+	server := captureServer(t, `{"content":[{"type":"text","text":"Once upon a"}],"stop_reason":"max_tokens",
+		"usage":{"input_tokens":10,"output_tokens":3}}`, &captured)
+	defer server.Close()
+	target, _ := url.Parse(server.URL)
+
+	engine, err := NewLLM(LlmOptions{Provider: ProviderAnthropic, ApiKey: "test-key", Model: "claude-sonnet-4", MaxTokens: 3})
+	if err != nil {
+		t.Fatalf("failed to create Anthropic LLM: %v", err)
+	}
+	engine.(*anthropicImplementation).httpClient = &http.Client{Transport: redirectTransport{target: target}}
+
+	text, raw, err := GenerateRaw(engine, "You are a storyteller.", "Tell me a story")
+	if !errors.Is(err, ErrMaxTokensReached) {
+		t.Fatalf("expected ErrMaxTokensReached, got %v", err)
+	}
+	if text != "Once upon a" {
+		t.Errorf("expected the truncated text, got %q", text)
+	}
+
+	if stopReason, ok := raw.StopReason(); !ok || stopReason != "max_tokens" {
+		t.Errorf("expected stop reason max_tokens, got %q, %v", stopReason, ok)
+	}
+	if usage, ok := raw.Usage(); !ok || usage.PromptTokens != 10 || usage.CompletionTokens != 3 {
+		t.Errorf("unexpected usage: %+v, %v", usage, ok)
+	}
+}
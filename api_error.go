@@ -0,0 +1,159 @@
+package llm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+	"google.golang.org/genai"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// APIError is an error response from a provider's HTTP API. All the
+// HTTP-based providers return it for non-2xx responses, so callers can
+// tell rate limits from authentication failures uniformly. Use errors.As
+// to read it, or the IsRateLimited and IsAuthError helpers.
+type APIError struct {
+	// StatusCode is the HTTP status code of the response
+	StatusCode int
+
+	// Provider is the provider that returned the error
+	Provider Provider
+
+	// Message is the error message reported by the provider, or the raw
+	// response body if it has none
+	Message string
+
+	// Retryable reports whether the failure is transient (a rate limit or
+	// a server error) and worth retrying
+	Retryable bool
+
+	// Body is the raw response body, if available
+	Body []byte
+
+	// Err is the SDK error the APIError was built from, if any, e.g. a
+	// go-openai *openai.APIError
+	Err error
+}
+
+// Error implements error
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s API error (status %d): %s", e.Provider, e.StatusCode, e.Message)
+}
+
+// Unwrap returns the SDK error the APIError was built from
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// IsRateLimited reports whether err is a provider rate limit or quota
+// failure: an HTTP 429, or a gRPC RESOURCE_EXHAUSTED status (Vertex AI)
+func IsRateLimited(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusTooManyRequests
+	}
+	if grpcStatus, ok := status.FromError(err); ok {
+		return grpcStatus.Code() == codes.ResourceExhausted
+	}
+	return false
+}
+
+// IsAuthError reports whether err is a provider authentication or
+// authorization failure: an HTTP 401 or 403, or a gRPC UNAUTHENTICATED or
+// PERMISSION_DENIED status (Vertex AI)
+func IsAuthError(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden
+	}
+	if grpcStatus, ok := status.FromError(err); ok {
+		return grpcStatus.Code() == codes.Unauthenticated || grpcStatus.Code() == codes.PermissionDenied
+	}
+	return false
+}
+
+// newAPIError builds the APIError of a non-2xx response body
+func newAPIError(provider Provider, statusCode int, body []byte) *APIError {
+	return &APIError{
+		StatusCode: statusCode,
+		Provider:   provider,
+		Message:    apiErrorMessage(body),
+		Retryable:  retryableStatus(statusCode),
+		Body:       body,
+	}
+}
+
+// apiErrorMessage extracts the error message of a response body. It
+// supports the OpenAI and Anthropic shape ({"error": {"message": ...}}) and
+// a top-level "message" (Cohere), and falls back to the trimmed body.
+func apiErrorMessage(body []byte) string {
+	var parsed struct {
+		Error   json.RawMessage `json:"error"`
+		Message string          `json:"message"`
+	}
+	if err := json.Unmarshal(body, &parsed); err == nil {
+		var nested struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(parsed.Error, &nested) == nil && nested.Message != "" {
+			return nested.Message
+		}
+		var message string
+		if json.Unmarshal(parsed.Error, &message) == nil && message != "" {
+			return message
+		}
+		if parsed.Message != "" {
+			return parsed.Message
+		}
+	}
+	return strings.TrimSpace(string(body))
+}
+
+// providerAPIError converts the HTTP errors of the go-openai and genai SDKs
+// to an *APIError wrapping them. Other errors are returned unchanged.
+func providerAPIError(provider Provider, err error) error {
+	var openaiErr *openai.APIError
+	if errors.As(err, &openaiErr) {
+		return &APIError{
+			StatusCode: openaiErr.HTTPStatusCode,
+			Provider:   provider,
+			Message:    openaiErr.Message,
+			Retryable:  retryableStatus(openaiErr.HTTPStatusCode),
+			Err:        err,
+		}
+	}
+
+	var requestErr *openai.RequestError
+	if errors.As(err, &requestErr) {
+		message := apiErrorMessage(requestErr.Body)
+		if message == "" && requestErr.Err != nil {
+			message = requestErr.Err.Error()
+		}
+		return &APIError{
+			StatusCode: requestErr.HTTPStatusCode,
+			Provider:   provider,
+			Message:    message,
+			Retryable:  retryableStatus(requestErr.HTTPStatusCode),
+			Body:       requestErr.Body,
+			Err:        err,
+		}
+	}
+
+	var genaiErr genai.APIError
+	if errors.As(err, &genaiErr) {
+		return &APIError{
+			StatusCode: genaiErr.Code,
+			Provider:   provider,
+			Message:    genaiErr.Message,
+			Retryable:  genaiErr.Status == "RESOURCE_EXHAUSTED" || retryableStatus(genaiErr.Code),
+			Err:        err,
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,99 @@
+package llm
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errorServer responds to every request with the status and body
+func errorServer(statusCode int, body string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(statusCode)
+		w.Write([]byte(body))
+	}))
+}
+
+func TestAPIError(t *testing.T) {
+	// Anthropic
+	server := errorServer(http.StatusTooManyRequests, `{"type":"error","error":{"type":"rate_limit_error","message":"Number of requests has exceeded your rate limit"}}`)
+	defer server.Close()
+	target, _ := url.Parse(server.URL)
+	engine, err := NewLLM(LlmOptions{Provider: ProviderAnthropic, ApiKey: "test-key", Model: "claude-sonnet-4"})
+	if err != nil {
+		t.Fatalf("failed to create Anthropic LLM: %v", err)
+	}
+	engine.(*anthropicImplementation).httpClient = &http.Client{Transport: redirectTransport{target: target}}
+
+	_, err = engine.GenerateText("system", "hello")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected an *APIError, got %v", err)
+	}
+	if apiErr.Provider != ProviderAnthropic || apiErr.StatusCode != http.StatusTooManyRequests || !apiErr.Retryable {
+		t.Errorf("unexpected APIError: %+v", apiErr)
+	}
+	if apiErr.Message != "Number of requests has exceeded your rate limit" {
+		t.Errorf("expected the provider message, got %q", apiErr.Message)
+	}
+	if !IsRateLimited(err) || IsAuthError(err) {
+		t.Errorf("expected a rate limit error, got %v", err)
+	}
+
+	// OpenAI, through go-openai
+	unauthorized := errorServer(http.StatusUnauthorized, `{"error":{"message":"Incorrect API key provided","type":"invalid_request_error"}}`)
+	defer unauthorized.Close()
+	cfg := openai.DefaultConfig("test-key")
+	cfg.BaseURL = unauthorized.URL
+	openaiEngine := &openaiImplementation{
+		client:               openai.NewClientWithConfig(cfg),
+		model:                "gpt-4.1",
+		lastResponseRecorder: newLastResponseRecorder(nil),
+	}
+
+	_, err = openaiEngine.GenerateText("system", "hello")
+	if !errors.As(err, &apiErr) || apiErr.Provider != ProviderOpenAI || apiErr.Retryable {
+		t.Fatalf("expected a non-retryable OpenAI *APIError, got %v", err)
+	}
+	if apiErr.Message != "Incorrect API key provided" {
+		t.Errorf("expected the provider message, got %q", apiErr.Message)
+	}
+	if !IsAuthError(err) || IsRateLimited(err) {
+		t.Errorf("expected an auth error, got %v", err)
+	}
+	var openaiErr *openai.APIError
+	if !errors.As(err, &openaiErr) {
+		t.Error("expected the go-openai error to stay reachable with errors.As")
+	}
+
+	// Custom, with a plain text body
+	forbidden := errorServer(http.StatusForbidden, "forbidden")
+	defer forbidden.Close()
+	engine, _ = NewLLM(LlmOptions{Provider: ProviderCustom, ProviderOptions: map[string]any{"url": forbidden.URL}})
+	_, err = engine.GenerateText("system", "hello")
+	if !errors.As(err, &apiErr) || apiErr.Provider != ProviderCustom || apiErr.Message != "forbidden" {
+		t.Errorf("expected a custom *APIError with the body as message, got %v", err)
+	}
+	if !IsAuthError(err) {
+		t.Errorf("expected an auth error, got %v", err)
+	}
+}
+
+func TestIsRateLimitedGRPC(t *testing.T) {
+	if !IsRateLimited(status.Error(codes.ResourceExhausted, "quota exceeded")) {
+		t.Error("expected RESOURCE_EXHAUSTED to be a rate limit")
+	}
+	if !IsAuthError(status.Error(codes.PermissionDenied, "denied")) {
+		t.Error("expected PERMISSION_DENIED to be an auth error")
+	}
+	if IsRateLimited(errors.New("boom")) || IsAuthError(errors.New("boom")) {
+		t.Error("expected a plain error to be neither")
+	}
+}
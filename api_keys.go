@@ -0,0 +1,160 @@
+package llm
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cast"
+)
+
+// defaultAPIKeyCooldown is how long a key that hit a rate limit is skipped,
+// unless the response has a Retry-After header or
+// ProviderOptions["api_key_cooldown_ms"] is set
+const defaultAPIKeyCooldown = time.Minute
+
+// providerAPIKeys returns the API keys of the options: the ApiKey followed
+// by the keys of ProviderOptions["api_keys"], without blanks and duplicates
+func providerAPIKeys(options LlmOptions) []string {
+	keys := []string{}
+	seen := map[string]bool{}
+	for _, key := range append([]string{options.ApiKey}, cast.ToStringSlice(options.ProviderOptions["api_keys"])...) {
+		key = strings.TrimSpace(key)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// providerAPIKey returns the first API key of the options, or an empty
+// string if there is none
+func providerAPIKey(options LlmOptions) string {
+	if keys := providerAPIKeys(options); len(keys) > 0 {
+		return keys[0]
+	}
+	return ""
+}
+
+// apiKeyAuthorizer sets the API key of a request, in the header the
+// provider reads it from
+type apiKeyAuthorizer func(req *http.Request, key string)
+
+// bearerAuthorizer sends the API key as a bearer token
+func bearerAuthorizer(req *http.Request, key string) {
+	req.Header.Set("Authorization", "Bearer "+key)
+}
+
+// apiKeyHTTPClient returns a copy of client that spreads the requests over
+// the API keys of the options, round-robin, setting each request's bearer
+// token. A key answered with 429 Too Many Requests is cooled down and
+// skipped until the cooldown ends. With a single key, client is returned
+// unchanged.
+func apiKeyHTTPClient(client *http.Client, options LlmOptions) *http.Client {
+	return apiKeyHTTPClientWith(client, options, bearerAuthorizer)
+}
+
+// apiKeyHTTPClientWith is apiKeyHTTPClient for the providers reading the
+// API key from another header than the bearer token, which authorize sets
+func apiKeyHTTPClientWith(client *http.Client, options LlmOptions, authorize apiKeyAuthorizer) *http.Client {
+	keys := providerAPIKeys(options)
+	if len(keys) < 2 {
+		return client
+	}
+
+	cooldown := defaultAPIKeyCooldown
+	if ms, ok := providerOptionInt(options.ProviderOptions, "api_key_cooldown_ms"); ok && ms > 0 {
+		cooldown = time.Duration(ms) * time.Millisecond
+	}
+
+	transport := client.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	withKeys := *client
+	withKeys.Transport = &apiKeyTransport{
+		base:      transport,
+		authorize: authorize,
+		pool:      &apiKeyPool{keys: keys, cooldown: cooldown, coolUntil: map[string]time.Time{}, now: time.Now},
+	}
+	return &withKeys
+}
+
+// apiKeyTransport authenticates every request with a key of the pool
+type apiKeyTransport struct {
+	base      http.RoundTripper
+	authorize apiKeyAuthorizer
+	pool      *apiKeyPool
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *apiKeyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := t.pool.pick()
+
+	req = req.Clone(req.Context())
+	t.authorize(req, key)
+
+	resp, err := t.base.RoundTrip(req)
+	if err == nil && resp.StatusCode == http.StatusTooManyRequests {
+		t.pool.coolDown(key, retryAfter(resp.Header))
+	}
+	return resp, err
+}
+
+// apiKeyPool selects the API keys round-robin, skipping the keys cooling
+// down after a rate limit. It is safe for concurrent use.
+type apiKeyPool struct {
+	mu        sync.Mutex
+	keys      []string
+	next      int
+	cooldown  time.Duration
+	coolUntil map[string]time.Time
+	now       func() time.Time
+}
+
+// pick returns the next key that is not cooling down. When all the keys
+// are cooling down, it returns the one available the soonest.
+func (p *apiKeyPool) pick() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	soonest := ""
+	for range p.keys {
+		key := p.keys[p.next]
+		p.next = (p.next + 1) % len(p.keys)
+		if !now.Before(p.coolUntil[key]) {
+			return key
+		}
+		if soonest == "" || p.coolUntil[key].Before(p.coolUntil[soonest]) {
+			soonest = key
+		}
+	}
+	return soonest
+}
+
+// coolDown skips the key for the given duration, or the pool's cooldown if
+// it is zero
+func (p *apiKeyPool) coolDown(key string, duration time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if duration <= 0 {
+		duration = p.cooldown
+	}
+	p.coolUntil[key] = p.now().Add(duration)
+}
+
+// retryAfter returns the delay of a Retry-After header in seconds, or zero
+// if there is none
+func retryAfter(header http.Header) time.Duration {
+	if seconds, err := strconv.Atoi(strings.TrimSpace(header.Get("Retry-After"))); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return 0
+}
@@ -0,0 +1,149 @@
+package llm
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAPIKeyRotation(t *testing.T) {
+	var mu sync.Mutex
+	var keys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		keys = append(keys, r.Header.Get("Authorization"))
+		mu.Unlock()
+
+		if r.Header.Get("Authorization") == "Bearer key-b" {
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"error":{"message":"rate limited"}}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"hi"}}]}`))
+	}))
+	defer server.Close()
+
+	originalDelay := retryBaseDelay
+	retryBaseDelay = time.Millisecond
+	defer func() { retryBaseDelay = originalDelay }()
+
+	engine, err := NewLLM(LlmOptions{
+		Provider:   ProviderOpenAI,
+		Model:      "gpt-4.1",
+		MaxRetries: 1,
+		ProviderOptions: map[string]any{
+			"base_url": server.URL,
+			"api_keys": []string{"key-a", "key-b", "key-c"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create OpenAI LLM: %v", err)
+	}
+
+	for i := 0; i < 4; i++ {
+		if _, err := engine.GenerateText("system", "hello"); err != nil {
+			t.Fatalf("GenerateText %d failed: %v", i, err)
+		}
+	}
+
+	// key-b is rate limited on its first use, retried with key-c, and then
+	// skipped while cooling down
+	expected := []string{"Bearer key-a", "Bearer key-b", "Bearer key-c", "Bearer key-a", "Bearer key-c"}
+	if len(keys) != len(expected) {
+		t.Fatalf("expected requests with %v, got %v", expected, keys)
+	}
+	for i := range expected {
+		if keys[i] != expected[i] {
+			t.Errorf("request %d: expected %s, got %s", i, expected[i], keys[i])
+		}
+	}
+}
+
+func TestAPIKeyPoolAllCoolingDown(t *testing.T) {
+	now := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	pool := &apiKeyPool{keys: []string{"a", "b"}, cooldown: time.Minute, coolUntil: map[string]time.Time{}, now: func() time.Time { return now }}
+
+	pool.coolDown("a", 0)
+	pool.coolDown("b", 30*time.Second)
+	if key := pool.pick(); key != "b" {
+		t.Errorf("expected the key available the soonest, got %s", key)
+	}
+
+	now = now.Add(time.Minute)
+	if key := pool.pick(); key != "a" {
+		t.Errorf("expected round-robin once the cooldowns ended, got %s", key)
+	}
+}
+
+func TestProviderAPIKeys(t *testing.T) {
+	keys := providerAPIKeys(LlmOptions{ApiKey: "a", ProviderOptions: map[string]any{"api_keys": []any{"b", " a ", ""}}})
+	if len(keys) != 2 || keys[0] != "a" || keys[1] != "b" {
+		t.Errorf("expected [a b], got %v", keys)
+	}
+
+	client := &http.Client{}
+	if apiKeyHTTPClient(client, LlmOptions{ApiKey: "a"}) != client {
+		t.Error("expected the client unchanged with a single key")
+	}
+}
+
+func TestAPIKeyRotationHTTPProviders(t *testing.T) {
+	tests := []struct {
+		provider Provider
+		header   string
+		response string
+		options  map[string]any
+	}{
+		{ProviderAnthropic, "x-api-key", `{"content":[{"type":"text","text":"hi"}],"stop_reason":"end_turn"}`, nil},
+		{ProviderCohere, "Authorization", `{"text":"hi"}`, nil},
+		{ProviderHuggingFace, "Authorization", `[{"generated_text":"hi"}]`, nil},
+		{ProviderCustom, "X-Key", `{"text":"hi"}`, map[string]any{"auth_header": "X-Key", "response_path": "text"}},
+	}
+
+	for _, tt := range tests {
+		var keys []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			keys = append(keys, r.Header.Get(tt.header))
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(tt.response))
+		}))
+
+		originalURL := anthropicAPIURL
+		anthropicAPIURL = server.URL
+
+		providerOptions := map[string]any{"base_url": server.URL, "url": server.URL, "api_keys": []string{"key-a", "key-b"}}
+		for key, value := range tt.options {
+			providerOptions[key] = value
+		}
+		if tt.provider == ProviderAnthropic {
+			delete(providerOptions, "base_url")
+		}
+		if tt.provider != ProviderCustom {
+			delete(providerOptions, "url")
+		}
+
+		engine, err := NewLLM(LlmOptions{Provider: tt.provider, Model: "test-model", ProviderOptions: providerOptions})
+		if err != nil {
+			t.Fatalf("%s: failed to create LLM: %v", tt.provider, err)
+		}
+		if err := ValidateProviderOptions(tt.provider, providerOptions); err != nil {
+			t.Errorf("%s: expected api_keys to be accepted, got %v", tt.provider, err)
+		}
+
+		for range 2 {
+			if _, err := engine.GenerateText("system", "hello"); err != nil {
+				t.Fatalf("%s: GenerateText failed: %v", tt.provider, err)
+			}
+		}
+		if len(keys) != 2 || !strings.HasSuffix(keys[0], "key-a") || !strings.HasSuffix(keys[1], "key-b") {
+			t.Errorf("%s: expected the keys in turn, got %v", tt.provider, keys)
+		}
+
+		anthropicAPIURL = originalURL
+		server.Close()
+	}
+}
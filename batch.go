@@ -0,0 +1,53 @@
+package llm
+
+import (
+	"context"
+	"sync"
+)
+
+// BatchRequest is a prompt pair of GenerateBatch
+type BatchRequest struct {
+	SystemPrompt string
+	UserPrompt   string
+}
+
+// GenerateBatch generates the responses of the requests with Generate,
+// running up to concurrency calls at a time (1 if concurrency is less).
+// The responses and errors are in the order of the requests: a failed
+// request has an empty response and its error. Once the per-call Context
+// is done, the requests not yet started fail with the context's error.
+func GenerateBatch(llm LlmInterface, requests []BatchRequest, concurrency int, options ...LlmOptions) ([]string, []error) {
+	responses := make([]string, len(requests))
+	errs := make([]error, len(requests))
+
+	ctx := firstOptions(options).Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	slots := make(chan struct{}, max(concurrency, 1))
+	var wg sync.WaitGroup
+	for i, request := range requests {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			errs[i] = ctx.Err()
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			<-slots
+			errs[i] = err
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			responses[i], errs[i] = llm.Generate(request.SystemPrompt, request.UserPrompt, options...)
+		}()
+	}
+	wg.Wait()
+
+	return responses, errs
+}
@@ -0,0 +1,72 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGenerateBatch(t *testing.T) {
+	var running, peak atomic.Int32
+	engine := &CustomTestLLM{generateFunc: func(systemPrompt, userPrompt string, options LlmOptions) (string, error) {
+		current := running.Add(1)
+		defer running.Add(-1)
+		for {
+			previous := peak.Load()
+			if current <= previous || peak.CompareAndSwap(previous, current) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		if userPrompt == "fail" {
+			return "", errors.New("failed")
+		}
+		return "response to " + userPrompt, nil
+	}}
+
+	requests := []BatchRequest{}
+	for i := range 8 {
+		requests = append(requests, BatchRequest{SystemPrompt: "system", UserPrompt: fmt.Sprint(i)})
+	}
+	requests[5].UserPrompt = "fail"
+
+	responses, errs := GenerateBatch(engine, requests, 3)
+	if peak.Load() > 3 {
+		t.Errorf("expected at most 3 concurrent calls, got %d", peak.Load())
+	}
+	for i, response := range responses {
+		if i == 5 {
+			if errs[i] == nil || response != "" {
+				t.Errorf("expected request 5 to fail, got %q, %v", response, errs[i])
+			}
+			continue
+		}
+		if errs[i] != nil || response != fmt.Sprintf("response to %d", i) {
+			t.Errorf("request %d: expected its own response, got %q, %v", i, response, errs[i])
+		}
+	}
+}
+
+func TestGenerateBatchCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	engine := &CustomTestLLM{generateFunc: func(systemPrompt, userPrompt string, options LlmOptions) (string, error) {
+		cancel()
+		return "done", nil
+	}}
+
+	requests := []BatchRequest{{UserPrompt: "a"}, {UserPrompt: "b"}, {UserPrompt: "c"}}
+	responses, errs := GenerateBatch(engine, requests, 1, LlmOptions{Context: ctx})
+
+	if errs[0] != nil || responses[0] != "done" {
+		t.Errorf("expected the first request to complete, got %q, %v", responses[0], errs[0])
+	}
+	for i := 1; i < len(requests); i++ {
+		if !errors.Is(errs[i], context.Canceled) {
+			t.Errorf("request %d: expected context.Canceled, got %v", i, errs[i])
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package llm
+
+// CandidatesInterface is implemented by providers that can return several
+// candidate responses in one call (currently OpenAI, OpenRouter, Gemini and
+// Vertex)
+type CandidatesInterface interface {
+	// GenerateN generates LlmOptions.N candidate responses to the prompt in
+	// one request, e.g. for re-ranking. Candidates without text are
+	// dropped, so fewer than N may be returned.
+	GenerateN(systemPrompt string, userPrompt string, options ...LlmOptions) ([]string, error)
+}
+
+// GenerateN generates LlmOptions.N candidate responses when the llm
+// implements CandidatesInterface. Other providers return Generate's
+// response as a single candidate. N is only read by GenerateN; the other
+// calls always request one response.
+func GenerateN(llm LlmInterface, systemPrompt string, userPrompt string, options ...LlmOptions) ([]string, error) {
+	if candidates, ok := llm.(CandidatesInterface); ok {
+		return candidates.GenerateN(systemPrompt, userPrompt, options...)
+	}
+
+	response, err := llm.Generate(systemPrompt, userPrompt, options...)
+	if response == "" {
+		return nil, err
+	}
+	return []string{response}, err
+}
+
+// candidateCount returns the number of candidates to request for the
+// options, at least 1
+func candidateCount(options LlmOptions) int {
+	return max(options.N, 1)
+}
@@ -0,0 +1,104 @@
+package llm
+
+import (
+	"strings"
+	"testing"
+
+	vertexgenai "cloud.google.com/go/vertexai/genai"
+)
+
+func TestGenerateNOpenAICompatible(t *testing.T) {
+	for _, provider := range []Provider{ProviderOpenAI, ProviderOpenRouter} {
+		t.Run(string(provider), func(t *testing.T) {
+			var captured map[string]any
+			server := captureServer(t, `{"choices":[
+				{"index":0,"message":{"role":"assistant","content":"first"}},
+				{"index":1,"message":{"role":"assistant","content":" "}},
+				{"index":2,"message":{"role":"assistant","content":"third"}}
+			]}`, &captured)
+			defer server.Close()
+
+			engine, err := NewLLM(LlmOptions{
+				Provider:        provider,
+				ApiKey:          "test-key",
+				Model:           "test-model",
+				ProviderOptions: map[string]any{"base_url": server.URL},
+			})
+			if err != nil {
+				t.Fatalf("failed to create %s LLM: %v", provider, err)
+			}
+
+			candidates, err := GenerateN(engine, "system", "hello", LlmOptions{N: 3})
+			if err != nil {
+				t.Fatalf("GenerateN failed: %v", err)
+			}
+			if captured["n"] != float64(3) {
+				t.Errorf("expected n 3, got %v", captured["n"])
+			}
+			if strings.Join(candidates, ",") != "first,third" {
+				t.Errorf("expected the candidates with text, got %v", candidates)
+			}
+
+			// The other calls request one response
+			captured = nil
+			if _, err := engine.GenerateText("system", "hello", LlmOptions{N: 3}); err != nil {
+				t.Fatalf("GenerateText failed: %v", err)
+			}
+			if _, ok := captured["n"]; ok {
+				t.Errorf("expected no n for GenerateText, got %v", captured["n"])
+			}
+		})
+	}
+}
+
+func TestGenerateNGemini(t *testing.T) {
+	var captured map[string]any
+	server := captureServer(t, `{"candidates":[
+		{"content":{"role":"model","parts":[{"text":"first"}]},"finishReason":"STOP"},
+		{"content":{"role":"model","parts":[{"text":"sec"},{"text":"ond"}]},"finishReason":"STOP"}
+	]}`, &captured)
+	defer server.Close()
+
+	candidates, err := GenerateN(newTestGemini(t, server.URL), "system", "hello", LlmOptions{N: 2})
+	if err != nil {
+		t.Fatalf("GenerateN failed: %v", err)
+	}
+	config, _ := captured["generationConfig"].(map[string]any)
+	if config["candidateCount"] != float64(2) {
+		t.Errorf("expected candidateCount 2, got %v", captured["generationConfig"])
+	}
+	if strings.Join(candidates, ",") != "first,second" {
+		t.Errorf("expected both candidates, got %v", candidates)
+	}
+}
+
+func TestVertexResponseTexts(t *testing.T) {
+	candidate := func(parts ...vertexgenai.Part) *vertexgenai.Candidate {
+		return &vertexgenai.Candidate{Content: &vertexgenai.Content{Role: "model", Parts: parts}, FinishReason: vertexgenai.FinishReasonStop}
+	}
+
+	texts, err := vertexResponseTexts(&vertexgenai.GenerateContentResponse{Candidates: []*vertexgenai.Candidate{
+		candidate(vertexgenai.Text("first")),
+		candidate(vertexgenai.FunctionCall{Name: "lookup"}),
+		candidate(vertexgenai.Text("sec"), vertexgenai.Text("ond")),
+	}})
+	if err != nil || strings.Join(texts, ",") != "first,second" {
+		t.Errorf("expected the candidates with text, got %v, %v", texts, err)
+	}
+
+	if _, err := vertexResponseTexts(&vertexgenai.GenerateContentResponse{}); err == nil {
+		t.Error("expected an error without candidates")
+	}
+}
+
+func TestGenerateNSingleCandidate(t *testing.T) {
+	engine, err := NewLLM(LlmOptions{Provider: ProviderMock, MockResponse: "only"})
+	if err != nil {
+		t.Fatalf("Failed to create mock LLM: %v", err)
+	}
+
+	candidates, err := GenerateN(engine, "system", "hello", LlmOptions{N: 3})
+	if err != nil || len(candidates) != 1 || candidates[0] != "only" {
+		t.Errorf("expected a single candidate, got %v, %v", candidates, err)
+	}
+}
@@ -0,0 +1,57 @@
+package llm
+
+import (
+	"slices"
+)
+
+// Capabilities describes what a built-in provider supports beyond text
+// generation
+type Capabilities struct {
+	// SupportsImages is true if GenerateImage is supported
+	SupportsImages bool
+
+	// SupportsEmbeddings is true if GenerateEmbedding is supported without
+	// an EmbeddingLlm
+	SupportsEmbeddings bool
+
+	// SupportsStreaming is true if the client implements StreamInterface
+	SupportsStreaming bool
+
+	// SupportsTools is true if the client implements ToolInterface
+	SupportsTools bool
+
+	// SupportsVision is true if the client implements VisionInterface
+	SupportsVision bool
+}
+
+// providerCapabilities maps each built-in provider to its capabilities.
+// Image support is taken from providerOutputFormats.
+var providerCapabilities = map[Provider]Capabilities{
+	ProviderOpenAI:      {SupportsEmbeddings: true, SupportsStreaming: true, SupportsTools: true, SupportsVision: true},
+	ProviderGemini:      {SupportsEmbeddings: true, SupportsTools: true, SupportsVision: true},
+	ProviderVertex:      {SupportsEmbeddings: true},
+	ProviderMock:        {SupportsEmbeddings: true},
+	ProviderAnthropic:   {SupportsTools: true, SupportsVision: true},
+	ProviderOpenRouter:  {SupportsEmbeddings: true, SupportsStreaming: true, SupportsVision: true},
+	ProviderCustom:      {},
+	ProviderCohere:      {SupportsEmbeddings: true},
+	ProviderMistral:     {SupportsEmbeddings: true},
+	ProviderGroq:        {},
+	ProviderDeepSeek:    {},
+	ProviderHuggingFace: {SupportsEmbeddings: true},
+}
+
+// ProviderCapabilities returns the capabilities of a built-in provider,
+// and false for a provider registered with RegisterProvider. The
+// capabilities are those of the provider: a client returned by NewLLM with a
+// SpendTracker, RequestsPerMinute or Middlewares implements all the optional
+// interfaces, forwarding them to the provider, which falls back or returns
+// an error wrapping ErrNotSupported for the unsupported calls.
+func ProviderCapabilities(provider Provider) (Capabilities, bool) {
+	capabilities, ok := providerCapabilities[provider]
+	if !ok {
+		return Capabilities{}, false
+	}
+	capabilities.SupportsImages = slices.Contains(providerOutputFormats[provider], OutputFormatImagePNG)
+	return capabilities, true
+}
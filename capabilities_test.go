@@ -0,0 +1,45 @@
+package llm
+
+import (
+	"testing"
+)
+
+func TestProviderCapabilities(t *testing.T) {
+	for provider := range providerCapabilities {
+		capabilities, ok := ProviderCapabilities(provider)
+		if !ok {
+			t.Fatalf("%s: expected capabilities", provider)
+		}
+
+		engine, err := NewLLM(LlmOptions{
+			Provider:        provider,
+			ApiKey:          "test-key",
+			Model:           "test-model",
+			ProjectID:       "test-project",
+			ProviderOptions: map[string]any{"url": "http://localhost"},
+		})
+		if err != nil {
+			t.Fatalf("%s: failed to create LLM: %v", provider, err)
+		}
+
+		if _, ok := engine.(StreamInterface); ok != capabilities.SupportsStreaming {
+			t.Errorf("%s: SupportsStreaming is %v but StreamInterface implemented is %v", provider, capabilities.SupportsStreaming, ok)
+		}
+		if _, ok := engine.(ToolInterface); ok != capabilities.SupportsTools {
+			t.Errorf("%s: SupportsTools is %v but ToolInterface implemented is %v", provider, capabilities.SupportsTools, ok)
+		}
+		if _, ok := engine.(VisionInterface); ok != capabilities.SupportsVision {
+			t.Errorf("%s: SupportsVision is %v but VisionInterface implemented is %v", provider, capabilities.SupportsVision, ok)
+		}
+	}
+
+	if capabilities, _ := ProviderCapabilities(ProviderAnthropic); capabilities.SupportsImages || capabilities.SupportsEmbeddings {
+		t.Errorf("expected Anthropic without image and embedding support, got %+v", capabilities)
+	}
+	if capabilities, _ := ProviderCapabilities(ProviderOpenAI); !capabilities.SupportsImages {
+		t.Errorf("expected OpenAI with image support, got %+v", capabilities)
+	}
+	if _, ok := ProviderCapabilities("unknown"); ok {
+		t.Error("expected no capabilities for an unknown provider")
+	}
+}
@@ -0,0 +1,70 @@
+package llm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Category is a label for ClassifyMulti, with a description telling the
+// model when it applies
+type Category struct {
+	Name        string
+	Description string
+}
+
+// classifyResponse is the JSON shape ClassifyMulti asks the model for
+type classifyResponse struct {
+	Categories []string `json:"categories"`
+}
+
+// ClassifyMulti assigns every applicable category to text (multi-label)
+// and returns their names. The returned labels are matched against the
+// category set ignoring case and surrounding whitespace, and returned with
+// the category's own spelling, without duplicates. A label outside the
+// set returns an error wrapping ErrSchemaMismatch.
+func ClassifyMulti(llm LlmInterface, text string, categories []Category, options ...LlmOptions) ([]string, error) {
+	if len(categories) == 0 {
+		return nil, fmt.Errorf("at least one category is required")
+	}
+
+	byName := make(map[string]string, len(categories))
+	var list strings.Builder
+	for _, category := range categories {
+		name := strings.TrimSpace(category.Name)
+		if name == "" {
+			return nil, fmt.Errorf("category name is required")
+		}
+		byName[strings.ToLower(name)] = name
+
+		list.WriteString("- " + name)
+		if description := strings.TrimSpace(category.Description); description != "" {
+			list.WriteString(": " + description)
+		}
+		list.WriteString("\n")
+	}
+
+	systemPrompt := "You are a text classifier. Assign every category that applies to the text, " +
+		"using only the category names listed below. A text may match several categories or none.\n\n" +
+		"Categories:\n" + list.String() + "\n" +
+		`Respond with a JSON object of the form {"categories": ["name", ...]}.`
+
+	response, err := GenerateInto[classifyResponse](llm, systemPrompt, text, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	labels := []string{}
+	seen := map[string]bool{}
+	for _, label := range response.Categories {
+		name, ok := byName[strings.ToLower(strings.TrimSpace(label))]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown category %q", ErrSchemaMismatch, label)
+		}
+		if !seen[name] {
+			seen[name] = true
+			labels = append(labels, name)
+		}
+	}
+
+	return labels, nil
+}
@@ -0,0 +1,55 @@
+package llm
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+var testCategories = []Category{
+	{Name: "billing", Description: "Invoices, payments and refunds"},
+	{Name: "technical", Description: "Bugs, errors and outages"},
+	{Name: "sales", Description: "Pricing and new purchases"},
+}
+
+func TestClassifyMulti(t *testing.T) {
+	engine, err := NewLLM(LlmOptions{
+		Provider:     ProviderMock,
+		MockResponse: "```json\n{\"categories\": [\"Billing\", \" technical \", \"billing\"]}\n```",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create mock LLM: %v", err)
+	}
+
+	labels, err := ClassifyMulti(engine, "I was charged twice and the app crashes", testCategories)
+	if err != nil {
+		t.Fatalf("ClassifyMulti failed: %v", err)
+	}
+	if !reflect.DeepEqual(labels, []string{"billing", "technical"}) {
+		t.Errorf("expected [billing technical], got %v", labels)
+	}
+
+	labels, err = ClassifyMulti(engine, "Hello", testCategories, LlmOptions{MockResponse: `{"categories": []}`})
+	if err != nil {
+		t.Fatalf("ClassifyMulti failed: %v", err)
+	}
+	if len(labels) != 0 {
+		t.Errorf("expected no labels, got %v", labels)
+	}
+}
+
+func TestClassifyMultiUnknownCategory(t *testing.T) {
+	engine, err := NewLLM(LlmOptions{Provider: ProviderMock, MockResponse: `{"categories": ["billing", "shipping"]}`})
+	if err != nil {
+		t.Fatalf("Failed to create mock LLM: %v", err)
+	}
+
+	_, err = ClassifyMulti(engine, "Where is my parcel?", testCategories)
+	if !errors.Is(err, ErrSchemaMismatch) {
+		t.Errorf("expected ErrSchemaMismatch, got %v", err)
+	}
+
+	if _, err := ClassifyMulti(engine, "text", nil); err == nil {
+		t.Error("expected error for no categories")
+	}
+}
@@ -0,0 +1,26 @@
+package llm
+
+import (
+	"io"
+	"net/http"
+)
+
+// Close releases the resources held by llm, such as idle HTTP connections
+// and SDK clients. Callers should defer Close on the clients they create
+// with NewLLM. All the built-in providers implement io.Closer. Other
+// implementations are closed if they implement io.Closer, and otherwise
+// Close returns nil.
+func Close(llm LlmInterface) error {
+	if closer, ok := llm.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// closeIdleConnections closes the idle connections of an HTTP client.
+// Other HTTP doers are left untouched.
+func closeIdleConnections(client any) {
+	if httpClient, ok := client.(*http.Client); ok && httpClient != nil {
+		httpClient.CloseIdleConnections()
+	}
+}
@@ -0,0 +1,60 @@
+package llm
+
+import (
+	"errors"
+	"io"
+	"testing"
+)
+
+// closeCountingLLM counts its Close calls and returns err
+type closeCountingLLM struct {
+	CustomTestLLM
+	closed int
+	err    error
+}
+
+func (c *closeCountingLLM) Close() error {
+	c.closed++
+	return c.err
+}
+
+func TestClose(t *testing.T) {
+	for _, provider := range []Provider{ProviderOpenAI, ProviderOpenRouter, ProviderAnthropic, ProviderMistral, ProviderGroq, ProviderDeepSeek, ProviderCohere, ProviderHuggingFace, ProviderMock} {
+		engine, err := NewLLM(LlmOptions{Provider: provider, ApiKey: "test-key", Model: "test-model"})
+		if err != nil {
+			t.Fatalf("%s: failed to create LLM: %v", provider, err)
+		}
+		if _, ok := engine.(io.Closer); !ok {
+			t.Errorf("%s: expected client to implement io.Closer", provider)
+		}
+		if err := Close(engine); err != nil {
+			t.Errorf("%s: expected Close to succeed, got %v", provider, err)
+		}
+	}
+
+	if err := Close(&CustomTestLLM{}); err != nil {
+		t.Errorf("expected nil for a client without Close, got %v", err)
+	}
+
+	inner := &closeCountingLLM{}
+	wrapped := withSpendTracker(inner, LlmOptions{SpendTracker: NewSpendTracker(1)})
+	if err := Close(wrapped); err != nil || inner.closed != 1 {
+		t.Errorf("expected the spend tracker to close the wrapped client, got closed=%d err=%v", inner.closed, err)
+	}
+}
+
+func TestRegistryClose(t *testing.T) {
+	failing := &closeCountingLLM{err: errors.New("close failed")}
+	registry := &Registry{llms: map[Provider]LlmInterface{
+		ProviderMock:   &closeCountingLLM{},
+		ProviderOpenAI: failing,
+	}}
+
+	err := registry.Close()
+	if err == nil || failing.closed != 1 {
+		t.Fatalf("expected the close error to be returned, got %v", err)
+	}
+	if !errors.Is(err, failing.err) {
+		t.Errorf("expected the error to wrap the client error, got %v", err)
+	}
+}
@@ -0,0 +1,309 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+const cohereDefaultBaseURL = "https://api.cohere.ai/v1"
+const cohereDefaultModel = "command-r-plus"
+const cohereDefaultEmbeddingModel = "embed-english-v3.0"
+
+// cohereImplementation implements LlmInterface for Cohere
+type cohereImplementation struct {
+	apiKey      string
+	baseURL     string
+	model       string
+	maxTokens   int
+	temperature float64
+	verbose     bool
+	logger      *slog.Logger
+	httpClient  *http.Client
+	options     LlmOptions
+	*lastResponseRecorder
+}
+
+// newCohereImplementation creates a new Cohere provider implementation
+func newCohereImplementation(options LlmOptions) (LlmInterface, error) {
+	apiKey := providerAPIKey(options)
+	if apiKey == "" {
+		return nil, fmt.Errorf("cohere API key is required")
+	}
+
+	model := strings.TrimSpace(options.Model)
+	if model == "" {
+		model = DefaultModel(ProviderCohere)
+	}
+
+	httpClient, err := secureHTTPClient(ProviderCohere, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure cohere http client: %w", err)
+	}
+	httpClient = apiKeyHTTPClient(httpClient, options)
+
+	return &cohereImplementation{
+		apiKey:      apiKey,
+		baseURL:     providerBaseURL(options, cohereDefaultBaseURL),
+		model:       model,
+		maxTokens:   options.MaxTokens,
+		temperature: derefFloat64(options.Temperature, 0.7),
+		verbose:     options.Verbose,
+		logger:      options.Logger,
+		httpClient:  httpClient,
+		options:     options,
+
+		lastResponseRecorder: newLastResponseRecorder(options.ProviderOptions),
+	}, nil
+}
+
+// baseOptions returns the construction options, with the struct fields
+// applied on top, as the base for merging per-call options.
+func (c *cohereImplementation) baseOptions() LlmOptions {
+	options := c.options
+	options.Model = c.model
+	options.MaxTokens = c.maxTokens
+	options.Temperature = &c.temperature
+	options.Verbose = c.verbose
+	options.Logger = c.logger
+	return options
+}
+
+// EffectiveOptions implements EffectiveOptionsInterface
+func (c *cohereImplementation) EffectiveOptions(opts ...LlmOptions) LlmOptions {
+	return mergeOptions(c.baseOptions(), firstOptions(opts))
+}
+
+// DebugMessages implements DebugMessagesInterface. The system message is
+// sent as Cohere's preamble, which is omitted when empty.
+func (c *cohereImplementation) DebugMessages(systemPrompt string, userMessage string, opts ...LlmOptions) []Message {
+	perCall := LlmOptions{}
+	if len(opts) > 0 {
+		perCall = opts[0]
+	}
+	systemPrompt = markupSystemPrompt(systemPrompt, mergeOptions(c.baseOptions(), perCall))
+
+	if systemPrompt == "" {
+		return []Message{{Role: MessageRoleUser, Content: userMessage}}
+	}
+	return promptMessages(systemPrompt, userMessage)
+}
+
+// Generate implements LlmInterface
+func (c *cohereImplementation) Generate(systemPrompt string, userMessage string, opts ...LlmOptions) (string, error) {
+	perCall := LlmOptions{}
+	if len(opts) > 0 {
+		perCall = opts[0]
+	}
+	merged := mergeOptions(c.baseOptions(), perCall)
+
+	if override, err := overrideProvider(c.options.Provider, merged, perCall); err != nil {
+		return "", err
+	} else if override != nil {
+		defer Close(override)
+		return override.Generate(systemPrompt, userMessage, perCall)
+	}
+
+	systemPrompt = markupSystemPrompt(systemPrompt, merged)
+
+	if err := checkCostBudget(merged, systemPrompt, userMessage); err != nil {
+		return "", err
+	}
+
+	type requestBody struct {
+		Model          string         `json:"model"`
+		Message        string         `json:"message"`
+		Preamble       string         `json:"preamble,omitempty"`
+		MaxTokens      int            `json:"max_tokens,omitempty"`
+		Temperature    float64        `json:"temperature"`
+		StopSequences  []string       `json:"stop_sequences,omitempty"`
+		ResponseFormat map[string]any `json:"response_format,omitempty"`
+	}
+
+	body := requestBody{
+		Model:         merged.Model,
+		Message:       userMessage,
+		Preamble:      systemPrompt,
+		MaxTokens:     requestMaxTokens(merged),
+		Temperature:   derefFloat64(merged.Temperature, c.temperature),
+		StopSequences: merged.Stop,
+	}
+
+	if merged.OutputFormat == OutputFormatJSON {
+		body.ResponseFormat = map[string]any{"type": "json_object"}
+	}
+
+	ctx, cancel := requestContext(merged)
+	defer cancel()
+
+	var respBody []byte
+	err := withRetry(ctx, merged, func() error {
+		var err error
+		respBody, err = c.post(ctx, "/chat", body)
+		return err
+	})
+	if err != nil {
+		if c.logger != nil {
+			c.logger.Error("Cohere generation error",
+				slog.String("error", err.Error()),
+				slog.String("model", merged.Model))
+		} else if c.verbose {
+			fmt.Printf("Cohere generation error: %v\n", err)
+		}
+		return "", err
+	}
+
+	var parsed struct {
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(respBody, &parsed); err != nil {
+		return "", fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	text := strings.TrimSpace(parsed.Text)
+	if text == "" {
+		return "", fmt.Errorf("%w: cohere returned empty content", ErrEmptyResponse)
+	}
+	return text, nil
+}
+
+// GenerateText implements LlmInterface
+func (c *cohereImplementation) GenerateText(systemPrompt string, userPrompt string, opts ...LlmOptions) (string, error) {
+	perCall := LlmOptions{}
+	if len(opts) > 0 {
+		perCall = opts[0]
+	}
+	perCall.OutputFormat = OutputFormatText
+	return c.Generate(systemPrompt, userPrompt, perCall)
+}
+
+// GenerateJSON implements LlmInterface
+func (c *cohereImplementation) GenerateJSON(systemPrompt string, userPrompt string, opts ...LlmOptions) (string, error) {
+	perCall := LlmOptions{}
+	if len(opts) > 0 {
+		perCall = opts[0]
+	}
+	perCall.OutputFormat = OutputFormatJSON
+	response, err := c.Generate(systemPrompt, userPrompt, perCall)
+	if err != nil {
+		return "", err
+	}
+	return sanitizeJSONResponse(response), nil
+}
+
+// GenerateXML implements LlmInterface
+func (c *cohereImplementation) GenerateXML(systemPrompt string, userPrompt string, opts ...LlmOptions) (string, error) {
+	return generateMarkup(c, OutputFormatXML, systemPrompt, userPrompt, opts...)
+}
+
+// GenerateYAML implements LlmInterface
+func (c *cohereImplementation) GenerateYAML(systemPrompt string, userPrompt string, opts ...LlmOptions) (string, error) {
+	return generateMarkup(c, OutputFormatYAML, systemPrompt, userPrompt, opts...)
+}
+
+// GenerateImage implements LlmInterface
+func (c *cohereImplementation) GenerateImage(prompt string, opts ...LlmOptions) ([]byte, error) {
+	return nil, fmt.Errorf("image generation not supported by Cohere")
+}
+
+// GenerateEmbedding implements LlmInterface
+func (c *cohereImplementation) GenerateEmbedding(text string) ([]float32, error) {
+	if c.options.EmbeddingLlm != nil {
+		return c.options.EmbeddingLlm.GenerateEmbedding(text)
+	}
+
+	// Chat models cannot embed, so only use the configured model
+	// when it is one of Cohere's embed-* models
+	embeddingModel := cohereDefaultEmbeddingModel
+	if strings.HasPrefix(c.model, "embed-") {
+		embeddingModel = c.model
+	}
+
+	body := map[string]any{
+		"model":      embeddingModel,
+		"texts":      []string{text},
+		"input_type": "search_document",
+	}
+
+	ctx, cancel := requestContext(c.options)
+	defer cancel()
+
+	respBody, err := c.post(ctx, "/embed", body)
+	if err != nil {
+		if c.logger != nil {
+			c.logger.Error("Cohere embedding generation error",
+				slog.String("error", err.Error()))
+		} else if c.verbose {
+			fmt.Printf("Cohere embedding generation error: %v\n", err)
+		}
+		return nil, err
+	}
+
+	var parsed struct {
+		Embeddings [][]float64 `json:"embeddings"`
+	}
+	if err := json.Unmarshal(respBody, &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	if len(parsed.Embeddings) == 0 || len(parsed.Embeddings[0]) == 0 {
+		return nil, fmt.Errorf("no embeddings generated")
+	}
+
+	// Convert float64 to float32
+	embeddings := make([]float32, len(parsed.Embeddings[0]))
+	for i, v := range parsed.Embeddings[0] {
+		embeddings[i] = float32(v)
+	}
+
+	return embeddings, nil
+}
+
+// post sends a JSON request to the given Cohere API path and returns the
+// response body. Non-2xx responses are returned as errors carrying Cohere's
+// own error message.
+func (c *cohereImplementation) post(ctx context.Context, path string, body any) ([]byte, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	c.recordHTTP(ProviderCohere, resp, respBody)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, newAPIError(ProviderCohere, resp.StatusCode, respBody)
+	}
+
+	return respBody, nil
+}
+
+// Close implements io.Closer. It closes the idle connections of the
+// HTTP client.
+func (c *cohereImplementation) Close() error {
+	closeIdleConnections(c.httpClient)
+	return nil
+}
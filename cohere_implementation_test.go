@@ -0,0 +1,95 @@
+package llm
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCohereGenerateAndEmbed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-key" {
+			t.Errorf("unexpected Authorization header: %s", r.Header.Get("Authorization"))
+		}
+
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("failed to decode request: %v", err)
+		}
+
+		switch r.URL.Path {
+		case "/chat":
+			if body["model"] != "command-r" {
+				t.Errorf("expected model command-r, got %v", body["model"])
+			}
+			if body["preamble"] != "system" || body["message"] != "hello" {
+				t.Errorf("unexpected prompts: %v", body)
+			}
+			if _, ok := body["response_format"]; !ok {
+				t.Errorf("expected response_format for JSON output")
+			}
+			w.Write([]byte(`{"text":" {\"ok\":true} "}`))
+		case "/embed":
+			if body["model"] != cohereDefaultEmbeddingModel {
+				t.Errorf("expected default embedding model, got %v", body["model"])
+			}
+			w.Write([]byte(`{"embeddings":[[0.5,0.25]]}`))
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	llm, err := NewLLM(LlmOptions{
+		Provider:        ProviderCohere,
+		ApiKey:          "test-key",
+		Model:           "command-r",
+		ProviderOptions: map[string]any{"base_url": server.URL},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create Cohere LLM: %v", err)
+	}
+
+	response, err := llm.GenerateJSON("system", "hello")
+	if err != nil {
+		t.Fatalf("GenerateJSON failed: %v", err)
+	}
+	if response != `{"ok":true}` {
+		t.Errorf("unexpected response: %s", response)
+	}
+
+	embedding, err := llm.GenerateEmbedding("hello")
+	if err != nil {
+		t.Fatalf("GenerateEmbedding failed: %v", err)
+	}
+	if len(embedding) != 2 || embedding[0] != 0.5 || embedding[1] != 0.25 {
+		t.Errorf("unexpected embedding: %v", embedding)
+	}
+}
+
+func TestCohereErrorMessage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"message":"invalid api token"}`))
+	}))
+	defer server.Close()
+
+	llm, err := NewLLM(LlmOptions{
+		Provider:        ProviderCohere,
+		ApiKey:          "bad-key",
+		ProviderOptions: map[string]any{"base_url": server.URL},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create Cohere LLM: %v", err)
+	}
+
+	_, err = llm.GenerateText("system", "hello")
+	if err == nil {
+		t.Fatal("expected error for non-2xx response")
+	}
+	if !strings.Contains(err.Error(), "401") || !strings.Contains(err.Error(), "invalid api token") {
+		t.Errorf("expected Cohere error message in error, got: %v", err)
+	}
+}
@@ -18,11 +18,16 @@ type Provider string
 
 // Supported LLM providers
 const (
-	ProviderOpenAI    Provider = "openai"
-	ProviderGemini    Provider = "gemini"
-	ProviderVertex    Provider = "vertex"
-	ProviderMock      Provider = "mock"
-	ProviderAnthropic Provider = "anthropic"
-	ProviderOpenRouter Provider = "openrouter"
-	ProviderCustom    Provider = "custom"
+	ProviderOpenAI      Provider = "openai"
+	ProviderGemini      Provider = "gemini"
+	ProviderVertex      Provider = "vertex"
+	ProviderMock        Provider = "mock"
+	ProviderAnthropic   Provider = "anthropic"
+	ProviderOpenRouter  Provider = "openrouter"
+	ProviderCustom      Provider = "custom"
+	ProviderCohere      Provider = "cohere"
+	ProviderMistral     Provider = "mistral"
+	ProviderGroq        Provider = "groq"
+	ProviderDeepSeek    Provider = "deepseek"
+	ProviderHuggingFace Provider = "huggingface"
 )
@@ -0,0 +1,87 @@
+package llm
+
+import (
+	"strings"
+)
+
+// modelContextWindows is the context window size (in tokens) of the
+// models, from the sizes listed alongside the model constants in
+// openrouter_models.go
+var modelContextWindows = map[string]int{
+	OPENROUTER_MODEL_GPT_OSS_20B:                    131_072,
+	OPENROUTER_MODEL_GPT_OSS_120B:                   131_072,
+	OPENROUTER_MODEL_O4_MINI:                        200_000,
+	OPENROUTER_MODEL_GPT_4_1_NANO:                   1_047_576,
+	OPENROUTER_MODEL_GPT_5_NANO:                     400_000,
+	OPENROUTER_MODEL_GPT_5_1:                        400_000,
+	OPENROUTER_MODEL_GPT_5_2:                        400_000,
+	OPENROUTER_MODEL_GPT_5_2_CHAT:                   128_000,
+	OPENROUTER_MODEL_GPT_5_2_PRO:                    400_000,
+	OPENROUTER_MODEL_GPT_5_2_CODEX:                  400_000,
+	OPENROUTER_MODEL_CLAUDE_SONNET_4:                1_000_000,
+	OPENROUTER_MODEL_CLAUDE_SONNET_4_5:              1_000_000,
+	OPENROUTER_MODEL_CLAUDE_HAIKU_4_5:               200_000,
+	OPENROUTER_MODEL_CLAUDE_OPUS_4_5:                200_000,
+	OPENROUTER_MODEL_CLAUDE_OPUS_4_6:                1_000_000,
+	OPENROUTER_MODEL_GEMMA_3_12B_IT:                 96_000,
+	OPENROUTER_MODEL_GEMMA_3_27B_IT:                 96_000,
+	OPENROUTER_MODEL_GEMINI_2_5_FLASH_LITE:          1_048_576,
+	OPENROUTER_MODEL_GEMINI_2_5_FLASH:               1_048_576,
+	OPENROUTER_MODEL_GEMINI_2_5_PRO:                 1_048_576,
+	OPENROUTER_MODEL_GEMINI_3_FLASH_PREVIEW:         1_048_576,
+	OPENROUTER_MODEL_GEMINI_3_PRO_PREVIEW:           1_048_576,
+	OPENROUTER_MODEL_MISTRAL_NEMO:                   131_072,
+	OPENROUTER_MODEL_MISTRAL_MEDIUM_3_1:             131_072,
+	OPENROUTER_MODEL_DEVSTRAL_2512:                  262_144,
+	OPENROUTER_MODEL_QWEN_3_235B_A22B_INSTRUCT_2507: 262_144,
+	OPENROUTER_MODEL_QWEN_3_30B_A3B:                 40_960,
+	OPENROUTER_MODEL_QWEN_3_MAX_THINKING:            262_144,
+	OPENROUTER_MODEL_QWEN_3_CODER_NEXT:              262_144,
+	OPENROUTER_MODEL_DEEPSEEK_V3_1:                  163_840,
+	OPENROUTER_MODEL_GROK_3:                         131_072,
+	OPENROUTER_MODEL_GROK_3_MINI:                    131_072,
+	OPENROUTER_MODEL_GROK_4:                         256_000,
+	OPENROUTER_MODEL_KIMI_K2_5:                      262_144,
+	OPENROUTER_MODEL_MINIMAX_M2_1:                   196_608,
+	OPENROUTER_MODEL_SEED_1_6:                       262_144,
+	OPENROUTER_MODEL_SEED_1_6_FLASH:                 262_144,
+	OPENROUTER_MODEL_MIMO_V2_FLASH:                  262_144,
+	OPENROUTER_MODEL_GLM_4_7:                        202_752,
+	OPENROUTER_MODEL_GLM_4_7_FLASH:                  202_752,
+	OPENROUTER_MODEL_STEP_3_5_FLASH:                 256_000,
+	OPENROUTER_MODEL_GEMINI_2_5_FLASH_IMAGE:         1_048_576,
+	OPENROUTER_MODEL_GPT_5_IMAGE_MINI:               1_048_576,
+	OPENROUTER_MODEL_GPT_5_IMAGE:                    400_000,
+}
+
+// ModelContextWindow returns the context window size in tokens of a model,
+// and false if it is unknown. Models are matched exactly first, then by the
+// name without the vendor prefix, so "gpt-5-nano" resolves to the
+// "openai/gpt-5-nano" entry.
+func ModelContextWindow(model string) (int, bool) {
+	model = strings.TrimSpace(model)
+	if size, ok := modelContextWindows[model]; ok {
+		return size, true
+	}
+
+	for id, size := range modelContextWindows {
+		if _, name, found := strings.Cut(id, "/"); found && name == model {
+			return size, true
+		}
+	}
+
+	return 0, false
+}
+
+// AutoMaxTokens returns the tokens left in the model's context window after
+// the prompt, for use as LlmOptions.MaxTokens. It returns 0 (the provider
+// default) when the model's context window is unknown or the prompt fills
+// it. The result may exceed the model's maximum output length.
+func AutoMaxTokens(model string, prompt string) int {
+	size, ok := ModelContextWindow(model)
+	if !ok {
+		return 0
+	}
+
+	return EstimateMaxTokens(CountTokensForModel(prompt, model), size)
+}
@@ -0,0 +1,34 @@
+package llm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestModelContextWindow(t *testing.T) {
+	size, ok := ModelContextWindow(OPENROUTER_MODEL_GPT_5_2_CHAT)
+	if !ok || size != 128_000 {
+		t.Errorf("expected 128000 for %s, got %d (found=%v)", OPENROUTER_MODEL_GPT_5_2_CHAT, size, ok)
+	}
+
+	if size, ok := ModelContextWindow("gpt-5-nano"); !ok || size != 400_000 {
+		t.Errorf("expected model without vendor prefix to resolve, got %d (found=%v)", size, ok)
+	}
+
+	if _, ok := ModelContextWindow("unknown-model"); ok {
+		t.Error("expected unknown model not to resolve")
+	}
+}
+
+func TestAutoMaxTokens(t *testing.T) {
+	prompt := strings.Repeat("hello ", 1000)
+	promptTokens := CountTokensForModel(prompt, OPENROUTER_MODEL_QWEN_3_30B_A3B)
+
+	if got := AutoMaxTokens(OPENROUTER_MODEL_QWEN_3_30B_A3B, prompt); got != 40_960-promptTokens {
+		t.Errorf("expected %d, got %d", 40_960-promptTokens, got)
+	}
+
+	if got := AutoMaxTokens("unknown-model", prompt); got != 0 {
+		t.Errorf("expected 0 for an unknown model, got %d", got)
+	}
+}
@@ -9,8 +9,11 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
+	"strconv"
 	"strings"
-	"time"
+
+	"github.com/spf13/cast"
+	"golang.org/x/net/http/httpguts"
 )
 
 type customImplementation struct {
@@ -22,38 +25,34 @@ type customImplementation struct {
 	verbose     bool
 	logger      *slog.Logger
 	httpClient  *http.Client
+	options     LlmOptions
+	*lastResponseRecorder
 }
 
 func newCustomImplementation(options LlmOptions) (LlmInterface, error) {
-	apiKey := strings.TrimSpace(options.ApiKey)
+	apiKey := providerAPIKey(options)
 
-	endpointURL := ""
-	if options.ProviderOptions != nil {
-		if v, ok := options.ProviderOptions["url"].(string); ok {
-			endpointURL = strings.TrimSpace(v)
-		}
-		if endpointURL == "" {
-			if v, ok := options.ProviderOptions["endpoint_url"].(string); ok {
-				endpointURL = strings.TrimSpace(v)
-			}
-		}
-		if endpointURL == "" {
-			if v, ok := options.ProviderOptions["base_url"].(string); ok {
-				endpointURL = strings.TrimSpace(v)
-			}
-		}
+	if _, err := customHeaders(options.ProviderOptions); err != nil {
+		return nil, err
 	}
 
+	endpointURL := customEndpointURL(options.ProviderOptions)
 	if endpointURL == "" {
 		return nil, fmt.Errorf("endpoint url is required")
 	}
 
 	model := strings.TrimSpace(options.Model)
 	if model == "" {
-		model = "default"
+		model = DefaultModel(ProviderCustom)
 	}
 
-	client := &http.Client{Timeout: 30 * time.Second}
+	client, err := secureHTTPClient(ProviderCustom, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure custom http client: %w", err)
+	}
+	client = apiKeyHTTPClientWith(client, options, func(req *http.Request, key string) {
+		customAuthorize(req, key, options.ProviderOptions)
+	})
 
 	return &customImplementation{
 		apiKey:      apiKey,
@@ -64,54 +63,75 @@ func newCustomImplementation(options LlmOptions) (LlmInterface, error) {
 		verbose:     options.Verbose,
 		logger:      options.Logger,
 		httpClient:  client,
+		options:     options,
+
+		lastResponseRecorder: newLastResponseRecorder(options.ProviderOptions),
 	}, nil
 }
 
-// baseOptions returns the base LlmOptions from the struct fields for merging.
+// baseOptions returns the construction options, with the struct fields
+// applied on top, as the base for merging per-call options.
 func (c *customImplementation) baseOptions() LlmOptions {
-	return LlmOptions{
-		Model:       c.model,
-		MaxTokens:   c.maxTokens,
-		Temperature: &c.temperature,
-		Verbose:     c.verbose,
-		Logger:      c.logger,
-		ProviderOptions: map[string]any{
-			"url": c.endpointURL,
-		},
-	}
+	options := c.options
+	options.Model = c.model
+	options.MaxTokens = c.maxTokens
+	options.Temperature = &c.temperature
+	options.Verbose = c.verbose
+	options.Logger = c.logger
+	return options
 }
 
 func (c *customImplementation) Generate(systemPrompt string, userMessage string, opts ...LlmOptions) (string, error) {
+	return c.GenerateChat(promptMessages(systemPrompt, userMessage), opts...)
+}
+
+// EffectiveOptions implements EffectiveOptionsInterface
+func (c *customImplementation) EffectiveOptions(opts ...LlmOptions) LlmOptions {
+	return mergeOptions(c.baseOptions(), firstOptions(opts))
+}
+
+// DebugMessages implements DebugMessagesInterface
+func (c *customImplementation) DebugMessages(systemPrompt string, userMessage string, opts ...LlmOptions) []Message {
 	perCall := LlmOptions{}
 	if len(opts) > 0 {
 		perCall = opts[0]
 	}
 	merged := mergeOptions(c.baseOptions(), perCall)
 
-	endpointURL := c.endpointURL
-	if merged.ProviderOptions != nil {
-		if v, ok := merged.ProviderOptions["url"].(string); ok {
-			if s := strings.TrimSpace(v); s != "" {
-				endpointURL = s
-			}
-		}
-		if endpointURL == "" {
-			if v, ok := merged.ProviderOptions["endpoint_url"].(string); ok {
-				endpointURL = strings.TrimSpace(v)
-			}
-		}
-		if endpointURL == "" {
-			if v, ok := merged.ProviderOptions["base_url"].(string); ok {
-				endpointURL = strings.TrimSpace(v)
-			}
-		}
+	return markupMessages(promptMessages(systemPrompt, userMessage), merged)
+}
+
+// GenerateChat implements ChatInterface
+func (c *customImplementation) GenerateChat(messages []Message, opts ...LlmOptions) (string, error) {
+	perCall := LlmOptions{}
+	if len(opts) > 0 {
+		perCall = opts[0]
+	}
+	merged := mergeOptions(c.baseOptions(), perCall)
+
+	if override, err := overrideProvider(c.options.Provider, merged, perCall); err != nil {
+		return "", err
+	} else if override != nil {
+		defer Close(override)
+		return GenerateChat(override, messages, perCall)
+	}
+
+	messages = markupMessages(messages, merged)
+
+	if err := checkCostBudget(merged, "", messagesContent(messages)); err != nil {
+		return "", err
+	}
+
+	endpointURL := customEndpointURL(merged.ProviderOptions)
+	if endpointURL == "" {
+		endpointURL = c.endpointURL
 	}
 	if endpointURL == "" {
 		return "", fmt.Errorf("endpoint url is required")
 	}
 
 	model := merged.Model
-	maxTokens := merged.MaxTokens
+	maxTokens := requestMaxTokens(merged)
 	temperature := derefFloat64(merged.Temperature, c.temperature)
 
 	responseFormat := "text"
@@ -119,27 +139,21 @@ func (c *customImplementation) Generate(systemPrompt string, userMessage string,
 		responseFormat = "json_object"
 	}
 
-	type requestMessage struct {
-		Role    string `json:"role"`
-		Content string `json:"content"`
-	}
-
 	type requestBody struct {
-		Model          string           `json:"model"`
-		Messages       []requestMessage `json:"messages"`
-		MaxTokens      int              `json:"max_tokens,omitempty"`
-		Temperature    float64          `json:"temperature,omitempty"`
-		ResponseFormat map[string]any   `json:"response_format,omitempty"`
+		Model          string         `json:"model"`
+		Messages       []Message      `json:"messages"`
+		MaxTokens      int            `json:"max_tokens,omitempty"`
+		Temperature    float64        `json:"temperature,omitempty"`
+		Stop           []string       `json:"stop,omitempty"`
+		ResponseFormat map[string]any `json:"response_format,omitempty"`
 	}
 
 	body := requestBody{
-		Model: model,
-		Messages: []requestMessage{
-			{Role: "system", Content: systemPrompt},
-			{Role: "user", Content: userMessage},
-		},
+		Model:       model,
+		Messages:    messages,
 		MaxTokens:   maxTokens,
 		Temperature: temperature,
+		Stop:        merged.Stop,
 		ResponseFormat: map[string]any{
 			"type": responseFormat,
 		},
@@ -150,35 +164,54 @@ func (c *customImplementation) Generate(systemPrompt string, userMessage string,
 		return "", fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	ctx := context.Background()
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpointURL, bytes.NewReader(payload))
+	ctx, cancel := requestContext(merged)
+	defer cancel()
+
+	var respBody []byte
+	err = withRetry(ctx, merged, func() error {
+		var err error
+		respBody, err = c.send(ctx, endpointURL, payload, merged.ProviderOptions)
+		return err
+	})
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return "", err
 	}
 
-	if strings.TrimSpace(c.apiKey) != "" {
-		req.Header.Set("Authorization", "Bearer "+c.apiKey)
-	}
-	req.Header.Set("Content-Type", "application/json")
+	return customResponseText(respBody, merged.ProviderOptions)
+}
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("request to %s failed: %w", endpointURL, err)
+// customEndpointURL returns the endpoint URL of the "url" provider option,
+// or else of "endpoint_url" or "base_url", or an empty string if none is set
+func customEndpointURL(providerOptions map[string]any) string {
+	for _, key := range []string{"url", "endpoint_url", "base_url"} {
+		if v, ok := providerOptionString(providerOptions, key); ok && strings.TrimSpace(v) != "" {
+			return strings.TrimSpace(v)
+		}
 	}
-	defer resp.Body.Close()
+	return ""
+}
 
-	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+// customResponseText returns the completion of the response body: the
+// field at the dotted ProviderOptions["response_path"] when set, e.g.
+// "result.output.text" or "choices.0.text", and otherwise the content of
+// an OpenAI-compatible response or else the body as plain text. An empty
+// completion is an error wrapping ErrEmptyResponse.
+func customResponseText(respBody []byte, providerOptions map[string]any) (string, error) {
+	text, err := customCompletion(respBody, providerOptions)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
+		return "", err
+	}
+	if strings.TrimSpace(text) == "" {
+		return "", fmt.Errorf("%w: custom endpoint returned empty content", ErrEmptyResponse)
 	}
+	return text, nil
+}
 
-	if resp.StatusCode < 200 || resp.StatusCode > 299 {
-		return "", fmt.Errorf(
-			"request to %s failed with status %d: %s",
-			endpointURL,
-			resp.StatusCode,
-			string(respBody),
-		)
+// customCompletion returns the completion of the response body, see
+// customResponseText
+func customCompletion(respBody []byte, providerOptions map[string]any) (string, error) {
+	if path, ok := providerOptionString(providerOptions, "response_path"); ok && strings.TrimSpace(path) != "" {
+		return jsonPathText(respBody, strings.TrimSpace(path))
 	}
 
 	// OpenAI-compatible response
@@ -204,6 +237,151 @@ func (c *customImplementation) Generate(systemPrompt string, userMessage string,
 	return strings.TrimSpace(string(respBody)), nil
 }
 
+// jsonPathText returns the value at the dotted path of the JSON body, with
+// numeric segments indexing arrays. A string is returned as is, other
+// values as JSON.
+func jsonPathText(body []byte, path string) (string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return "", fmt.Errorf("response is not JSON, cannot read response_path %s: %w", path, err)
+	}
+
+	for _, segment := range strings.Split(path, ".") {
+		switch node := value.(type) {
+		case map[string]any:
+			child, ok := node[segment]
+			if !ok {
+				return "", fmt.Errorf("response_path %s: field %q not found", path, segment)
+			}
+			value = child
+		case []any:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(node) {
+				return "", fmt.Errorf("response_path %s: index %q out of range", path, segment)
+			}
+			value = node[index]
+		default:
+			return "", fmt.Errorf("response_path %s: %q is not an object or array", path, segment)
+		}
+	}
+
+	if text, ok := value.(string); ok {
+		return strings.TrimSpace(text), nil
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("response_path %s: %w", path, err)
+	}
+	return string(encoded), nil
+}
+
+// send posts the payload to the endpoint and returns the response body.
+// Non-2xx responses are returned as an *APIError.
+func (c *customImplementation) send(ctx context.Context, endpointURL string, payload []byte, providerOptions map[string]any) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpointURL, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	headers, err := customHeaders(providerOptions)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range headers {
+		req.Header[name] = values
+	}
+	customAuthorize(req, c.apiKey, providerOptions)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request to %s failed: %w", endpointURL, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	c.recordHTTP(ProviderCustom, resp, respBody)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, newAPIError(ProviderCustom, resp.StatusCode, respBody)
+	}
+
+	return respBody, nil
+}
+
+// customHeaders returns the static headers of ProviderOptions["headers"]
+// (a map[string]string, or a map[string]any of strings), sent with every
+// request. The Content-Type and authentication headers take precedence.
+// Invalid header names or values are an error.
+func customHeaders(providerOptions map[string]any) (http.Header, error) {
+	raw, ok := providerOptions["headers"]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+
+	values, err := cast.ToStringMapStringE(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid headers option: %w", err)
+	}
+
+	headers := http.Header{}
+	for name, value := range values {
+		name = strings.TrimSpace(name)
+		if !httpguts.ValidHeaderFieldName(name) {
+			return nil, fmt.Errorf("invalid header name %q", name)
+		}
+		if !httpguts.ValidHeaderFieldValue(value) {
+			return nil, fmt.Errorf("invalid value for header %s", name)
+		}
+		headers.Set(name, value)
+	}
+	return headers, nil
+}
+
+// customAuthorize adds the API key to the request, if there is one. By
+// default it is sent as "Authorization: Bearer <key>".
+// ProviderOptions["auth_header"] sends it in another header, e.g.
+// "x-api-key" or "api-key", prefixed with ProviderOptions["auth_scheme"]
+// if set (Bearer only applies to the Authorization header), and
+// ProviderOptions["auth_query_param"] sends it as a query parameter
+// instead of a header.
+func customAuthorize(req *http.Request, apiKey string, providerOptions map[string]any) {
+	apiKey = strings.TrimSpace(apiKey)
+	if apiKey == "" {
+		return
+	}
+
+	if param, ok := providerOptionString(providerOptions, "auth_query_param"); ok && strings.TrimSpace(param) != "" {
+		query := req.URL.Query()
+		query.Set(strings.TrimSpace(param), apiKey)
+		req.URL.RawQuery = query.Encode()
+		return
+	}
+
+	header := "Authorization"
+	if v, ok := providerOptionString(providerOptions, "auth_header"); ok && strings.TrimSpace(v) != "" {
+		header = strings.TrimSpace(v)
+	}
+
+	scheme := ""
+	if v, ok := providerOptionString(providerOptions, "auth_scheme"); ok {
+		scheme = strings.TrimSpace(v)
+	} else if http.CanonicalHeaderKey(header) == "Authorization" {
+		scheme = "Bearer"
+	}
+
+	if scheme != "" {
+		req.Header.Set(header, scheme+" "+apiKey)
+		return
+	}
+	req.Header.Set(header, apiKey)
+}
+
 func (c *customImplementation) GenerateText(systemPrompt string, userPrompt string, opts ...LlmOptions) (string, error) {
 	perCall := LlmOptions{}
 	if len(opts) > 0 {
@@ -219,7 +397,21 @@ func (c *customImplementation) GenerateJSON(systemPrompt string, userPrompt stri
 		perCall = opts[0]
 	}
 	perCall.OutputFormat = OutputFormatJSON
-	return c.Generate(systemPrompt, userPrompt, perCall)
+	response, err := c.Generate(systemPrompt, userPrompt, perCall)
+	if err != nil {
+		return "", err
+	}
+	return sanitizeJSONResponse(response), nil
+}
+
+// GenerateXML implements LlmInterface
+func (c *customImplementation) GenerateXML(systemPrompt string, userPrompt string, opts ...LlmOptions) (string, error) {
+	return generateMarkup(c, OutputFormatXML, systemPrompt, userPrompt, opts...)
+}
+
+// GenerateYAML implements LlmInterface
+func (c *customImplementation) GenerateYAML(systemPrompt string, userPrompt string, opts ...LlmOptions) (string, error) {
+	return generateMarkup(c, OutputFormatYAML, systemPrompt, userPrompt, opts...)
 }
 
 func (c *customImplementation) GenerateImage(prompt string, opts ...LlmOptions) ([]byte, error) {
@@ -227,6 +419,10 @@ func (c *customImplementation) GenerateImage(prompt string, opts ...LlmOptions)
 }
 
 func (c *customImplementation) GenerateEmbedding(text string) ([]float32, error) {
+	if c.options.EmbeddingLlm != nil {
+		return c.options.EmbeddingLlm.GenerateEmbedding(text)
+	}
+
 	return nil, fmt.Errorf("embedding generation not supported by custom provider")
 }
 
@@ -237,3 +433,10 @@ func decodeBase64Image(data string) ([]byte, error) {
 	}
 	return base64.StdEncoding.DecodeString(data)
 }
+
+// Close implements io.Closer. It closes the idle connections of the
+// HTTP client.
+func (c *customImplementation) Close() error {
+	closeIdleConnections(c.httpClient)
+	return nil
+}
@@ -0,0 +1,138 @@
+package llm
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCustomResponsePath(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"result":{"outputs":[{"text":" Hello there "}],"meta":{"tokens":3}}}`))
+	}))
+	defer server.Close()
+
+	engine, err := NewLLM(LlmOptions{
+		Provider:        ProviderCustom,
+		ProviderOptions: map[string]any{"url": server.URL, "response_path": "result.outputs.0.text"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create custom LLM: %v", err)
+	}
+
+	response, err := engine.GenerateText("system", "hello")
+	if err != nil {
+		t.Fatalf("GenerateText failed: %v", err)
+	}
+	if response != "Hello there" {
+		t.Errorf("expected the text at the response path, got %q", response)
+	}
+
+	// Non-string values are returned as JSON
+	response, err = engine.GenerateText("system", "hello", LlmOptions{ProviderOptions: map[string]any{"url": server.URL, "response_path": "result.meta"}})
+	if err != nil || response != `{"tokens":3}` {
+		t.Errorf("expected the object as JSON, got %q, %v", response, err)
+	}
+
+	for _, path := range []string{"result.missing", "result.outputs.5.text", "result.outputs.0.text.more"} {
+		if _, err := engine.GenerateText("system", "hello", LlmOptions{ProviderOptions: map[string]any{"url": server.URL, "response_path": path}}); err == nil {
+			t.Errorf("expected an error for response_path %s", path)
+		}
+	}
+}
+
+func TestCustomResponseTextHeuristic(t *testing.T) {
+	text, err := customResponseText([]byte(`{"choices":[{"message":{"role":"assistant","content":" hi "}}]}`), nil)
+	if err != nil || text != "hi" {
+		t.Errorf("expected the OpenAI-compatible content, got %q, %v", text, err)
+	}
+
+	text, err = customResponseText([]byte("plain answer\n"), nil)
+	if err != nil || text != "plain answer" {
+		t.Errorf("expected the plain text body, got %q, %v", text, err)
+	}
+}
+
+func TestCustomAuthorize(t *testing.T) {
+	testCases := []struct {
+		name            string
+		providerOptions map[string]any
+		header          string
+		value           string
+		query           string
+	}{
+		{"default bearer", nil, "Authorization", "Bearer key", ""},
+		{"raw api-key header", map[string]any{"auth_header": "api-key"}, "Api-Key", "key", ""},
+		{"x-api-key with scheme", map[string]any{"auth_header": "x-api-key", "auth_scheme": "Token"}, "X-Api-Key", "Token key", ""},
+		{"raw authorization", map[string]any{"auth_scheme": ""}, "Authorization", "key", ""},
+		{"query parameter", map[string]any{"auth_query_param": "api_key"}, "Authorization", "", "key"},
+	}
+
+	for _, tc := range testCases {
+		req := httptest.NewRequest(http.MethodPost, "http://localhost/v1/chat?x=1", nil)
+		customAuthorize(req, "key", tc.providerOptions)
+
+		if got := req.Header.Get(tc.header); got != tc.value {
+			t.Errorf("%s: expected %s header %q, got %q", tc.name, tc.header, tc.value, got)
+		}
+		if got := req.URL.Query().Get("api_key"); got != tc.query {
+			t.Errorf("%s: expected api_key query %q, got %q", tc.name, tc.query, got)
+		}
+		if req.URL.Query().Get("x") != "1" {
+			t.Errorf("%s: expected the existing query to be kept", tc.name)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "http://localhost/v1/chat", nil)
+	customAuthorize(req, "", nil)
+	if len(req.Header) != 0 {
+		t.Errorf("expected no auth header without a key, got %v", req.Header)
+	}
+}
+
+func TestCustomHeaders(t *testing.T) {
+	var headers http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header.Clone()
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	engine, err := NewLLM(LlmOptions{
+		Provider: ProviderCustom,
+		ApiKey:   "key",
+		ProviderOptions: map[string]any{
+			"url": server.URL,
+			"headers": map[string]string{
+				" X-Tenant-ID ": "acme",
+				"Authorization": "spoofed",
+				"Content-Type":  "text/plain",
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create custom LLM: %v", err)
+	}
+
+	if _, err := engine.GenerateText("system", "hello"); err != nil {
+		t.Fatalf("GenerateText failed: %v", err)
+	}
+	if got := headers.Get("X-Tenant-ID"); got != "acme" {
+		t.Errorf("expected the static header, got %q", got)
+	}
+	if got := headers.Get("Authorization"); got != "Bearer key" {
+		t.Errorf("expected the Authorization header to be kept, got %q", got)
+	}
+	if got := headers.Get("Content-Type"); got != "application/json" {
+		t.Errorf("expected the Content-Type header to be kept, got %q", got)
+	}
+
+	_, err = NewLLM(LlmOptions{
+		Provider:        ProviderCustom,
+		ProviderOptions: map[string]any{"url": server.URL, "headers": map[string]any{"Bad Header": "x"}},
+	})
+	if err == nil {
+		t.Error("expected an error for an invalid header name")
+	}
+}
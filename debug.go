@@ -0,0 +1,29 @@
+package llm
+
+// DebugMessagesInterface is implemented by the built-in providers to show
+// how they assemble a prompt before sending it
+type DebugMessagesInterface interface {
+	// DebugMessages returns the role/content pairs that Generate would send
+	// for the prompt, after the provider's own transforms, such as the JSON
+	// instruction appended to the system prompt. Nothing is sent.
+	DebugMessages(systemPrompt string, userMessage string, options ...LlmOptions) []Message
+}
+
+// DebugMessages returns the messages the provider would send for the
+// prompt, to inspect the per-provider prompt transforms. Providers not
+// implementing DebugMessagesInterface are assumed to send the system and
+// user messages unchanged.
+func DebugMessages(llm LlmInterface, systemPrompt string, userMessage string, options ...LlmOptions) []Message {
+	if debug, ok := llm.(DebugMessagesInterface); ok {
+		return debug.DebugMessages(systemPrompt, userMessage, options...)
+	}
+	return promptMessages(systemPrompt, userMessage)
+}
+
+// promptMessages returns the system and user messages of a prompt
+func promptMessages(systemPrompt string, userMessage string) []Message {
+	return []Message{
+		{Role: MessageRoleSystem, Content: systemPrompt},
+		{Role: MessageRoleUser, Content: userMessage},
+	}
+}
@@ -0,0 +1,79 @@
+package llm
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestDebugMessages(t *testing.T) {
+	openaiEngine := &openaiImplementation{
+		client:               openai.NewClient("test-key"),
+		model:                "gpt-4o-mini",
+		lastResponseRecorder: newLastResponseRecorder(nil),
+	}
+	vertexEngine, err := newVertexImplementation(LlmOptions{ProjectID: "project", Region: "europe-west1"})
+	if err != nil {
+		t.Fatalf("failed to create Vertex LLM: %v", err)
+	}
+	anthropicEngine, err := NewLLM(LlmOptions{Provider: ProviderAnthropic, ApiKey: "test-key"})
+	if err != nil {
+		t.Fatalf("failed to create Anthropic LLM: %v", err)
+	}
+	jsonOptions := LlmOptions{OutputFormat: OutputFormatJSON}
+
+	testCases := []struct {
+		name     string
+		engine   LlmInterface
+		options  []LlmOptions
+		expected []Message
+	}{
+		{"openai", openaiEngine, nil, []Message{
+			{Role: MessageRoleSystem, Content: "Be brief"},
+			{Role: MessageRoleUser, Content: "Hello"},
+		}},
+		// OpenAI enforces JSON with the response format, not the prompt
+		{"openai json", openaiEngine, []LlmOptions{jsonOptions}, []Message{
+			{Role: MessageRoleSystem, Content: "Be brief"},
+			{Role: MessageRoleUser, Content: "Hello"},
+		}},
+		{"vertex", vertexEngine, nil, []Message{
+			{Role: MessageRoleSystem, Content: "Be brief"},
+			{Role: MessageRoleUser, Content: "Hello"},
+		}},
+		{"vertex json", vertexEngine, []LlmOptions{jsonOptions}, []Message{
+			{Role: MessageRoleSystem, Content: "Be brief\nYou must respond with a JSON object only. Do not include any text outside the JSON."},
+			{Role: MessageRoleUser, Content: "Hello"},
+		}},
+		// The system prompt is sent verbatim with DisableJSONInstruction
+		{"vertex json verbatim", vertexEngine, []LlmOptions{{OutputFormat: OutputFormatJSON, DisableJSONInstruction: true}}, []Message{
+			{Role: MessageRoleSystem, Content: "Be brief"},
+			{Role: MessageRoleUser, Content: "Hello"},
+		}},
+		{"anthropic json", anthropicEngine, []LlmOptions{jsonOptions}, []Message{
+			{Role: MessageRoleSystem, Content: "Be brief\n" + jsonInstruction},
+			{Role: MessageRoleUser, Content: "Hello"},
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := DebugMessages(tc.engine, "Be brief", "Hello", tc.options...)
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("DebugMessages() = %#v, expected %#v", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestDebugMessagesFallback(t *testing.T) {
+	got := DebugMessages(&CustomTestLLM{}, "Be brief", "Hello")
+	expected := []Message{
+		{Role: MessageRoleSystem, Content: "Be brief"},
+		{Role: MessageRoleUser, Content: "Hello"},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("DebugMessages() = %#v, expected %#v", got, expected)
+	}
+}
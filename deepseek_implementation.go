@@ -0,0 +1,227 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+const deepseekDefaultBaseURL = "https://api.deepseek.com"
+const deepseekDefaultModel = "deepseek-chat"
+
+// deepseekImplementation implements LlmInterface using DeepSeek's
+// OpenAI-compatible API
+type deepseekImplementation struct {
+	client      *openai.Client
+	httpClient  openai.HTTPDoer
+	model       string
+	maxTokens   int
+	temperature float64
+	verbose     bool
+	logger      *slog.Logger
+	options     LlmOptions
+	*lastResponseRecorder
+}
+
+// newDeepSeekImplementation creates a new DeepSeek provider implementation
+func newDeepSeekImplementation(options LlmOptions) (LlmInterface, error) {
+	apiKey := providerAPIKey(options)
+	if apiKey == "" {
+		return nil, fmt.Errorf("deepseek API key is required")
+	}
+
+	model := strings.TrimSpace(options.Model)
+	if model == "" {
+		model = DefaultModel(ProviderDeepSeek)
+	}
+
+	cfg := openai.DefaultConfig(apiKey)
+	cfg.BaseURL = providerBaseURL(options, deepseekDefaultBaseURL)
+	httpClient, err := secureHTTPClient(ProviderDeepSeek, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure deepseek http client: %w", err)
+	}
+	cfg.HTTPClient = apiKeyHTTPClient(httpClient, options)
+
+	return &deepseekImplementation{
+		client:      openai.NewClientWithConfig(cfg),
+		httpClient:  cfg.HTTPClient,
+		model:       model,
+		maxTokens:   options.MaxTokens,
+		temperature: derefFloat64(options.Temperature, 0.7),
+		verbose:     options.Verbose,
+		logger:      options.Logger,
+		options:     options,
+
+		lastResponseRecorder: newLastResponseRecorder(options.ProviderOptions),
+	}, nil
+}
+
+// baseOptions returns the construction options, with the struct fields
+// applied on top, as the base for merging per-call options.
+func (d *deepseekImplementation) baseOptions() LlmOptions {
+	options := d.options
+	options.Model = d.model
+	options.MaxTokens = d.maxTokens
+	options.Temperature = &d.temperature
+	options.Verbose = d.verbose
+	options.Logger = d.logger
+	return options
+}
+
+// Generate implements LlmInterface
+func (d *deepseekImplementation) Generate(systemPrompt string, userMessage string, opts ...LlmOptions) (string, error) {
+	return d.GenerateChat(promptMessages(systemPrompt, userMessage), opts...)
+}
+
+// EffectiveOptions implements EffectiveOptionsInterface
+func (d *deepseekImplementation) EffectiveOptions(opts ...LlmOptions) LlmOptions {
+	return mergeOptions(d.baseOptions(), firstOptions(opts))
+}
+
+// DebugMessages implements DebugMessagesInterface
+func (d *deepseekImplementation) DebugMessages(systemPrompt string, userMessage string, opts ...LlmOptions) []Message {
+	perCall := LlmOptions{}
+	if len(opts) > 0 {
+		perCall = opts[0]
+	}
+	merged := mergeOptions(d.baseOptions(), perCall)
+
+	return markupMessages(promptMessages(systemPrompt, userMessage), merged)
+}
+
+// GenerateChat implements ChatInterface
+func (d *deepseekImplementation) GenerateChat(messages []Message, opts ...LlmOptions) (string, error) {
+	perCall := LlmOptions{}
+	if len(opts) > 0 {
+		perCall = opts[0]
+	}
+	merged := mergeOptions(d.baseOptions(), perCall)
+
+	if override, err := overrideProvider(d.options.Provider, merged, perCall); err != nil {
+		return "", err
+	} else if override != nil {
+		defer Close(override)
+		return GenerateChat(override, messages, perCall)
+	}
+
+	messages = markupMessages(messages, merged)
+
+	if err := checkCostBudget(merged, "", messagesContent(messages)); err != nil {
+		return "", err
+	}
+
+	ctx, cancel := requestContext(merged)
+	defer cancel()
+
+	model := merged.Model
+
+	// DeepSeek supports json_object but not json_schema response formats
+	responseFormat := &openai.ChatCompletionResponseFormat{
+		Type: openai.ChatCompletionResponseFormatTypeText,
+	}
+	if merged.OutputFormat == OutputFormatJSON {
+		responseFormat.Type = openai.ChatCompletionResponseFormatTypeJSONObject
+	}
+
+	chatMessages, err := openaiChatMessages(messages)
+	if err != nil {
+		return "", err
+	}
+
+	req := openai.ChatCompletionRequest{
+		Model:            model,
+		ResponseFormat:   responseFormat,
+		Messages:         chatMessages,
+		MaxTokens:        requestMaxTokens(merged),
+		Temperature:      float32(derefFloat64(merged.Temperature, d.temperature)),
+		Stop:             merged.Stop,
+		TopP:             float32(derefFloat64(merged.TopP, 0)),
+		PresencePenalty:  float32(derefFloat64(merged.PresencePenalty, 0)),
+		FrequencyPenalty: float32(derefFloat64(merged.FrequencyPenalty, 0)),
+	}
+
+	var resp openai.ChatCompletionResponse
+	err = withRetry(ctx, merged, func() error {
+		var err error
+		resp, err = d.client.CreateChatCompletion(ctx, req)
+		return providerAPIError(ProviderDeepSeek, err)
+	})
+	if err != nil {
+		if d.logger != nil {
+			d.logger.Error("DeepSeek generation error",
+				slog.String("error", err.Error()),
+				slog.String("model", model))
+		} else if d.verbose {
+			fmt.Printf("DeepSeek generation error: %v\n", err)
+		}
+		return "", err
+	}
+	d.recordObject(ctx, ProviderDeepSeek, http.StatusOK, resp.Header(), resp)
+
+	return openaiChoiceContent(ProviderDeepSeek, resp)
+}
+
+// GenerateText implements LlmInterface
+func (d *deepseekImplementation) GenerateText(systemPrompt string, userPrompt string, opts ...LlmOptions) (string, error) {
+	perCall := LlmOptions{}
+	if len(opts) > 0 {
+		perCall = opts[0]
+	}
+	perCall.OutputFormat = OutputFormatText
+	return d.Generate(systemPrompt, userPrompt, perCall)
+}
+
+// GenerateJSON implements LlmInterface
+func (d *deepseekImplementation) GenerateJSON(systemPrompt string, userPrompt string, opts ...LlmOptions) (string, error) {
+	perCall := LlmOptions{}
+	if len(opts) > 0 {
+		perCall = opts[0]
+	}
+	perCall.OutputFormat = OutputFormatJSON
+	response, err := d.Generate(systemPrompt, userPrompt, perCall)
+	if err != nil {
+		return "", err
+	}
+	return sanitizeJSONResponse(response), nil
+}
+
+// GenerateXML implements LlmInterface
+func (d *deepseekImplementation) GenerateXML(systemPrompt string, userPrompt string, opts ...LlmOptions) (string, error) {
+	return generateMarkup(d, OutputFormatXML, systemPrompt, userPrompt, opts...)
+}
+
+// GenerateYAML implements LlmInterface
+func (d *deepseekImplementation) GenerateYAML(systemPrompt string, userPrompt string, opts ...LlmOptions) (string, error) {
+	return generateMarkup(d, OutputFormatYAML, systemPrompt, userPrompt, opts...)
+}
+
+// GenerateStructured implements StructuredOutputInterface
+func (d *deepseekImplementation) GenerateStructured(systemPrompt string, userPrompt string, schema json.RawMessage, opts ...LlmOptions) (json.RawMessage, error) {
+	return generateStructured(d.GenerateJSON, systemPrompt, userPrompt, schema, opts...)
+}
+
+// GenerateImage implements LlmInterface
+func (d *deepseekImplementation) GenerateImage(prompt string, opts ...LlmOptions) ([]byte, error) {
+	return nil, fmt.Errorf("image generation not supported by deepseek provider")
+}
+
+// GenerateEmbedding implements LlmInterface
+func (d *deepseekImplementation) GenerateEmbedding(text string) ([]float32, error) {
+	if d.options.EmbeddingLlm != nil {
+		return d.options.EmbeddingLlm.GenerateEmbedding(text)
+	}
+
+	return nil, fmt.Errorf("embedding generation not supported by deepseek provider")
+}
+
+// Close implements io.Closer. It closes the idle connections of the
+// HTTP client.
+func (d *deepseekImplementation) Close() error {
+	closeIdleConnections(d.httpClient)
+	return nil
+}
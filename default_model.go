@@ -0,0 +1,69 @@
+package llm
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// builtinDefaultModels are the models the providers use when neither the
+// options nor SetDefaultModel name one
+var builtinDefaultModels = map[Provider]string{
+	ProviderOpenAI:      openai.GPT4TurboPreview,
+	ProviderGemini:      GEMINI_MODEL_2_5_FLASH,
+	ProviderVertex:      GEMINI_MODEL_2_5_FLASH,
+	ProviderMock:        "mock-model",
+	ProviderAnthropic:   "claude-3-opus-20240229",
+	ProviderOpenRouter:  "openrouter/auto",
+	ProviderCustom:      "default",
+	ProviderCohere:      cohereDefaultModel,
+	ProviderMistral:     mistralDefaultModel,
+	ProviderGroq:        groqDefaultModel,
+	ProviderDeepSeek:    deepseekDefaultModel,
+	ProviderHuggingFace: huggingfaceDefaultModel,
+}
+
+var (
+	// defaultModelsMu protects defaultModels from concurrent access
+	defaultModelsMu sync.RWMutex
+	// defaultModels maps providers to the default models set by the
+	// application
+	defaultModels = make(map[Provider]string)
+)
+
+// SetDefaultModel sets the model used by the provider when the options do
+// not name one, overriding the built-in default. This lets an application
+// configure its models centrally, e.g. at startup. An empty model restores
+// the built-in default.
+func SetDefaultModel(provider Provider, model string) {
+	defaultModelsMu.Lock()
+	defer defaultModelsMu.Unlock()
+
+	model = strings.TrimSpace(model)
+	if model == "" {
+		delete(defaultModels, provider)
+		return
+	}
+	defaultModels[provider] = model
+}
+
+// DefaultModel returns the model used by the provider when the options do
+// not name one: the one set with SetDefaultModel, or else the built-in
+// default. It returns an empty string for a provider without a default.
+func DefaultModel(provider Provider) string {
+	if model, ok := configuredDefaultModel(provider); ok {
+		return model
+	}
+	return builtinDefaultModels[provider]
+}
+
+// configuredDefaultModel returns the default model set with SetDefaultModel
+// for the provider, if any
+func configuredDefaultModel(provider Provider) (string, bool) {
+	defaultModelsMu.RLock()
+	defer defaultModelsMu.RUnlock()
+
+	model, ok := defaultModels[provider]
+	return model, ok
+}
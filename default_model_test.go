@@ -0,0 +1,49 @@
+package llm
+
+import "testing"
+
+func TestDefaultModel(t *testing.T) {
+	if got := DefaultModel(ProviderOpenRouter); got != "openrouter/auto" {
+		t.Errorf("expected the built-in OpenRouter default, got %q", got)
+	}
+	if got := DefaultModel(Provider("unknown")); got != "" {
+		t.Errorf("expected no default for an unknown provider, got %q", got)
+	}
+
+	SetDefaultModel(ProviderMock, "configured-model")
+	defer SetDefaultModel(ProviderMock, "")
+
+	if got := DefaultModel(ProviderMock); got != "configured-model" {
+		t.Errorf("expected the configured default, got %q", got)
+	}
+
+	engine, err := NewLLM(LlmOptions{Provider: ProviderMock})
+	if err != nil {
+		t.Fatalf("Failed to create mock LLM: %v", err)
+	}
+	if got := engine.(*mockImplementation).options.Model; got != "configured-model" {
+		t.Errorf("expected the constructor to use the configured default, got %q", got)
+	}
+
+	SetDefaultModel(ProviderMock, "")
+	if got := DefaultModel(ProviderMock); got != "mock-model" {
+		t.Errorf("expected the built-in default after clearing, got %q", got)
+	}
+}
+
+func TestDefaultModelInTextModel(t *testing.T) {
+	if _, err := TextModel(ProviderOpenAI, LlmOptions{ApiKey: "test-key"}); err == nil {
+		t.Fatal("expected an error without a model")
+	}
+
+	SetDefaultModel(ProviderOpenAI, "gpt-4.1")
+	defer SetDefaultModel(ProviderOpenAI, "")
+
+	engine, err := TextModel(ProviderOpenAI, LlmOptions{ApiKey: "test-key"})
+	if err != nil {
+		t.Fatalf("expected the configured default to satisfy the model check, got %v", err)
+	}
+	if got := engine.(*openaiImplementation).model; got != "gpt-4.1" {
+		t.Errorf("expected the configured default, got %q", got)
+	}
+}
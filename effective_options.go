@@ -0,0 +1,21 @@
+package llm
+
+// EffectiveOptionsInterface is implemented by the built-in providers to
+// show the options a call uses
+type EffectiveOptionsInterface interface {
+	// EffectiveOptions returns the options a call with the given per-call
+	// options uses: the client's options, with the defaults applied at
+	// construction, merged with the per-call options. Nothing is sent.
+	EffectiveOptions(options ...LlmOptions) LlmOptions
+}
+
+// EffectiveOptions returns the options a call to the client with the given
+// per-call options uses, e.g. to check the model and temperature after the
+// defaults and merges. Providers not implementing EffectiveOptionsInterface
+// are assumed to use the per-call options as given.
+func EffectiveOptions(llm LlmInterface, options ...LlmOptions) LlmOptions {
+	if effective, ok := llm.(EffectiveOptionsInterface); ok {
+		return effective.EffectiveOptions(options...)
+	}
+	return firstOptions(options)
+}
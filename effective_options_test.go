@@ -0,0 +1,47 @@
+package llm
+
+import "testing"
+
+func TestEffectiveOptions(t *testing.T) {
+	engine, err := TextModel(ProviderOpenAI, LlmOptions{ApiKey: "test-key", Model: "gpt-4.1"})
+	if err != nil {
+		t.Fatalf("Failed to create OpenAI LLM: %v", err)
+	}
+
+	options := EffectiveOptions(engine)
+	if options.Model != "gpt-4.1" || options.MaxTokens != 4096 || options.Temperature == nil || *options.Temperature != 0.7 {
+		t.Errorf("expected the construction defaults, got model %q, max tokens %d, temperature %v", options.Model, options.MaxTokens, options.Temperature)
+	}
+
+	options = EffectiveOptions(engine, LlmOptions{Model: "gpt-4.1-mini", Temperature: PtrFloat64(0.2)})
+	if options.Model != "gpt-4.1-mini" || options.MaxTokens != 4096 || *options.Temperature != 0.2 {
+		t.Errorf("expected the per-call options merged, got model %q, max tokens %d, temperature %v", options.Model, options.MaxTokens, *options.Temperature)
+	}
+
+	// Providers without EffectiveOptionsInterface use the per-call options
+	options = EffectiveOptions(&CustomTestLLM{}, LlmOptions{Model: "custom"})
+	if options.Model != "custom" {
+		t.Errorf("expected the per-call options, got model %q", options.Model)
+	}
+}
+
+func TestEffectiveOptionsBuiltinProviders(t *testing.T) {
+	for provider := range providerCapabilities {
+		engine, err := NewLLM(LlmOptions{
+			Provider:        provider,
+			ApiKey:          "test-key",
+			ProjectID:       "test-project",
+			ProviderOptions: map[string]any{"url": "http://localhost"},
+		})
+		if err != nil {
+			t.Fatalf("%s: failed to create LLM: %v", provider, err)
+		}
+
+		if _, ok := engine.(EffectiveOptionsInterface); !ok {
+			t.Errorf("%s: EffectiveOptionsInterface not implemented", provider)
+		}
+		if model := EffectiveOptions(engine).Model; model != DefaultModel(provider) {
+			t.Errorf("%s: expected the default model %q, got %q", provider, DefaultModel(provider), model)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package llm
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrContentBlocked is returned when the provider blocks the prompt or the
+// response, e.g. for safety reasons
+var ErrContentBlocked = errors.New("content blocked by provider")
+
+// ErrCostExceeded is returned when the estimated cost of a call is above
+// the LlmOptions.MaxCostUSD budget. The call is not sent to the provider.
+var ErrCostExceeded = errors.New("estimated cost exceeds budget")
+
+// ErrBudgetExhausted is returned by a client with a SpendTracker once the
+// tracker's ceiling has been reached. The call is not sent to the provider.
+var ErrBudgetExhausted = errors.New("spend budget exhausted")
+
+// ErrMaxTokensReached is returned when the response was cut off at the
+// token limit. Providers return the truncated text along with it.
+var ErrMaxTokensReached = errors.New("max tokens reached")
+
+// ErrInvalidImage is returned when image data is empty or cannot be
+// decoded as an image
+var ErrInvalidImage = errors.New("invalid image")
+
+// ErrModelNotFound is matched by errors.Is for a *ModelNotFoundError
+var ErrModelNotFound = errors.New("model not found")
+
+// ModelNotFoundError is returned when the provider rejects the requested
+// model as unknown or unavailable. Use errors.As to read the model.
+type ModelNotFoundError struct {
+	// Provider is the provider that rejected the model
+	Provider Provider
+
+	// Model is the model that was requested
+	Model string
+
+	// Err is the original provider error
+	Err error
+}
+
+// Error implements error
+func (e *ModelNotFoundError) Error() string {
+	return fmt.Sprintf("model %s is not available on %s", e.Model, e.Provider)
+}
+
+// Is makes errors.Is(err, ErrModelNotFound) match
+func (e *ModelNotFoundError) Is(target error) bool {
+	return target == ErrModelNotFound
+}
+
+// Unwrap returns the original provider error
+func (e *ModelNotFoundError) Unwrap() error {
+	return e.Err
+}
+
+// ErrNotSupported is returned when the provider does not support the
+// requested capability
+var ErrNotSupported = errors.New("not supported by provider")
+
+// ErrSchemaMismatch is returned by GenerateStructured when the response
+// does not conform to the requested JSON schema
+var ErrSchemaMismatch = errors.New("response does not match schema")
+
+// ErrInvalidJSON is returned by GenerateValidJSON when the model did not
+// produce valid JSON within the repair attempts
+var ErrInvalidJSON = errors.New("invalid JSON response")
+
+// ErrEmptyResponse is returned when the response has no text content, no
+// tool calls and no refusal
+var ErrEmptyResponse = errors.New("empty response from provider")
+
+// ErrNoContent is matched by errors.Is for a *NoContentError
+var ErrNoContent = errors.New("response has no text content")
+
+// ToolCall is a tool call requested by the model
+type ToolCall struct {
+	// ID is the provider's identifier for the call
+	ID string
+
+	// Name is the name of the called tool
+	Name string
+
+	// Arguments is the JSON encoded arguments of the call
+	Arguments string
+}
+
+// NoContentError is returned when the response has no text content because
+// the model called tools or refused. Use errors.As to read them.
+type NoContentError struct {
+	// Provider is the provider that returned the response
+	Provider Provider
+
+	// ToolCalls are the tool calls made instead of a text response
+	ToolCalls []ToolCall
+
+	// Refusal is the refusal message, if the model refused
+	Refusal string
+}
+
+// Error implements error
+func (e *NoContentError) Error() string {
+	if e.Refusal != "" {
+		return fmt.Sprintf("%s refused: %s", e.Provider, e.Refusal)
+	}
+	return fmt.Sprintf("%s responded with %d tool call(s) and no text content", e.Provider, len(e.ToolCalls))
+}
+
+// Is makes errors.Is(err, ErrNoContent) match
+func (e *NoContentError) Is(target error) bool {
+	return target == ErrNoContent
+}
@@ -0,0 +1,276 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	vertexgenai "cloud.google.com/go/vertexai/genai"
+	"github.com/sashabaranov/go-openai"
+	"google.golang.org/genai"
+)
+
+// redirectTransport sends every request to the test server
+type redirectTransport struct {
+	target *url.URL
+}
+
+func (t redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req.URL.Scheme = t.target.Scheme
+	req.URL.Host = t.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestOpenaiModelNotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":{"message":"The model 'gpt-9' does not exist","type":"invalid_request_error","code":"model_not_found"}}`))
+	}))
+	defer server.Close()
+
+	config := openai.DefaultConfig("test-key")
+	config.BaseURL = server.URL
+	engine := &openaiImplementation{
+		client:               openai.NewClientWithConfig(config),
+		model:                "gpt-9",
+		lastResponseRecorder: newLastResponseRecorder(nil),
+	}
+
+	_, err := engine.GenerateText("system", "hello")
+	if !errors.Is(err, ErrModelNotFound) {
+		t.Fatalf("expected ErrModelNotFound, got %v", err)
+	}
+
+	var notFound *ModelNotFoundError
+	if !errors.As(err, &notFound) {
+		t.Fatalf("expected *ModelNotFoundError, got %T", err)
+	}
+	if notFound.Model != "gpt-9" || notFound.Provider != ProviderOpenAI {
+		t.Errorf("unexpected error details: %+v", notFound)
+	}
+}
+
+func TestOpenaiNoContentResponses(t *testing.T) {
+	responses := map[string]string{
+		"tool_calls": `{"choices":[{"message":{"role":"assistant","content":"","tool_calls":[{"id":"call_1","type":"function","function":{"name":"get_weather","arguments":"{\"city\":\"Paris\"}"}}]},"finish_reason":"tool_calls"}]}`,
+		"refusal":    `{"choices":[{"message":{"role":"assistant","content":"","refusal":"I can't help with that"}}]}`,
+		"empty":      `{"choices":[{"message":{"role":"assistant","content":"  "}}]}`,
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(responses[r.Header.Get("X-Case")]))
+	}))
+	defer server.Close()
+
+	newEngine := func(testCase string) *openaiImplementation {
+		config := openai.DefaultConfig("test-key")
+		config.BaseURL = server.URL
+		config.HTTPClient = &http.Client{Transport: headerTransport{"X-Case", testCase}}
+		return &openaiImplementation{
+			client:               openai.NewClientWithConfig(config),
+			model:                "gpt-4o",
+			lastResponseRecorder: newLastResponseRecorder(nil),
+		}
+	}
+
+	_, err := newEngine("tool_calls").GenerateText("system", "weather?")
+	var noContent *NoContentError
+	if !errors.As(err, &noContent) || !errors.Is(err, ErrNoContent) {
+		t.Fatalf("expected *NoContentError, got %v", err)
+	}
+	if len(noContent.ToolCalls) != 1 || noContent.ToolCalls[0].Name != "get_weather" || noContent.ToolCalls[0].Arguments != `{"city":"Paris"}` {
+		t.Errorf("unexpected tool calls: %+v", noContent.ToolCalls)
+	}
+
+	_, err = newEngine("refusal").GenerateText("system", "hello")
+	if !errors.As(err, &noContent) || noContent.Refusal != "I can't help with that" {
+		t.Errorf("expected refusal *NoContentError, got %v", err)
+	}
+
+	_, err = newEngine("empty").GenerateText("system", "hello")
+	if !errors.Is(err, ErrEmptyResponse) || errors.Is(err, ErrNoContent) {
+		t.Errorf("expected ErrEmptyResponse, got %v", err)
+	}
+}
+
+// headerTransport sets a header on every request
+type headerTransport struct {
+	name  string
+	value string
+}
+
+func (t headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req.Header.Set(t.name, t.value)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestAnthropicModelNotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"type":"error","error":{"type":"not_found_error","message":"model: claude-9"}}`))
+	}))
+	defer server.Close()
+
+	target, _ := url.Parse(server.URL)
+	engine, err := NewLLM(LlmOptions{Provider: ProviderAnthropic, ApiKey: "test-key", Model: "claude-9"})
+	if err != nil {
+		t.Fatalf("failed to create Anthropic LLM: %v", err)
+	}
+	engine.(*anthropicImplementation).httpClient = &http.Client{Transport: redirectTransport{target: target}}
+
+	_, err = engine.GenerateText("system", "hello")
+	var notFound *ModelNotFoundError
+	if !errors.As(err, &notFound) {
+		t.Fatalf("expected *ModelNotFoundError, got %v", err)
+	}
+	if notFound.Model != "claude-9" || notFound.Provider != ProviderAnthropic {
+		t.Errorf("unexpected error details: %+v", notFound)
+	}
+	if !errors.Is(err, ErrModelNotFound) {
+		t.Error("expected errors.Is to match ErrModelNotFound")
+	}
+}
+
+func TestAnthropicOtherErrorsAreNotModelNotFound(t *testing.T) {
+	if anthropicModelNotFound([]byte(`{"type":"error","error":{"type":"invalid_request_error","message":"max_tokens too large"}}`)) {
+		t.Error("expected invalid_request_error not to be treated as model not found")
+	}
+}
+
+// newTestGemini returns a Gemini implementation sending its requests to the
+// test server
+func newTestGemini(t *testing.T, serverURL string) *geminiImplementation {
+	t.Helper()
+	client, err := genai.NewClient(context.Background(), &genai.ClientConfig{
+		APIKey:      "test-key",
+		Backend:     genai.BackendGeminiAPI,
+		HTTPOptions: genai.HTTPOptions{BaseURL: serverURL},
+	})
+	if err != nil {
+		t.Fatalf("failed to create genai client: %v", err)
+	}
+	return &geminiImplementation{
+		client:               client,
+		model:                GEMINI_MODEL_2_5_FLASH,
+		lastResponseRecorder: newLastResponseRecorder(nil),
+	}
+}
+
+func TestGeminiFinishReasonErrors(t *testing.T) {
+	var captured map[string]any
+
+	blocked := captureServer(t, `{"candidates":[{"finishReason":"SAFETY","safetyRatings":[{"category":"HARM_CATEGORY_HARASSMENT","probability":"HIGH","blocked":true}]}]}`, &captured)
+	defer blocked.Close()
+	_, err := newTestGemini(t, blocked.URL).GenerateText("system", "hello")
+	if !errors.Is(err, ErrContentBlocked) {
+		t.Fatalf("expected ErrContentBlocked, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "HARM_CATEGORY_HARASSMENT") {
+		t.Errorf("expected the blocked category in the error, got %v", err)
+	}
+
+	promptBlocked := captureServer(t, `{"promptFeedback":{"blockReason":"PROHIBITED_CONTENT"}}`, &captured)
+	defer promptBlocked.Close()
+	if _, err := newTestGemini(t, promptBlocked.URL).GenerateText("system", "hello"); !errors.Is(err, ErrContentBlocked) {
+		t.Errorf("expected ErrContentBlocked for a blocked prompt, got %v", err)
+	}
+
+	truncated := captureServer(t, `{"candidates":[{"content":{"role":"model","parts":[{"text":"Once upon"}]},"finishReason":"MAX_TOKENS"}]}`, &captured)
+	defer truncated.Close()
+	response, err := newTestGemini(t, truncated.URL).GenerateText("system", "hello")
+	if !errors.Is(err, ErrMaxTokensReached) {
+		t.Fatalf("expected ErrMaxTokensReached, got %v", err)
+	}
+	if response != "Once upon" {
+		t.Errorf("expected the truncated text, got %q", response)
+	}
+}
+
+func TestVertexFinishError(t *testing.T) {
+	resp := &vertexgenai.GenerateContentResponse{
+		Candidates: []*vertexgenai.Candidate{{
+			FinishReason: vertexgenai.FinishReasonProhibitedContent,
+		}},
+	}
+	if err := vertexFinishError(resp); !errors.Is(err, ErrContentBlocked) {
+		t.Errorf("expected ErrContentBlocked, got %v", err)
+	}
+
+	resp.Candidates[0].FinishReason = vertexgenai.FinishReasonMaxTokens
+	if err := vertexFinishError(resp); !errors.Is(err, ErrMaxTokensReached) {
+		t.Errorf("expected ErrMaxTokensReached, got %v", err)
+	}
+
+	resp.Candidates[0].FinishReason = vertexgenai.FinishReasonStop
+	if err := vertexFinishError(resp); err != nil {
+		t.Errorf("expected no error for STOP, got %v", err)
+	}
+}
+
+// TestEmptyResponses checks that every provider returns an error wrapping
+// ErrEmptyResponse, and no text, for a response without text
+func TestEmptyResponses(t *testing.T) {
+	openaiBody := `{"choices":[{"message":{"role":"assistant","content":"  "}}]}`
+	tests := []struct {
+		provider Provider
+		body     string
+	}{
+		{ProviderOpenAI, openaiBody},
+		{ProviderOpenRouter, openaiBody},
+		{ProviderMistral, openaiBody},
+		{ProviderGroq, openaiBody},
+		{ProviderDeepSeek, openaiBody},
+		{ProviderCustom, openaiBody},
+		{ProviderAnthropic, `{"content":[{"type":"text","text":""}],"stop_reason":"end_turn"}`},
+		{ProviderCohere, `{"text":""}`},
+		{ProviderHuggingFace, `[{"generated_text":""}]`},
+		{ProviderGemini, `{"candidates":[{"content":{"role":"model","parts":[{"text":""}]},"finishReason":"STOP"}]}`},
+		{ProviderMock, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.provider), func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(test.body))
+			}))
+			defer server.Close()
+
+			var engine LlmInterface
+			switch test.provider {
+			case ProviderGemini:
+				engine = newTestGemini(t, server.URL)
+			case ProviderAnthropic:
+				originalURL := anthropicAPIURL
+				anthropicAPIURL = server.URL
+				defer func() { anthropicAPIURL = originalURL }()
+				fallthrough
+			default:
+				var err error
+				engine, err = NewLLM(LlmOptions{
+					Provider:        test.provider,
+					ApiKey:          "test-key",
+					Model:           "test-model",
+					ProviderOptions: map[string]any{"base_url": server.URL},
+				})
+				if err != nil {
+					t.Fatalf("failed to create %s LLM: %v", test.provider, err)
+				}
+			}
+
+			text, err := engine.GenerateText("system", "hello")
+			if !errors.Is(err, ErrEmptyResponse) {
+				t.Errorf("expected ErrEmptyResponse, got %v", err)
+			}
+			if text != "" {
+				t.Errorf("expected no text, got %q", text)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package llm
 
 import (
 	"fmt"
+	"slices"
 )
 
 // TextModel creates an LLM model for text output
@@ -25,24 +26,20 @@ func createProvider(provider Provider, outputFormat OutputFormat, options LlmOpt
 	options.Provider = provider
 	options.OutputFormat = outputFormat
 
-	if provider == ProviderOpenAI && options.ApiKey == "" {
-		return nil, fmt.Errorf("openai api key is required")
+	if llm, ok := testModeLLM(options); ok {
+		return withMiddlewares(withSpendTracker(withRateLimiter(llm, options), options), llm, options), nil
 	}
 
-	if provider == ProviderGemini && options.ApiKey == "" {
-		return nil, fmt.Errorf("google gemini api key is required")
+	if err := validateCredentials(provider, options); err != nil {
+		return nil, err
 	}
 
-	if provider == ProviderVertex && options.ProjectID == "" {
-		return nil, fmt.Errorf("vertexai project id is required")
+	if err := validateOutputFormat(provider, outputFormat); err != nil {
+		return nil, err
 	}
 
-	if provider == ProviderAnthropic && options.ApiKey == "" {
-		return nil, fmt.Errorf("anthropic api key is required")
-	}
-
-	if provider == ProviderOpenRouter && options.ApiKey == "" {
-		return nil, fmt.Errorf("openrouter api key is required")
+	if options.Model == "" {
+		options.Model, _ = configuredDefaultModel(provider)
 	}
 
 	// Skip model check for mock provider
@@ -67,3 +64,93 @@ func createProvider(provider Provider, outputFormat OutputFormat, options LlmOpt
 
 	return NewLLM(options)
 }
+
+// validateCredentials checks that the credentials required by the provider
+// are set
+func validateCredentials(provider Provider, options LlmOptions) error {
+	if isTestMode() {
+		return nil
+	}
+
+	if provider == ProviderOpenAI && providerAPIKey(options) == "" {
+		return fmt.Errorf("openai api key is required")
+	}
+
+	if provider == ProviderGemini && options.ApiKey == "" {
+		return fmt.Errorf("google gemini api key is required")
+	}
+
+	if provider == ProviderVertex && options.ProjectID == "" {
+		return fmt.Errorf("vertexai project id is required")
+	}
+
+	if provider == ProviderAnthropic && providerAPIKey(options) == "" {
+		return fmt.Errorf("anthropic api key is required")
+	}
+
+	if provider == ProviderOpenRouter && providerAPIKey(options) == "" {
+		return fmt.Errorf("openrouter api key is required")
+	}
+
+	if provider == ProviderCohere && providerAPIKey(options) == "" {
+		return fmt.Errorf("cohere api key is required")
+	}
+
+	if provider == ProviderMistral && providerAPIKey(options) == "" {
+		return fmt.Errorf("mistral api key is required")
+	}
+
+	if provider == ProviderGroq && providerAPIKey(options) == "" {
+		return fmt.Errorf("groq api key is required")
+	}
+
+	if provider == ProviderDeepSeek && providerAPIKey(options) == "" {
+		return fmt.Errorf("deepseek api key is required")
+	}
+
+	if provider == ProviderHuggingFace && providerAPIKey(options) == "" {
+		return fmt.Errorf("huggingface api token is required")
+	}
+
+	return nil
+}
+
+// textOutputFormats are the output formats every built-in provider
+// supports, natively or through a system prompt instruction
+var textOutputFormats = []OutputFormat{OutputFormatText, OutputFormatJSON, OutputFormatXML, OutputFormatYAML}
+
+// imageOutputFormats are the output formats of image generation
+var imageOutputFormats = []OutputFormat{OutputFormatImagePNG, OutputFormatImageJPG}
+
+// providerOutputFormats maps each built-in provider to the output formats
+// it supports. Providers registered with RegisterProvider are not listed
+// and accept any output format.
+var providerOutputFormats = map[Provider][]OutputFormat{
+	ProviderOpenAI:      slices.Concat(textOutputFormats, imageOutputFormats),
+	ProviderGemini:      textOutputFormats,
+	ProviderVertex:      slices.Concat(textOutputFormats, []OutputFormat{OutputFormatEnum}, imageOutputFormats),
+	ProviderMock:        slices.Concat(textOutputFormats, []OutputFormat{OutputFormatEnum}, imageOutputFormats),
+	ProviderAnthropic:   textOutputFormats,
+	ProviderOpenRouter:  slices.Concat(textOutputFormats, imageOutputFormats),
+	ProviderCustom:      textOutputFormats,
+	ProviderCohere:      textOutputFormats,
+	ProviderMistral:     textOutputFormats,
+	ProviderGroq:        textOutputFormats,
+	ProviderDeepSeek:    textOutputFormats,
+	ProviderHuggingFace: textOutputFormats,
+}
+
+// validateOutputFormat checks that the provider supports the output
+// format. An empty format (the provider's default) is always valid.
+func validateOutputFormat(provider Provider, format OutputFormat) error {
+	formats, known := providerOutputFormats[provider]
+	if format == "" || !known || slices.Contains(formats, format) {
+		return nil
+	}
+
+	kind := string(format)
+	if slices.Contains(imageOutputFormats, format) {
+		kind = "image"
+	}
+	return fmt.Errorf("provider %s does not support %s output: %w", provider, kind, ErrNotSupported)
+}
@@ -2,6 +2,8 @@ package llm
 
 import (
 	"errors"
+	"fmt"
+	"sync"
 	"testing"
 )
 
@@ -99,11 +101,11 @@ func TestMockLLM(t *testing.T) {
 		t.Errorf("Mock LLM GenerateImage failed: %v", err)
 	}
 
-	// Test empty user message returns empty (using mock without default MockResponse)
+	// Test a mock without a response returns ErrEmptyResponse, like the providers
 	emptyMock, _ := newMockImplementation(LlmOptions{})
 	emptyResponse, err := emptyMock.Generate("system prompt", "")
-	if err != nil {
-		t.Errorf("Mock LLM Generate with empty message failed: %v", err)
+	if !errors.Is(err, ErrEmptyResponse) {
+		t.Errorf("Mock LLM Generate without a response should return ErrEmptyResponse, got: %v", err)
 	}
 	if emptyResponse != "" {
 		t.Errorf("Mock LLM should return empty for empty user message, got: %s", emptyResponse)
@@ -177,6 +179,24 @@ func (c *CustomTestLLM) GenerateJSON(systemPrompt, userPrompt string, opts ...Ll
 	return c.Generate(systemPrompt, userPrompt, options)
 }
 
+func (c *CustomTestLLM) GenerateXML(systemPrompt, userPrompt string, opts ...LlmOptions) (string, error) {
+	options := LlmOptions{}
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+	options.OutputFormat = OutputFormatXML
+	return c.Generate(systemPrompt, userPrompt, options)
+}
+
+func (c *CustomTestLLM) GenerateYAML(systemPrompt, userPrompt string, opts ...LlmOptions) (string, error) {
+	options := LlmOptions{}
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+	options.OutputFormat = OutputFormatYAML
+	return c.Generate(systemPrompt, userPrompt, options)
+}
+
 func (c *CustomTestLLM) GenerateImage(prompt string, opts ...LlmOptions) ([]byte, error) {
 	return []byte("test image data"), nil
 }
@@ -280,7 +300,7 @@ func TestOptionsMerging(t *testing.T) {
 
 // TestOutputFormats tests that output formats are correctly handled
 func TestOutputFormats(t *testing.T) {
-	mockLLM, _ := newMockImplementation(LlmOptions{})
+	mockLLM, _ := newMockImplementation(LlmOptions{MockResponse: `{"ok":true}`})
 
 	// Test text format
 	_, err := mockLLM.GenerateText("test", "test", LlmOptions{})
@@ -300,3 +320,172 @@ func TestOutputFormats(t *testing.T) {
 		t.Errorf("GenerateImage failed: %v", err)
 	}
 }
+
+// TestUnsupportedOutputFormat tests that unsupported provider and output
+// format combinations are rejected at construction
+func TestUnsupportedOutputFormat(t *testing.T) {
+	_, err := ImageModel(ProviderAnthropic, LlmOptions{ApiKey: "test-key", Model: "claude-sonnet-4"})
+	if !errors.Is(err, ErrNotSupported) {
+		t.Fatalf("expected ErrNotSupported, got %v", err)
+	}
+	if err.Error() != "provider anthropic does not support image output: not supported by provider" {
+		t.Errorf("unexpected error message: %v", err)
+	}
+
+	_, err = NewLLM(LlmOptions{Provider: ProviderOpenAI, ApiKey: "test-key", Model: "gpt-4.1", OutputFormat: OutputFormatEnum})
+	if !errors.Is(err, ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported for enum output on OpenAI, got %v", err)
+	}
+
+	if _, err := ImageModel(ProviderOpenAI, LlmOptions{ApiKey: "test-key", Model: "gpt-image-1"}); err != nil {
+		t.Errorf("expected image output on OpenAI to be accepted, got %v", err)
+	}
+	if _, err := NewLLM(LlmOptions{Provider: ProviderGroq, ApiKey: "test-key", Model: "llama", OutputFormat: OutputFormatYAML}); err != nil {
+		t.Errorf("expected YAML output on Groq to be accepted, got %v", err)
+	}
+}
+
+// TestBuiltInProvidersRegistered tests that the custom and openrouter providers
+// are reachable through NewLLM
+func TestBuiltInProvidersRegistered(t *testing.T) {
+	custom, err := NewLLM(LlmOptions{
+		Provider:        ProviderCustom,
+		ProviderOptions: map[string]any{"url": "http://localhost/v1/chat/completions"},
+	})
+	if err != nil {
+		t.Errorf("Failed to create LLM with custom provider: %v", err)
+	}
+	if custom == nil {
+		t.Errorf("Created custom LLM is nil")
+	}
+
+	openrouter, err := NewLLM(LlmOptions{
+		Provider: ProviderOpenRouter,
+		ApiKey:   "test-key",
+	})
+	if err != nil {
+		t.Errorf("Failed to create LLM with openrouter provider: %v", err)
+	}
+	if openrouter == nil {
+		t.Errorf("Created openrouter LLM is nil")
+	}
+}
+
+// TestProviderRegistryConcurrency tests that providers can be registered and
+// resolved from multiple goroutines (run with -race)
+func TestProviderRegistryConcurrency(t *testing.T) {
+	providerMu.Lock()
+	originalProviders := providerFactories
+	providerFactories = make(map[Provider]LlmFactory)
+	providerMu.Unlock()
+	defer func() {
+		providerMu.Lock()
+		providerFactories = originalProviders
+		providerMu.Unlock()
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			provider := Provider(fmt.Sprintf("concurrent-%d", i))
+			RegisterCustomProvider(string(provider), func(options LlmOptions) (LlmInterface, error) {
+				return newMockImplementation(options)
+			})
+			if _, err := NewLLM(LlmOptions{Provider: provider}); err != nil {
+				t.Errorf("Failed to create LLM with %s: %v", provider, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	providerMu.RLock()
+	count := len(providerFactories)
+	providerMu.RUnlock()
+	if count != 20 {
+		t.Errorf("Expected 20 registered providers, got %d", count)
+	}
+}
+
+// embeddingTestLLM is an embedding-capable mock recording the embedded texts
+type embeddingTestLLM struct {
+	LlmInterface
+	texts []string
+}
+
+func (e *embeddingTestLLM) GenerateEmbedding(text string) ([]float32, error) {
+	e.texts = append(e.texts, text)
+	return []float32{0.7, 0.8}, nil
+}
+
+// TestEmbeddingLlmRouting tests that embeddings are delegated to EmbeddingLlm
+func TestEmbeddingLlmRouting(t *testing.T) {
+	mockLLM, _ := newMockImplementation(LlmOptions{})
+	embedder := &embeddingTestLLM{LlmInterface: mockLLM}
+
+	chat, err := NewLLM(LlmOptions{
+		Provider:     ProviderMock,
+		MockResponse: "chat response",
+		EmbeddingLlm: embedder,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create mock LLM: %v", err)
+	}
+
+	embedding, err := chat.GenerateEmbedding("hello")
+	if err != nil {
+		t.Fatalf("GenerateEmbedding failed: %v", err)
+	}
+	if len(embedding) != 2 || embedding[0] != 0.7 {
+		t.Errorf("expected the embedding from EmbeddingLlm, got %v", embedding)
+	}
+	if len(embedder.texts) != 1 || embedder.texts[0] != "hello" {
+		t.Errorf("expected EmbeddingLlm to receive the text, got %v", embedder.texts)
+	}
+
+	// Chat calls still go to the chat provider
+	response, err := chat.GenerateText("system", "hi")
+	if err != nil || response != "chat response" {
+		t.Errorf("expected chat response, got %q (err=%v)", response, err)
+	}
+
+	// Providers without embedding support delegate as well
+	anthropic, err := NewLLM(LlmOptions{Provider: ProviderAnthropic, ApiKey: "test-key", EmbeddingLlm: embedder})
+	if err != nil {
+		t.Fatalf("Failed to create Anthropic LLM: %v", err)
+	}
+	if _, err := anthropic.GenerateEmbedding("world"); err != nil {
+		t.Errorf("expected Anthropic embeddings to route to EmbeddingLlm, got %v", err)
+	}
+}
+
+func TestFactoryWithOnlyAPIKeys(t *testing.T) {
+	providers := []Provider{
+		ProviderOpenAI,
+		ProviderOpenRouter,
+		ProviderMistral,
+		ProviderGroq,
+		ProviderDeepSeek,
+		ProviderAnthropic,
+		ProviderCohere,
+		ProviderHuggingFace,
+	}
+
+	config := MultiConfig{Providers: map[Provider]LlmOptions{}}
+	for _, provider := range providers {
+		options := LlmOptions{Model: "test-model", ProviderOptions: map[string]any{"api_keys": []string{"key-a", "key-b"}}}
+		config.Providers[provider] = options
+
+		if _, err := TextModel(provider, options); err != nil {
+			t.Errorf("%s: expected TextModel to accept api_keys only, got %v", provider, err)
+		}
+		if _, err := JSONModel(provider, options); err != nil {
+			t.Errorf("%s: expected JSONModel to accept api_keys only, got %v", provider, err)
+		}
+	}
+
+	if _, err := NewRegistry(config); err != nil {
+		t.Errorf("expected NewRegistry to accept api_keys only, got %v", err)
+	}
+}
@@ -1,5 +1,16 @@
 package llm
 
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// defaultHTTPTimeout is the http.Client timeout used when no Timeout is configured
+const defaultHTTPTimeout = 30 * time.Second
+
 // derefFloat64 returns the value pointed to by p, or defaultVal if p is nil.
 func derefFloat64(p *float64, defaultVal float64) float64 {
 	if p != nil {
@@ -8,6 +19,68 @@ func derefFloat64(p *float64, defaultVal float64) float64 {
 	return defaultVal
 }
 
+// resolveTimeout returns the configured request timeout, taken from
+// LlmOptions.Timeout or the "timeout_ms" provider option, or zero if unset.
+func resolveTimeout(options LlmOptions) time.Duration {
+	if options.Timeout > 0 {
+		return options.Timeout
+	}
+
+	if ms, ok := providerOptionInt(options.ProviderOptions, "timeout_ms"); ok && ms > 0 {
+		return time.Duration(ms) * time.Millisecond
+	}
+
+	return 0
+}
+
+// requestMaxTokens returns the max tokens to send to the provider.
+// MaxTokens <= 0 means "provider default": it returns 0, and callers omit
+// the field from the request.
+func requestMaxTokens(options LlmOptions) int {
+	return max(options.MaxTokens, 0)
+}
+
+// httpTimeout returns the http.Client timeout for the options
+func httpTimeout(options LlmOptions) time.Duration {
+	if timeout := resolveTimeout(options); timeout > 0 {
+		return timeout
+	}
+	return defaultHTTPTimeout
+}
+
+// providerBaseURL returns ProviderOptions["base_url"] without its trailing
+// slash, or defaultURL if it is not set
+func providerBaseURL(options LlmOptions, defaultURL string) string {
+	if v, ok := providerOptionString(options.ProviderOptions, "base_url"); ok && strings.TrimSpace(v) != "" {
+		return strings.TrimRight(strings.TrimSpace(v), "/")
+	}
+	return defaultURL
+}
+
+// providerHTTPClient returns the caller's HTTPClient, or a new client
+// with the configured timeout
+func providerHTTPClient(options LlmOptions) *http.Client {
+	if options.HTTPClient != nil {
+		return options.HTTPClient
+	}
+	return &http.Client{Timeout: httpTimeout(options)}
+}
+
+// requestContext returns the context for a provider request: the caller's
+// LlmOptions.Context (or context.Background()), bounded by the configured
+// timeout if there is one
+func requestContext(options LlmOptions) (context.Context, context.CancelFunc) {
+	ctx := options.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx = withRawCapture(ctx, options.rawCapture)
+	if timeout := resolveTimeout(options); timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
 // mergeOptions merges the provided options with the default options
 // and returns the merged options.
 // This allows the user to override the default options.
@@ -21,10 +94,31 @@ func mergeOptions(oldOptions LlmOptions, newOptions LlmOptions) LlmOptions {
 	options.ProjectID = oldOptions.ProjectID
 	options.Region = oldOptions.Region
 	options.Temperature = oldOptions.Temperature // may be nil
+	options.Stop = oldOptions.Stop
+	options.TopP = oldOptions.TopP                         // may be nil
+	options.TopK = oldOptions.TopK                         // may be nil
+	options.Seed = oldOptions.Seed                         // may be nil
+	options.PresencePenalty = oldOptions.PresencePenalty   // may be nil
+	options.FrequencyPenalty = oldOptions.FrequencyPenalty // may be nil
+	options.N = oldOptions.N
 	options.Verbose = oldOptions.Verbose
 	options.OutputFormat = oldOptions.OutputFormat
+	options.DisableJSONInstruction = oldOptions.DisableJSONInstruction
 	options.Logger = oldOptions.Logger
 	options.MockResponse = oldOptions.MockResponse
+	options.MockResponses = oldOptions.MockResponses
+	options.MaxCostUSD = oldOptions.MaxCostUSD
+	options.SpendTracker = oldOptions.SpendTracker
+	options.RequestsPerMinute = oldOptions.RequestsPerMinute
+	options.Middlewares = oldOptions.Middlewares
+	options.Timeout = oldOptions.Timeout
+	options.HTTPClient = oldOptions.HTTPClient
+	options.responseSchema = oldOptions.responseSchema
+	options.rawCapture = oldOptions.rawCapture
+	options.Context = oldOptions.Context
+	options.EmbeddingLlm = oldOptions.EmbeddingLlm
+	options.MaxRetries = oldOptions.MaxRetries
+	options.OnRetry = oldOptions.OnRetry
 
 	if newOptions.Provider != "" {
 		options.Provider = newOptions.Provider
@@ -54,12 +148,45 @@ func mergeOptions(oldOptions LlmOptions, newOptions LlmOptions) LlmOptions {
 		options.Temperature = newOptions.Temperature
 	}
 
+	if newOptions.Stop != nil {
+		options.Stop = newOptions.Stop
+	}
+
+	if newOptions.TopP != nil {
+		options.TopP = newOptions.TopP
+	}
+
+	if newOptions.TopK != nil {
+		options.TopK = newOptions.TopK
+	}
+
+	if newOptions.Seed != nil {
+		options.Seed = newOptions.Seed
+	}
+
+	if newOptions.PresencePenalty != nil {
+		options.PresencePenalty = newOptions.PresencePenalty
+	}
+
+	if newOptions.FrequencyPenalty != nil {
+		options.FrequencyPenalty = newOptions.FrequencyPenalty
+	}
+
+	if newOptions.N != 0 {
+		options.N = newOptions.N
+	}
+
 	// Verbose can only be turned on via merge, not turned off,
 	// because the zero value (false) is indistinguishable from "not set".
 	if newOptions.Verbose {
 		options.Verbose = true
 	}
 
+	// DisableJSONInstruction, like Verbose, can only be turned on via merge
+	if newOptions.DisableJSONInstruction {
+		options.DisableJSONInstruction = true
+	}
+
 	if newOptions.OutputFormat != "" {
 		options.OutputFormat = newOptions.OutputFormat
 	}
@@ -76,5 +203,137 @@ func mergeOptions(oldOptions LlmOptions, newOptions LlmOptions) LlmOptions {
 		options.MockResponse = newOptions.MockResponse
 	}
 
+	if newOptions.MockResponses != nil {
+		options.MockResponses = newOptions.MockResponses
+	}
+
+	if newOptions.MaxCostUSD > 0 {
+		options.MaxCostUSD = newOptions.MaxCostUSD
+	}
+
+	if newOptions.SpendTracker != nil {
+		options.SpendTracker = newOptions.SpendTracker
+	}
+	if newOptions.RequestsPerMinute > 0 {
+		options.RequestsPerMinute = newOptions.RequestsPerMinute
+	}
+
+	if newOptions.Middlewares != nil {
+		options.Middlewares = newOptions.Middlewares
+	}
+
+	if newOptions.Timeout > 0 {
+		options.Timeout = newOptions.Timeout
+	}
+
+	if newOptions.HTTPClient != nil {
+		options.HTTPClient = newOptions.HTTPClient
+	}
+
+	if newOptions.Context != nil {
+		options.Context = newOptions.Context
+	}
+
+	if newOptions.MaxRetries > 0 {
+		options.MaxRetries = newOptions.MaxRetries
+	}
+
+	if newOptions.OnRetry != nil {
+		options.OnRetry = newOptions.OnRetry
+	}
+
+	if newOptions.EmbeddingLlm != nil {
+		options.EmbeddingLlm = newOptions.EmbeddingLlm
+	}
+
+	if len(newOptions.responseSchema) > 0 {
+		options.responseSchema = newOptions.responseSchema
+	}
+
+	if newOptions.rawCapture != nil {
+		options.rawCapture = newOptions.rawCapture
+	}
+
+	// A per-call model may be an alias set with SetModelAlias
+	options.Model = ResolveModelAlias(options.Provider, options.Model)
+
 	return options
 }
+
+// jsonInstruction is appended to the system prompt by providers without a
+// native JSON mode
+const jsonInstruction = "You must respond with valid JSON only. Do not include any text outside the JSON."
+
+// jsonSystemPrompt returns the system prompt with jsonInstruction appended,
+// unless the options disable it or the prompt already contains it
+func jsonSystemPrompt(systemPrompt string, options LlmOptions) string {
+	if options.DisableJSONInstruction || strings.Contains(systemPrompt, jsonInstruction) {
+		return systemPrompt
+	}
+	return systemPrompt + "\n" + jsonInstruction
+}
+
+// markupInstructions are appended to the system prompt for XML and YAML
+// output, as most providers have no native mode for these formats
+var markupInstructions = map[OutputFormat]string{
+	OutputFormatXML:  "You must respond with valid XML only. Do not include any text outside the XML.",
+	OutputFormatYAML: "You must respond with valid YAML only. Do not include any text outside the YAML.",
+}
+
+// formatSystemPrompt returns the system prompt with the instruction for
+// the output format appended: the JSON instruction (see jsonSystemPrompt),
+// or the XML or YAML instruction (see markupSystemPrompt)
+func formatSystemPrompt(systemPrompt string, options LlmOptions) string {
+	if options.OutputFormat == OutputFormatJSON {
+		return jsonSystemPrompt(systemPrompt, options)
+	}
+	return markupSystemPrompt(systemPrompt, options)
+}
+
+// markupSystemPrompt returns the system prompt with the XML or YAML
+// instruction appended for these output formats, unless the prompt
+// already contains it
+func markupSystemPrompt(systemPrompt string, options LlmOptions) string {
+	instruction, ok := markupInstructions[options.OutputFormat]
+	if !ok || strings.Contains(systemPrompt, instruction) {
+		return systemPrompt
+	}
+	if systemPrompt == "" {
+		return instruction
+	}
+	return systemPrompt + "\n" + instruction
+}
+
+// markupMessages returns the messages with the XML or YAML instruction
+// appended to the first system message, or prepended as a system message
+// if there is none, for these output formats
+func markupMessages(messages []Message, options LlmOptions) []Message {
+	instruction, ok := markupInstructions[options.OutputFormat]
+	if !ok {
+		return messages
+	}
+
+	result := slices.Clone(messages)
+	for i, message := range result {
+		if message.Role == MessageRoleSystem {
+			result[i].Content = markupSystemPrompt(message.Content, options)
+			return result
+		}
+	}
+	return append([]Message{{Role: MessageRoleSystem, Content: instruction}}, result...)
+}
+
+// generateMarkup generates a response in the XML or YAML output format
+// with llm.Generate, and strips the code fences models often add
+func generateMarkup(llm LlmInterface, format OutputFormat, systemPrompt string, userPrompt string, opts ...LlmOptions) (string, error) {
+	perCall := LlmOptions{}
+	if len(opts) > 0 {
+		perCall = opts[0]
+	}
+	perCall.OutputFormat = format
+	response, err := llm.Generate(systemPrompt, userPrompt, perCall)
+	if err != nil {
+		return "", err
+	}
+	return sanitizeMarkupResponse(response), nil
+}
@@ -1,8 +1,13 @@
 package llm
 
 import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestMergeOptions(t *testing.T) {
@@ -158,3 +163,153 @@ func TestMergeOptions(t *testing.T) {
 		})
 	}
 }
+
+func TestMergeOptionsTopPTopK(t *testing.T) {
+	base := LlmOptions{TopP: PtrFloat64(0.8)}
+
+	merged := mergeOptions(base, LlmOptions{})
+	if merged.TopP == nil || *merged.TopP != 0.8 || merged.TopK != nil {
+		t.Errorf("expected base TopP and nil TopK, got %v %v", merged.TopP, merged.TopK)
+	}
+
+	merged = mergeOptions(base, LlmOptions{TopP: PtrFloat64(0.5), TopK: PtrInt(10)})
+	if *merged.TopP != 0.5 || *merged.TopK != 10 {
+		t.Errorf("expected overridden TopP 0.5 and TopK 10, got %v %v", *merged.TopP, *merged.TopK)
+	}
+}
+
+func TestMergeOptionsPenalties(t *testing.T) {
+	base := LlmOptions{PresencePenalty: PtrFloat64(0.5)}
+	merged := mergeOptions(base, LlmOptions{})
+	if merged.PresencePenalty == nil || *merged.PresencePenalty != 0.5 || merged.FrequencyPenalty != nil {
+		t.Errorf("expected base PresencePenalty and nil FrequencyPenalty, got %v %v", merged.PresencePenalty, merged.FrequencyPenalty)
+	}
+
+	merged = mergeOptions(base, LlmOptions{PresencePenalty: PtrFloat64(1), FrequencyPenalty: PtrFloat64(0.2)})
+	if *merged.PresencePenalty != 1 || *merged.FrequencyPenalty != 0.2 {
+		t.Errorf("expected overridden penalties 1 and 0.2, got %v %v", *merged.PresencePenalty, *merged.FrequencyPenalty)
+	}
+}
+
+func TestMergeOptionsSeed(t *testing.T) {
+	merged := mergeOptions(LlmOptions{Seed: PtrInt(1)}, LlmOptions{})
+	if merged.Seed == nil || *merged.Seed != 1 {
+		t.Errorf("expected base Seed 1, got %v", merged.Seed)
+	}
+
+	merged = mergeOptions(LlmOptions{Seed: PtrInt(1)}, LlmOptions{Seed: PtrInt(42)})
+	if *merged.Seed != 42 {
+		t.Errorf("expected overridden Seed 42, got %v", *merged.Seed)
+	}
+}
+
+func TestResolveTimeout(t *testing.T) {
+	testCases := []struct {
+		name     string
+		options  LlmOptions
+		want     time.Duration
+		wantHTTP time.Duration
+	}{
+		{"unset", LlmOptions{}, 0, defaultHTTPTimeout},
+		{"timeout field", LlmOptions{Timeout: 5 * time.Second}, 5 * time.Second, 5 * time.Second},
+		{"timeout_ms option", LlmOptions{ProviderOptions: map[string]any{"timeout_ms": 1500}}, 1500 * time.Millisecond, 1500 * time.Millisecond},
+		{"timeout_ms string", LlmOptions{ProviderOptions: map[string]any{"timeout_ms": "250"}}, 250 * time.Millisecond, 250 * time.Millisecond},
+		{"field wins", LlmOptions{Timeout: time.Second, ProviderOptions: map[string]any{"timeout_ms": 1500}}, time.Second, time.Second},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := resolveTimeout(tc.options); got != tc.want {
+				t.Errorf("resolveTimeout() = %v, want %v", got, tc.want)
+			}
+			if got := httpTimeout(tc.options); got != tc.wantHTTP {
+				t.Errorf("httpTimeout() = %v, want %v", got, tc.wantHTTP)
+			}
+		})
+	}
+}
+
+func TestTimeoutAppliedToRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Read the body so the server notices when the client goes away
+		io.ReadAll(r.Body)
+		select {
+		case <-time.After(2 * time.Second):
+		case <-r.Context().Done():
+		}
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"hi"}}]}`))
+	}))
+	defer server.Close()
+
+	llm, err := NewLLM(LlmOptions{
+		Provider:        ProviderCustom,
+		Timeout:         50 * time.Millisecond,
+		ProviderOptions: map[string]any{"url": server.URL},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create custom LLM: %v", err)
+	}
+
+	start := time.Now()
+	if _, err := llm.GenerateText("system", "hello"); err == nil {
+		t.Fatal("expected timeout error")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("request was not bounded by the timeout, took %v", elapsed)
+	}
+}
+
+// countingTransport counts the requests it redirects to the target server
+type countingTransport struct {
+	redirectTransport
+	requests *int
+}
+
+func (t countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	*t.requests++
+	return t.redirectTransport.RoundTrip(req)
+}
+
+func TestProviderHTTPClient(t *testing.T) {
+	client := providerHTTPClient(LlmOptions{Timeout: 5 * time.Second})
+	if client.Timeout != 5*time.Second {
+		t.Errorf("expected default client with 5s timeout, got %v", client.Timeout)
+	}
+
+	custom := &http.Client{}
+	if providerHTTPClient(LlmOptions{HTTPClient: custom}) != custom {
+		t.Error("expected the configured client")
+	}
+}
+
+func TestHTTPClientOptionIsUsed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"hi"}}]}`))
+	}))
+	defer server.Close()
+	target, _ := url.Parse(server.URL)
+
+	providers := []Provider{ProviderOpenAI, ProviderOpenRouter, ProviderMistral, ProviderGroq, ProviderDeepSeek, ProviderCohere, ProviderHuggingFace, ProviderCustom}
+	for _, provider := range providers {
+		t.Run(string(provider), func(t *testing.T) {
+			requests := 0
+			engine, err := NewLLM(LlmOptions{
+				Provider:        provider,
+				ApiKey:          "test-key",
+				Model:           "test-model",
+				ProviderOptions: map[string]any{"url": server.URL},
+				HTTPClient:      &http.Client{Transport: countingTransport{redirectTransport{target}, &requests}},
+			})
+			if err != nil {
+				t.Fatalf("failed to create %s LLM: %v", provider, err)
+			}
+
+			// Only the transport matters, not whether the response parses
+			engine.GenerateText("system", "hello")
+			if requests != 1 {
+				t.Errorf("expected 1 request through the configured client, got %d", requests)
+			}
+		})
+	}
+}
@@ -4,11 +4,12 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
-	"time"
+	"strings"
 
 	"google.golang.org/genai"
 )
@@ -23,6 +24,8 @@ type geminiImplementation struct {
 	logger      *slog.Logger
 	apiKey      string
 	httpClient  *http.Client
+	options     LlmOptions
+	*lastResponseRecorder
 }
 
 // newGeminiImplementation creates a new Gemini provider implementation
@@ -47,8 +50,7 @@ func newGeminiImplementation(options LlmOptions) (LlmInterface, error) {
 		return nil, fmt.Errorf("failed to create Gemini client: %w", err)
 	}
 
-	// Default to Gemini Flash model
-	modelName := GEMINI_MODEL_2_5_FLASH
+	modelName := DefaultModel(ProviderGemini)
 	if options.Model != "" {
 		modelName = options.Model
 	}
@@ -61,94 +63,363 @@ func newGeminiImplementation(options LlmOptions) (LlmInterface, error) {
 		verbose:     options.Verbose,
 		logger:      options.Logger,
 		apiKey:      options.ApiKey,
-		httpClient:  &http.Client{Timeout: 30 * time.Second},
+		httpClient:  &http.Client{Timeout: httpTimeout(options)},
+		options:     options,
+
+		lastResponseRecorder: newLastResponseRecorder(options.ProviderOptions),
 	}, nil
 }
 
-// baseOptions returns the base LlmOptions from the struct fields for merging.
+// baseOptions returns the construction options, with the struct fields
+// applied on top, as the base for merging per-call options.
 func (g *geminiImplementation) baseOptions() LlmOptions {
-	return LlmOptions{
-		Model:       g.model,
-		MaxTokens:   g.maxTokens,
-		Temperature: &g.temperature,
-		Verbose:     g.verbose,
-		Logger:      g.logger,
-	}
+	options := g.options
+	options.Model = g.model
+	options.MaxTokens = g.maxTokens
+	options.Temperature = &g.temperature
+	options.Verbose = g.verbose
+	options.Logger = g.logger
+	return options
 }
 
 // Generate implements LlmInterface
 func (g *geminiImplementation) Generate(systemPrompt string, userMessage string, opts ...LlmOptions) (string, error) {
+	return g.generate(systemPrompt, userMessage, nil, opts...)
+}
+
+// EffectiveOptions implements EffectiveOptionsInterface
+func (g *geminiImplementation) EffectiveOptions(opts ...LlmOptions) LlmOptions {
+	return mergeOptions(g.baseOptions(), firstOptions(opts))
+}
+
+// DebugMessages implements DebugMessagesInterface. The system message is
+// sent as Gemini's system instruction.
+func (g *geminiImplementation) DebugMessages(systemPrompt string, userMessage string, opts ...LlmOptions) []Message {
+	perCall := LlmOptions{}
+	if len(opts) > 0 {
+		perCall = opts[0]
+	}
+	merged := mergeOptions(g.baseOptions(), perCall)
+
+	return promptMessages(geminiSystemPrompt(systemPrompt, merged), userMessage)
+}
+
+// geminiThinkingBudget returns the thinking budget in tokens of
+// ProviderOptions["thinking_budget"], used by Gemini and Vertex: 0 disables
+// thinking where the model allows it and -1 lets the model decide. It
+// returns false when the option is not set, leaving the model's default.
+func geminiThinkingBudget(providerOptions map[string]any) (int32, bool) {
+	budget, ok := providerOptionInt(providerOptions, "thinking_budget")
+	if !ok {
+		return 0, false
+	}
+	return int32(budget), true
+}
+
+// geminiSystemPrompt returns the system instruction for the prompt, with
+// the JSON, XML or YAML instruction for these output formats
+func geminiSystemPrompt(systemPrompt string, options LlmOptions) string {
+	return formatSystemPrompt(systemPrompt, options)
+}
+
+// generate sends the user message, with the images as inline data parts,
+// to Gemini
+func (g *geminiImplementation) generate(systemPrompt string, userMessage string, images [][]byte, opts ...LlmOptions) (string, error) {
+	perCall := LlmOptions{}
+	if len(opts) > 0 {
+		perCall = opts[0]
+	}
+	merged := mergeOptions(g.baseOptions(), perCall)
+
+	if override, err := overrideProvider(g.options.Provider, merged, perCall); err != nil {
+		return "", err
+	} else if override != nil {
+		defer Close(override)
+		return GenerateWithImages(override, systemPrompt, userMessage, images, perCall)
+	}
+
+	resp, err := g.generateContent(systemPrompt, userMessage, images, nil, 1, merged)
+	if err != nil {
+		return "", err
+	}
+
+	finishErr := geminiFinishError(resp)
+	if errors.Is(finishErr, ErrContentBlocked) {
+		return "", finishErr
+	}
+
+	if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil || len(resp.Candidates[0].Content.Parts) == 0 {
+		if finishErr != nil {
+			return "", finishErr
+		}
+		return "", fmt.Errorf("%w: no candidates from gemini", ErrEmptyResponse)
+	}
+
+	// Get the text from the first candidate
+	var result string
+	for _, part := range resp.Candidates[0].Content.Parts {
+		if part.Text != "" {
+			result += part.Text
+		}
+	}
+
+	// Return the truncated text along with ErrMaxTokensReached
+	if finishErr != nil {
+		return result, finishErr
+	}
+
+	if strings.TrimSpace(result) == "" {
+		return "", fmt.Errorf("%w: gemini returned empty content", ErrEmptyResponse)
+	}
+
+	return result, nil
+}
+
+// GenerateN implements CandidatesInterface, requesting merged.N candidates
+func (g *geminiImplementation) GenerateN(systemPrompt string, userPrompt string, opts ...LlmOptions) ([]string, error) {
+	perCall := firstOptions(opts)
+	merged := mergeOptions(g.baseOptions(), perCall)
+
+	if override, err := overrideProvider(g.options.Provider, merged, perCall); err != nil {
+		return nil, err
+	} else if override != nil {
+		defer Close(override)
+		return GenerateN(override, systemPrompt, userPrompt, perCall)
+	}
+
+	resp, err := g.generateContent(systemPrompt, userPrompt, nil, nil, candidateCount(merged), merged)
+	if err != nil {
+		return nil, err
+	}
+
+	texts := []string{}
+	for _, candidate := range resp.Candidates {
+		if candidate.Content == nil {
+			continue
+		}
+		var text strings.Builder
+		for _, part := range candidate.Content.Parts {
+			text.WriteString(part.Text)
+		}
+		if trimmed := strings.TrimSpace(text.String()); trimmed != "" {
+			texts = append(texts, trimmed)
+		}
+	}
+
+	// A blocked prompt has no candidates
+	if len(texts) == 0 {
+		if finishErr := geminiFinishError(resp); finishErr != nil {
+			return nil, finishErr
+		}
+		return nil, fmt.Errorf("%w: no candidates with text from gemini", ErrEmptyResponse)
+	}
+	return texts, nil
+}
+
+// GenerateWithTools implements ToolInterface, declaring the tools as
+// Gemini function declarations
+func (g *geminiImplementation) GenerateWithTools(systemPrompt string, userPrompt string, tools []ToolDefinition, opts ...LlmOptions) (ToolResult, error) {
 	perCall := LlmOptions{}
 	if len(opts) > 0 {
 		perCall = opts[0]
 	}
 	merged := mergeOptions(g.baseOptions(), perCall)
 
+	if override, err := overrideProvider(g.options.Provider, merged, perCall); err != nil {
+		return ToolResult{}, err
+	} else if override != nil {
+		defer Close(override)
+		return GenerateWithTools(override, systemPrompt, userPrompt, tools, perCall)
+	}
+
+	resp, err := g.generateContent(systemPrompt, userPrompt, nil, tools, 1, merged)
+	if err != nil {
+		return ToolResult{}, err
+	}
+
+	if finishErr := geminiFinishError(resp); finishErr != nil {
+		return ToolResult{}, finishErr
+	}
+
+	if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil || len(resp.Candidates[0].Content.Parts) == 0 {
+		return ToolResult{}, fmt.Errorf("%w: no candidates from gemini", ErrEmptyResponse)
+	}
+
+	result := ToolResult{}
+	for _, part := range resp.Candidates[0].Content.Parts {
+		if part.Text != "" {
+			result.Text += part.Text
+		}
+		if call := part.FunctionCall; call != nil {
+			arguments, err := json.Marshal(call.Args)
+			if err != nil {
+				return ToolResult{}, fmt.Errorf("failed to marshal function call arguments: %w", err)
+			}
+			result.ToolCalls = append(result.ToolCalls, ToolCall{ID: call.ID, Name: call.Name, Arguments: string(arguments)})
+		}
+	}
+
+	return result, nil
+}
+
+// generateContent sends the user message, with the images as inline data
+// parts and the tools as function declarations, to Gemini
+func (g *geminiImplementation) generateContent(systemPrompt string, userMessage string, images [][]byte, tools []ToolDefinition, candidates int, merged LlmOptions) (*genai.GenerateContentResponse, error) {
+	if err := checkCostBudget(merged, systemPrompt, userMessage); err != nil {
+		return nil, err
+	}
+
 	if g.client == nil {
-		return "", fmt.Errorf("gemini client not initialized")
+		return nil, fmt.Errorf("gemini client not initialized")
 	}
 
 	// Prepare user message content
+	parts := []*genai.Part{}
+	for _, image := range images {
+		mediaType, err := imageMediaType(image)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, &genai.Part{
+			InlineData: &genai.Blob{MIMEType: mediaType, Data: image},
+		})
+	}
+	if userMessage != "" || len(parts) == 0 {
+		parts = append(parts, &genai.Part{Text: userMessage})
+	}
 	userContent := &genai.Content{
 		Role:  "user",
-		Parts: []*genai.Part{{Text: userMessage}},
+		Parts: parts,
 	}
 
-	// Prepare system instruction
-	effectiveSystemPrompt := systemPrompt
-	if merged.OutputFormat == OutputFormatJSON {
-		effectiveSystemPrompt += "\nYou must respond with valid JSON only. Do not include any text outside the JSON."
-	}
-
-	// Prepare generation config
-	genConfig := &genai.GenerateContentConfig{
-		SystemInstruction: &genai.Content{
+	// Prepare generation config. The system prompt is sent as the native
+	// system instruction, apart from the user turn, and omitted when empty.
+	genConfig := &genai.GenerateContentConfig{}
+	if effectiveSystemPrompt := geminiSystemPrompt(systemPrompt, merged); effectiveSystemPrompt != "" {
+		genConfig.SystemInstruction = &genai.Content{
 			Parts: []*genai.Part{{Text: effectiveSystemPrompt}},
-		},
+		}
 	}
-	if merged.MaxTokens > 0 {
-		genConfig.MaxOutputTokens = int32(merged.MaxTokens)
+	if maxTokens := requestMaxTokens(merged); maxTokens > 0 {
+		genConfig.MaxOutputTokens = int32(maxTokens)
 	}
 	if merged.Temperature != nil {
 		genConfig.Temperature = genai.Ptr(float32(*merged.Temperature))
 	}
+	if merged.TopP != nil {
+		genConfig.TopP = genai.Ptr(float32(*merged.TopP))
+	}
+	if merged.TopK != nil {
+		genConfig.TopK = genai.Ptr(float32(*merged.TopK))
+	}
+	if len(merged.Stop) > 0 {
+		genConfig.StopSequences = merged.Stop
+	}
+	if candidates > 1 {
+		genConfig.CandidateCount = int32(candidates)
+	}
+	if budget, ok := geminiThinkingBudget(merged.ProviderOptions); ok {
+		genConfig.ThinkingConfig = &genai.ThinkingConfig{ThinkingBudget: genai.Ptr(budget)}
+	}
+	if merged.OutputFormat == OutputFormatJSON && len(merged.responseSchema) > 0 {
+		genConfig.ResponseMIMEType = "application/json"
+		genConfig.ResponseJsonSchema = merged.responseSchema
+	}
+	if len(tools) > 0 {
+		genConfig.Tools = []*genai.Tool{{FunctionDeclarations: geminiFunctionDeclarations(tools)}}
+	}
+
+	ctx, cancel := requestContext(merged)
+	defer cancel()
 
 	// Generate response
-	resp, err := g.client.Models.GenerateContent(
-		context.Background(),
-		g.model,
-		[]*genai.Content{userContent},
-		genConfig,
-	)
+	var resp *genai.GenerateContentResponse
+	err := withRetry(ctx, merged, func() error {
+		var err error
+		resp, err = g.client.Models.GenerateContent(
+			ctx,
+			merged.Model,
+			[]*genai.Content{userContent},
+			genConfig,
+		)
+		return providerAPIError(ProviderGemini, err)
+	})
 
 	if err != nil {
 		if g.logger != nil {
 			g.logger.Error("Gemini generation error",
 				slog.String("error", err.Error()),
-				slog.String("model", g.model))
+				slog.String("model", merged.Model))
 		} else if g.verbose {
 			fmt.Printf("Gemini generation error: %v\n", err)
 		}
-		return "", fmt.Errorf("failed to generate content: %w", err)
+		var apiErr genai.APIError
+		if errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound {
+			return nil, &ModelNotFoundError{Provider: ProviderGemini, Model: merged.Model, Err: err}
+		}
+		return nil, fmt.Errorf("failed to generate content: %w", err)
 	}
+	g.recordObject(ctx, ProviderGemini, http.StatusOK, nil, resp)
 
-	if len(resp.Candidates) == 0 || len(resp.Candidates[0].Content.Parts) == 0 {
-		return "", fmt.Errorf("no response from gemini")
-	}
+	return resp, nil
+}
 
-	// Get the text from the first candidate
-	var result string
-	for _, part := range resp.Candidates[0].Content.Parts {
-		if part.Text != "" {
-			result += part.Text
+// geminiFunctionDeclarations converts tool definitions to Gemini function
+// declarations
+func geminiFunctionDeclarations(tools []ToolDefinition) []*genai.FunctionDeclaration {
+	declarations := make([]*genai.FunctionDeclaration, len(tools))
+	for i, tool := range tools {
+		declarations[i] = &genai.FunctionDeclaration{
+			Name:                 tool.Name,
+			Description:          tool.Description,
+			ParametersJsonSchema: toolParameters(tool),
 		}
 	}
+	return declarations
+}
 
-	if result == "" {
-		return "", fmt.Errorf("empty response from gemini")
+// geminiFinishError returns an error wrapping ErrContentBlocked if the
+// prompt or the first candidate was blocked, or ErrMaxTokensReached if the
+// candidate was cut off at the token limit
+func geminiFinishError(resp *genai.GenerateContentResponse) error {
+	if feedback := resp.PromptFeedback; feedback != nil && feedback.BlockReason != "" && feedback.BlockReason != genai.BlockedReasonUnspecified {
+		return fmt.Errorf("%w: prompt blocked with reason %s%s", ErrContentBlocked, feedback.BlockReason, geminiBlockedCategories(feedback.SafetyRatings))
 	}
 
-	return result, nil
+	if len(resp.Candidates) == 0 {
+		return nil
+	}
+
+	candidate := resp.Candidates[0]
+	switch candidate.FinishReason {
+	case genai.FinishReasonSafety,
+		genai.FinishReasonRecitation,
+		genai.FinishReasonBlocklist,
+		genai.FinishReasonProhibitedContent,
+		genai.FinishReasonSPII,
+		genai.FinishReasonImageSafety,
+		genai.FinishReasonImageProhibitedContent,
+		genai.FinishReasonImageRecitation:
+		return fmt.Errorf("%w: response blocked with reason %s%s", ErrContentBlocked, candidate.FinishReason, geminiBlockedCategories(candidate.SafetyRatings))
+	case genai.FinishReasonMaxTokens:
+		return fmt.Errorf("%w: gemini finish reason %s", ErrMaxTokensReached, candidate.FinishReason)
+	}
+
+	return nil
+}
+
+// geminiBlockedCategories describes the harm categories that caused a block
+func geminiBlockedCategories(ratings []*genai.SafetyRating) string {
+	categories := []string{}
+	for _, rating := range ratings {
+		if rating != nil && rating.Blocked {
+			categories = append(categories, string(rating.Category))
+		}
+	}
+	if len(categories) == 0 {
+		return ""
+	}
+	return ", blocked categories: " + strings.Join(categories, ", ")
 }
 
 // GenerateText implements LlmInterface
@@ -168,7 +439,31 @@ func (g *geminiImplementation) GenerateJSON(systemPrompt string, userPrompt stri
 		perCall = opts[0]
 	}
 	perCall.OutputFormat = OutputFormatJSON
-	return g.Generate(systemPrompt, userPrompt, perCall)
+	response, err := g.Generate(systemPrompt, userPrompt, perCall)
+	if err != nil {
+		return "", err
+	}
+	return sanitizeJSONModeResponse(ProviderGemini, response)
+}
+
+// GenerateXML implements LlmInterface
+func (g *geminiImplementation) GenerateXML(systemPrompt string, userPrompt string, opts ...LlmOptions) (string, error) {
+	return generateMarkup(g, OutputFormatXML, systemPrompt, userPrompt, opts...)
+}
+
+// GenerateYAML implements LlmInterface
+func (g *geminiImplementation) GenerateYAML(systemPrompt string, userPrompt string, opts ...LlmOptions) (string, error) {
+	return generateMarkup(g, OutputFormatYAML, systemPrompt, userPrompt, opts...)
+}
+
+// GenerateWithImages implements VisionInterface
+func (g *geminiImplementation) GenerateWithImages(systemPrompt string, userPrompt string, images [][]byte, opts ...LlmOptions) (string, error) {
+	return g.generate(systemPrompt, userPrompt, images, opts...)
+}
+
+// GenerateStructured implements StructuredOutputInterface
+func (g *geminiImplementation) GenerateStructured(systemPrompt string, userPrompt string, schema json.RawMessage, opts ...LlmOptions) (json.RawMessage, error) {
+	return generateStructured(g.GenerateJSON, systemPrompt, userPrompt, schema, opts...)
 }
 
 // GenerateImage implements LlmInterface
@@ -180,7 +475,12 @@ func (g *geminiImplementation) GenerateImage(prompt string, opts ...LlmOptions)
 
 // GenerateEmbedding generates embeddings for the given text
 func (g *geminiImplementation) GenerateEmbedding(text string) ([]float32, error) {
-	ctx := context.Background()
+	if g.options.EmbeddingLlm != nil {
+		return g.options.EmbeddingLlm.GenerateEmbedding(text)
+	}
+
+	ctx, cancel := requestContext(g.options)
+	defer cancel()
 
 	// Gemini requires a custom HTTP request for embeddings
 	reqBody := map[string]interface{}{
@@ -213,7 +513,7 @@ func (g *geminiImplementation) GenerateEmbedding(text string) ([]float32, error)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("embedding request failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, newAPIError(ProviderGemini, resp.StatusCode, body)
 	}
 
 	var result struct {
@@ -238,3 +538,10 @@ func (g *geminiImplementation) GenerateEmbedding(text string) ([]float32, error)
 
 	return embeddings, nil
 }
+
+// Close implements io.Closer. It closes the idle connections of the
+// embedding HTTP client; the genai client holds no resources to release.
+func (g *geminiImplementation) Close() error {
+	closeIdleConnections(g.httpClient)
+	return nil
+}
@@ -0,0 +1,96 @@
+package llm
+
+import (
+	"testing"
+)
+
+func TestGeminiSystemInstruction(t *testing.T) {
+	var captured map[string]any
+	server := captureServer(t, `{"candidates":[{"content":{"role":"model","parts":[{"text":"hi"}]}}]}`, &captured)
+	defer server.Close()
+	gemini := newTestGemini(t, server.URL)
+
+	if _, err := gemini.GenerateText("Be brief", "Hello"); err != nil {
+		t.Fatalf("GenerateText failed: %v", err)
+	}
+
+	instruction, _ := captured["systemInstruction"].(map[string]any)
+	parts, _ := instruction["parts"].([]any)
+	if len(parts) != 1 || parts[0].(map[string]any)["text"] != "Be brief" {
+		t.Errorf("expected the system prompt as the system instruction, got %v", captured["systemInstruction"])
+	}
+
+	contents, _ := captured["contents"].([]any)
+	if len(contents) != 1 {
+		t.Fatalf("expected a single user turn, got %v", captured["contents"])
+	}
+	turn, _ := contents[0].(map[string]any)
+	userParts, _ := turn["parts"].([]any)
+	if turn["role"] != "user" || len(userParts) != 1 || userParts[0].(map[string]any)["text"] != "Hello" {
+		t.Errorf("expected only the user message in the user turn, got %v", turn)
+	}
+
+	// An empty system prompt sends no system instruction
+	captured = nil
+	if _, err := gemini.GenerateText("", "Hello"); err != nil {
+		t.Fatalf("GenerateText failed: %v", err)
+	}
+	if _, ok := captured["systemInstruction"]; ok {
+		t.Errorf("expected no system instruction, got %v", captured["systemInstruction"])
+	}
+}
+
+func TestGeminiClientMaxTokensAndTemperature(t *testing.T) {
+	var captured map[string]any
+	server := captureServer(t, `{"candidates":[{"content":{"role":"model","parts":[{"text":"hi"}]}}]}`, &captured)
+	defer server.Close()
+
+	engine, err := newGeminiImplementation(LlmOptions{ApiKey: "test-key", MaxTokens: 1000, Temperature: PtrFloat64(0.25)})
+	if err != nil {
+		t.Fatalf("Failed to create Gemini LLM: %v", err)
+	}
+	gemini := engine.(*geminiImplementation)
+	gemini.client = newTestGemini(t, server.URL).client
+
+	if _, err := gemini.GenerateText("system", "hello"); err != nil {
+		t.Fatalf("GenerateText failed: %v", err)
+	}
+	generationConfig, _ := captured["generationConfig"].(map[string]any)
+	if generationConfig["maxOutputTokens"] != 1000.0 || generationConfig["temperature"] != 0.25 {
+		t.Errorf("expected the client's maxOutputTokens 1000 and temperature 0.25, got %v", generationConfig)
+	}
+
+	// Per-call options take precedence
+	captured = nil
+	if _, err := gemini.GenerateText("system", "hello", LlmOptions{MaxTokens: 50, Temperature: PtrFloat64(0.5)}); err != nil {
+		t.Fatalf("GenerateText failed: %v", err)
+	}
+	generationConfig, _ = captured["generationConfig"].(map[string]any)
+	if generationConfig["maxOutputTokens"] != 50.0 || generationConfig["temperature"] != 0.5 {
+		t.Errorf("expected the per-call maxOutputTokens 50 and temperature 0.5, got %v", generationConfig)
+	}
+}
+
+func TestGeminiThinkingBudget(t *testing.T) {
+	var captured map[string]any
+	server := captureServer(t, `{"candidates":[{"content":{"role":"model","parts":[{"text":"hi"}]}}]}`, &captured)
+	defer server.Close()
+	gemini := newTestGemini(t, server.URL)
+
+	if _, err := gemini.GenerateText("", "Hello"); err != nil {
+		t.Fatalf("GenerateText failed: %v", err)
+	}
+	config, _ := captured["generationConfig"].(map[string]any)
+	if _, ok := config["thinkingConfig"]; ok {
+		t.Errorf("expected no thinking config by default, got %v", config["thinkingConfig"])
+	}
+
+	if _, err := gemini.GenerateText("", "Hello", LlmOptions{ProviderOptions: map[string]any{"thinking_budget": 0}}); err != nil {
+		t.Fatalf("GenerateText failed: %v", err)
+	}
+	config, _ = captured["generationConfig"].(map[string]any)
+	thinking, _ := config["thinkingConfig"].(map[string]any)
+	if budget, ok := thinking["thinkingBudget"]; !ok || budget != float64(0) {
+		t.Errorf("expected a thinking budget of 0, got %v", config["thinkingConfig"])
+	}
+}
@@ -0,0 +1,24 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// GenerateInto generates a JSON response and unmarshals it into a value of
+// type T. Markdown code fences and prose around the JSON are removed before
+// unmarshaling. If unmarshaling fails, the error includes the raw text.
+func GenerateInto[T any](llm LlmInterface, systemPrompt string, userPrompt string, options ...LlmOptions) (T, error) {
+	var result T
+
+	response, err := llm.GenerateJSON(systemPrompt, userPrompt, options...)
+	if err != nil {
+		return result, err
+	}
+
+	if err := json.Unmarshal([]byte(sanitizeJSONResponse(response)), &result); err != nil {
+		return result, fmt.Errorf("failed to unmarshal response into %T: %w; raw response: %s", result, err, response)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,55 @@
+package llm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateInto(t *testing.T) {
+	type person struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+
+	testCases := []struct {
+		name     string
+		response string
+	}{
+		{"plain", `{"name": "John", "age": 30}`},
+		{"json fence", "```json\n{\"name\": \"John\", \"age\": 30}\n```"},
+		{"bare fence", "```\n{\"name\": \"John\", \"age\": 30}\n```"},
+		{"surrounding whitespace", "\n  ```JSON\n{\"name\": \"John\", \"age\": 30}```  \n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			engine, err := NewLLM(LlmOptions{Provider: ProviderMock, MockResponse: tc.response})
+			if err != nil {
+				t.Fatalf("Failed to create mock LLM: %v", err)
+			}
+
+			got, err := GenerateInto[person](engine, "system", "user")
+			if err != nil {
+				t.Fatalf("GenerateInto failed: %v", err)
+			}
+			if got != (person{Name: "John", Age: 30}) {
+				t.Errorf("GenerateInto() = %+v", got)
+			}
+		})
+	}
+}
+
+func TestGenerateIntoInvalidJSON(t *testing.T) {
+	engine, err := NewLLM(LlmOptions{Provider: ProviderMock, MockResponse: "Sorry, I cannot help"})
+	if err != nil {
+		t.Fatalf("Failed to create mock LLM: %v", err)
+	}
+
+	_, err = GenerateInto[map[string]any](engine, "system", "user")
+	if err == nil {
+		t.Fatal("expected error for invalid JSON")
+	}
+	if !strings.Contains(err.Error(), "Sorry, I cannot help") {
+		t.Errorf("expected raw response in error, got %v", err)
+	}
+}
@@ -0,0 +1,226 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+const groqDefaultBaseURL = "https://api.groq.com/openai/v1"
+const groqDefaultModel = "llama-3.1-70b-versatile"
+
+// groqImplementation implements LlmInterface using Groq's OpenAI-compatible
+// API for low latency inference
+type groqImplementation struct {
+	client      *openai.Client
+	httpClient  openai.HTTPDoer
+	model       string
+	maxTokens   int
+	temperature float64
+	verbose     bool
+	logger      *slog.Logger
+	options     LlmOptions
+	*lastResponseRecorder
+}
+
+// newGroqImplementation creates a new Groq provider implementation
+func newGroqImplementation(options LlmOptions) (LlmInterface, error) {
+	apiKey := providerAPIKey(options)
+	if apiKey == "" {
+		return nil, fmt.Errorf("groq API key is required")
+	}
+
+	model := strings.TrimSpace(options.Model)
+	if model == "" {
+		model = DefaultModel(ProviderGroq)
+	}
+
+	cfg := openai.DefaultConfig(apiKey)
+	cfg.BaseURL = providerBaseURL(options, groqDefaultBaseURL)
+	httpClient, err := secureHTTPClient(ProviderGroq, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure groq http client: %w", err)
+	}
+	cfg.HTTPClient = apiKeyHTTPClient(httpClient, options)
+
+	return &groqImplementation{
+		client:      openai.NewClientWithConfig(cfg),
+		httpClient:  cfg.HTTPClient,
+		model:       model,
+		maxTokens:   options.MaxTokens,
+		temperature: derefFloat64(options.Temperature, 0.7),
+		verbose:     options.Verbose,
+		logger:      options.Logger,
+		options:     options,
+
+		lastResponseRecorder: newLastResponseRecorder(options.ProviderOptions),
+	}, nil
+}
+
+// baseOptions returns the construction options, with the struct fields
+// applied on top, as the base for merging per-call options.
+func (g *groqImplementation) baseOptions() LlmOptions {
+	options := g.options
+	options.Model = g.model
+	options.MaxTokens = g.maxTokens
+	options.Temperature = &g.temperature
+	options.Verbose = g.verbose
+	options.Logger = g.logger
+	return options
+}
+
+// Generate implements LlmInterface
+func (g *groqImplementation) Generate(systemPrompt string, userMessage string, opts ...LlmOptions) (string, error) {
+	return g.GenerateChat(promptMessages(systemPrompt, userMessage), opts...)
+}
+
+// EffectiveOptions implements EffectiveOptionsInterface
+func (g *groqImplementation) EffectiveOptions(opts ...LlmOptions) LlmOptions {
+	return mergeOptions(g.baseOptions(), firstOptions(opts))
+}
+
+// DebugMessages implements DebugMessagesInterface
+func (g *groqImplementation) DebugMessages(systemPrompt string, userMessage string, opts ...LlmOptions) []Message {
+	perCall := LlmOptions{}
+	if len(opts) > 0 {
+		perCall = opts[0]
+	}
+	merged := mergeOptions(g.baseOptions(), perCall)
+
+	return markupMessages(promptMessages(systemPrompt, userMessage), merged)
+}
+
+// GenerateChat implements ChatInterface
+func (g *groqImplementation) GenerateChat(messages []Message, opts ...LlmOptions) (string, error) {
+	perCall := LlmOptions{}
+	if len(opts) > 0 {
+		perCall = opts[0]
+	}
+	merged := mergeOptions(g.baseOptions(), perCall)
+
+	if override, err := overrideProvider(g.options.Provider, merged, perCall); err != nil {
+		return "", err
+	} else if override != nil {
+		defer Close(override)
+		return GenerateChat(override, messages, perCall)
+	}
+
+	messages = markupMessages(messages, merged)
+
+	if err := checkCostBudget(merged, "", messagesContent(messages)); err != nil {
+		return "", err
+	}
+
+	ctx, cancel := requestContext(merged)
+	defer cancel()
+
+	model := merged.Model
+
+	responseFormat := &openai.ChatCompletionResponseFormat{
+		Type: openai.ChatCompletionResponseFormatTypeText,
+	}
+	if merged.OutputFormat == OutputFormatJSON {
+		responseFormat.Type = openai.ChatCompletionResponseFormatTypeJSONObject
+	}
+
+	chatMessages, err := openaiChatMessages(messages)
+	if err != nil {
+		return "", err
+	}
+
+	req := openai.ChatCompletionRequest{
+		Model:            model,
+		ResponseFormat:   responseFormat,
+		Messages:         chatMessages,
+		MaxTokens:        requestMaxTokens(merged),
+		Temperature:      float32(derefFloat64(merged.Temperature, g.temperature)),
+		Stop:             merged.Stop,
+		TopP:             float32(derefFloat64(merged.TopP, 0)),
+		PresencePenalty:  float32(derefFloat64(merged.PresencePenalty, 0)),
+		FrequencyPenalty: float32(derefFloat64(merged.FrequencyPenalty, 0)),
+	}
+
+	var resp openai.ChatCompletionResponse
+	err = withRetry(ctx, merged, func() error {
+		var err error
+		resp, err = g.client.CreateChatCompletion(ctx, req)
+		return providerAPIError(ProviderGroq, err)
+	})
+	if err != nil {
+		if g.logger != nil {
+			g.logger.Error("Groq generation error",
+				slog.String("error", err.Error()),
+				slog.String("model", model))
+		} else if g.verbose {
+			fmt.Printf("Groq generation error: %v\n", err)
+		}
+		return "", err
+	}
+	g.recordObject(ctx, ProviderGroq, http.StatusOK, resp.Header(), resp)
+
+	return openaiChoiceContent(ProviderGroq, resp)
+}
+
+// GenerateText implements LlmInterface
+func (g *groqImplementation) GenerateText(systemPrompt string, userPrompt string, opts ...LlmOptions) (string, error) {
+	perCall := LlmOptions{}
+	if len(opts) > 0 {
+		perCall = opts[0]
+	}
+	perCall.OutputFormat = OutputFormatText
+	return g.Generate(systemPrompt, userPrompt, perCall)
+}
+
+// GenerateJSON implements LlmInterface
+func (g *groqImplementation) GenerateJSON(systemPrompt string, userPrompt string, opts ...LlmOptions) (string, error) {
+	perCall := LlmOptions{}
+	if len(opts) > 0 {
+		perCall = opts[0]
+	}
+	perCall.OutputFormat = OutputFormatJSON
+	response, err := g.Generate(systemPrompt, userPrompt, perCall)
+	if err != nil {
+		return "", err
+	}
+	return sanitizeJSONResponse(response), nil
+}
+
+// GenerateXML implements LlmInterface
+func (g *groqImplementation) GenerateXML(systemPrompt string, userPrompt string, opts ...LlmOptions) (string, error) {
+	return generateMarkup(g, OutputFormatXML, systemPrompt, userPrompt, opts...)
+}
+
+// GenerateYAML implements LlmInterface
+func (g *groqImplementation) GenerateYAML(systemPrompt string, userPrompt string, opts ...LlmOptions) (string, error) {
+	return generateMarkup(g, OutputFormatYAML, systemPrompt, userPrompt, opts...)
+}
+
+// GenerateStructured implements StructuredOutputInterface
+func (g *groqImplementation) GenerateStructured(systemPrompt string, userPrompt string, schema json.RawMessage, opts ...LlmOptions) (json.RawMessage, error) {
+	return generateStructured(g.GenerateJSON, systemPrompt, userPrompt, schema, opts...)
+}
+
+// GenerateImage implements LlmInterface
+func (g *groqImplementation) GenerateImage(prompt string, opts ...LlmOptions) ([]byte, error) {
+	return nil, fmt.Errorf("image generation not supported by groq provider")
+}
+
+// GenerateEmbedding implements LlmInterface
+func (g *groqImplementation) GenerateEmbedding(text string) ([]float32, error) {
+	if g.options.EmbeddingLlm != nil {
+		return g.options.EmbeddingLlm.GenerateEmbedding(text)
+	}
+
+	return nil, fmt.Errorf("embedding generation not supported by groq provider")
+}
+
+// Close implements io.Closer. It closes the idle connections of the
+// HTTP client.
+func (g *groqImplementation) Close() error {
+	closeIdleConnections(g.httpClient)
+	return nil
+}
@@ -0,0 +1,64 @@
+package llm
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGroqGenerateJSON(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/completions" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer test-key" {
+			t.Errorf("unexpected Authorization header: %s", r.Header.Get("Authorization"))
+		}
+
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("failed to decode request: %v", err)
+		}
+		if body["model"] != groqDefaultModel {
+			t.Errorf("expected default model, got %v", body["model"])
+		}
+		format, _ := body["response_format"].(map[string]any)
+		if format["type"] != "json_object" {
+			t.Errorf("expected json_object response format, got %v", body["response_format"])
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"{\"ok\":true}"}}]}`))
+	}))
+	defer server.Close()
+
+	llm, err := NewLLM(LlmOptions{
+		Provider:        ProviderGroq,
+		ApiKey:          "test-key",
+		Model:           groqDefaultModel,
+		ProviderOptions: map[string]any{"base_url": server.URL},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create Groq LLM: %v", err)
+	}
+
+	response, err := llm.GenerateJSON("system", "hello")
+	if err != nil {
+		t.Fatalf("GenerateJSON failed: %v", err)
+	}
+	if response != `{"ok":true}` {
+		t.Errorf("unexpected response: %s", response)
+	}
+
+	if _, err := llm.GenerateEmbedding("hello"); err == nil {
+		t.Error("expected embeddings to be unsupported")
+	}
+}
+
+func TestGroqRequiresApiKey(t *testing.T) {
+	_, err := NewLLM(LlmOptions{Provider: ProviderGroq, Model: groqDefaultModel})
+	if err == nil {
+		t.Fatal("expected error without API key")
+	}
+}
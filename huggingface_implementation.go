@@ -0,0 +1,390 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const huggingfaceDefaultBaseURL = "https://router.huggingface.co/hf-inference/models"
+const huggingfaceDefaultModel = "mistralai/Mistral-7B-Instruct-v0.3"
+const huggingfaceDefaultEmbeddingModel = "sentence-transformers/all-MiniLM-L6-v2"
+
+// huggingfaceLoadingRetries is the number of times a request answered with
+// 503 "model is currently loading" is retried, as cold models load on the
+// first request.
+const huggingfaceLoadingRetries = 3
+
+// huggingfaceMaxLoadingWait caps the wait before retrying a loading model,
+// which Hugging Face estimates in the response. Tests shorten it.
+var huggingfaceMaxLoadingWait = 20 * time.Second
+
+// huggingfaceImplementation implements LlmInterface for the Hugging Face
+// Inference API, with the text-generation and feature-extraction tasks
+type huggingfaceImplementation struct {
+	apiKey      string
+	baseURL     string
+	model       string
+	maxTokens   int
+	temperature float64
+	verbose     bool
+	logger      *slog.Logger
+	httpClient  *http.Client
+	options     LlmOptions
+	*lastResponseRecorder
+}
+
+// newHuggingFaceImplementation creates a new Hugging Face provider implementation
+func newHuggingFaceImplementation(options LlmOptions) (LlmInterface, error) {
+	apiKey := providerAPIKey(options)
+	if apiKey == "" {
+		return nil, fmt.Errorf("hugging face API token is required")
+	}
+
+	model := strings.TrimSpace(options.Model)
+	if model == "" {
+		model = DefaultModel(ProviderHuggingFace)
+	}
+
+	httpClient, err := secureHTTPClient(ProviderHuggingFace, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure huggingface http client: %w", err)
+	}
+	httpClient = apiKeyHTTPClient(httpClient, options)
+
+	return &huggingfaceImplementation{
+		apiKey:      apiKey,
+		baseURL:     providerBaseURL(options, huggingfaceDefaultBaseURL),
+		model:       model,
+		maxTokens:   options.MaxTokens,
+		temperature: derefFloat64(options.Temperature, 0.7),
+		verbose:     options.Verbose,
+		logger:      options.Logger,
+		httpClient:  httpClient,
+		options:     options,
+
+		lastResponseRecorder: newLastResponseRecorder(options.ProviderOptions),
+	}, nil
+}
+
+// baseOptions returns the construction options, with the struct fields
+// applied on top, as the base for merging per-call options.
+func (h *huggingfaceImplementation) baseOptions() LlmOptions {
+	options := h.options
+	options.Model = h.model
+	options.MaxTokens = h.maxTokens
+	options.Temperature = &h.temperature
+	options.Verbose = h.verbose
+	options.Logger = h.logger
+	return options
+}
+
+// EffectiveOptions implements EffectiveOptionsInterface
+func (h *huggingfaceImplementation) EffectiveOptions(opts ...LlmOptions) LlmOptions {
+	return mergeOptions(h.baseOptions(), firstOptions(opts))
+}
+
+// DebugMessages implements DebugMessagesInterface. The text-generation
+// task takes a single input, so the system prompt is sent ahead of the
+// user message.
+func (h *huggingfaceImplementation) DebugMessages(systemPrompt string, userMessage string, opts ...LlmOptions) []Message {
+	merged := mergeOptions(h.baseOptions(), firstOptions(opts))
+	return []Message{{Role: MessageRoleUser, Content: huggingfaceInput(systemPrompt, userMessage, merged)}}
+}
+
+// huggingfaceInput returns the text-generation input for the prompt: the
+// system prompt, with the output format instruction, followed by the user
+// message
+func huggingfaceInput(systemPrompt string, userMessage string, options LlmOptions) string {
+	systemPrompt = formatSystemPrompt(systemPrompt, options)
+	if systemPrompt == "" {
+		return userMessage
+	}
+	return systemPrompt + "\n\n" + userMessage
+}
+
+// Generate implements LlmInterface
+func (h *huggingfaceImplementation) Generate(systemPrompt string, userMessage string, opts ...LlmOptions) (string, error) {
+	perCall := firstOptions(opts)
+	merged := mergeOptions(h.baseOptions(), perCall)
+
+	if override, err := overrideProvider(h.options.Provider, merged, perCall); err != nil {
+		return "", err
+	} else if override != nil {
+		defer Close(override)
+		return override.Generate(systemPrompt, userMessage, perCall)
+	}
+
+	input := huggingfaceInput(systemPrompt, userMessage, merged)
+
+	if err := checkCostBudget(merged, "", input); err != nil {
+		return "", err
+	}
+
+	parameters := map[string]any{
+		"temperature":      derefFloat64(merged.Temperature, h.temperature),
+		"return_full_text": false,
+	}
+	if maxTokens := requestMaxTokens(merged); maxTokens > 0 {
+		parameters["max_new_tokens"] = maxTokens
+	}
+	if len(merged.Stop) > 0 {
+		parameters["stop"] = merged.Stop
+	}
+	if merged.TopP != nil {
+		parameters["top_p"] = *merged.TopP
+	}
+	if merged.TopK != nil {
+		parameters["top_k"] = *merged.TopK
+	}
+	if merged.Seed != nil {
+		parameters["seed"] = *merged.Seed
+	}
+
+	body := map[string]any{
+		"inputs":     input,
+		"parameters": parameters,
+	}
+
+	ctx, cancel := requestContext(merged)
+	defer cancel()
+
+	var respBody []byte
+	err := withRetry(ctx, merged, func() error {
+		var err error
+		respBody, err = h.post(ctx, merged.Model, body)
+		return err
+	})
+	if err != nil {
+		if h.logger != nil {
+			h.logger.Error("Hugging Face generation error",
+				slog.String("error", err.Error()),
+				slog.String("model", merged.Model))
+		} else if h.verbose {
+			fmt.Printf("Hugging Face generation error: %v\n", err)
+		}
+		return "", err
+	}
+
+	var parsed []struct {
+		GeneratedText string `json:"generated_text"`
+	}
+	if err := json.Unmarshal(respBody, &parsed); err != nil {
+		return "", fmt.Errorf("failed to parse response: %w", err)
+	}
+	if len(parsed) == 0 || strings.TrimSpace(parsed[0].GeneratedText) == "" {
+		return "", fmt.Errorf("%w: hugging face returned empty content", ErrEmptyResponse)
+	}
+
+	return strings.TrimSpace(parsed[0].GeneratedText), nil
+}
+
+// GenerateText implements LlmInterface
+func (h *huggingfaceImplementation) GenerateText(systemPrompt string, userPrompt string, opts ...LlmOptions) (string, error) {
+	perCall := firstOptions(opts)
+	perCall.OutputFormat = OutputFormatText
+	return h.Generate(systemPrompt, userPrompt, perCall)
+}
+
+// GenerateJSON implements LlmInterface
+func (h *huggingfaceImplementation) GenerateJSON(systemPrompt string, userPrompt string, opts ...LlmOptions) (string, error) {
+	perCall := firstOptions(opts)
+	perCall.OutputFormat = OutputFormatJSON
+	response, err := h.Generate(systemPrompt, userPrompt, perCall)
+	if err != nil {
+		return "", err
+	}
+	return sanitizeJSONResponse(response), nil
+}
+
+// GenerateXML implements LlmInterface
+func (h *huggingfaceImplementation) GenerateXML(systemPrompt string, userPrompt string, opts ...LlmOptions) (string, error) {
+	return generateMarkup(h, OutputFormatXML, systemPrompt, userPrompt, opts...)
+}
+
+// GenerateYAML implements LlmInterface
+func (h *huggingfaceImplementation) GenerateYAML(systemPrompt string, userPrompt string, opts ...LlmOptions) (string, error) {
+	return generateMarkup(h, OutputFormatYAML, systemPrompt, userPrompt, opts...)
+}
+
+// GenerateImage implements LlmInterface
+func (h *huggingfaceImplementation) GenerateImage(prompt string, opts ...LlmOptions) ([]byte, error) {
+	return nil, fmt.Errorf("image generation not supported by Hugging Face")
+}
+
+// GenerateEmbedding generates embeddings for the given text with the
+// feature-extraction task of sentence-transformers/all-MiniLM-L6-v2, unless
+// overridden by ProviderOptions["embedding_model"]. Models returning one
+// vector per token are mean pooled.
+func (h *huggingfaceImplementation) GenerateEmbedding(text string) ([]float32, error) {
+	if h.options.EmbeddingLlm != nil {
+		return h.options.EmbeddingLlm.GenerateEmbedding(text)
+	}
+
+	embeddingModel := huggingfaceDefaultEmbeddingModel
+	if v, ok := providerOptionString(h.options.ProviderOptions, "embedding_model"); ok && strings.TrimSpace(v) != "" {
+		embeddingModel = strings.TrimSpace(v)
+	}
+
+	ctx, cancel := requestContext(h.options)
+	defer cancel()
+
+	respBody, err := h.post(ctx, embeddingModel, map[string]any{"inputs": text})
+	if err != nil {
+		if h.logger != nil {
+			h.logger.Error("Hugging Face embedding generation error",
+				slog.String("error", err.Error()))
+		} else if h.verbose {
+			fmt.Printf("Hugging Face embedding generation error: %v\n", err)
+		}
+		return nil, err
+	}
+
+	return huggingfaceEmbedding(respBody)
+}
+
+// huggingfaceEmbedding returns the embedding of a feature-extraction
+// response: a vector, or one vector per token (possibly nested in a batch
+// of one), which is mean pooled
+func huggingfaceEmbedding(respBody []byte) ([]float32, error) {
+	var vector []float64
+	if err := json.Unmarshal(respBody, &vector); err == nil {
+		return huggingfaceFloat32(vector)
+	}
+
+	var tokens [][]float64
+	if err := json.Unmarshal(respBody, &tokens); err != nil {
+		var batch [][][]float64
+		if err := json.Unmarshal(respBody, &batch); err != nil || len(batch) == 0 {
+			return nil, fmt.Errorf("failed to parse response: unexpected feature-extraction shape")
+		}
+		tokens = batch[0]
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("no embeddings generated")
+	}
+
+	pooled := make([]float64, len(tokens[0]))
+	for _, token := range tokens {
+		if len(token) != len(pooled) {
+			return nil, fmt.Errorf("failed to parse response: token vectors of different sizes")
+		}
+		for i, v := range token {
+			pooled[i] += v / float64(len(tokens))
+		}
+	}
+	return huggingfaceFloat32(pooled)
+}
+
+// huggingfaceFloat32 converts a vector to float32
+func huggingfaceFloat32(vector []float64) ([]float32, error) {
+	if len(vector) == 0 {
+		return nil, fmt.Errorf("no embeddings generated")
+	}
+	embeddings := make([]float32, len(vector))
+	for i, v := range vector {
+		embeddings[i] = float32(v)
+	}
+	return embeddings, nil
+}
+
+// post sends a JSON request for the model and returns the response body.
+// A model still loading answers 503 with an estimated time, and the
+// request is retried after it, up to huggingfaceLoadingRetries times.
+// Other non-2xx responses are returned as an *APIError.
+func (h *huggingfaceImplementation) post(ctx context.Context, model string, body any) ([]byte, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	for attempt := 0; ; attempt++ {
+		respBody, wait, err := h.send(ctx, model, payload)
+		if err == nil || wait == 0 || attempt == huggingfaceLoadingRetries {
+			return respBody, err
+		}
+
+		if h.logger != nil {
+			h.logger.Info("Hugging Face model loading, retrying",
+				slog.String("model", model),
+				slog.Duration("wait", wait))
+		} else if h.verbose {
+			fmt.Printf("Hugging Face model %s loading, retrying in %s\n", model, wait)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// send posts the payload to the model's endpoint. For a model still
+// loading it returns the error with the wait before retrying.
+func (h *huggingfaceImplementation) send(ctx context.Context, model string, payload []byte) ([]byte, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.baseURL+"/"+model, bytes.NewReader(payload))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+h.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := h.httpClient.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read response: %w", err)
+	}
+	h.recordHTTP(ProviderHuggingFace, resp, respBody)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, huggingfaceLoadingWait(resp.StatusCode, respBody), newAPIError(ProviderHuggingFace, resp.StatusCode, respBody)
+	}
+
+	return respBody, 0, nil
+}
+
+// huggingfaceLoadingWait returns the wait before retrying a model that is
+// loading: its estimated loading time, capped at huggingfaceMaxLoadingWait.
+// It returns zero for other responses.
+func huggingfaceLoadingWait(statusCode int, respBody []byte) time.Duration {
+	if statusCode != http.StatusServiceUnavailable {
+		return 0
+	}
+
+	var parsed struct {
+		Error         string  `json:"error"`
+		EstimatedTime float64 `json:"estimated_time"`
+	}
+	if err := json.Unmarshal(respBody, &parsed); err != nil || !strings.Contains(strings.ToLower(parsed.Error), "loading") {
+		return 0
+	}
+
+	wait := time.Duration(parsed.EstimatedTime * float64(time.Second))
+	if wait <= 0 || wait > huggingfaceMaxLoadingWait {
+		wait = huggingfaceMaxLoadingWait
+	}
+	return wait
+}
+
+// Close implements io.Closer. It closes the idle connections of the
+// HTTP client.
+func (h *huggingfaceImplementation) Close() error {
+	closeIdleConnections(h.httpClient)
+	return nil
+}
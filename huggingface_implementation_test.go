@@ -0,0 +1,128 @@
+package llm
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHuggingFaceGenerateAndEmbed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			t.Errorf("unexpected Authorization header: %s", r.Header.Get("Authorization"))
+		}
+
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("failed to decode request: %v", err)
+		}
+
+		switch r.URL.Path {
+		case "/org/text-model":
+			input, _ := body["inputs"].(string)
+			if !strings.HasPrefix(input, "system\n") || !strings.Contains(input, jsonInstruction) || !strings.HasSuffix(input, "\n\nhello") {
+				t.Errorf("unexpected inputs: %q", input)
+			}
+			parameters, _ := body["parameters"].(map[string]any)
+			if parameters["max_new_tokens"] != float64(64) || parameters["return_full_text"] != false {
+				t.Errorf("unexpected parameters: %v", parameters)
+			}
+			w.Write([]byte(`[{"generated_text":" {\"ok\":true} "}]`))
+		case "/" + huggingfaceDefaultEmbeddingModel:
+			if body["inputs"] != "hello" {
+				t.Errorf("unexpected inputs: %v", body["inputs"])
+			}
+			// One vector per token, mean pooled by the provider
+			w.Write([]byte(`[[1.0,0.5],[0.0,0.0]]`))
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	llm, err := NewLLM(LlmOptions{
+		Provider:        ProviderHuggingFace,
+		ApiKey:          "test-token",
+		Model:           "org/text-model",
+		MaxTokens:       64,
+		ProviderOptions: map[string]any{"base_url": server.URL},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create Hugging Face LLM: %v", err)
+	}
+
+	response, err := llm.GenerateJSON("system", "hello")
+	if err != nil {
+		t.Fatalf("GenerateJSON failed: %v", err)
+	}
+	if response != `{"ok":true}` {
+		t.Errorf("unexpected response: %s", response)
+	}
+
+	embedding, err := llm.GenerateEmbedding("hello")
+	if err != nil {
+		t.Fatalf("GenerateEmbedding failed: %v", err)
+	}
+	if len(embedding) != 2 || embedding[0] != 0.5 || embedding[1] != 0.25 {
+		t.Errorf("unexpected embedding: %v", embedding)
+	}
+}
+
+func TestHuggingFaceModelLoading(t *testing.T) {
+	originalWait := huggingfaceMaxLoadingWait
+	huggingfaceMaxLoadingWait = time.Millisecond
+	defer func() { huggingfaceMaxLoadingWait = originalWait }()
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"error":"Model org/text-model is currently loading","estimated_time":20.0}`))
+			return
+		}
+		w.Write([]byte(`[{"generated_text":"ready"}]`))
+	}))
+	defer server.Close()
+
+	llm, err := NewLLM(LlmOptions{
+		Provider:        ProviderHuggingFace,
+		ApiKey:          "test-token",
+		Model:           "org/text-model",
+		ProviderOptions: map[string]any{"base_url": server.URL},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create Hugging Face LLM: %v", err)
+	}
+
+	response, err := llm.GenerateText("", "hello")
+	if err != nil {
+		t.Fatalf("GenerateText failed: %v", err)
+	}
+	if response != "ready" || requests != 3 {
+		t.Errorf("expected ready after 3 requests, got %q after %d", response, requests)
+	}
+
+	// Other 503s are not retried as loading
+	requests = 0
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"error":"Service unavailable"}`))
+	})
+	if _, err := llm.GenerateText("", "hello"); err == nil {
+		t.Fatal("expected error for a 503 response")
+	}
+	if requests != 1 {
+		t.Errorf("expected 1 request, got %d", requests)
+	}
+}
+
+func TestHuggingFaceRequiresToken(t *testing.T) {
+	if _, err := NewLLM(LlmOptions{Provider: ProviderHuggingFace}); err == nil {
+		t.Fatal("expected error without an API token")
+	}
+}
@@ -0,0 +1,446 @@
+package llm
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// ImageSizeInterface is implemented by providers that can generate an image
+// from explicit pixel dimensions (currently OpenAI and OpenRouter)
+type ImageSizeInterface interface {
+	// GenerateImageSize generates an image, mapping width and height to the
+	// nearest size supported by the provider. Dimensions whose aspect ratio
+	// is not supported return an error.
+	GenerateImageSize(prompt string, width int, height int, options ...LlmOptions) ([]byte, error)
+}
+
+// generateImageSizeWith calls the GenerateImageSize of llm, returning an
+// error wrapping ErrNotSupported if it does not implement
+// ImageSizeInterface
+func generateImageSizeWith(llm LlmInterface, prompt string, width int, height int, options ...LlmOptions) ([]byte, error) {
+	sizer, ok := llm.(ImageSizeInterface)
+	if !ok {
+		return nil, fmt.Errorf("%w: image size", ErrNotSupported)
+	}
+	return sizer.GenerateImageSize(prompt, width, height, options...)
+}
+
+// ImageTextInterface is implemented by providers whose image generation
+// can return text along with the image (currently OpenRouter and Vertex)
+type ImageTextInterface interface {
+	// GenerateImageWithText generates an image like GenerateImage, and also
+	// returns the text the model sent with it, e.g. a caption or an
+	// explanation
+	GenerateImageWithText(prompt string, options ...LlmOptions) (image []byte, text string, err error)
+}
+
+// GenerateImageWithText generates an image and returns the text the model
+// sent with it when the llm implements ImageTextInterface. Otherwise it
+// returns GenerateImage's image with an empty text.
+func GenerateImageWithText(llm LlmInterface, prompt string, options ...LlmOptions) ([]byte, string, error) {
+	if imageText, ok := llm.(ImageTextInterface); ok {
+		return imageText.GenerateImageWithText(prompt, options...)
+	}
+
+	image, err := llm.GenerateImage(prompt, options...)
+	return image, "", err
+}
+
+// GenerateImageResult is an image with its detected format and dimensions
+type GenerateImageResult struct {
+	// Data is the encoded image
+	Data []byte
+
+	// MimeType is the detected media type, e.g. "image/png"
+	MimeType string
+
+	// Width and Height are the dimensions in pixels
+	Width  int
+	Height int
+}
+
+// GenerateImageEx generates an image like GenerateImage and returns it with
+// its detected format and dimensions. Data that is empty or not a valid
+// PNG, JPEG or GIF image returns an error wrapping ErrInvalidImage.
+func GenerateImageEx(llm LlmInterface, prompt string, options ...LlmOptions) (GenerateImageResult, error) {
+	data, err := llm.GenerateImage(prompt, options...)
+	if err != nil {
+		return GenerateImageResult{}, err
+	}
+	return DecodeImageResult(data)
+}
+
+// DecodeImageResult detects the format of image data with
+// http.DetectContentType and decodes its dimensions, returning an error
+// wrapping ErrInvalidImage if the data is empty or not a valid PNG, JPEG
+// or GIF image
+func DecodeImageResult(data []byte) (GenerateImageResult, error) {
+	if len(data) == 0 {
+		return GenerateImageResult{}, fmt.Errorf("%w: no image data", ErrInvalidImage)
+	}
+
+	mimeType := http.DetectContentType(data)
+	if !strings.HasPrefix(mimeType, "image/") {
+		return GenerateImageResult{}, fmt.Errorf("%w: data is %s", ErrInvalidImage, mimeType)
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return GenerateImageResult{}, fmt.Errorf("%w: failed to decode %s: %v", ErrInvalidImage, mimeType, err)
+	}
+
+	return GenerateImageResult{
+		Data:     data,
+		MimeType: mimeType,
+		Width:    config.Width,
+		Height:   config.Height,
+	}, nil
+}
+
+// imageExtensions maps the image media types to their file extension
+var imageExtensions = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// ImageToDataURI encodes image data as a base64 data URI, e.g. for an HTML
+// img tag, with the media type detected from the data. Data that is not a
+// PNG, JPEG, GIF or WebP image returns an error wrapping ErrInvalidImage.
+func ImageToDataURI(data []byte) (string, error) {
+	if len(data) == 0 {
+		return "", fmt.Errorf("%w: no image data", ErrInvalidImage)
+	}
+
+	dataURI, err := imageDataURI(data)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	return dataURI, nil
+}
+
+// SaveImage writes image data to path, adding the extension of the
+// detected type (".png", ".jpg", ".gif" or ".webp") if path has none. A
+// path whose extension does not match the detected type, or data that is
+// not an image, returns an error wrapping ErrInvalidImage.
+func SaveImage(data []byte, path string) error {
+	if len(data) == 0 {
+		return fmt.Errorf("%w: no image data", ErrInvalidImage)
+	}
+
+	mimeType := http.DetectContentType(data)
+	extension, ok := imageExtensions[mimeType]
+	if !ok {
+		return fmt.Errorf("%w: data is %s", ErrInvalidImage, mimeType)
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); {
+	case ext == "":
+		path += extension
+	case ext == extension, ext == ".jpeg" && extension == ".jpg":
+	default:
+		return fmt.Errorf("%w: %s data cannot be saved as %s", ErrInvalidImage, mimeType, path)
+	}
+
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to save image: %w", err)
+	}
+	return nil
+}
+
+// imageAspectTolerance is the maximum relative difference between the
+// requested aspect ratio and a supported one for them to be considered equal
+const imageAspectTolerance = 0.05
+
+// openaiImageSizes lists the sizes supported by each OpenAI image model
+var openaiImageSizes = map[string][]string{
+	openai.CreateImageModelDallE2: {
+		openai.CreateImageSize256x256,
+		openai.CreateImageSize512x512,
+		openai.CreateImageSize1024x1024,
+	},
+	openai.CreateImageModelDallE3: {
+		openai.CreateImageSize1024x1024,
+		openai.CreateImageSize1792x1024,
+		openai.CreateImageSize1024x1792,
+	},
+	openai.CreateImageModelGptImage1: {
+		openai.CreateImageSize1024x1024,
+		openai.CreateImageSize1536x1024,
+		openai.CreateImageSize1024x1536,
+	},
+}
+
+// openaiImageQualities lists the qualities supported by each OpenAI image model
+var openaiImageQualities = map[string][]string{
+	openai.CreateImageModelDallE2: {
+		openai.CreateImageQualityStandard,
+	},
+	openai.CreateImageModelDallE3: {
+		openai.CreateImageQualityStandard,
+		openai.CreateImageQualityHD,
+	},
+	openai.CreateImageModelGptImage1: {
+		openai.CreateImageQualityLow,
+		openai.CreateImageQualityMedium,
+		openai.CreateImageQualityHigh,
+		"auto",
+	},
+}
+
+// openaiImageStyles lists the styles supported by each OpenAI image model.
+// Models missing from the map do not support styles.
+var openaiImageStyles = map[string][]string{
+	openai.CreateImageModelDallE3: {
+		openai.CreateImageStyleVivid,
+		openai.CreateImageStyleNatural,
+	},
+}
+
+// openaiImageBackgrounds lists the backgrounds supported by each OpenAI
+// image model. Models missing from the map do not support backgrounds.
+var openaiImageBackgrounds = map[string][]string{
+	openai.CreateImageModelGptImage1: {
+		openai.CreateImageBackgroundTransparent,
+		openai.CreateImageBackgroundOpaque,
+		"auto",
+	},
+}
+
+// openrouterAspectRatios lists the aspect ratios accepted by OpenRouter's image_config
+var openrouterAspectRatios = []string{
+	"1:1", "2:3", "3:2", "3:4", "4:3", "4:5", "5:4", "9:16", "16:9", "21:9",
+}
+
+// imageOutputFormat returns the image format requested by the options.
+// Anything but OutputFormatImageJPG, including the text formats of engines
+// not created with ImageModel, defaults to OutputFormatImagePNG.
+func imageOutputFormat(options LlmOptions) OutputFormat {
+	if options.OutputFormat == OutputFormatImageJPG {
+		return OutputFormatImageJPG
+	}
+	return OutputFormatImagePNG
+}
+
+// convertImage returns the image encoded in the format, re-encoding it when
+// the provider returned another format
+func convertImage(data []byte, format OutputFormat) ([]byte, error) {
+	if http.DetectContentType(data) == string(format) {
+		return data, nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s image for conversion to %s: %w", http.DetectContentType(data), format, err)
+	}
+
+	var buf bytes.Buffer
+	switch format {
+	case OutputFormatImagePNG:
+		err = png.Encode(&buf, img)
+	case OutputFormatImageJPG:
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90})
+	default:
+		return nil, fmt.Errorf("unsupported image format %s", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode image as %s: %w", format, err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// openaiImageSizeFor maps pixel dimensions to the nearest size supported by
+// the OpenAI image model. Unknown models accept any of the known sizes.
+func openaiImageSizeFor(model string, width int, height int) (string, error) {
+	if width <= 0 || height <= 0 {
+		return "", fmt.Errorf("invalid image dimensions %dx%d", width, height)
+	}
+
+	sizes, ok := openaiImageSizes[model]
+	if !ok {
+		seen := map[string]bool{}
+		for _, modelSizes := range openaiImageSizes {
+			for _, size := range modelSizes {
+				if !seen[size] {
+					seen[size] = true
+					sizes = append(sizes, size)
+				}
+			}
+		}
+	}
+
+	best := ""
+	bestDistance := math.MaxFloat64
+	for _, size := range sizes {
+		w, h := parseImageSize(size)
+		if !sameAspectRatio(width, height, w, h) {
+			continue
+		}
+		distance := math.Abs(float64(w*h - width*height))
+		if distance < bestDistance || (distance == bestDistance && size < best) {
+			best = size
+			bestDistance = distance
+		}
+	}
+
+	if best == "" {
+		return "", fmt.Errorf("image size %dx%d is not supported by OpenAI", width, height)
+	}
+
+	return best, nil
+}
+
+// openaiImageModel returns the image model, taken from
+// ProviderOptions["model"] or else the configured model
+func openaiImageModel(options LlmOptions) string {
+	if v, ok := providerOptionString(options.ProviderOptions, "model"); ok && v != "" {
+		return v
+	}
+	return options.Model
+}
+
+// openaiImageRequest builds the image request from the "model", "size",
+// "quality", "style" and "background" provider options, rejecting values
+// the model does not support. Unknown models are not validated.
+func openaiImageRequest(prompt string, options LlmOptions) (openai.ImageRequest, error) {
+	stringOption := func(key string) string {
+		v, _ := providerOptionString(options.ProviderOptions, key)
+		return strings.TrimSpace(v)
+	}
+
+	model := openaiImageModel(options)
+
+	size := stringOption("size")
+	if size == "" {
+		// "image_size" is the previous name of the "size" option
+		size = stringOption("image_size")
+	}
+	if size == "" {
+		size = openai.CreateImageSize1024x1024
+	}
+
+	req := openai.ImageRequest{
+		Model:          model,
+		Prompt:         prompt,
+		Size:           size,
+		Quality:        stringOption("quality"),
+		Style:          stringOption("style"),
+		Background:     stringOption("background"),
+		N:              1,
+		ResponseFormat: openai.CreateImageResponseFormatB64JSON,
+	}
+
+	// gpt-image-1 always returns base64 and rejects response_format, but
+	// can encode the requested format itself
+	if model == openai.CreateImageModelGptImage1 {
+		req.ResponseFormat = ""
+		req.OutputFormat = openai.CreateImageOutputFormatPNG
+		if imageOutputFormat(options) == OutputFormatImageJPG {
+			req.OutputFormat = openai.CreateImageOutputFormatJPEG
+		}
+	}
+
+	sizes, known := openaiImageSizes[model]
+	if !known {
+		return req, nil
+	}
+
+	if !slices.Contains(sizes, req.Size) {
+		return req, fmt.Errorf("image size %s is not supported by %s, supported sizes: %s", req.Size, model, strings.Join(sizes, ", "))
+	}
+
+	if qualities := openaiImageQualities[model]; req.Quality != "" && !slices.Contains(qualities, req.Quality) {
+		return req, fmt.Errorf("image quality %s is not supported by %s, supported qualities: %s", req.Quality, model, strings.Join(qualities, ", "))
+	}
+
+	if req.Style != "" {
+		styles, ok := openaiImageStyles[model]
+		if !ok {
+			return req, fmt.Errorf("image style is not supported by %s", model)
+		}
+		if !slices.Contains(styles, req.Style) {
+			return req, fmt.Errorf("image style %s is not supported by %s, supported styles: %s", req.Style, model, strings.Join(styles, ", "))
+		}
+	}
+
+	if req.Background != "" {
+		backgrounds, ok := openaiImageBackgrounds[model]
+		if !ok {
+			return req, fmt.Errorf("image background is not supported by %s", model)
+		}
+		if !slices.Contains(backgrounds, req.Background) {
+			return req, fmt.Errorf("image background %s is not supported by %s, supported backgrounds: %s", req.Background, model, strings.Join(backgrounds, ", "))
+		}
+		// JPEG has no alpha channel
+		if req.Background == openai.CreateImageBackgroundTransparent && req.OutputFormat == openai.CreateImageOutputFormatJPEG {
+			return req, fmt.Errorf("transparent image background requires PNG output")
+		}
+	}
+
+	return req, nil
+}
+
+// openrouterAspectRatioFor maps pixel dimensions to the nearest aspect ratio
+// accepted by OpenRouter
+func openrouterAspectRatioFor(width int, height int) (string, error) {
+	if width <= 0 || height <= 0 {
+		return "", fmt.Errorf("invalid image dimensions %dx%d", width, height)
+	}
+
+	requested := float64(width) / float64(height)
+	best := ""
+	bestDiff := math.MaxFloat64
+	for _, ratio := range openrouterAspectRatios {
+		w, h := parseAspectRatio(ratio)
+		diff := math.Abs(requested-float64(w)/float64(h)) / (float64(w) / float64(h))
+		if diff < bestDiff {
+			best = ratio
+			bestDiff = diff
+		}
+	}
+
+	if bestDiff > imageAspectTolerance {
+		return "", fmt.Errorf("image size %dx%d is not supported by OpenRouter", width, height)
+	}
+
+	return best, nil
+}
+
+// sameAspectRatio reports whether two sizes have the same aspect ratio
+// within imageAspectTolerance
+func sameAspectRatio(w1 int, h1 int, w2 int, h2 int) bool {
+	r1 := float64(w1) / float64(h1)
+	r2 := float64(w2) / float64(h2)
+	return math.Abs(r1-r2)/r2 <= imageAspectTolerance
+}
+
+// parseImageSize parses a "WIDTHxHEIGHT" size string
+func parseImageSize(size string) (int, int) {
+	w, h, _ := strings.Cut(size, "x")
+	width, _ := strconv.Atoi(w)
+	height, _ := strconv.Atoi(h)
+	return width, height
+}
+
+// parseAspectRatio parses a "W:H" aspect ratio string
+func parseAspectRatio(ratio string) (int, int) {
+	w, h, _ := strings.Cut(ratio, ":")
+	width, _ := strconv.Atoi(w)
+	height, _ := strconv.Atoi(h)
+	return width, height
+}
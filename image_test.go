@@ -0,0 +1,427 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	vertexgenai "cloud.google.com/go/vertexai/genai"
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestOpenaiImageSizeFor(t *testing.T) {
+	size, err := openaiImageSizeFor("dall-e-3", 1024, 1792)
+	if err != nil {
+		t.Fatalf("expected 1024x1792 to be supported: %v", err)
+	}
+	if size != "1024x1792" {
+		t.Errorf("expected 1024x1792, got %s", size)
+	}
+
+	// Nearest square size for dall-e-2
+	size, err = openaiImageSizeFor("dall-e-2", 500, 500)
+	if err != nil || size != "512x512" {
+		t.Errorf("expected 512x512, got %s (err=%v)", size, err)
+	}
+
+	if _, err := openaiImageSizeFor("dall-e-3", 800, 600); err == nil {
+		t.Error("expected 800x600 to be rejected")
+	}
+
+	// 1536x1024 is only supported by gpt-image-1
+	if _, err := openaiImageSizeFor("dall-e-3", 1536, 1024); err == nil {
+		t.Error("expected 1536x1024 to be rejected for dall-e-3")
+	}
+	if size, err := openaiImageSizeFor("gpt-image-1", 1536, 1024); err != nil || size != "1536x1024" {
+		t.Errorf("expected 1536x1024 for gpt-image-1, got %s (err=%v)", size, err)
+	}
+}
+
+func TestOpenrouterAspectRatioFor(t *testing.T) {
+	ratio, err := openrouterAspectRatioFor(1024, 1792)
+	if err != nil || ratio != "9:16" {
+		t.Errorf("expected 9:16, got %s (err=%v)", ratio, err)
+	}
+
+	ratio, err = openrouterAspectRatioFor(800, 600)
+	if err != nil || ratio != "4:3" {
+		t.Errorf("expected 4:3, got %s (err=%v)", ratio, err)
+	}
+
+	if _, err := openrouterAspectRatioFor(3000, 500); err == nil {
+		t.Error("expected 6:1 to be rejected")
+	}
+}
+
+func TestGenerateImageSizeRejectsUnsupported(t *testing.T) {
+	engine, err := NewLLM(LlmOptions{Provider: ProviderOpenAI, ApiKey: "test-key", Model: "dall-e-3"})
+	if err != nil {
+		t.Fatalf("Failed to create OpenAI LLM: %v", err)
+	}
+
+	sizer, ok := engine.(ImageSizeInterface)
+	if !ok {
+		t.Fatal("OpenAI provider does not implement ImageSizeInterface")
+	}
+
+	if _, err := sizer.GenerateImageSize("a cat", 800, 600); err == nil {
+		t.Error("expected 800x600 to be rejected before calling the API")
+	}
+}
+
+func TestOpenaiImageRequest(t *testing.T) {
+	req, err := openaiImageRequest("a cat", LlmOptions{
+		Model: "gpt-4o",
+		ProviderOptions: map[string]any{
+			"model":   "dall-e-3",
+			"size":    "1792x1024",
+			"quality": "hd",
+			"style":   "natural",
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if req.Model != "dall-e-3" || req.Size != "1792x1024" || req.Quality != "hd" || req.Style != "natural" {
+		t.Errorf("provider options not applied: %+v", req)
+	}
+	if req.ResponseFormat != "b64_json" {
+		t.Errorf("expected b64_json response format, got %q", req.ResponseFormat)
+	}
+
+	// Defaults and the legacy image_size key
+	req, err = openaiImageRequest("a cat", LlmOptions{Model: "dall-e-2"})
+	if err != nil || req.Size != "1024x1024" {
+		t.Errorf("expected default 1024x1024, got %s (err=%v)", req.Size, err)
+	}
+	req, err = openaiImageRequest("a cat", LlmOptions{Model: "dall-e-2", ProviderOptions: map[string]any{"image_size": "512x512"}})
+	if err != nil || req.Size != "512x512" {
+		t.Errorf("expected image_size to be honored, got %s (err=%v)", req.Size, err)
+	}
+
+	// gpt-image-1 rejects response_format
+	req, err = openaiImageRequest("a cat", LlmOptions{Model: "gpt-image-1", ProviderOptions: map[string]any{"quality": "high"}})
+	if err != nil || req.ResponseFormat != "" {
+		t.Errorf("expected no response format for gpt-image-1, got %q (err=%v)", req.ResponseFormat, err)
+	}
+}
+
+func TestOpenaiImageRequestRejectsUnsupported(t *testing.T) {
+	cases := map[string]LlmOptions{
+		"size":        {Model: "dall-e-3", ProviderOptions: map[string]any{"size": "1536x1024"}},
+		"quality":     {Model: "dall-e-3", ProviderOptions: map[string]any{"quality": "high"}},
+		"style":       {Model: "gpt-image-1", ProviderOptions: map[string]any{"style": "vivid"}},
+		"style value": {Model: "dall-e-3", ProviderOptions: map[string]any{"style": "cartoon"}},
+	}
+	for name, options := range cases {
+		if _, err := openaiImageRequest("a cat", options); err == nil {
+			t.Errorf("expected unsupported %s to be rejected", name)
+		}
+	}
+}
+
+func TestOpenaiImageBackground(t *testing.T) {
+	req, err := openaiImageRequest("a logo", LlmOptions{
+		Model:           openai.CreateImageModelGptImage1,
+		ProviderOptions: map[string]any{"background": "transparent"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	body, _ := json.Marshal(req)
+	if !strings.Contains(string(body), `"background":"transparent"`) {
+		t.Errorf("expected background to be sent, got %s", body)
+	}
+
+	// Unset backgrounds are not sent
+	req, _ = openaiImageRequest("a logo", LlmOptions{Model: openai.CreateImageModelGptImage1})
+	body, _ = json.Marshal(req)
+	if strings.Contains(string(body), `"background"`) {
+		t.Errorf("expected no background, got %s", body)
+	}
+
+	cases := map[string]LlmOptions{
+		"dall-e-3":    {Model: openai.CreateImageModelDallE3, ProviderOptions: map[string]any{"background": "transparent"}},
+		"value":       {Model: openai.CreateImageModelGptImage1, ProviderOptions: map[string]any{"background": "checkered"}},
+		"jpeg output": {Model: openai.CreateImageModelGptImage1, OutputFormat: OutputFormatImageJPG, ProviderOptions: map[string]any{"background": "transparent"}},
+	}
+	for name, options := range cases {
+		if _, err := openaiImageRequest("a logo", options); err == nil {
+			t.Errorf("expected unsupported background for %s to be rejected", name)
+		}
+	}
+}
+
+// testPNG returns a small encoded PNG image
+func testPNG(t *testing.T) []byte {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatalf("failed to encode PNG: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestOpenaiImageOutputFormat(t *testing.T) {
+	encoded := base64.StdEncoding.EncodeToString(testPNG(t))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"data":[{"b64_json":%q}]}`, encoded)
+	}))
+	defer server.Close()
+
+	config := openai.DefaultConfig("test-key")
+	config.BaseURL = server.URL
+	engine := &openaiImplementation{
+		client:               openai.NewClientWithConfig(config),
+		model:                openai.CreateImageModelDallE3,
+		lastResponseRecorder: newLastResponseRecorder(nil),
+	}
+
+	for _, format := range []OutputFormat{"", OutputFormatImagePNG, OutputFormatImageJPG} {
+		data, err := engine.GenerateImage("a cat", LlmOptions{OutputFormat: format})
+		if err != nil {
+			t.Fatalf("GenerateImage(%q) failed: %v", format, err)
+		}
+		want := string(imageOutputFormat(LlmOptions{OutputFormat: format}))
+		if got := http.DetectContentType(data); got != want {
+			t.Errorf("GenerateImage(%q) returned %s, want %s", format, got, want)
+		}
+	}
+
+	req, err := openaiImageRequest("a cat", LlmOptions{Model: openai.CreateImageModelGptImage1, OutputFormat: OutputFormatImageJPG})
+	if err != nil || req.OutputFormat != openai.CreateImageOutputFormatJPEG {
+		t.Errorf("expected jpeg output_format for gpt-image-1, got %q (err=%v)", req.OutputFormat, err)
+	}
+}
+
+func TestOpenrouterImageOutputFormat(t *testing.T) {
+	encoded := base64.StdEncoding.EncodeToString(testPNG(t))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"choices":[{"message":{"role":"assistant","images":[{"type":"image_url","image_url":{"url":"data:image/png;base64,%s"}}]}}]}`, encoded)
+	}))
+	defer server.Close()
+
+	engine := &openrouterImplementation{
+		model:                OPENROUTER_MODEL_GEMINI_2_5_FLASH_IMAGE,
+		baseURL:              server.URL,
+		httpClient:           http.DefaultClient,
+		lastResponseRecorder: newLastResponseRecorder(nil),
+	}
+
+	data, err := engine.GenerateImage("a cat")
+	if err != nil {
+		t.Fatalf("GenerateImage failed: %v", err)
+	}
+	if got := http.DetectContentType(data); got != "image/png" {
+		t.Errorf("expected PNG by default, got %s", got)
+	}
+
+	data, err = engine.GenerateImage("a cat", LlmOptions{OutputFormat: OutputFormatImageJPG})
+	if err != nil {
+		t.Fatalf("GenerateImage failed: %v", err)
+	}
+	if got := http.DetectContentType(data); got != "image/jpeg" {
+		t.Errorf("expected JPEG, got %s", got)
+	}
+}
+
+func TestGenerateImageWithText(t *testing.T) {
+	encoded := base64.StdEncoding.EncodeToString(testPNG(t))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"choices":[{"message":{"role":"assistant","content":"A cat on a sofa.","images":[{"type":"image_url","image_url":{"url":"data:image/png;base64,%s"}}]}}]}`, encoded)
+	}))
+	defer server.Close()
+
+	engine := &openrouterImplementation{
+		model:                OPENROUTER_MODEL_GEMINI_2_5_FLASH_IMAGE,
+		baseURL:              server.URL,
+		httpClient:           http.DefaultClient,
+		lastResponseRecorder: newLastResponseRecorder(nil),
+	}
+
+	data, text, err := GenerateImageWithText(engine, "a cat")
+	if err != nil {
+		t.Fatalf("GenerateImageWithText failed: %v", err)
+	}
+	if http.DetectContentType(data) != "image/png" || text != "A cat on a sofa." {
+		t.Errorf("expected the image and its caption, got %s and %q", http.DetectContentType(data), text)
+	}
+
+	// Providers without ImageTextInterface return the image without text
+	data, text, err = GenerateImageWithText(&CustomTestLLM{}, "a cat")
+	if err != nil || string(data) != "test image data" || text != "" {
+		t.Errorf("expected the image without text, got %q, %q, %v", data, text, err)
+	}
+}
+
+func TestVertexImageOutputFormat(t *testing.T) {
+	resp := &vertexgenai.GenerateContentResponse{
+		Candidates: []*vertexgenai.Candidate{{
+			Content: &vertexgenai.Content{Parts: []vertexgenai.Part{
+				vertexgenai.Text("here is your image"),
+				vertexgenai.Blob{MIMEType: "image/png", Data: testPNG(t)},
+			}},
+		}},
+	}
+
+	data, text, err := vertexImageAndText(resp, OutputFormatImageJPG)
+	if err != nil {
+		t.Fatalf("vertexImageAndText failed: %v", err)
+	}
+	if got := http.DetectContentType(data); got != "image/jpeg" {
+		t.Errorf("expected JPEG, got %s", got)
+	}
+	if text != "here is your image" {
+		t.Errorf("expected the accompanying text, got %q", text)
+	}
+
+	data, _, err = vertexImageAndText(resp, OutputFormatImagePNG)
+	if err != nil || http.DetectContentType(data) != "image/png" {
+		t.Errorf("expected the PNG unchanged, got %s (err=%v)", http.DetectContentType(data), err)
+	}
+
+	// The blob in the requested format is preferred over the first image
+	jpeg, err := convertImage(testPNG(t), OutputFormatImageJPG)
+	if err != nil {
+		t.Fatalf("convertImage failed: %v", err)
+	}
+	resp.Candidates[0].Content.Parts = append(resp.Candidates[0].Content.Parts,
+		vertexgenai.Blob{MIMEType: "image/jpeg", Data: jpeg})
+	data, _, err = vertexImageAndText(resp, OutputFormatImageJPG)
+	if err != nil || !bytes.Equal(data, jpeg) {
+		t.Errorf("expected the JPEG blob as returned, got %s (err=%v)", http.DetectContentType(data), err)
+	}
+}
+
+func TestConvertImageRejectsUndecodable(t *testing.T) {
+	webp := []byte("RIFF\x00\x00\x00\x00WEBPVP8 ")
+	if _, err := convertImage(webp, OutputFormatImagePNG); err == nil {
+		t.Error("expected an error converting an undecodable image")
+	}
+}
+
+func TestGenerateImageCancellation(t *testing.T) {
+	// The server hangs until the client gives up on the request, or the
+	// test ends
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	for _, provider := range []Provider{ProviderOpenAI, ProviderOpenRouter} {
+		engine, err := NewLLM(LlmOptions{
+			Provider:        provider,
+			ApiKey:          "test-key",
+			Model:           "gpt-image-1",
+			ProviderOptions: map[string]any{"base_url": server.URL},
+		})
+		if err != nil {
+			t.Fatalf("%s: failed to create LLM: %v", provider, err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(50*time.Millisecond, cancel)
+		start := time.Now()
+		if _, err := engine.GenerateImage("a cat", LlmOptions{Context: ctx}); !errors.Is(err, context.Canceled) {
+			t.Errorf("%s: expected context.Canceled, got %v", provider, err)
+		}
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Errorf("%s: expected the call to stop on cancel, took %s", provider, elapsed)
+		}
+
+		if _, err := engine.GenerateImage("a cat", LlmOptions{Timeout: 50 * time.Millisecond}); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("%s: expected context.DeadlineExceeded, got %v", provider, err)
+		}
+	}
+}
+
+func TestGenerateImageEx(t *testing.T) {
+	data := testPNG(t)
+	result, err := DecodeImageResult(data)
+	if err != nil {
+		t.Fatalf("DecodeImageResult failed: %v", err)
+	}
+	if result.MimeType != "image/png" || result.Width != 4 || result.Height != 4 || !bytes.Equal(result.Data, data) {
+		t.Errorf("unexpected result: %s %dx%d", result.MimeType, result.Width, result.Height)
+	}
+
+	invalid := map[string][]byte{
+		"empty":     nil,
+		"text":      []byte("test image data"),
+		"truncated": data[:12],
+	}
+	for name, data := range invalid {
+		if _, err := DecodeImageResult(data); !errors.Is(err, ErrInvalidImage) {
+			t.Errorf("%s: expected ErrInvalidImage, got %v", name, err)
+		}
+	}
+
+	// The generated image is checked
+	if _, err := GenerateImageEx(&CustomTestLLM{}, "a cat"); !errors.Is(err, ErrInvalidImage) {
+		t.Errorf("expected ErrInvalidImage for a non-image payload, got %v", err)
+	}
+}
+
+func TestImageToDataURI(t *testing.T) {
+	data := testPNG(t)
+	dataURI, err := ImageToDataURI(data)
+	if err != nil {
+		t.Fatalf("ImageToDataURI failed: %v", err)
+	}
+	if dataURI != "data:image/png;base64,"+base64.StdEncoding.EncodeToString(data) {
+		t.Errorf("unexpected data URI: %s", dataURI)
+	}
+
+	for _, data := range [][]byte{nil, []byte("test image data")} {
+		if _, err := ImageToDataURI(data); !errors.Is(err, ErrInvalidImage) {
+			t.Errorf("expected ErrInvalidImage for %q, got %v", data, err)
+		}
+	}
+}
+
+func TestSaveImage(t *testing.T) {
+	data := testPNG(t)
+	dir := t.TempDir()
+
+	// The extension is added when missing
+	if err := SaveImage(data, filepath.Join(dir, "cat")); err != nil {
+		t.Fatalf("SaveImage failed: %v", err)
+	}
+	saved, err := os.ReadFile(filepath.Join(dir, "cat.png"))
+	if err != nil || !bytes.Equal(saved, data) {
+		t.Errorf("expected the image in cat.png, got %v", err)
+	}
+
+	if err := SaveImage(data, filepath.Join(dir, "dog.PNG")); err != nil {
+		t.Errorf("expected a matching extension to be kept, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "dog.PNG")); err != nil {
+		t.Errorf("expected dog.PNG to be written: %v", err)
+	}
+
+	if err := SaveImage(data, filepath.Join(dir, "cat.jpg")); !errors.Is(err, ErrInvalidImage) {
+		t.Errorf("expected ErrInvalidImage for a mismatched extension, got %v", err)
+	}
+	if err := SaveImage([]byte("test image data"), filepath.Join(dir, "text")); !errors.Is(err, ErrInvalidImage) {
+		t.Errorf("expected ErrInvalidImage for non-image data, got %v", err)
+	}
+}
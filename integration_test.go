@@ -254,7 +254,7 @@ func TestFactoryIntegration(t *testing.T) {
 			}
 
 			// Create LLM using factory
-			llmEngine, err := createProvider(p.provider, OutputFormatText, LlmOptions{})
+			llmEngine, err := createProvider(p.provider, OutputFormatText, LlmOptions{MockResponse: "A contract is a binding agreement."})
 			if err != nil {
 				t.Fatalf("Failed to create %s LLM: %v", p.name, err)
 			}
@@ -269,7 +269,7 @@ func TestFactoryIntegration(t *testing.T) {
 			if err != nil {
 				t.Errorf("%s generation failed: %v", p.name, err)
 			}
-			if response == "" && p.provider != ProviderMock {
+			if response == "" {
 				t.Errorf("%s returned empty response", p.name)
 			}
 			t.Logf("%s response: %s", p.name, response)
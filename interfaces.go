@@ -1,9 +1,13 @@
 package llm
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"sync"
+	"time"
 )
 
 // LlmInterface is an interface for making LLM API calls
@@ -14,6 +18,12 @@ type LlmInterface interface {
 	// GenerateJSON generates a JSON response from the LLM based on the given prompt
 	GenerateJSON(systemPrompt string, userPrompt string, options ...LlmOptions) (string, error)
 
+	// GenerateXML generates an XML response from the LLM based on the given prompt
+	GenerateXML(systemPrompt string, userPrompt string, options ...LlmOptions) (string, error)
+
+	// GenerateYAML generates a YAML response from the LLM based on the given prompt
+	GenerateYAML(systemPrompt string, userPrompt string, options ...LlmOptions) (string, error)
+
 	// GenerateImage generates an image from the LLM based on the given prompt
 	GenerateImage(prompt string, options ...LlmOptions) ([]byte, error)
 
@@ -32,6 +42,16 @@ type LlmOptions struct {
 	// instead of making an actual API call. This is useful for testing.
 	MockResponse string `json:"-"`
 
+	// MockResponses scripts the mock implementation's responses by user
+	// message. A key equal to the user message wins, then the first key, in
+	// sorted order, that matches it as a regular expression, then the ""
+	// key as the fallback. MockResponse takes precedence when set.
+	MockResponses map[string]string `json:"-"`
+
+	// Context, if set, is the parent context of the provider requests,
+	// allowing callers to cancel them or bound them by a deadline
+	Context context.Context `json:"-"`
+
 	// ApiKey specifies the API key for the LLM provider
 	ApiKey string
 
@@ -53,6 +73,40 @@ type LlmOptions struct {
 	// Use PtrFloat64(0.7) to set, or leave nil to use the provider default.
 	Temperature *float64
 
+	// Stop lists sequences at which the provider stops generating.
+	// An empty slice is omitted from the request. The mock truncates its
+	// response at the first stop sequence.
+	Stop []string
+
+	// TopP sets nucleus sampling: only the tokens within the top TopP
+	// probability mass are considered (OpenAI-compatible providers, Gemini,
+	// Vertex). Use PtrFloat64(0.9) to set, or leave nil to use the provider
+	// default.
+	TopP *float64
+
+	// TopK limits sampling to the TopK most likely tokens (Gemini, Vertex).
+	// Use PtrInt(40) to set, or leave nil to use the provider default.
+	TopK *int
+
+	// Seed requests deterministic sampling, so repeated calls with the same
+	// seed and parameters return the same output (OpenAI, OpenRouter).
+	// Other providers ignore it. The mock includes it in its default response.
+	// Use PtrInt(42) to set, or leave nil for non-deterministic sampling.
+	Seed *int
+
+	// PresencePenalty and FrequencyPenalty, between -2.0 and 2.0, penalize
+	// tokens that already appeared in the text, or by how often they
+	// appeared, to reduce repetition (OpenAI, OpenRouter, Mistral, Groq,
+	// DeepSeek). Gemini, Vertex, Anthropic and the others ignore them. Use
+	// PtrFloat64(0.5) to set, or leave nil to omit them from the request.
+	PresencePenalty  *float64
+	FrequencyPenalty *float64
+
+	// N is the number of candidate responses GenerateN requests (OpenAI,
+	// OpenRouter, Gemini, Vertex). The other calls ignore it and request
+	// one response. 0 requests one candidate.
+	N int
+
 	// Verbose controls whether to log detailed information
 	Verbose bool
 
@@ -62,8 +116,71 @@ type LlmOptions struct {
 	// OutputFormat specifies the output format from the LLM
 	OutputFormat OutputFormat
 
+	// DisableJSONInstruction stops Anthropic, Gemini and Vertex from appending
+	// "You must respond with valid JSON only..." to the system prompt for
+	// JSON output, for callers that already craft their own JSON guidance
+	DisableJSONInstruction bool
+
+	// Timeout bounds each request. HTTP-based providers use it as the
+	// http.Client timeout (default 30s), SDK-based providers wrap the request
+	// context with it. Can also be set via ProviderOptions["timeout_ms"].
+	Timeout time.Duration
+
+	// HTTPClient, if set, sends the requests of the OpenAI-compatible
+	// providers (OpenAI, OpenRouter, Mistral, Groq, DeepSeek), Cohere,
+	// Hugging Face and Custom, e.g. to share a transport or set your own timeout. Otherwise
+	// they use a client with the Timeout.
+	HTTPClient *http.Client `json:"-"`
+
+	// MaxCostUSD, if greater than zero, is a hard budget for a single call.
+	// The worst-case cost (prompt tokens at the input price plus MaxTokens
+	// at the output price) is estimated from the pricing catalog before
+	// sending, and ErrCostExceeded is returned if it is above the budget.
+	// MaxTokens must be set, otherwise the calls fail as the output is
+	// unbounded.
+	MaxCostUSD float64
+
+	// SpendTracker, if set, accumulates the estimated cost of the client's
+	// calls and returns ErrBudgetExhausted once its ceiling is reached.
+	// The optional interfaces are forwarded to the provider client, and
+	// their text calls are billed too.
+	SpendTracker *SpendTracker `json:"-"`
+
+	// RequestsPerMinute, if greater than zero, limits the calls made through
+	// the client returned by NewLLM: calls are spaced evenly and block until
+	// the limiter allows them or their Context is done. The optional
+	// interfaces are forwarded to the provider client through the limiter.
+	RequestsPerMinute int
+
+	// Middlewares wrap the calls made through the client returned by NewLLM,
+	// the first being the outermost, e.g. LoggingMiddleware to record
+	// latency, tokens and errors. They run around the RequestsPerMinute and
+	// SpendTracker checks. The calls of the optional interfaces, such as
+	// GenerateStream, run through them too, except ListModels,
+	// DebugMessages, EffectiveOptions and LastRawResponse.
+	Middlewares []Middleware `json:"-"`
+
+	// EmbeddingLlm, if set, receives the GenerateEmbedding calls instead of
+	// this provider, e.g. to chat with Anthropic but embed with OpenAI
+	EmbeddingLlm LlmInterface `json:"-"`
+
+	// MaxRetries is the number of times a generation request failing with a
+	// transient error (429, 5xx or a network timeout) is retried, with
+	// exponential backoff. Zero disables retries.
+	MaxRetries int
+
+	// OnRetry, if set, is called before each retry sleep with the retry
+	// number (starting at 1), the error being retried and the delay
+	OnRetry func(attempt int, err error, delay time.Duration) `json:"-"`
+
 	// Additional options specific to the LLM provider
 	ProviderOptions map[string]any
+
+	// responseSchema is the JSON schema set by GenerateStructured
+	responseSchema json.RawMessage
+
+	// rawCapture receives the raw response of a GenerateRaw call
+	rawCapture *RawResponse
 }
 
 // LlmFactory is a function type that creates a new LLM instance
@@ -89,12 +206,18 @@ func RegisterCustomProvider(name string, factory LlmFactory) {
 	RegisterProvider(Provider(name), factory)
 }
 
-// NewLLM creates a new LLM instance based on the provider specified in options
+// NewLLM creates a new LLM instance based on the provider specified in
+// options, or a mock while test mode is on (see SetTestMode)
 func NewLLM(options LlmOptions) (LlmInterface, error) {
+	if llm, ok := testModeLLM(options); ok {
+		return withMiddlewares(withSpendTracker(withRateLimiter(llm, options), options), llm, options), nil
+	}
+
 	if options.Provider == "" {
 		// Default to OpenAI if no provider is specified
 		options.Provider = ProviderOpenAI
 	}
+	options.Model = ResolveModelAlias(options.Provider, options.Model)
 
 	providerMu.RLock()
 	factory, exists := providerFactories[options.Provider]
@@ -103,11 +226,15 @@ func NewLLM(options LlmOptions) (LlmInterface, error) {
 		return nil, fmt.Errorf("unsupported LLM provider: %s", options.Provider)
 	}
 
+	if err := validateOutputFormat(options.Provider, options.OutputFormat); err != nil {
+		return nil, err
+	}
+
 	llm, err := factory(options)
 	if err != nil {
 		return nil, err
 	}
-	return llm, nil
+	return withMiddlewares(withSpendTracker(withRateLimiter(llm, options), options), llm, options), nil
 }
 
 // PtrFloat64 returns a pointer to the given float64 value.
@@ -116,6 +243,12 @@ func PtrFloat64(v float64) *float64 {
 	return &v
 }
 
+// PtrInt returns a pointer to the given int value.
+// This is a convenience helper for setting TopK and Seed in LlmOptions.
+func PtrInt(v int) *int {
+	return &v
+}
+
 // init registers the built-in LLM providers
 func init() {
 	// Register built-in providers
@@ -146,4 +279,24 @@ func init() {
 	RegisterProvider(ProviderCustom, func(options LlmOptions) (LlmInterface, error) {
 		return newCustomImplementation(options)
 	})
+
+	RegisterProvider(ProviderCohere, func(options LlmOptions) (LlmInterface, error) {
+		return newCohereImplementation(options)
+	})
+
+	RegisterProvider(ProviderMistral, func(options LlmOptions) (LlmInterface, error) {
+		return newMistralImplementation(options)
+	})
+
+	RegisterProvider(ProviderGroq, func(options LlmOptions) (LlmInterface, error) {
+		return newGroqImplementation(options)
+	})
+
+	RegisterProvider(ProviderDeepSeek, func(options LlmOptions) (LlmInterface, error) {
+		return newDeepSeekImplementation(options)
+	})
+
+	RegisterProvider(ProviderHuggingFace, func(options LlmOptions) (LlmInterface, error) {
+		return newHuggingFaceImplementation(options)
+	})
 }
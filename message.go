@@ -0,0 +1,107 @@
+package llm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Message roles
+const (
+	MessageRoleSystem    = "system"
+	MessageRoleUser      = "user"
+	MessageRoleAssistant = "assistant"
+)
+
+// Message is a single message of a chat conversation
+type Message struct {
+	// Role is one of MessageRoleSystem, MessageRoleUser or MessageRoleAssistant
+	Role string `json:"role"`
+
+	// Content is the text of the message
+	Content string `json:"content"`
+
+	// Images are attached to the message as image input, only supported by
+	// providers implementing VisionInterface
+	Images [][]byte `json:"-"`
+}
+
+// ChatInterface is implemented by providers that accept a conversation as
+// a list of chat messages natively (currently OpenAI, OpenRouter, Anthropic
+// and Custom)
+type ChatInterface interface {
+	// GenerateChat generates the next assistant message for the conversation
+	GenerateChat(messages []Message, options ...LlmOptions) (string, error)
+}
+
+// GenerateChat generates the next assistant message for a conversation.
+// Providers implementing ChatInterface receive the messages as they are,
+// for the others the system messages are joined into the system prompt and
+// the remaining messages are sent as a transcript in the user message.
+func GenerateChat(llm LlmInterface, messages []Message, options ...LlmOptions) (string, error) {
+	if chat, ok := llm.(ChatInterface); ok {
+		return chat.GenerateChat(messages, options...)
+	}
+
+	if hasImages(messages) {
+		return "", fmt.Errorf("%w: image input", ErrNotSupported)
+	}
+
+	systemPrompt, userMessage := flattenMessages(messages)
+	return llm.Generate(systemPrompt, userMessage, options...)
+}
+
+// flattenMessages converts a conversation into a system prompt and a single
+// user message. A conversation with only one user message keeps it as is.
+func flattenMessages(messages []Message) (string, string) {
+	system, conversation := splitSystemMessages(messages)
+
+	if len(conversation) == 1 && conversation[0].Role == MessageRoleUser {
+		return system, conversation[0].Content
+	}
+
+	lines := make([]string, 0, len(conversation))
+	for _, message := range conversation {
+		role := message.Role
+		if role != "" {
+			role = strings.ToUpper(role[:1]) + role[1:]
+		}
+		lines = append(lines, role+": "+message.Content)
+	}
+
+	return system, strings.Join(lines, "\n\n")
+}
+
+// splitSystemMessages returns the joined content of the system messages and
+// the remaining messages
+func splitSystemMessages(messages []Message) (string, []Message) {
+	system := []string{}
+	conversation := make([]Message, 0, len(messages))
+	for _, message := range messages {
+		if message.Role == MessageRoleSystem {
+			system = append(system, message.Content)
+			continue
+		}
+		conversation = append(conversation, message)
+	}
+	return strings.Join(system, "\n\n"), conversation
+}
+
+// messagesContent joins the content of all messages, used for estimating
+// the prompt size of a conversation
+func messagesContent(messages []Message) string {
+	contents := make([]string, len(messages))
+	for i, message := range messages {
+		contents[i] = message.Content
+	}
+	return strings.Join(contents, "\n")
+}
+
+// hasImages reports whether any of the messages has images attached
+func hasImages(messages []Message) bool {
+	for _, message := range messages {
+		if len(message.Images) > 0 {
+			return true
+		}
+	}
+	return false
+}
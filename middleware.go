@@ -0,0 +1,386 @@
+package llm
+
+import (
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// Call names of GenerateRequest.Call
+const (
+	CallGenerate          = "Generate"
+	CallGenerateText      = "GenerateText"
+	CallGenerateJSON      = "GenerateJSON"
+	CallGenerateXML       = "GenerateXML"
+	CallGenerateYAML      = "GenerateYAML"
+	CallGenerateChat      = "GenerateChat"
+	CallGenerateImage     = "GenerateImage"
+	CallGenerateEmbedding = "GenerateEmbedding"
+
+	// Calls of the optional interfaces. CallGenerateStream names both
+	// GenerateStream and GenerateStreamWithUsage.
+	CallGenerateStream        = "GenerateStream"
+	CallGenerateWithImages    = "GenerateWithImages"
+	CallGenerateWithTools     = "GenerateWithTools"
+	CallGenerateN             = "GenerateN"
+	CallGenerateStructured    = "GenerateStructured"
+	CallGenerateImageSize     = "GenerateImageSize"
+	CallGenerateImageWithText = "GenerateImageWithText"
+)
+
+// GenerateRequest describes a call passed through the middleware chain
+type GenerateRequest struct {
+	// Call is the name of the called method, e.g. CallGenerateJSON
+	Call string
+
+	// Provider and Model are those the call uses, after the defaults and
+	// the per-call options are applied
+	Provider Provider
+	Model    string
+
+	// SystemPrompt and UserPrompt are the prompts of the text calls.
+	// UserPrompt is the prompt of the image calls and the text of
+	// GenerateEmbedding.
+	SystemPrompt string
+	UserPrompt   string
+
+	// Messages are the messages of GenerateChat
+	Messages []Message
+
+	// Images are the images of GenerateWithImages
+	Images [][]byte
+
+	// Tools are the tools of GenerateWithTools
+	Tools []ToolDefinition
+
+	// Schema is the JSON schema of GenerateStructured
+	Schema json.RawMessage
+
+	// Width and Height are the dimensions of GenerateImageSize
+	Width  int
+	Height int
+
+	// Options are the per-call options, if any
+	Options LlmOptions
+
+	// onChunk and onDone are the callbacks of the streaming calls, onDone
+	// being set only for GenerateStreamWithUsage
+	onChunk func(chunk string) error
+	onDone  func(usage Usage)
+}
+
+// GenerateResult is the result of a call passed through the middleware
+// chain. Only the fields of the call's result type are set.
+type GenerateResult struct {
+	// Text is the response of the text calls, the whole streamed text of
+	// GenerateStream, the JSON of GenerateStructured and the text sent
+	// with the image of GenerateImageWithText
+	Text string
+
+	// Texts are the candidates of GenerateN
+	Texts []string
+
+	// ToolCalls are the tool calls of GenerateWithTools
+	ToolCalls []ToolCall
+
+	// Image is the image of the image calls
+	Image []byte
+
+	// Embedding is the embedding of GenerateEmbedding
+	Embedding []float32
+}
+
+// GenerateFunc runs a call, the next middleware or eventually the client
+type GenerateFunc func(request GenerateRequest) (GenerateResult, error)
+
+// Middleware wraps the calls of a client, e.g. to record their latency,
+// tokens and errors. It calls next to run the call, and may change the
+// request or the result.
+type Middleware func(next GenerateFunc) GenerateFunc
+
+// withMiddlewares wraps llm to run its calls through options.Middlewares,
+// if any. The first middleware is the outermost. provider is the client
+// without wrappers, used to resolve the model of the calls.
+func withMiddlewares(llm LlmInterface, provider LlmInterface, options LlmOptions) LlmInterface {
+	if len(options.Middlewares) == 0 {
+		return llm
+	}
+
+	m := &middlewareLLM{llm: llm, provider: provider, providerName: options.Provider}
+	m.chain = m.call
+	for i := len(options.Middlewares) - 1; i >= 0; i-- {
+		m.chain = options.Middlewares[i](m.chain)
+	}
+	return m
+}
+
+// middlewareLLM runs the calls of the wrapped client through a middleware
+// chain
+type middlewareLLM struct {
+	llm          LlmInterface
+	provider     LlmInterface
+	providerName Provider
+	chain        GenerateFunc
+}
+
+// run passes the call through the chain, completing the request with the
+// provider, the model and the per-call options
+func (m *middlewareLLM) run(request GenerateRequest, opts []LlmOptions) (GenerateResult, error) {
+	perCall := firstOptions(opts)
+	effective := EffectiveOptions(m.provider, perCall)
+
+	request.Provider = m.providerName
+	if effective.Provider != "" {
+		request.Provider = effective.Provider
+	}
+	request.Model = effective.Model
+	request.Options = perCall
+
+	return m.chain(request)
+}
+
+// call is the end of the chain, calling the wrapped client
+func (m *middlewareLLM) call(request GenerateRequest) (GenerateResult, error) {
+	opts := []LlmOptions{request.Options}
+	result := GenerateResult{}
+	var err error
+
+	switch request.Call {
+	case CallGenerateText:
+		result.Text, err = m.llm.GenerateText(request.SystemPrompt, request.UserPrompt, opts...)
+	case CallGenerateJSON:
+		result.Text, err = m.llm.GenerateJSON(request.SystemPrompt, request.UserPrompt, opts...)
+	case CallGenerateXML:
+		result.Text, err = m.llm.GenerateXML(request.SystemPrompt, request.UserPrompt, opts...)
+	case CallGenerateYAML:
+		result.Text, err = m.llm.GenerateYAML(request.SystemPrompt, request.UserPrompt, opts...)
+	case CallGenerateChat:
+		result.Text, err = GenerateChat(m.llm, request.Messages, opts...)
+	case CallGenerateImage:
+		result.Image, err = m.llm.GenerateImage(request.UserPrompt, opts...)
+	case CallGenerateEmbedding:
+		result.Embedding, err = m.llm.GenerateEmbedding(request.UserPrompt)
+	case CallGenerateStream:
+		result.Text, err = m.stream(request, opts)
+	case CallGenerateWithImages:
+		result.Text, err = GenerateWithImages(m.llm, request.SystemPrompt, request.UserPrompt, request.Images, opts...)
+	case CallGenerateWithTools:
+		var toolResult ToolResult
+		toolResult, err = GenerateWithTools(m.llm, request.SystemPrompt, request.UserPrompt, request.Tools, opts...)
+		result.Text, result.ToolCalls = toolResult.Text, toolResult.ToolCalls
+	case CallGenerateN:
+		result.Texts, err = GenerateN(m.llm, request.SystemPrompt, request.UserPrompt, opts...)
+	case CallGenerateStructured:
+		var response json.RawMessage
+		response, err = generateStructuredWith(m.llm, request.SystemPrompt, request.UserPrompt, request.Schema, opts...)
+		result.Text = string(response)
+	case CallGenerateImageSize:
+		result.Image, err = generateImageSizeWith(m.llm, request.UserPrompt, request.Width, request.Height, opts...)
+	case CallGenerateImageWithText:
+		result.Image, result.Text, err = GenerateImageWithText(m.llm, request.UserPrompt, opts...)
+	default:
+		result.Text, err = m.llm.Generate(request.SystemPrompt, request.UserPrompt, opts...)
+	}
+
+	return result, err
+}
+
+// stream streams the response of a streaming call to its callbacks, and
+// returns the whole streamed text
+func (m *middlewareLLM) stream(request GenerateRequest, opts []LlmOptions) (string, error) {
+	var response strings.Builder
+	onChunk := func(chunk string) error {
+		response.WriteString(chunk)
+		return request.onChunk(chunk)
+	}
+
+	if request.onDone != nil {
+		err := GenerateStreamWithUsage(m.llm, request.SystemPrompt, request.UserPrompt, onChunk, request.onDone, opts...)
+		return response.String(), err
+	}
+	err := GenerateStream(m.llm, request.SystemPrompt, request.UserPrompt, onChunk, opts...)
+	return response.String(), err
+}
+
+// GenerateText implements LlmInterface
+func (m *middlewareLLM) GenerateText(systemPrompt string, userPrompt string, opts ...LlmOptions) (string, error) {
+	result, err := m.run(GenerateRequest{Call: CallGenerateText, SystemPrompt: systemPrompt, UserPrompt: userPrompt}, opts)
+	return result.Text, err
+}
+
+// GenerateJSON implements LlmInterface
+func (m *middlewareLLM) GenerateJSON(systemPrompt string, userPrompt string, opts ...LlmOptions) (string, error) {
+	result, err := m.run(GenerateRequest{Call: CallGenerateJSON, SystemPrompt: systemPrompt, UserPrompt: userPrompt}, opts)
+	return result.Text, err
+}
+
+// GenerateXML implements LlmInterface
+func (m *middlewareLLM) GenerateXML(systemPrompt string, userPrompt string, opts ...LlmOptions) (string, error) {
+	result, err := m.run(GenerateRequest{Call: CallGenerateXML, SystemPrompt: systemPrompt, UserPrompt: userPrompt}, opts)
+	return result.Text, err
+}
+
+// GenerateYAML implements LlmInterface
+func (m *middlewareLLM) GenerateYAML(systemPrompt string, userPrompt string, opts ...LlmOptions) (string, error) {
+	result, err := m.run(GenerateRequest{Call: CallGenerateYAML, SystemPrompt: systemPrompt, UserPrompt: userPrompt}, opts)
+	return result.Text, err
+}
+
+// Generate implements LlmInterface
+func (m *middlewareLLM) Generate(systemPrompt string, userMessage string, opts ...LlmOptions) (string, error) {
+	result, err := m.run(GenerateRequest{Call: CallGenerate, SystemPrompt: systemPrompt, UserPrompt: userMessage}, opts)
+	return result.Text, err
+}
+
+// GenerateChat implements ChatInterface
+func (m *middlewareLLM) GenerateChat(messages []Message, opts ...LlmOptions) (string, error) {
+	result, err := m.run(GenerateRequest{Call: CallGenerateChat, Messages: messages}, opts)
+	return result.Text, err
+}
+
+// GenerateImage implements LlmInterface
+func (m *middlewareLLM) GenerateImage(prompt string, opts ...LlmOptions) ([]byte, error) {
+	result, err := m.run(GenerateRequest{Call: CallGenerateImage, UserPrompt: prompt}, opts)
+	return result.Image, err
+}
+
+// GenerateEmbedding implements LlmInterface
+func (m *middlewareLLM) GenerateEmbedding(text string) ([]float32, error) {
+	result, err := m.run(GenerateRequest{Call: CallGenerateEmbedding, UserPrompt: text}, nil)
+	return result.Embedding, err
+}
+
+// GenerateStream implements StreamInterface
+func (m *middlewareLLM) GenerateStream(systemPrompt string, userMessage string, onChunk func(chunk string) error, opts ...LlmOptions) error {
+	_, err := m.run(GenerateRequest{Call: CallGenerateStream, SystemPrompt: systemPrompt, UserPrompt: userMessage, onChunk: onChunk}, opts)
+	return err
+}
+
+// GenerateStreamWithUsage implements StreamUsageInterface
+func (m *middlewareLLM) GenerateStreamWithUsage(systemPrompt string, userMessage string, onChunk func(chunk string) error, onDone func(usage Usage), opts ...LlmOptions) error {
+	_, err := m.run(GenerateRequest{Call: CallGenerateStream, SystemPrompt: systemPrompt, UserPrompt: userMessage, onChunk: onChunk, onDone: onDone}, opts)
+	return err
+}
+
+// GenerateWithImages implements VisionInterface
+func (m *middlewareLLM) GenerateWithImages(systemPrompt string, userPrompt string, images [][]byte, opts ...LlmOptions) (string, error) {
+	result, err := m.run(GenerateRequest{Call: CallGenerateWithImages, SystemPrompt: systemPrompt, UserPrompt: userPrompt, Images: images}, opts)
+	return result.Text, err
+}
+
+// GenerateWithTools implements ToolInterface
+func (m *middlewareLLM) GenerateWithTools(systemPrompt string, userPrompt string, tools []ToolDefinition, opts ...LlmOptions) (ToolResult, error) {
+	result, err := m.run(GenerateRequest{Call: CallGenerateWithTools, SystemPrompt: systemPrompt, UserPrompt: userPrompt, Tools: tools}, opts)
+	return ToolResult{Text: result.Text, ToolCalls: result.ToolCalls}, err
+}
+
+// GenerateN implements CandidatesInterface
+func (m *middlewareLLM) GenerateN(systemPrompt string, userPrompt string, opts ...LlmOptions) ([]string, error) {
+	result, err := m.run(GenerateRequest{Call: CallGenerateN, SystemPrompt: systemPrompt, UserPrompt: userPrompt}, opts)
+	return result.Texts, err
+}
+
+// GenerateStructured implements StructuredOutputInterface
+func (m *middlewareLLM) GenerateStructured(systemPrompt string, userPrompt string, schema json.RawMessage, opts ...LlmOptions) (json.RawMessage, error) {
+	result, err := m.run(GenerateRequest{Call: CallGenerateStructured, SystemPrompt: systemPrompt, UserPrompt: userPrompt, Schema: schema}, opts)
+	if result.Text == "" {
+		return nil, err
+	}
+	return json.RawMessage(result.Text), err
+}
+
+// GenerateImageSize implements ImageSizeInterface
+func (m *middlewareLLM) GenerateImageSize(prompt string, width int, height int, opts ...LlmOptions) ([]byte, error) {
+	result, err := m.run(GenerateRequest{Call: CallGenerateImageSize, UserPrompt: prompt, Width: width, Height: height}, opts)
+	return result.Image, err
+}
+
+// GenerateImageWithText implements ImageTextInterface
+func (m *middlewareLLM) GenerateImageWithText(prompt string, opts ...LlmOptions) ([]byte, string, error) {
+	result, err := m.run(GenerateRequest{Call: CallGenerateImageWithText, UserPrompt: prompt}, opts)
+	return result.Image, result.Text, err
+}
+
+// ListModels implements ModelListInterface. Listing is not passed through
+// the chain.
+func (m *middlewareLLM) ListModels() ([]ModelInfo, error) {
+	return ListModels(m.llm)
+}
+
+// DebugMessages implements DebugMessagesInterface
+func (m *middlewareLLM) DebugMessages(systemPrompt string, userMessage string, opts ...LlmOptions) []Message {
+	return DebugMessages(m.llm, systemPrompt, userMessage, opts...)
+}
+
+// EffectiveOptions implements EffectiveOptionsInterface
+func (m *middlewareLLM) EffectiveOptions(opts ...LlmOptions) LlmOptions {
+	return EffectiveOptions(m.llm, opts...)
+}
+
+// LastRawResponse implements RawResponseRecorderInterface
+func (m *middlewareLLM) LastRawResponse() (RawResponse, bool) {
+	return lastRawResponse(m.llm)
+}
+
+// Close implements io.Closer, closing the wrapped client
+func (m *middlewareLLM) Close() error {
+	return Close(m.llm)
+}
+
+// unwrap implements wrapperInterface
+func (m *middlewareLLM) unwrap() LlmInterface {
+	return m.llm
+}
+
+// LoggingMiddleware returns a middleware logging each call at info level,
+// or error level when it fails, with its provider, model, latency and
+// token counts estimated with CountTokensForModel. Prompts and responses
+// are not logged.
+func LoggingMiddleware(logger *slog.Logger) Middleware {
+	return func(next GenerateFunc) GenerateFunc {
+		return func(request GenerateRequest) (GenerateResult, error) {
+			start := time.Now()
+			result, err := next(request)
+
+			attrs := []any{
+				slog.String("call", request.Call),
+				slog.String("provider", string(request.Provider)),
+				slog.String("model", request.Model),
+				slog.Duration("latency", time.Since(start)),
+			}
+
+			if !isImageOrEmbeddingCall(request.Call) {
+				promptTokens := CountTokensForModel(request.SystemPrompt, request.Model) + CountTokensForModel(request.UserPrompt, request.Model)
+				if request.Call == CallGenerateChat {
+					promptTokens = CountChatTokens(request.Messages, request.Model)
+				}
+				completionTokens := CountTokensForModel(result.Text, request.Model)
+				for _, text := range result.Texts {
+					completionTokens += CountTokensForModel(text, request.Model)
+				}
+				attrs = append(attrs,
+					slog.Int("prompt_tokens", promptTokens),
+					slog.Int("completion_tokens", completionTokens))
+			}
+
+			if err != nil {
+				logger.Error("LLM call failed", append(attrs, slog.String("error", err.Error()))...)
+			} else {
+				logger.Info("LLM call", attrs...)
+			}
+
+			return result, err
+		}
+	}
+}
+
+// isImageOrEmbeddingCall reports whether a call generates an image or an
+// embedding, whose tokens are not logged
+func isImageOrEmbeddingCall(call string) bool {
+	switch call {
+	case CallGenerateImage, CallGenerateImageSize, CallGenerateImageWithText, CallGenerateEmbedding:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,153 @@
+package llm
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestMiddlewares(t *testing.T) {
+	calls := []string{}
+	record := func(name string) Middleware {
+		return func(next GenerateFunc) GenerateFunc {
+			return func(request GenerateRequest) (GenerateResult, error) {
+				calls = append(calls, name+" "+request.Call+" "+request.Model)
+				result, err := next(request)
+				calls = append(calls, name+" done")
+				return result, err
+			}
+		}
+	}
+
+	engine, err := NewLLM(LlmOptions{
+		Provider:     ProviderMock,
+		Model:        "gpt-4.1",
+		MockResponse: `{"ok":true}`,
+		Middlewares:  []Middleware{record("outer"), record("inner")},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create mock LLM: %v", err)
+	}
+
+	response, err := engine.GenerateJSON("system", "hello", LlmOptions{Model: "gpt-4.1-mini"})
+	if err != nil || response != `{"ok":true}` {
+		t.Fatalf("unexpected result: %q, %v", response, err)
+	}
+
+	expected := []string{"outer GenerateJSON gpt-4.1-mini", "inner GenerateJSON gpt-4.1-mini", "inner done", "outer done"}
+	if strings.Join(calls, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected calls %v, got %v", expected, calls)
+	}
+
+	calls = nil
+	if _, err := engine.GenerateImage("a cat"); err != nil {
+		t.Fatalf("GenerateImage failed: %v", err)
+	}
+	if len(calls) != 4 || calls[0] != "outer GenerateImage gpt-4.1" {
+		t.Errorf("expected GenerateImage through the chain, got %v", calls)
+	}
+}
+
+func TestMiddlewareChangesResult(t *testing.T) {
+	failure := errors.New("blocked")
+	engine, err := NewLLM(LlmOptions{
+		Provider:     ProviderMock,
+		MockResponse: "ok",
+		Middlewares: []Middleware{func(next GenerateFunc) GenerateFunc {
+			return func(request GenerateRequest) (GenerateResult, error) {
+				if strings.Contains(request.UserPrompt, "secret") {
+					return GenerateResult{}, failure
+				}
+				return next(request)
+			}
+		}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create mock LLM: %v", err)
+	}
+
+	if _, err := engine.GenerateText("system", "the secret"); !errors.Is(err, failure) {
+		t.Errorf("expected the middleware's error, got %v", err)
+	}
+	if response, err := engine.GenerateText("system", "hello"); err != nil || response != "ok" {
+		t.Errorf("unexpected result: %q, %v", response, err)
+	}
+}
+
+func TestLoggingMiddleware(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+
+	engine, err := NewLLM(LlmOptions{
+		Provider:     ProviderMock,
+		Model:        "gpt-4.1",
+		MockResponse: "hello world",
+		Middlewares:  []Middleware{LoggingMiddleware(logger)},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create mock LLM: %v", err)
+	}
+
+	if _, err := engine.GenerateText("system", "say hello"); err != nil {
+		t.Fatalf("GenerateText failed: %v", err)
+	}
+
+	line := logs.String()
+	for _, expected := range []string{"level=INFO", "call=GenerateText", "provider=mock", "model=gpt-4.1", "latency=", "prompt_tokens=3", "completion_tokens=2"} {
+		if !strings.Contains(line, expected) {
+			t.Errorf("expected %q in log line: %s", expected, line)
+		}
+	}
+	if strings.Contains(line, "say hello") {
+		t.Errorf("expected prompts not to be logged: %s", line)
+	}
+}
+
+func TestMiddlewareWrappedClientStreams(t *testing.T) {
+	var path string
+	server := usageStreamServer(t, &path)
+	defer server.Close()
+
+	var request GenerateRequest
+	var result GenerateResult
+	engine, err := NewLLM(LlmOptions{
+		Provider:          ProviderOpenAI,
+		ApiKey:            "test-key",
+		Model:             "gpt-5-nano",
+		RequestsPerMinute: 600,
+		SpendTracker:      NewSpendTracker(1),
+		ProviderOptions:   map[string]any{"base_url": server.URL},
+		Middlewares: []Middleware{func(next GenerateFunc) GenerateFunc {
+			return func(r GenerateRequest) (GenerateResult, error) {
+				request = r
+				res, err := next(r)
+				result = res
+				return res, err
+			}
+		}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create LLM: %v", err)
+	}
+
+	if _, ok := engine.(StreamInterface); !ok {
+		t.Fatal("expected the wrapped client to implement StreamInterface")
+	}
+
+	var chunks []string
+	err = GenerateStream(engine, "system", "hello", func(chunk string) error {
+		chunks = append(chunks, chunk)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("GenerateStream failed: %v", err)
+	}
+	if len(chunks) != 3 {
+		t.Errorf("expected the provider's stream, got chunks %q", chunks)
+	}
+	if request.Call != CallGenerateStream || result.Text != "Hello, world" {
+		t.Errorf("expected the stream through the chain, got call %q with text %q", request.Call, result.Text)
+	}
+}
@@ -0,0 +1,257 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+const mistralDefaultBaseURL = "https://api.mistral.ai/v1"
+const mistralDefaultModel = "mistral-medium"
+const mistralDefaultEmbeddingModel = "mistral-embed"
+
+// mistralImplementation implements LlmInterface using Mistral's
+// OpenAI-compatible La Plateforme API
+type mistralImplementation struct {
+	client      *openai.Client
+	httpClient  openai.HTTPDoer
+	model       string
+	maxTokens   int
+	temperature float64
+	verbose     bool
+	logger      *slog.Logger
+	options     LlmOptions
+	*lastResponseRecorder
+}
+
+// newMistralImplementation creates a new Mistral provider implementation
+func newMistralImplementation(options LlmOptions) (LlmInterface, error) {
+	apiKey := providerAPIKey(options)
+	if apiKey == "" {
+		return nil, fmt.Errorf("mistral API key is required")
+	}
+
+	model := strings.TrimSpace(options.Model)
+	if model == "" {
+		model = DefaultModel(ProviderMistral)
+	}
+
+	cfg := openai.DefaultConfig(apiKey)
+	cfg.BaseURL = providerBaseURL(options, mistralDefaultBaseURL)
+	httpClient, err := secureHTTPClient(ProviderMistral, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure mistral http client: %w", err)
+	}
+	cfg.HTTPClient = apiKeyHTTPClient(httpClient, options)
+
+	return &mistralImplementation{
+		client:      openai.NewClientWithConfig(cfg),
+		httpClient:  cfg.HTTPClient,
+		model:       model,
+		maxTokens:   options.MaxTokens,
+		temperature: derefFloat64(options.Temperature, 0.7),
+		verbose:     options.Verbose,
+		logger:      options.Logger,
+		options:     options,
+
+		lastResponseRecorder: newLastResponseRecorder(options.ProviderOptions),
+	}, nil
+}
+
+// baseOptions returns the construction options, with the struct fields
+// applied on top, as the base for merging per-call options.
+func (m *mistralImplementation) baseOptions() LlmOptions {
+	options := m.options
+	options.Model = m.model
+	options.MaxTokens = m.maxTokens
+	options.Temperature = &m.temperature
+	options.Verbose = m.verbose
+	options.Logger = m.logger
+	return options
+}
+
+// Generate implements LlmInterface
+func (m *mistralImplementation) Generate(systemPrompt string, userMessage string, opts ...LlmOptions) (string, error) {
+	return m.GenerateChat(promptMessages(systemPrompt, userMessage), opts...)
+}
+
+// EffectiveOptions implements EffectiveOptionsInterface
+func (m *mistralImplementation) EffectiveOptions(opts ...LlmOptions) LlmOptions {
+	return mergeOptions(m.baseOptions(), firstOptions(opts))
+}
+
+// DebugMessages implements DebugMessagesInterface
+func (m *mistralImplementation) DebugMessages(systemPrompt string, userMessage string, opts ...LlmOptions) []Message {
+	perCall := LlmOptions{}
+	if len(opts) > 0 {
+		perCall = opts[0]
+	}
+	merged := mergeOptions(m.baseOptions(), perCall)
+
+	return markupMessages(promptMessages(systemPrompt, userMessage), merged)
+}
+
+// GenerateChat implements ChatInterface
+func (m *mistralImplementation) GenerateChat(messages []Message, opts ...LlmOptions) (string, error) {
+	perCall := LlmOptions{}
+	if len(opts) > 0 {
+		perCall = opts[0]
+	}
+	merged := mergeOptions(m.baseOptions(), perCall)
+
+	if override, err := overrideProvider(m.options.Provider, merged, perCall); err != nil {
+		return "", err
+	} else if override != nil {
+		defer Close(override)
+		return GenerateChat(override, messages, perCall)
+	}
+
+	messages = markupMessages(messages, merged)
+
+	if err := checkCostBudget(merged, "", messagesContent(messages)); err != nil {
+		return "", err
+	}
+
+	ctx, cancel := requestContext(merged)
+	defer cancel()
+
+	model := merged.Model
+
+	// Mistral supports json_object but not json_schema response formats
+	responseFormat := &openai.ChatCompletionResponseFormat{
+		Type: openai.ChatCompletionResponseFormatTypeText,
+	}
+	if merged.OutputFormat == OutputFormatJSON {
+		responseFormat.Type = openai.ChatCompletionResponseFormatTypeJSONObject
+	}
+
+	chatMessages, err := openaiChatMessages(messages)
+	if err != nil {
+		return "", err
+	}
+
+	req := openai.ChatCompletionRequest{
+		Model:            model,
+		ResponseFormat:   responseFormat,
+		Messages:         chatMessages,
+		MaxTokens:        requestMaxTokens(merged),
+		Temperature:      float32(derefFloat64(merged.Temperature, m.temperature)),
+		Stop:             merged.Stop,
+		TopP:             float32(derefFloat64(merged.TopP, 0)),
+		PresencePenalty:  float32(derefFloat64(merged.PresencePenalty, 0)),
+		FrequencyPenalty: float32(derefFloat64(merged.FrequencyPenalty, 0)),
+	}
+
+	var resp openai.ChatCompletionResponse
+	err = withRetry(ctx, merged, func() error {
+		var err error
+		resp, err = m.client.CreateChatCompletion(ctx, req)
+		return providerAPIError(ProviderMistral, err)
+	})
+	if err != nil {
+		if m.logger != nil {
+			m.logger.Error("Mistral generation error",
+				slog.String("error", err.Error()),
+				slog.String("model", model))
+		} else if m.verbose {
+			fmt.Printf("Mistral generation error: %v\n", err)
+		}
+		return "", err
+	}
+	m.recordObject(ctx, ProviderMistral, http.StatusOK, resp.Header(), resp)
+
+	return openaiChoiceContent(ProviderMistral, resp)
+}
+
+// GenerateText implements LlmInterface
+func (m *mistralImplementation) GenerateText(systemPrompt string, userPrompt string, opts ...LlmOptions) (string, error) {
+	perCall := LlmOptions{}
+	if len(opts) > 0 {
+		perCall = opts[0]
+	}
+	perCall.OutputFormat = OutputFormatText
+	return m.Generate(systemPrompt, userPrompt, perCall)
+}
+
+// GenerateJSON implements LlmInterface
+func (m *mistralImplementation) GenerateJSON(systemPrompt string, userPrompt string, opts ...LlmOptions) (string, error) {
+	perCall := LlmOptions{}
+	if len(opts) > 0 {
+		perCall = opts[0]
+	}
+	perCall.OutputFormat = OutputFormatJSON
+	response, err := m.Generate(systemPrompt, userPrompt, perCall)
+	if err != nil {
+		return "", err
+	}
+	return sanitizeJSONResponse(response), nil
+}
+
+// GenerateXML implements LlmInterface
+func (m *mistralImplementation) GenerateXML(systemPrompt string, userPrompt string, opts ...LlmOptions) (string, error) {
+	return generateMarkup(m, OutputFormatXML, systemPrompt, userPrompt, opts...)
+}
+
+// GenerateYAML implements LlmInterface
+func (m *mistralImplementation) GenerateYAML(systemPrompt string, userPrompt string, opts ...LlmOptions) (string, error) {
+	return generateMarkup(m, OutputFormatYAML, systemPrompt, userPrompt, opts...)
+}
+
+// GenerateStructured implements StructuredOutputInterface
+func (m *mistralImplementation) GenerateStructured(systemPrompt string, userPrompt string, schema json.RawMessage, opts ...LlmOptions) (json.RawMessage, error) {
+	return generateStructured(m.GenerateJSON, systemPrompt, userPrompt, schema, opts...)
+}
+
+// GenerateImage implements LlmInterface
+func (m *mistralImplementation) GenerateImage(prompt string, opts ...LlmOptions) ([]byte, error) {
+	return nil, fmt.Errorf("image generation not supported by mistral provider")
+}
+
+// GenerateEmbedding implements LlmInterface using the /v1/embeddings
+// endpoint with mistral-embed, or ProviderOptions["embedding_model"]
+func (m *mistralImplementation) GenerateEmbedding(text string) ([]float32, error) {
+	if m.options.EmbeddingLlm != nil {
+		return m.options.EmbeddingLlm.GenerateEmbedding(text)
+	}
+
+	ctx, cancel := requestContext(m.options)
+	defer cancel()
+
+	embeddingModel := mistralDefaultEmbeddingModel
+	if v, ok := providerOptionString(m.options.ProviderOptions, "embedding_model"); ok && strings.TrimSpace(v) != "" {
+		embeddingModel = strings.TrimSpace(v)
+	}
+
+	resp, err := m.client.CreateEmbeddings(ctx, openai.EmbeddingRequest{
+		Input:          []string{text},
+		Model:          openai.EmbeddingModel(embeddingModel),
+		EncodingFormat: openai.EmbeddingEncodingFormatFloat,
+	})
+	if err != nil {
+		err = providerAPIError(ProviderMistral, err)
+		if m.logger != nil {
+			m.logger.Error("Mistral embedding generation error",
+				slog.String("error", err.Error()))
+		} else if m.verbose {
+			fmt.Printf("Mistral embedding generation error: %v\n", err)
+		}
+		return nil, err
+	}
+
+	if len(resp.Data) == 0 {
+		return nil, fmt.Errorf("no embeddings generated")
+	}
+
+	return resp.Data[0].Embedding, nil
+}
+
+// Close implements io.Closer. It closes the idle connections of the
+// HTTP client.
+func (m *mistralImplementation) Close() error {
+	closeIdleConnections(m.httpClient)
+	return nil
+}
@@ -0,0 +1,75 @@
+package llm
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMistralGenerateAndEmbed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-key" {
+			t.Errorf("unexpected Authorization header: %s", r.Header.Get("Authorization"))
+		}
+
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("failed to decode request: %v", err)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/chat/completions":
+			if body["model"] != mistralDefaultModel {
+				t.Errorf("expected default model, got %v", body["model"])
+			}
+			format, _ := body["response_format"].(map[string]any)
+			if format["type"] != "json_object" {
+				t.Errorf("expected json_object response format, got %v", body["response_format"])
+			}
+			w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"{\"ok\":true}"}}]}`))
+		case "/embeddings":
+			if body["model"] != mistralDefaultEmbeddingModel {
+				t.Errorf("expected default embedding model, got %v", body["model"])
+			}
+			w.Write([]byte(`{"data":[{"embedding":[0.5,0.25]}]}`))
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	llm, err := NewLLM(LlmOptions{
+		Provider:        ProviderMistral,
+		ApiKey:          "test-key",
+		Model:           mistralDefaultModel,
+		ProviderOptions: map[string]any{"base_url": server.URL},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create Mistral LLM: %v", err)
+	}
+
+	response, err := llm.GenerateJSON("system", "hello")
+	if err != nil {
+		t.Fatalf("GenerateJSON failed: %v", err)
+	}
+	if response != `{"ok":true}` {
+		t.Errorf("unexpected response: %s", response)
+	}
+
+	embedding, err := llm.GenerateEmbedding("hello")
+	if err != nil {
+		t.Fatalf("GenerateEmbedding failed: %v", err)
+	}
+	if len(embedding) != 2 || embedding[0] != 0.5 {
+		t.Errorf("unexpected embedding: %v", embedding)
+	}
+}
+
+func TestMistralRequiresApiKey(t *testing.T) {
+	_, err := NewLLM(LlmOptions{Provider: ProviderMistral, Model: mistralDefaultModel})
+	if err == nil {
+		t.Fatal("expected error without API key")
+	}
+}
@@ -1,5 +1,13 @@
 package llm
 
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+)
+
 // =======================================================================
 // == CONSTRUCTOR
 // =======================================================================
@@ -7,7 +15,7 @@ package llm
 func newMockImplementation(options LlmOptions) (LlmInterface, error) {
 	// Set default model if not provided
 	if options.Model == "" {
-		options.Model = "mock-model"
+		options.Model = DefaultModel(ProviderMock)
 	}
 	return &mockImplementation{
 		options: options,
@@ -21,34 +29,116 @@ func newMockImplementation(options LlmOptions) (LlmInterface, error) {
 // mockImplementation implements LlmInterface for Mock provider
 type mockImplementation struct {
 	options LlmOptions
+
+	// responses are the test mode responses keyed by user message, with
+	// the "" key as the fallback
+	responses map[string]string
 }
 
 // =======================================================================
 // == IMPLEMENTATION
 // =======================================================================
 
+// EffectiveOptions implements EffectiveOptionsInterface
+func (c *mockImplementation) EffectiveOptions(opts ...LlmOptions) LlmOptions {
+	return mergeOptions(c.options, firstOptions(opts))
+}
+
+// DebugMessages implements DebugMessagesInterface
+func (c *mockImplementation) DebugMessages(systemPrompt string, userMessage string, opts ...LlmOptions) []Message {
+	return promptMessages(systemPrompt, userMessage)
+}
+
 func (c *mockImplementation) Generate(systemPrompt string, userMessage string, opts ...LlmOptions) (string, error) {
 	options := LlmOptions{}
 	if len(opts) > 0 {
 		options = opts[0]
 	}
 
-	// Return mock response if provided in options
+	merged := mergeOptions(c.options, options)
+
+	if override, err := overrideProvider(c.options.Provider, merged, options); err != nil {
+		return "", err
+	} else if override != nil {
+		defer Close(override)
+		return override.Generate(systemPrompt, userMessage, options)
+	}
+
+	if err := checkCostBudget(merged, systemPrompt, userMessage); err != nil {
+		return "", err
+	}
+
+	if merged.Context != nil && merged.Context.Err() != nil {
+		return "", merged.Context.Err()
+	}
+
+	response := c.response(merged, userMessage)
+	if response == "" && merged.Seed != nil {
+		response = fmt.Sprintf("mock response (seed %d)", *merged.Seed)
+	}
+
+	response = truncateAtStop(response, merged.Stop)
+	if strings.TrimSpace(response) == "" {
+		return "", fmt.Errorf("%w: mock has no response for the message", ErrEmptyResponse)
+	}
+	return response, nil
+}
+
+// response returns the canned response for the call
+func (c *mockImplementation) response(options LlmOptions, userMessage string) string {
+	// Return mock response if provided in the per-call or client options
 	if options.MockResponse != "" {
-		return options.MockResponse, nil
+		return options.MockResponse
 	}
 
-	// Or use the one from the client options
-	if c.options.MockResponse != "" {
-		return c.options.MockResponse, nil
+	// Or the response scripted for the prompt
+	if response, ok := matchMockResponse(options.MockResponses, userMessage); ok {
+		return response
 	}
 
-	// Handle empty input
-	if userMessage == "" {
-		return "", nil
+	// Or the scripted test mode response
+	if response, ok := c.responses[userMessage]; ok {
+		return response
 	}
 
-	return "", nil
+	return c.responses[""]
+}
+
+// matchMockResponse returns the response whose key is the user message,
+// or else whose key matches it as a regular expression, trying the keys in
+// sorted order, or else the response with the "" key. Keys that are not
+// valid regular expressions only match exactly.
+func matchMockResponse(responses map[string]string, userMessage string) (string, bool) {
+	if response, ok := responses[userMessage]; ok {
+		return response, true
+	}
+
+	for _, pattern := range slices.Sorted(maps.Keys(responses)) {
+		if pattern == "" {
+			continue
+		}
+		re, err := regexp.Compile(pattern)
+		if err == nil && re.MatchString(userMessage) {
+			return responses[pattern], true
+		}
+	}
+
+	response, ok := responses[""]
+	return response, ok
+}
+
+// truncateAtStop cuts the response at the earliest stop sequence, as a
+// provider stops generating there
+func truncateAtStop(response string, stop []string) string {
+	for _, sequence := range stop {
+		if sequence == "" {
+			continue
+		}
+		if i := strings.Index(response, sequence); i >= 0 {
+			response = response[:i]
+		}
+	}
+	return response
 }
 
 func (c *mockImplementation) GenerateText(systemPrompt string, userPrompt string, opts ...LlmOptions) (string, error) {
@@ -66,7 +156,19 @@ func (c *mockImplementation) GenerateJSON(systemPrompt string, userPrompt string
 		perCall = opts[0]
 	}
 	perCall.OutputFormat = OutputFormatJSON
-	return c.Generate(systemPrompt, userPrompt, perCall)
+	response, err := c.Generate(systemPrompt, userPrompt, perCall)
+	if err != nil {
+		return "", err
+	}
+	return sanitizeJSONResponse(response), nil
+}
+
+func (c *mockImplementation) GenerateXML(systemPrompt string, userPrompt string, opts ...LlmOptions) (string, error) {
+	return generateMarkup(c, OutputFormatXML, systemPrompt, userPrompt, opts...)
+}
+
+func (c *mockImplementation) GenerateYAML(systemPrompt string, userPrompt string, opts ...LlmOptions) (string, error) {
+	return generateMarkup(c, OutputFormatYAML, systemPrompt, userPrompt, opts...)
 }
 
 func (c *mockImplementation) GenerateImage(prompt string, opts ...LlmOptions) ([]byte, error) {
@@ -76,5 +178,14 @@ func (c *mockImplementation) GenerateImage(prompt string, opts ...LlmOptions) ([
 }
 
 func (m *mockImplementation) GenerateEmbedding(text string) ([]float32, error) {
+	if m.options.EmbeddingLlm != nil {
+		return m.options.EmbeddingLlm.GenerateEmbedding(text)
+	}
+
 	return []float32{0.1, 0.2, 0.3}, nil
 }
+
+// Close implements io.Closer. The mock holds no resources.
+func (c *mockImplementation) Close() error {
+	return nil
+}
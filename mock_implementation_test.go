@@ -0,0 +1,107 @@
+package llm
+
+import "testing"
+
+func TestMockResponses(t *testing.T) {
+	engine, err := NewLLM(LlmOptions{
+		Provider: ProviderMock,
+		MockResponses: map[string]string{
+			"Find the details of the contract": "exact",
+			"(?i)^summari[sz]e":                "summary",
+			"contract":                         "contract",
+			"":                                 "fallback",
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create mock LLM: %v", err)
+	}
+
+	testCases := []struct {
+		userMessage string
+		expected    string
+	}{
+		{"Find the details of the contract", "exact"},
+		{"Summarize this document", "summary"},
+		// Keys are tried in sorted order, so "(?i)^summari[sz]e" wins over "contract"
+		{"summarise the contract", "summary"},
+		{"Review the contract terms", "contract"},
+		{"Hello", "fallback"},
+	}
+
+	for _, tc := range testCases {
+		response, err := engine.GenerateText("system", tc.userMessage)
+		if err != nil {
+			t.Fatalf("GenerateText failed: %v", err)
+		}
+		if response != tc.expected {
+			t.Errorf("GenerateText(%q) = %q, expected %q", tc.userMessage, response, tc.expected)
+		}
+	}
+
+	// Per-call responses replace the client ones, and MockResponse wins
+	response, _ := engine.GenerateText("system", "Hello", LlmOptions{MockResponses: map[string]string{"Hello": "per call"}})
+	if response != "per call" {
+		t.Errorf("expected per-call response, got %q", response)
+	}
+	response, _ = engine.GenerateText("system", "Hello", LlmOptions{MockResponse: "override"})
+	if response != "override" {
+		t.Errorf("expected MockResponse to win, got %q", response)
+	}
+}
+
+func TestMatchMockResponseInvalidPattern(t *testing.T) {
+	responses := map[string]string{"price (USD": "exact only"}
+
+	if response, ok := matchMockResponse(responses, "price (USD"); !ok || response != "exact only" {
+		t.Errorf("expected exact match, got %q (ok=%v)", response, ok)
+	}
+	if _, ok := matchMockResponse(responses, "the price (USD) is"); ok {
+		t.Error("expected an invalid pattern not to match as a regular expression")
+	}
+}
+
+func TestRecordingMock(t *testing.T) {
+	mock := NewRecordingMock(LlmOptions{Model: "client-model", Temperature: PtrFloat64(0.2), MockResponse: "ok"})
+
+	// The code under test only sees an LlmInterface
+	var engine LlmInterface = mock
+	if _, err := engine.GenerateText("system", "first", LlmOptions{Temperature: PtrFloat64(0.9)}); err != nil {
+		t.Fatalf("GenerateText failed: %v", err)
+	}
+	if _, err := engine.GenerateJSON("system", "second", LlmOptions{Model: "call-model"}); err != nil {
+		t.Fatalf("GenerateJSON failed: %v", err)
+	}
+	if _, err := engine.GenerateEmbedding("third"); err != nil {
+		t.Fatalf("GenerateEmbedding failed: %v", err)
+	}
+
+	calls := mock.Calls()
+	if len(calls) != 3 {
+		t.Fatalf("expected 3 calls, got %d", len(calls))
+	}
+
+	first := calls[0]
+	if first.Method != "GenerateText" || first.SystemPrompt != "system" || first.UserPrompt != "first" {
+		t.Errorf("unexpected first call: %+v", first)
+	}
+	if first.Options.Temperature == nil || *first.Options.Temperature != 0.9 || first.Options.Model != "client-model" {
+		t.Errorf("expected per-call temperature merged over the client options, got %+v", first.Options)
+	}
+	if first.Options.OutputFormat != OutputFormatText {
+		t.Errorf("expected text output format, got %s", first.Options.OutputFormat)
+	}
+
+	second := calls[1]
+	if second.Method != "GenerateJSON" || second.Options.Model != "call-model" || *second.Options.Temperature != 0.2 {
+		t.Errorf("unexpected second call: %+v", second)
+	}
+
+	if last, ok := mock.LastCall(); !ok || last.Method != "GenerateEmbedding" || last.UserPrompt != "third" {
+		t.Errorf("unexpected last call: %+v", last)
+	}
+
+	mock.Reset()
+	if _, ok := mock.LastCall(); ok || len(mock.Calls()) != 0 {
+		t.Error("expected no calls after Reset")
+	}
+}
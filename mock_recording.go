@@ -0,0 +1,159 @@
+package llm
+
+import (
+	"sync"
+)
+
+// RecordedCall is a call captured by a RecordingMock
+type RecordedCall struct {
+	// Method is the name of the LlmInterface method called, e.g. "GenerateText"
+	Method string
+
+	// SystemPrompt is the system prompt of the call (empty for GenerateImage
+	// and GenerateEmbedding)
+	SystemPrompt string
+
+	// UserPrompt is the user prompt of the call, or the image prompt or
+	// embedding text
+	UserPrompt string
+
+	// Options are the client options merged with the per-call options, as
+	// a provider would use them
+	Options LlmOptions
+}
+
+// RecordingMock is a mock LLM that records every call, so tests of
+// application code can assert the prompts and options it sends. It
+// responds like the mock provider, so MockResponse and MockResponses
+// script its responses.
+type RecordingMock struct {
+	mock *mockImplementation
+
+	mu    sync.Mutex
+	calls []RecordedCall
+}
+
+var _ LlmInterface = (*RecordingMock)(nil)
+
+// NewRecordingMock creates a recording mock with the given client options
+func NewRecordingMock(options ...LlmOptions) *RecordingMock {
+	clientOptions := LlmOptions{}
+	if len(options) > 0 {
+		clientOptions = options[0]
+	}
+	clientOptions.Provider = ProviderMock
+	if clientOptions.Model == "" {
+		clientOptions.Model = DefaultModel(ProviderMock)
+	}
+
+	return &RecordingMock{mock: &mockImplementation{options: clientOptions}}
+}
+
+// Calls returns a copy of the recorded calls, in call order
+func (r *RecordingMock) Calls() []RecordedCall {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]RecordedCall(nil), r.calls...)
+}
+
+// LastCall returns the most recent call, and false if there was none
+func (r *RecordingMock) LastCall() (RecordedCall, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.calls) == 0 {
+		return RecordedCall{}, false
+	}
+	return r.calls[len(r.calls)-1], true
+}
+
+// Reset clears the recorded calls
+func (r *RecordingMock) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.calls = nil
+}
+
+// record captures a call with the options merged as the mock uses them
+func (r *RecordingMock) record(method string, systemPrompt string, userPrompt string, perCall LlmOptions) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.calls = append(r.calls, RecordedCall{
+		Method:       method,
+		SystemPrompt: systemPrompt,
+		UserPrompt:   userPrompt,
+		Options:      mergeOptions(r.mock.options, perCall),
+	})
+}
+
+// EffectiveOptions implements EffectiveOptionsInterface
+func (r *RecordingMock) EffectiveOptions(opts ...LlmOptions) LlmOptions {
+	return mergeOptions(r.mock.options, firstOptions(opts))
+}
+
+// Generate implements LlmInterface
+func (r *RecordingMock) Generate(systemPrompt string, userMessage string, opts ...LlmOptions) (string, error) {
+	r.record("Generate", systemPrompt, userMessage, firstOptions(opts))
+	return r.mock.Generate(systemPrompt, userMessage, opts...)
+}
+
+// GenerateText implements LlmInterface
+func (r *RecordingMock) GenerateText(systemPrompt string, userPrompt string, opts ...LlmOptions) (string, error) {
+	perCall := firstOptions(opts)
+	perCall.OutputFormat = OutputFormatText
+	r.record("GenerateText", systemPrompt, userPrompt, perCall)
+	return r.mock.GenerateText(systemPrompt, userPrompt, opts...)
+}
+
+// GenerateJSON implements LlmInterface
+func (r *RecordingMock) GenerateJSON(systemPrompt string, userPrompt string, opts ...LlmOptions) (string, error) {
+	perCall := firstOptions(opts)
+	perCall.OutputFormat = OutputFormatJSON
+	r.record("GenerateJSON", systemPrompt, userPrompt, perCall)
+	return r.mock.GenerateJSON(systemPrompt, userPrompt, opts...)
+}
+
+// GenerateXML implements LlmInterface
+func (r *RecordingMock) GenerateXML(systemPrompt string, userPrompt string, opts ...LlmOptions) (string, error) {
+	perCall := firstOptions(opts)
+	perCall.OutputFormat = OutputFormatXML
+	r.record("GenerateXML", systemPrompt, userPrompt, perCall)
+	return r.mock.GenerateXML(systemPrompt, userPrompt, opts...)
+}
+
+// GenerateYAML implements LlmInterface
+func (r *RecordingMock) GenerateYAML(systemPrompt string, userPrompt string, opts ...LlmOptions) (string, error) {
+	perCall := firstOptions(opts)
+	perCall.OutputFormat = OutputFormatYAML
+	r.record("GenerateYAML", systemPrompt, userPrompt, perCall)
+	return r.mock.GenerateYAML(systemPrompt, userPrompt, opts...)
+}
+
+// GenerateImage implements LlmInterface
+func (r *RecordingMock) GenerateImage(prompt string, opts ...LlmOptions) ([]byte, error) {
+	r.record("GenerateImage", "", prompt, firstOptions(opts))
+	return r.mock.GenerateImage(prompt, opts...)
+}
+
+// GenerateEmbedding implements LlmInterface
+func (r *RecordingMock) GenerateEmbedding(text string) ([]float32, error) {
+	r.record("GenerateEmbedding", "", text, LlmOptions{})
+	return r.mock.GenerateEmbedding(text)
+}
+
+// Close implements io.Closer. The recording mock holds no resources.
+func (r *RecordingMock) Close() error {
+	return nil
+}
+
+// firstOptions returns the per-call options, or empty options if none
+// were given
+func firstOptions(opts []LlmOptions) LlmOptions {
+	if len(opts) > 0 {
+		return opts[0]
+	}
+	return LlmOptions{}
+}
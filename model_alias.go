@@ -0,0 +1,46 @@
+package llm
+
+import (
+	"strings"
+	"sync"
+)
+
+var (
+	// modelAliasesMu protects modelAliases from concurrent access
+	modelAliasesMu sync.RWMutex
+	// modelAliases maps providers to their model aliases set by the
+	// application
+	modelAliases = make(map[Provider]map[string]string)
+)
+
+// SetModelAlias sets a friendly name, e.g. "cheap", resolved to the model
+// of the provider, e.g. "gpt-4o-mini" for OpenAI, wherever a Model is
+// given: at construction and per call. An empty model removes the alias.
+func SetModelAlias(provider Provider, alias string, model string) {
+	modelAliasesMu.Lock()
+	defer modelAliasesMu.Unlock()
+
+	alias = strings.TrimSpace(alias)
+	model = strings.TrimSpace(model)
+	if model == "" {
+		delete(modelAliases[provider], alias)
+		return
+	}
+
+	if modelAliases[provider] == nil {
+		modelAliases[provider] = make(map[string]string)
+	}
+	modelAliases[provider][alias] = model
+}
+
+// ResolveModelAlias returns the model the alias is set to for the provider
+// with SetModelAlias. Other names are returned unchanged.
+func ResolveModelAlias(provider Provider, model string) string {
+	modelAliasesMu.RLock()
+	defer modelAliasesMu.RUnlock()
+
+	if resolved, ok := modelAliases[provider][strings.TrimSpace(model)]; ok {
+		return resolved
+	}
+	return model
+}
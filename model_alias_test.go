@@ -0,0 +1,98 @@
+package llm
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestModelAliases(t *testing.T) {
+	SetModelAlias(ProviderOpenAI, "cheap", "gpt-4o-mini")
+	SetModelAlias(ProviderOpenAI, "smart", "gpt-4.1")
+	SetModelAlias(ProviderOpenRouter, "cheap", "google/gemini-2.5-flash-lite")
+	t.Cleanup(func() {
+		SetModelAlias(ProviderOpenAI, "cheap", "")
+		SetModelAlias(ProviderOpenAI, "smart", "")
+		SetModelAlias(ProviderOpenRouter, "cheap", "")
+	})
+
+	if got := ResolveModelAlias(ProviderOpenRouter, "cheap"); got != "google/gemini-2.5-flash-lite" {
+		t.Errorf("expected the OpenRouter model, got %q", got)
+	}
+	if got := ResolveModelAlias(ProviderGemini, "cheap"); got != "cheap" {
+		t.Errorf("expected an alias of another provider to pass through, got %q", got)
+	}
+	if got := ResolveModelAlias(ProviderOpenAI, "gpt-4o"); got != "gpt-4o" {
+		t.Errorf("expected an unknown name to pass through, got %q", got)
+	}
+
+	var captured map[string]any
+	server := captureServer(t, `{"choices":[{"message":{"role":"assistant","content":"hi"}}]}`, &captured)
+	defer server.Close()
+
+	engine, err := NewLLM(LlmOptions{
+		Provider:        ProviderOpenAI,
+		ApiKey:          "test-key",
+		Model:           "cheap",
+		ProviderOptions: map[string]any{"base_url": server.URL},
+	})
+	if err != nil {
+		t.Fatalf("failed to create OpenAI LLM: %v", err)
+	}
+
+	if _, err := engine.GenerateText("system", "hello"); err != nil {
+		t.Fatalf("GenerateText failed: %v", err)
+	}
+	if captured["model"] != "gpt-4o-mini" {
+		t.Errorf("expected the constructor alias to be resolved, got %v", captured["model"])
+	}
+
+	if _, err := engine.GenerateText("system", "hello", LlmOptions{Model: "smart"}); err != nil {
+		t.Fatalf("GenerateText failed: %v", err)
+	}
+	if captured["model"] != "gpt-4.1" {
+		t.Errorf("expected the per-call alias to be resolved, got %v", captured["model"])
+	}
+
+	SetModelAlias(ProviderOpenAI, "smart", "")
+	if got := ResolveModelAlias(ProviderOpenAI, "smart"); got != "smart" {
+		t.Errorf("expected the removed alias to pass through, got %q", got)
+	}
+}
+
+func TestGeminiPerCallModelAlias(t *testing.T) {
+	SetModelAlias(ProviderGemini, "cheap", GEMINI_MODEL_2_5_FLASH_LITE)
+	t.Cleanup(func() {
+		SetModelAlias(ProviderGemini, "cheap", "")
+	})
+
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"candidates":[{"content":{"role":"model","parts":[{"text":"hi"}]},"finishReason":"STOP"}]}`))
+	}))
+	defer server.Close()
+
+	engine := newTestGemini(t, server.URL)
+	engine.options.Provider = ProviderGemini
+
+	if _, err := engine.GenerateText("system", "hello", LlmOptions{Model: GEMINI_MODEL_2_5_PRO}); err != nil {
+		t.Fatalf("GenerateText failed: %v", err)
+	}
+	if !strings.Contains(path, "/models/"+GEMINI_MODEL_2_5_PRO+":") {
+		t.Errorf("expected the per-call model in %s", path)
+	}
+
+	if _, err := engine.GenerateText("system", "hello", LlmOptions{Model: "cheap"}); err != nil {
+		t.Fatalf("GenerateText failed: %v", err)
+	}
+	if !strings.Contains(path, "/models/"+GEMINI_MODEL_2_5_FLASH_LITE+":") {
+		t.Errorf("expected the per-call alias to be resolved in %s", path)
+	}
+
+	if got := engine.EffectiveOptions(LlmOptions{Model: "cheap"}).Model; got != GEMINI_MODEL_2_5_FLASH_LITE {
+		t.Errorf("expected EffectiveOptions to report the resolved model, got %q", got)
+	}
+}
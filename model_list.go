@@ -0,0 +1,38 @@
+package llm
+
+import "fmt"
+
+// ModelInfo describes a model listed by a provider
+type ModelInfo struct {
+	// ID is the model ID to use as LlmOptions.Model
+	ID string
+
+	// Name is the display name of the model
+	Name string
+
+	// ContextLength is the context window in tokens, 0 if not listed
+	ContextLength int
+
+	// InputPrice and OutputPrice are the USD prices per million input and
+	// output tokens, 0 if free or not listed
+	InputPrice  float64
+	OutputPrice float64
+}
+
+// ModelListInterface is implemented by providers that can list their
+// available models (currently OpenRouter)
+type ModelListInterface interface {
+	// ListModels fetches the models currently available from the provider
+	ListModels() ([]ModelInfo, error)
+}
+
+// ListModels fetches the models available from the provider, returning an
+// error wrapping ErrNotSupported if the provider does not implement
+// ModelListInterface
+func ListModels(llm LlmInterface) ([]ModelInfo, error) {
+	lister, ok := llm.(ModelListInterface)
+	if !ok {
+		return nil, fmt.Errorf("%w: model listing", ErrNotSupported)
+	}
+	return lister.ListModels()
+}
@@ -3,8 +3,11 @@ package llm
 import (
 	"context"
 	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"strings"
 
 	"github.com/sashabaranov/go-openai"
@@ -13,107 +16,352 @@ import (
 // openaiImplementation implements LlmInterface using OpenAI's API
 type openaiImplementation struct {
 	client      *openai.Client
+	httpClient  openai.HTTPDoer
 	model       string
 	maxTokens   int
 	temperature float64
 	verbose     bool
 	logger      *slog.Logger
+	options     LlmOptions
+	*lastResponseRecorder
 }
 
 // newOpenaiImplementation creates a new OpenAI provider implementation
 func newOpenaiImplementation(options LlmOptions) (LlmInterface, error) {
 	o := options
 
-	apiKey := o.ApiKey
+	apiKey := providerAPIKey(o)
 	if apiKey == "" {
 		return nil, fmt.Errorf("OpenAI API key is required")
 	}
 
 	model := o.Model
 	if model == "" {
-		model = openai.GPT4TurboPreview
+		model = DefaultModel(ProviderOpenAI)
 	}
 
+	// base_url and organization let traffic go through a corporate gateway
+	// or proxy (e.g. LiteLLM, Helicone) and bill an organization
+	cfg := openai.DefaultConfig(apiKey)
+	cfg.BaseURL = providerBaseURL(o, cfg.BaseURL)
+	if organization, ok := providerOptionString(o.ProviderOptions, "organization"); ok {
+		cfg.OrgID = strings.TrimSpace(organization)
+	}
+	httpClient, err := secureHTTPClient(ProviderOpenAI, o)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure openai http client: %w", err)
+	}
+	cfg.HTTPClient = apiKeyHTTPClient(httpClient, o)
+
 	return &openaiImplementation{
-		client:      openai.NewClient(apiKey),
+		client:      openai.NewClientWithConfig(cfg),
+		httpClient:  cfg.HTTPClient,
 		model:       model,
 		maxTokens:   o.MaxTokens,
 		temperature: derefFloat64(o.Temperature, 0.7),
 		verbose:     o.Verbose,
 		logger:      o.Logger,
+		options:     o,
+
+		lastResponseRecorder: newLastResponseRecorder(o.ProviderOptions),
 	}, nil
 }
 
-// baseOptions returns the base LlmOptions from the struct fields for merging.
+// baseOptions returns the construction options, with the struct fields
+// applied on top, as the base for merging per-call options.
 func (o *openaiImplementation) baseOptions() LlmOptions {
-	return LlmOptions{
-		Model:       o.model,
-		MaxTokens:   o.maxTokens,
-		Temperature: &o.temperature,
-		Verbose:     o.verbose,
-		Logger:      o.logger,
-	}
+	options := o.options
+	options.Model = o.model
+	options.MaxTokens = o.maxTokens
+	options.Temperature = &o.temperature
+	options.Verbose = o.verbose
+	options.Logger = o.logger
+	return options
 }
 
 // Generate implements LlmInterface
 func (o *openaiImplementation) Generate(systemPrompt string, userMessage string, opts ...LlmOptions) (string, error) {
+	return o.GenerateChat(promptMessages(systemPrompt, userMessage), opts...)
+}
+
+// EffectiveOptions implements EffectiveOptionsInterface
+func (o *openaiImplementation) EffectiveOptions(opts ...LlmOptions) LlmOptions {
+	return mergeOptions(o.baseOptions(), firstOptions(opts))
+}
+
+// DebugMessages implements DebugMessagesInterface
+func (o *openaiImplementation) DebugMessages(systemPrompt string, userMessage string, opts ...LlmOptions) []Message {
+	perCall := LlmOptions{}
+	if len(opts) > 0 {
+		perCall = opts[0]
+	}
+	merged := mergeOptions(o.baseOptions(), perCall)
+
+	return markupMessages(promptMessages(systemPrompt, userMessage), merged)
+}
+
+// GenerateChat implements ChatInterface
+func (o *openaiImplementation) GenerateChat(messages []Message, opts ...LlmOptions) (string, error) {
 	perCall := LlmOptions{}
 	if len(opts) > 0 {
 		perCall = opts[0]
 	}
 	merged := mergeOptions(o.baseOptions(), perCall)
 
-	ctx := context.Background()
+	if override, err := overrideProvider(o.options.Provider, merged, perCall); err != nil {
+		return "", err
+	} else if override != nil {
+		defer Close(override)
+		return GenerateChat(override, messages, perCall)
+	}
+
+	messages = markupMessages(messages, merged)
+
+	if err := checkCostBudget(merged, "", messagesContent(messages)); err != nil {
+		return "", err
+	}
+
+	ctx, cancel := requestContext(merged)
+	defer cancel()
+
+	req, err := o.chatRequest(messages, merged)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := o.createChatCompletion(ctx, merged, req)
+	if err != nil {
+		return "", err
+	}
+
+	return openaiChoiceContent(ProviderOpenAI, resp)
+}
+
+// GenerateN implements CandidatesInterface, requesting merged.N choices
+func (o *openaiImplementation) GenerateN(systemPrompt string, userPrompt string, opts ...LlmOptions) ([]string, error) {
+	perCall := firstOptions(opts)
+	merged := mergeOptions(o.baseOptions(), perCall)
+
+	if override, err := overrideProvider(o.options.Provider, merged, perCall); err != nil {
+		return nil, err
+	} else if override != nil {
+		defer Close(override)
+		return GenerateN(override, systemPrompt, userPrompt, perCall)
+	}
+
+	messages := markupMessages(promptMessages(systemPrompt, userPrompt), merged)
+
+	if err := checkCostBudget(merged, "", messagesContent(messages)); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := requestContext(merged)
+	defer cancel()
+
+	req, err := o.chatRequest(messages, merged)
+	if err != nil {
+		return nil, err
+	}
+	if candidates := candidateCount(merged); candidates > 1 {
+		req.N = candidates
+	}
+
+	resp, err := o.createChatCompletion(ctx, merged, req)
+	if err != nil {
+		return nil, err
+	}
 
-	model := merged.Model
-	maxTokens := merged.MaxTokens
-	temperature := derefFloat64(merged.Temperature, o.temperature)
+	return openaiChoicesContent(ProviderOpenAI, resp)
+}
+
+// createChatCompletion sends the chat completion request, with retries,
+// and records the response
+func (o *openaiImplementation) createChatCompletion(ctx context.Context, merged LlmOptions, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	var resp openai.ChatCompletionResponse
+	err := withRetry(ctx, merged, func() error {
+		var err error
+		resp, err = o.client.CreateChatCompletion(ctx, req)
+		return providerAPIError(ProviderOpenAI, err)
+	})
+	if err != nil {
+		if o.logger != nil {
+			o.logger.Error("OpenAI generation error",
+				slog.String("error", err.Error()),
+				slog.String("model", req.Model))
+		} else if o.verbose {
+			fmt.Printf("OpenAI generation error: %v\n", err)
+		}
+		if openaiModelNotFound(err) {
+			return resp, &ModelNotFoundError{Provider: ProviderOpenAI, Model: req.Model, Err: err}
+		}
+		return resp, err
+	}
+	o.recordObject(ctx, ProviderOpenAI, http.StatusOK, resp.Header(), resp)
+	return resp, nil
+}
 
+// chatRequest builds the chat completion request for the merged options
+func (o *openaiImplementation) chatRequest(messages []Message, merged LlmOptions) (openai.ChatCompletionRequest, error) {
 	// Configure response format based on output format
 	responseFormat := &openai.ChatCompletionResponseFormat{}
-	if merged.OutputFormat == OutputFormatJSON {
+	if merged.OutputFormat == OutputFormatJSON && len(merged.responseSchema) > 0 {
+		responseFormat.Type = openai.ChatCompletionResponseFormatTypeJSONSchema
+		responseFormat.JSONSchema = &openai.ChatCompletionResponseFormatJSONSchema{
+			Name:   "response",
+			Schema: merged.responseSchema,
+			Strict: true,
+		}
+	} else if merged.OutputFormat == OutputFormatJSON {
 		responseFormat.Type = openai.ChatCompletionResponseFormatTypeJSONObject
 	} else {
 		responseFormat.Type = openai.ChatCompletionResponseFormatTypeText
 	}
 
-	// Create request
+	chatMessages, err := openaiChatMessages(messages)
+	if err != nil {
+		return openai.ChatCompletionRequest{}, err
+	}
+
+	effort, err := reasoningEffort(merged)
+	if err != nil {
+		return openai.ChatCompletionRequest{}, err
+	}
+
 	req := openai.ChatCompletionRequest{
-		Model:          model,
-		ResponseFormat: responseFormat,
-		Messages: []openai.ChatCompletionMessage{
-			{
-				Role:    openai.ChatMessageRoleSystem,
-				Content: systemPrompt,
-			},
-			{
-				Role:    openai.ChatMessageRoleUser,
-				Content: userMessage,
-			},
-		},
-		MaxTokens:   maxTokens,
-		Temperature: float32(temperature),
+		Model:            merged.Model,
+		ResponseFormat:   responseFormat,
+		Messages:         chatMessages,
+		MaxTokens:        requestMaxTokens(merged),
+		Temperature:      float32(derefFloat64(merged.Temperature, o.temperature)),
+		Stop:             merged.Stop,
+		TopP:             float32(derefFloat64(merged.TopP, 0)),
+		PresencePenalty:  float32(derefFloat64(merged.PresencePenalty, 0)),
+		FrequencyPenalty: float32(derefFloat64(merged.FrequencyPenalty, 0)),
+		Seed:             merged.Seed,
+		ReasoningEffort:  effort,
+	}
+
+	// Reasoning models take max_completion_tokens and fix the sampling
+	// parameters, which the go-openai client enforces
+	if isReasoningModel(req.Model) {
+		req.MaxCompletionTokens = req.MaxTokens
+		req.MaxTokens = 0
+		req.Temperature = 0
+		req.TopP = 0
+		req.PresencePenalty = 0
+		req.FrequencyPenalty = 0
 	}
 
-	// Generate response
-	resp, err := o.client.CreateChatCompletion(ctx, req)
+	return req, nil
+}
+
+// GenerateStream implements StreamInterface
+func (o *openaiImplementation) GenerateStream(systemPrompt string, userMessage string, onChunk func(chunk string) error, opts ...LlmOptions) error {
+	return o.generateStream(systemPrompt, userMessage, onChunk, nil, opts...)
+}
+
+// GenerateStreamWithUsage implements StreamUsageInterface
+func (o *openaiImplementation) GenerateStreamWithUsage(systemPrompt string, userMessage string, onChunk func(chunk string) error, onDone func(usage Usage), opts ...LlmOptions) error {
+	return o.generateStream(systemPrompt, userMessage, onChunk, onDone, opts...)
+}
+
+// generateStream streams the response, asking for the usage in the last
+// chunk and reporting it to onDone when set
+func (o *openaiImplementation) generateStream(systemPrompt string, userMessage string, onChunk func(chunk string) error, onDone func(usage Usage), opts ...LlmOptions) error {
+	perCall := LlmOptions{}
+	if len(opts) > 0 {
+		perCall = opts[0]
+	}
+	merged := mergeOptions(o.baseOptions(), perCall)
+
+	if override, err := overrideProvider(o.options.Provider, merged, perCall); err != nil {
+		return err
+	} else if override != nil {
+		defer Close(override)
+		return GenerateStreamWithUsage(override, systemPrompt, userMessage, onChunk, onDone, perCall)
+	}
+
+	if err := checkCostBudget(merged, systemPrompt, userMessage); err != nil {
+		return err
+	}
+
+	ctx, cancel := requestContext(merged)
+	defer cancel()
+
+	req, err := o.chatRequest([]Message{
+		{Role: MessageRoleSystem, Content: systemPrompt},
+		{Role: MessageRoleUser, Content: userMessage},
+	}, merged)
+	if err != nil {
+		return err
+	}
+
+	var response strings.Builder
+	usage, err := openaiChatStream(ctx, o.client, req, onDone != nil, func(chunk string) error {
+		response.WriteString(chunk)
+		return onChunk(chunk)
+	})
+	err = providerAPIError(ProviderOpenAI, err)
 	if err != nil {
 		if o.logger != nil {
-			o.logger.Error("OpenAI generation error",
+			o.logger.Error("OpenAI stream error",
 				slog.String("error", err.Error()),
-				slog.String("model", model))
+				slog.String("model", req.Model))
 		} else if o.verbose {
-			fmt.Printf("OpenAI generation error: %v\n", err)
+			fmt.Printf("OpenAI stream error: %v\n", err)
 		}
-		return "", err
+		if openaiModelNotFound(err) {
+			return &ModelNotFoundError{Provider: ProviderOpenAI, Model: req.Model, Err: err}
+		}
+		return err
 	}
 
-	if len(resp.Choices) == 0 {
-		return "", fmt.Errorf("no response from OpenAI")
+	if onDone != nil {
+		onDone(streamUsage(usage, systemPrompt+"\n"+userMessage, response.String(), req.Model))
+	}
+	return nil
+}
+
+// GenerateWithTools implements ToolInterface
+func (o *openaiImplementation) GenerateWithTools(systemPrompt string, userPrompt string, tools []ToolDefinition, opts ...LlmOptions) (ToolResult, error) {
+	perCall := LlmOptions{}
+	if len(opts) > 0 {
+		perCall = opts[0]
+	}
+	merged := mergeOptions(o.baseOptions(), perCall)
+
+	if override, err := overrideProvider(o.options.Provider, merged, perCall); err != nil {
+		return ToolResult{}, err
+	} else if override != nil {
+		defer Close(override)
+		return GenerateWithTools(override, systemPrompt, userPrompt, tools, perCall)
+	}
+
+	if err := checkCostBudget(merged, systemPrompt, userPrompt); err != nil {
+		return ToolResult{}, err
+	}
+
+	ctx, cancel := requestContext(merged)
+	defer cancel()
+
+	req, err := o.chatRequest([]Message{
+		{Role: MessageRoleSystem, Content: systemPrompt},
+		{Role: MessageRoleUser, Content: userPrompt},
+	}, merged)
+	if err != nil {
+		return ToolResult{}, err
+	}
+	req.Tools = openaiTools(tools)
+	if len(req.Tools) > 0 {
+		req.ToolChoice = "auto"
+	}
+
+	resp, err := o.createChatCompletion(ctx, merged, req)
+	if err != nil {
+		return ToolResult{}, err
 	}
 
-	response := resp.Choices[0].Message.Content
-	return strings.TrimSpace(response), nil
+	return openaiToolResult(ProviderOpenAI, resp)
 }
 
 // GenerateText implements LlmInterface
@@ -133,7 +381,31 @@ func (o *openaiImplementation) GenerateJSON(systemPrompt string, userPrompt stri
 		perCall = opts[0]
 	}
 	perCall.OutputFormat = OutputFormatJSON
-	return o.Generate(systemPrompt, userPrompt, perCall)
+	response, err := o.Generate(systemPrompt, userPrompt, perCall)
+	if err != nil {
+		return "", err
+	}
+	return sanitizeJSONResponse(response), nil
+}
+
+// GenerateXML implements LlmInterface
+func (o *openaiImplementation) GenerateXML(systemPrompt string, userPrompt string, opts ...LlmOptions) (string, error) {
+	return generateMarkup(o, OutputFormatXML, systemPrompt, userPrompt, opts...)
+}
+
+// GenerateYAML implements LlmInterface
+func (o *openaiImplementation) GenerateYAML(systemPrompt string, userPrompt string, opts ...LlmOptions) (string, error) {
+	return generateMarkup(o, OutputFormatYAML, systemPrompt, userPrompt, opts...)
+}
+
+// GenerateWithImages implements VisionInterface
+func (o *openaiImplementation) GenerateWithImages(systemPrompt string, userPrompt string, images [][]byte, opts ...LlmOptions) (string, error) {
+	return o.GenerateChat(visionMessages(systemPrompt, userPrompt, images), opts...)
+}
+
+// GenerateStructured implements StructuredOutputInterface
+func (o *openaiImplementation) GenerateStructured(systemPrompt string, userPrompt string, schema json.RawMessage, opts ...LlmOptions) (json.RawMessage, error) {
+	return generateStructured(o.GenerateJSON, systemPrompt, userPrompt, schema, opts...)
 }
 
 // GenerateImage implements LlmInterface
@@ -143,28 +415,18 @@ func (o *openaiImplementation) GenerateImage(prompt string, opts ...LlmOptions)
 		perCall = opts[0]
 	}
 	merged := mergeOptions(o.baseOptions(), perCall)
-	ctx := context.Background()
-
-	model := merged.Model
+	ctx, cancel := requestContext(merged)
+	defer cancel()
 
-	// Determine image size from provider options or default
-	size := openai.CreateImageSize1024x1024
-	if merged.ProviderOptions != nil {
-		if v, ok := merged.ProviderOptions["image_size"].(string); ok && v != "" {
-			size = v
-		}
-	}
-
-	req := openai.ImageRequest{
-		Model:          model,
-		Prompt:         prompt,
-		Size:           size,
-		N:              1,
-		ResponseFormat: openai.CreateImageResponseFormatB64JSON,
+	req, err := openaiImageRequest(prompt, merged)
+	if err != nil {
+		return nil, err
 	}
+	model := req.Model
 
 	resp, err := o.client.CreateImage(ctx, req)
 	if err != nil {
+		err = providerAPIError(ProviderOpenAI, err)
 		if o.logger != nil {
 			o.logger.Error("OpenAI image generation error",
 				slog.String("error", err.Error()),
@@ -189,12 +451,34 @@ func (o *openaiImplementation) GenerateImage(prompt string, opts ...LlmOptions)
 		return nil, fmt.Errorf("failed to decode image data: %w", err)
 	}
 
-	return bytes, nil
+	return convertImage(bytes, imageOutputFormat(merged))
+}
+
+// GenerateImageSize implements ImageSizeInterface
+func (o *openaiImplementation) GenerateImageSize(prompt string, width int, height int, opts ...LlmOptions) ([]byte, error) {
+	perCall := LlmOptions{}
+	if len(opts) > 0 {
+		perCall = opts[0]
+	}
+	merged := mergeOptions(o.baseOptions(), perCall)
+
+	size, err := openaiImageSizeFor(openaiImageModel(merged), width, height)
+	if err != nil {
+		return nil, err
+	}
+
+	perCall.ProviderOptions = mergeProviderOptions(merged.ProviderOptions, map[string]any{"size": size})
+	return o.GenerateImage(prompt, perCall)
 }
 
 // GenerateEmbedding implements LlmInterface
 func (o *openaiImplementation) GenerateEmbedding(text string) ([]float32, error) {
-	ctx := context.Background()
+	if o.options.EmbeddingLlm != nil {
+		return o.options.EmbeddingLlm.GenerateEmbedding(text)
+	}
+
+	ctx, cancel := requestContext(o.options)
+	defer cancel()
 
 	// Use the configured model if set, otherwise fall back to Ada
 	embeddingModel := openai.EmbeddingModel(o.model)
@@ -209,6 +493,7 @@ func (o *openaiImplementation) GenerateEmbedding(text string) ([]float32, error)
 
 	resp, err := o.client.CreateEmbeddings(ctx, req)
 	if err != nil {
+		err = providerAPIError(ProviderOpenAI, err)
 		if o.logger != nil {
 			o.logger.Error("OpenAI embedding generation error",
 				slog.String("error", err.Error()))
@@ -224,3 +509,153 @@ func (o *openaiImplementation) GenerateEmbedding(text string) ([]float32, error)
 
 	return resp.Data[0].Embedding, nil
 }
+
+// openaiChatMessages converts messages to go-openai chat messages. Messages
+// with images are sent as multi-part content with data URI image parts.
+func openaiChatMessages(messages []Message) ([]openai.ChatCompletionMessage, error) {
+	chatMessages := make([]openai.ChatCompletionMessage, len(messages))
+	for i, message := range messages {
+		if len(message.Images) == 0 {
+			chatMessages[i] = openai.ChatCompletionMessage{
+				Role:    message.Role,
+				Content: message.Content,
+			}
+			continue
+		}
+
+		parts := []openai.ChatMessagePart{}
+		if message.Content != "" {
+			parts = append(parts, openai.ChatMessagePart{
+				Type: openai.ChatMessagePartTypeText,
+				Text: message.Content,
+			})
+		}
+		for _, image := range message.Images {
+			dataURI, err := imageDataURI(image)
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, openai.ChatMessagePart{
+				Type:     openai.ChatMessagePartTypeImageURL,
+				ImageURL: &openai.ChatMessageImageURL{URL: dataURI},
+			})
+		}
+
+		chatMessages[i] = openai.ChatCompletionMessage{
+			Role:         message.Role,
+			MultiContent: parts,
+		}
+	}
+	return chatMessages, nil
+}
+
+// openaiModelNotFound reports whether an OpenAI API error has the
+// model_not_found error code
+func openaiModelNotFound(err error) bool {
+	var apiErr *openai.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	code, _ := apiErr.Code.(string)
+	return code == "model_not_found"
+}
+
+// openaiChoiceContent returns the trimmed text content of the first choice.
+// A choice without text returns a *NoContentError when the model called
+// tools or refused, and ErrEmptyResponse otherwise.
+func openaiChoiceContent(provider Provider, resp openai.ChatCompletionResponse) (string, error) {
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("%w: no choices from %s", ErrEmptyResponse, provider)
+	}
+
+	message := resp.Choices[0].Message
+	content := strings.TrimSpace(message.Content)
+	if content != "" {
+		return content, nil
+	}
+
+	if len(message.ToolCalls) > 0 || message.Refusal != "" {
+		return "", &NoContentError{Provider: provider, ToolCalls: openaiToolCalls(message.ToolCalls), Refusal: message.Refusal}
+	}
+
+	return "", fmt.Errorf("%w: %s returned empty content", ErrEmptyResponse, provider)
+}
+
+// openaiChoicesContent returns the trimmed text content of every choice
+// with text. Without any, it returns the error of openaiChoiceContent.
+func openaiChoicesContent(provider Provider, resp openai.ChatCompletionResponse) ([]string, error) {
+	contents := []string{}
+	for _, choice := range resp.Choices {
+		if content := strings.TrimSpace(choice.Message.Content); content != "" {
+			contents = append(contents, content)
+		}
+	}
+
+	if len(contents) == 0 {
+		_, err := openaiChoiceContent(provider, resp)
+		return nil, err
+	}
+	return contents, nil
+}
+
+// openaiToolResult returns the text and tool calls of the first choice.
+// A refusal returns a *NoContentError, and a response with neither text
+// nor tool calls an error wrapping ErrEmptyResponse.
+func openaiToolResult(provider Provider, resp openai.ChatCompletionResponse) (ToolResult, error) {
+	if len(resp.Choices) == 0 {
+		return ToolResult{}, fmt.Errorf("%w: no choices from %s", ErrEmptyResponse, provider)
+	}
+
+	message := resp.Choices[0].Message
+	result := ToolResult{
+		Text:      strings.TrimSpace(message.Content),
+		ToolCalls: openaiToolCalls(message.ToolCalls),
+	}
+	if result.Text == "" && !result.HasToolCalls() {
+		if message.Refusal != "" {
+			return ToolResult{}, &NoContentError{Provider: provider, Refusal: message.Refusal}
+		}
+		return ToolResult{}, fmt.Errorf("%w: %s returned empty content", ErrEmptyResponse, provider)
+	}
+
+	return result, nil
+}
+
+// openaiToolCalls converts the function tool calls of a chat message
+func openaiToolCalls(calls []openai.ToolCall) []ToolCall {
+	if len(calls) == 0 {
+		return nil
+	}
+	toolCalls := make([]ToolCall, len(calls))
+	for i, call := range calls {
+		toolCalls[i] = ToolCall{
+			ID:        call.ID,
+			Name:      call.Function.Name,
+			Arguments: call.Function.Arguments,
+		}
+	}
+	return toolCalls
+}
+
+// openaiTools converts tool definitions to chat completion function tools
+func openaiTools(tools []ToolDefinition) []openai.Tool {
+	openaiTools := make([]openai.Tool, len(tools))
+	for i, tool := range tools {
+		openaiTools[i] = openai.Tool{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  toolParameters(tool),
+			},
+		}
+	}
+	return openaiTools
+}
+
+// Close implements io.Closer. It closes the idle connections of the
+// HTTP client.
+func (o *openaiImplementation) Close() error {
+	closeIdleConnections(o.httpClient)
+	return nil
+}
@@ -0,0 +1,42 @@
+package llm
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOpenaiBaseURLAndOrganization(t *testing.T) {
+	var path string
+	var headers http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		headers = r.Header.Clone()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"hi"}}]}`))
+	}))
+	defer server.Close()
+
+	engine, err := NewLLM(LlmOptions{
+		Provider: ProviderOpenAI,
+		ApiKey:   "test-key",
+		Model:    "gpt-4.1",
+		ProviderOptions: map[string]any{
+			"base_url":     server.URL + "/gateway/v1/",
+			"organization": "org-123",
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create OpenAI LLM: %v", err)
+	}
+
+	if _, err := engine.GenerateText("system", "hello"); err != nil {
+		t.Fatalf("GenerateText failed: %v", err)
+	}
+	if path != "/gateway/v1/chat/completions" {
+		t.Errorf("expected the request to go through the gateway, got path %s", path)
+	}
+	if got := headers.Get("OpenAI-Organization"); got != "org-123" {
+		t.Errorf("expected OpenAI-Organization org-123, got %q", got)
+	}
+}
@@ -5,15 +5,21 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/sashabaranov/go-openai"
+	"github.com/spf13/cast"
 )
 
+// openrouterDefaultBaseURL is the OpenRouter API endpoint
+const openrouterDefaultBaseURL = "https://openrouter.ai/api/v1"
+
 // openrouterImplementation implements LlmInterface using OpenRouter (OpenAI-compatible API)
 type openrouterImplementation struct {
 	client      *openai.Client
@@ -25,27 +31,34 @@ type openrouterImplementation struct {
 	apiKey      string
 	baseURL     string
 	httpClient  openai.HTTPDoer
+	options     LlmOptions
+	*lastResponseRecorder
 }
 
 // newOpenRouterImplementation creates a new OpenRouter provider implementation
 func newOpenRouterImplementation(options LlmOptions) (LlmInterface, error) {
 	o := options
 
-	apiKey := o.ApiKey
+	apiKey := providerAPIKey(o)
 	if apiKey == "" {
 		return nil, fmt.Errorf("OpenRouter API key is required")
 	}
 
 	model := o.Model
 	if model == "" {
-		// Default to a widely available OpenRouter model alias if not supplied
-		model = "openrouter/auto"
+		model = DefaultModel(ProviderOpenRouter)
 	}
 
-	baseURL := "https://openrouter.ai/api/v1"
+	// base_url lets traffic go through a gateway or proxy
+	baseURL := providerBaseURL(o, openrouterDefaultBaseURL)
 
 	cfg := openai.DefaultConfig(apiKey)
 	cfg.BaseURL = baseURL
+	httpClient, err := secureHTTPClient(ProviderOpenRouter, o)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure openrouter http client: %w", err)
+	}
+	cfg.HTTPClient = openrouterHTTPClient(apiKeyHTTPClient(httpClient, o), o.ProviderOptions)
 
 	client := openai.NewClientWithConfig(cfg)
 
@@ -59,68 +72,173 @@ func newOpenRouterImplementation(options LlmOptions) (LlmInterface, error) {
 		apiKey:      apiKey,
 		baseURL:     baseURL,
 		httpClient:  cfg.HTTPClient,
+		options:     o,
+
+		lastResponseRecorder: newLastResponseRecorder(o.ProviderOptions),
 	}, nil
 }
 
-// baseOptions returns the base LlmOptions from the struct fields for merging.
-func (o *openrouterImplementation) baseOptions() LlmOptions {
-	return LlmOptions{
-		Model:       o.model,
-		MaxTokens:   o.maxTokens,
-		Temperature: &o.temperature,
-		Verbose:     o.verbose,
-		Logger:      o.logger,
+// openrouterHTTPClient returns a copy of client sending OpenRouter's app
+// attribution headers, HTTP-Referer and X-Title, from
+// ProviderOptions["referer"] and ProviderOptions["title"]. Without them,
+// client is returned unchanged.
+func openrouterHTTPClient(client *http.Client, providerOptions map[string]any) *http.Client {
+	headers := http.Header{}
+	if referer, ok := providerOptionString(providerOptions, "referer"); ok && strings.TrimSpace(referer) != "" {
+		headers.Set("HTTP-Referer", strings.TrimSpace(referer))
+	}
+	if title, ok := providerOptionString(providerOptions, "title"); ok && strings.TrimSpace(title) != "" {
+		headers.Set("X-Title", strings.TrimSpace(title))
+	}
+	if len(headers) == 0 {
+		return client
+	}
+
+	transport := client.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	withHeaders := *client
+	withHeaders.Transport = openrouterHeaderTransport{base: transport, headers: headers}
+	return &withHeaders
+}
+
+// openrouterHeaderTransport adds the headers to every request
+type openrouterHeaderTransport struct {
+	base    http.RoundTripper
+	headers http.Header
+}
+
+// RoundTrip implements http.RoundTripper
+func (t openrouterHeaderTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for name, values := range t.headers {
+		req.Header[name] = values
 	}
+	return t.base.RoundTrip(req)
+}
+
+// baseOptions returns the construction options, with the struct fields
+// applied on top, as the base for merging per-call options.
+func (o *openrouterImplementation) baseOptions() LlmOptions {
+	options := o.options
+	options.Model = o.model
+	options.MaxTokens = o.maxTokens
+	options.Temperature = &o.temperature
+	options.Verbose = o.verbose
+	options.Logger = o.logger
+	return options
 }
 
 // Generate implements LlmInterface
 func (o *openrouterImplementation) Generate(systemPrompt string, userMessage string, opts ...LlmOptions) (string, error) {
+	return o.GenerateChat(promptMessages(systemPrompt, userMessage), opts...)
+}
+
+// EffectiveOptions implements EffectiveOptionsInterface
+func (o *openrouterImplementation) EffectiveOptions(opts ...LlmOptions) LlmOptions {
+	return mergeOptions(o.baseOptions(), firstOptions(opts))
+}
+
+// DebugMessages implements DebugMessagesInterface
+func (o *openrouterImplementation) DebugMessages(systemPrompt string, userMessage string, opts ...LlmOptions) []Message {
 	perCall := LlmOptions{}
 	if len(opts) > 0 {
 		perCall = opts[0]
 	}
 	merged := mergeOptions(o.baseOptions(), perCall)
 
-	ctx := context.Background()
+	return markupMessages(promptMessages(systemPrompt, userMessage), merged)
+}
 
-	model := merged.Model
-	maxTokens := merged.MaxTokens
-	temperature := derefFloat64(merged.Temperature, o.temperature)
-	verbose := merged.Verbose
+// GenerateChat implements ChatInterface
+func (o *openrouterImplementation) GenerateChat(messages []Message, opts ...LlmOptions) (string, error) {
+	perCall := LlmOptions{}
+	if len(opts) > 0 {
+		perCall = opts[0]
+	}
+	merged := mergeOptions(o.baseOptions(), perCall)
 
-	// Configure response format based on output format
-	responseFormat := &openai.ChatCompletionResponseFormat{}
-	if merged.OutputFormat == OutputFormatJSON {
-		responseFormat.Type = openai.ChatCompletionResponseFormatTypeJSONObject
-	} else {
-		responseFormat.Type = openai.ChatCompletionResponseFormatTypeText
+	if override, err := overrideProvider(o.options.Provider, merged, perCall); err != nil {
+		return "", err
+	} else if override != nil {
+		defer Close(override)
+		return GenerateChat(override, messages, perCall)
 	}
 
-	if o.logger != nil {
-		o.logger.Debug("OpenRouter request",
-			slog.String("model", model),
-			slog.Int("max_tokens", maxTokens),
-			slog.Float64("temperature", temperature),
-			slog.Int("system_prompt_len", len(systemPrompt)),
-			slog.Int("user_message_len", len(userMessage)))
-	} else if verbose {
-		fmt.Printf("OpenRouter request: model=%s, maxTokens=%d, temperature=%f\n", model, maxTokens, temperature)
+	resp, err := o.chatCompletion(messages, merged, 1)
+	if err != nil {
+		return "", err
 	}
+	return openaiChoiceContent(ProviderOpenRouter, resp)
+}
 
-	// Create request
-	req := openai.ChatCompletionRequest{
-		Model:          model,
-		ResponseFormat: responseFormat,
-		Messages: []openai.ChatCompletionMessage{
-			{Role: openai.ChatMessageRoleSystem, Content: systemPrompt},
-			{Role: openai.ChatMessageRoleUser, Content: userMessage},
-		},
-		MaxTokens:   maxTokens,
-		Temperature: float32(temperature),
+// GenerateN implements CandidatesInterface, requesting merged.N choices
+func (o *openrouterImplementation) GenerateN(systemPrompt string, userPrompt string, opts ...LlmOptions) ([]string, error) {
+	perCall := firstOptions(opts)
+	merged := mergeOptions(o.baseOptions(), perCall)
+
+	if override, err := overrideProvider(o.options.Provider, merged, perCall); err != nil {
+		return nil, err
+	} else if override != nil {
+		defer Close(override)
+		return GenerateN(override, systemPrompt, userPrompt, perCall)
+	}
+
+	resp, err := o.chatCompletion(promptMessages(systemPrompt, userPrompt), merged, candidateCount(merged))
+	if err != nil {
+		return nil, err
+	}
+	return openaiChoicesContent(ProviderOpenRouter, resp)
+}
+
+// chatCompletion sends the messages as a chat completion request for the
+// number of candidates, with retries, routing and logging
+func (o *openrouterImplementation) chatCompletion(messages []Message, merged LlmOptions, candidates int) (openai.ChatCompletionResponse, error) {
+	merged.Model = openrouterModelFor(merged.Model, merged.OutputFormat)
+	messages = markupMessages(messages, merged)
+
+	if err := checkCostBudget(merged, "", messagesContent(messages)); err != nil {
+		return openai.ChatCompletionResponse{}, err
 	}
 
-	// Generate response
-	resp, err := o.client.CreateChatCompletion(ctx, req)
+	ctx, cancel := requestContext(merged)
+	defer cancel()
+
+	req, err := o.chatRequest(messages, merged)
+	if err != nil {
+		return openai.ChatCompletionResponse{}, err
+	}
+	if candidates > 1 {
+		req.N = candidates
+	}
+	model := req.Model
+	verbose := merged.Verbose
+
+	if o.logger != nil {
+		o.logger.Debug("OpenRouter request",
+			slog.String("model", model),
+			slog.Int("max_tokens", req.MaxTokens),
+			slog.Float64("temperature", float64(req.Temperature)),
+			slog.Int("messages", len(messages)))
+	} else if verbose {
+		fmt.Printf("OpenRouter request: model=%s, maxTokens=%d, temperature=%f\n", model, req.MaxTokens, req.Temperature)
+	}
+
+	// Generate response. The routing fields are not part of the go-openai
+	// request, so routed requests are sent directly.
+	routing := openrouterRouting(merged.ProviderOptions)
+	var resp openai.ChatCompletionResponse
+	err = withRetry(ctx, merged, func() error {
+		var err error
+		if len(routing) > 0 {
+			resp, err = o.createRoutedChatCompletion(ctx, req, routing)
+		} else {
+			resp, err = o.client.CreateChatCompletion(ctx, req)
+		}
+		return providerAPIError(ProviderOpenRouter, err)
+	})
 	if err != nil {
 		if o.logger != nil {
 			o.logger.Error("OpenRouter API request failed",
@@ -130,7 +248,13 @@ func (o *openrouterImplementation) Generate(systemPrompt string, userMessage str
 		} else if verbose {
 			fmt.Printf("OpenRouter generation error: %v\n", err)
 		}
-		return "", err
+		if openrouterModelNotFound(err) {
+			return resp, &ModelNotFoundError{Provider: ProviderOpenRouter, Model: model, Err: err}
+		}
+		return resp, err
+	}
+	if len(routing) == 0 {
+		o.recordObject(ctx, ProviderOpenRouter, http.StatusOK, resp.Header(), resp)
 	}
 
 	if o.logger != nil {
@@ -147,7 +271,7 @@ func (o *openrouterImplementation) Generate(systemPrompt string, userMessage str
 		} else if verbose {
 			fmt.Printf("no response from OpenRouter: model=%s\n", model)
 		}
-		return "", fmt.Errorf("no response from OpenRouter")
+		return resp, fmt.Errorf("%w: no choices from OpenRouter", ErrEmptyResponse)
 	}
 
 	response := resp.Choices[0].Message.Content
@@ -158,7 +282,208 @@ func (o *openrouterImplementation) Generate(systemPrompt string, userMessage str
 	} else if verbose {
 		fmt.Printf("OpenRouter response: length=%d\n", len(response))
 	}
-	return strings.TrimSpace(response), nil
+	return resp, nil
+}
+
+// chatRequest builds the chat completion request for the merged options
+func (o *openrouterImplementation) chatRequest(messages []Message, merged LlmOptions) (openai.ChatCompletionRequest, error) {
+	// Configure response format based on output format
+	responseFormat := &openai.ChatCompletionResponseFormat{}
+	if merged.OutputFormat == OutputFormatJSON && len(merged.responseSchema) > 0 {
+		responseFormat.Type = openai.ChatCompletionResponseFormatTypeJSONSchema
+		responseFormat.JSONSchema = &openai.ChatCompletionResponseFormatJSONSchema{
+			Name:   "response",
+			Schema: merged.responseSchema,
+			Strict: true,
+		}
+	} else if merged.OutputFormat == OutputFormatJSON {
+		responseFormat.Type = openai.ChatCompletionResponseFormatTypeJSONObject
+	} else {
+		responseFormat.Type = openai.ChatCompletionResponseFormatTypeText
+	}
+
+	chatMessages, err := openaiChatMessages(messages)
+	if err != nil {
+		return openai.ChatCompletionRequest{}, err
+	}
+
+	effort, err := reasoningEffort(merged)
+	if err != nil {
+		return openai.ChatCompletionRequest{}, err
+	}
+
+	return openai.ChatCompletionRequest{
+		Model:            merged.Model,
+		ResponseFormat:   responseFormat,
+		Messages:         chatMessages,
+		MaxTokens:        requestMaxTokens(merged),
+		Temperature:      float32(derefFloat64(merged.Temperature, o.temperature)),
+		Stop:             merged.Stop,
+		TopP:             float32(derefFloat64(merged.TopP, 0)),
+		PresencePenalty:  float32(derefFloat64(merged.PresencePenalty, 0)),
+		FrequencyPenalty: float32(derefFloat64(merged.FrequencyPenalty, 0)),
+		Seed:             merged.Seed,
+		ReasoningEffort:  effort,
+	}, nil
+}
+
+// GenerateStream implements StreamInterface
+func (o *openrouterImplementation) GenerateStream(systemPrompt string, userMessage string, onChunk func(chunk string) error, opts ...LlmOptions) error {
+	return o.generateStream(systemPrompt, userMessage, onChunk, nil, opts...)
+}
+
+// GenerateStreamWithUsage implements StreamUsageInterface
+func (o *openrouterImplementation) GenerateStreamWithUsage(systemPrompt string, userMessage string, onChunk func(chunk string) error, onDone func(usage Usage), opts ...LlmOptions) error {
+	return o.generateStream(systemPrompt, userMessage, onChunk, onDone, opts...)
+}
+
+// generateStream streams the response, reporting the usage to onDone if
+// set. Routed requests (fallback models or provider preferences) are not
+// streamed: onChunk is called once with the full response.
+func (o *openrouterImplementation) generateStream(systemPrompt string, userMessage string, onChunk func(chunk string) error, onDone func(usage Usage), opts ...LlmOptions) error {
+	perCall := LlmOptions{}
+	if len(opts) > 0 {
+		perCall = opts[0]
+	}
+	merged := mergeOptions(o.baseOptions(), perCall)
+
+	if override, err := overrideProvider(o.options.Provider, merged, perCall); err != nil {
+		return err
+	} else if override != nil {
+		defer Close(override)
+		return GenerateStreamWithUsage(override, systemPrompt, userMessage, onChunk, onDone, perCall)
+	}
+
+	merged.Model = openrouterModelFor(merged.Model, merged.OutputFormat)
+
+	if len(openrouterRouting(merged.ProviderOptions)) > 0 {
+		response, err := o.Generate(systemPrompt, userMessage, perCall)
+		if err != nil {
+			return err
+		}
+		if err := onChunk(response); err != nil {
+			return err
+		}
+		if onDone != nil {
+			onDone(estimateUsage(systemPrompt+"\n"+userMessage, response, merged.Model))
+		}
+		return nil
+	}
+
+	messages := markupMessages(promptMessages(systemPrompt, userMessage), merged)
+	if err := checkCostBudget(merged, "", messagesContent(messages)); err != nil {
+		return err
+	}
+
+	ctx, cancel := requestContext(merged)
+	defer cancel()
+
+	req, err := o.chatRequest(messages, merged)
+	if err != nil {
+		return err
+	}
+
+	var response strings.Builder
+	usage, err := openaiChatStream(ctx, o.client, req, onDone != nil, func(chunk string) error {
+		response.WriteString(chunk)
+		return onChunk(chunk)
+	})
+	if err != nil {
+		err = providerAPIError(ProviderOpenRouter, err)
+		if o.logger != nil {
+			o.logger.Error("OpenRouter stream error",
+				slog.String("error", err.Error()),
+				slog.String("model", req.Model))
+		} else if o.verbose {
+			fmt.Printf("OpenRouter stream error: %v\n", err)
+		}
+		if openrouterModelNotFound(err) {
+			return &ModelNotFoundError{Provider: ProviderOpenRouter, Model: req.Model, Err: err}
+		}
+		return err
+	}
+
+	if onDone != nil {
+		onDone(streamUsage(usage, messagesContent(messages), response.String(), req.Model))
+	}
+	return nil
+}
+
+// openrouterRouting returns the OpenRouter routing fields of the request
+// body: "models" with "route": "fallback" from
+// ProviderOptions["fallback_models"], and "provider" from
+// ProviderOptions["provider_preferences"]
+func openrouterRouting(providerOptions map[string]any) map[string]any {
+	routing := map[string]any{}
+
+	if fallbackModels := cast.ToStringSlice(providerOptions["fallback_models"]); len(fallbackModels) > 0 {
+		routing["models"] = fallbackModels
+		routing["route"] = "fallback"
+	}
+
+	if preferences, ok := providerOptions["provider_preferences"].(map[string]any); ok && len(preferences) > 0 {
+		routing["provider"] = preferences
+	}
+
+	return routing
+}
+
+// createRoutedChatCompletion sends the chat completion request with the
+// routing fields added to the body. Error responses are returned as an
+// *openai.APIError, like the go-openai client does.
+func (o *openrouterImplementation) createRoutedChatCompletion(ctx context.Context, chatRequest openai.ChatCompletionRequest, routing map[string]any) (openai.ChatCompletionResponse, error) {
+	var resp openai.ChatCompletionResponse
+
+	reqJSON, err := json.Marshal(chatRequest)
+	if err != nil {
+		return resp, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	reqBody := map[string]any{}
+	if err := json.Unmarshal(reqJSON, &reqBody); err != nil {
+		return resp, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	for key, value := range routing {
+		reqBody[key] = value
+	}
+	reqJSON, err = json.Marshal(reqBody)
+	if err != nil {
+		return resp, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", o.baseURL+"/chat/completions", bytes.NewReader(reqJSON))
+	if err != nil {
+		return resp, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+o.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	httpResp, err := o.httpClient.Do(req)
+	if err != nil {
+		return resp, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer httpResp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(httpResp.Body, 10<<20))
+	if err != nil {
+		return resp, fmt.Errorf("failed to read response: %w", err)
+	}
+	o.recordHTTP(ProviderOpenRouter, httpResp, body)
+
+	if httpResp.StatusCode != http.StatusOK {
+		var errResp openai.ErrorResponse
+		if err := json.Unmarshal(body, &errResp); err != nil || errResp.Error == nil {
+			return resp, &openai.RequestError{HTTPStatus: httpResp.Status, HTTPStatusCode: httpResp.StatusCode, Err: fmt.Errorf("%s", body), Body: body}
+		}
+		errResp.Error.HTTPStatus = httpResp.Status
+		errResp.Error.HTTPStatusCode = httpResp.StatusCode
+		return resp, errResp.Error
+	}
+
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return resp, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return resp, nil
 }
 
 // GenerateText implements LlmInterface
@@ -178,21 +503,53 @@ func (o *openrouterImplementation) GenerateJSON(systemPrompt string, userPrompt
 		perCall = opts[0]
 	}
 	perCall.OutputFormat = OutputFormatJSON
-	return o.Generate(systemPrompt, userPrompt, perCall)
+	response, err := o.Generate(systemPrompt, userPrompt, perCall)
+	if err != nil {
+		return "", err
+	}
+	return sanitizeJSONResponse(response), nil
+}
+
+// GenerateXML implements LlmInterface
+func (o *openrouterImplementation) GenerateXML(systemPrompt string, userPrompt string, opts ...LlmOptions) (string, error) {
+	return generateMarkup(o, OutputFormatXML, systemPrompt, userPrompt, opts...)
+}
+
+// GenerateYAML implements LlmInterface
+func (o *openrouterImplementation) GenerateYAML(systemPrompt string, userPrompt string, opts ...LlmOptions) (string, error) {
+	return generateMarkup(o, OutputFormatYAML, systemPrompt, userPrompt, opts...)
+}
+
+// GenerateWithImages implements VisionInterface
+func (o *openrouterImplementation) GenerateWithImages(systemPrompt string, userPrompt string, images [][]byte, opts ...LlmOptions) (string, error) {
+	return o.GenerateChat(visionMessages(systemPrompt, userPrompt, images), opts...)
+}
+
+// GenerateStructured implements StructuredOutputInterface
+func (o *openrouterImplementation) GenerateStructured(systemPrompt string, userPrompt string, schema json.RawMessage, opts ...LlmOptions) (json.RawMessage, error) {
+	return generateStructured(o.GenerateJSON, systemPrompt, userPrompt, schema, opts...)
 }
 
 // GenerateImage implements LlmInterface
 // OpenRouter uses the chat completions endpoint with modalities parameter for image generation
 func (o *openrouterImplementation) GenerateImage(prompt string, opts ...LlmOptions) ([]byte, error) {
+	image, _, err := o.GenerateImageWithText(prompt, opts...)
+	return image, err
+}
+
+// GenerateImageWithText implements ImageTextInterface. The text is the
+// content of the response message.
+func (o *openrouterImplementation) GenerateImageWithText(prompt string, opts ...LlmOptions) ([]byte, string, error) {
 	perCall := LlmOptions{}
 	if len(opts) > 0 {
 		perCall = opts[0]
 	}
 	merged := mergeOptions(o.baseOptions(), perCall)
 
-	ctx := context.Background()
+	ctx, cancel := requestContext(merged)
+	defer cancel()
 
-	model := merged.Model
+	model := openrouterModelFor(merged.Model, OutputFormatImagePNG)
 	verbose := merged.Verbose
 
 	if o.logger != nil {
@@ -216,6 +573,12 @@ func (o *openrouterImplementation) GenerateImage(prompt string, opts ...LlmOptio
 		ImageConfig *imageConfig                   `json:"image_config,omitempty"`
 	}
 
+	// Default to square images
+	aspectRatio := "1:1"
+	if v, ok := providerOptionString(merged.ProviderOptions, "aspect_ratio"); ok && v != "" {
+		aspectRatio = v
+	}
+
 	// Create the request with modalities
 	reqBody := chatRequest{
 		Model: model,
@@ -227,19 +590,19 @@ func (o *openrouterImplementation) GenerateImage(prompt string, opts ...LlmOptio
 		},
 		Modalities: []string{"image", "text"},
 		ImageConfig: &imageConfig{
-			AspectRatio: "1:1", // Default to square images
+			AspectRatio: aspectRatio,
 		},
 	}
 
 	// We need to make a custom HTTP request since the standard client doesn't support modalities
 	reqJSON, err := json.Marshal(reqBody)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+		return nil, "", fmt.Errorf("failed to marshal request: %w", err)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, "POST", o.baseURL+"/chat/completions", bytes.NewReader(reqJSON))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, "", fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Authorization", "Bearer "+o.apiKey)
@@ -247,17 +610,18 @@ func (o *openrouterImplementation) GenerateImage(prompt string, opts ...LlmOptio
 
 	resp, err := o.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+		return nil, "", fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(io.LimitReader(resp.Body, 100<<20))
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return nil, "", fmt.Errorf("failed to read response: %w", err)
 	}
+	o.recordHTTP(ProviderOpenRouter, resp, body)
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("image generation failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, "", newAPIError(ProviderOpenRouter, resp.StatusCode, body)
 	}
 
 	// Parse the response to extract the image
@@ -286,32 +650,32 @@ func (o *openrouterImplementation) GenerateImage(prompt string, opts ...LlmOptio
 
 	var chatResp chatResponse
 	if err := json.Unmarshal(body, &chatResp); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w", err)
+		return nil, "", fmt.Errorf("failed to parse response: %w", err)
 	}
 
 	if len(chatResp.Choices) == 0 {
-		return nil, fmt.Errorf("no choices in response")
+		return nil, "", fmt.Errorf("no choices in response")
 	}
 
 	if len(chatResp.Choices[0].Message.Images) == 0 {
-		return nil, fmt.Errorf("no images in response")
+		return nil, "", fmt.Errorf("no images in response")
 	}
 
 	// Extract the base64 image data from the data URL
 	dataURL := chatResp.Choices[0].Message.Images[0].ImageURL.URL
 	if !strings.HasPrefix(dataURL, "data:image/") {
-		return nil, fmt.Errorf("unexpected image URL format: %s", dataURL)
+		return nil, "", fmt.Errorf("unexpected image URL format: %s", dataURL)
 	}
 
 	// Extract base64 data from data URL (format: data:image/png;base64,...)
 	parts := strings.SplitN(dataURL, ",", 2)
 	if len(parts) != 2 {
-		return nil, fmt.Errorf("invalid data URL format")
+		return nil, "", fmt.Errorf("invalid data URL format")
 	}
 
 	imageBytes, err := base64.StdEncoding.DecodeString(parts[1])
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode base64 image: %w", err)
+		return nil, "", fmt.Errorf("failed to decode base64 image: %w", err)
 	}
 
 	if o.logger != nil {
@@ -321,17 +685,45 @@ func (o *openrouterImplementation) GenerateImage(prompt string, opts ...LlmOptio
 		fmt.Printf("Successfully generated image: %d bytes\n", len(imageBytes))
 	}
 
-	return imageBytes, nil
+	image, err := convertImage(imageBytes, imageOutputFormat(merged))
+	if err != nil {
+		return nil, "", err
+	}
+	return image, chatResp.Choices[0].Message.Content, nil
+}
+
+// GenerateImageSize implements ImageSizeInterface
+func (o *openrouterImplementation) GenerateImageSize(prompt string, width int, height int, opts ...LlmOptions) ([]byte, error) {
+	perCall := LlmOptions{}
+	if len(opts) > 0 {
+		perCall = opts[0]
+	}
+	merged := mergeOptions(o.baseOptions(), perCall)
+
+	aspectRatio, err := openrouterAspectRatioFor(width, height)
+	if err != nil {
+		return nil, err
+	}
+
+	perCall.ProviderOptions = mergeProviderOptions(merged.ProviderOptions, map[string]any{"aspect_ratio": aspectRatio})
+	return o.GenerateImage(prompt, perCall)
 }
 
 func (o *openrouterImplementation) GenerateEmbedding(text string) ([]float32, error) {
-	ctx := context.Background()
+	if o.options.EmbeddingLlm != nil {
+		return o.options.EmbeddingLlm.GenerateEmbedding(text)
+	}
+
+	ctx, cancel := requestContext(o.options)
+	defer cancel()
 
 	// OpenRouter uses OpenAI-compatible embeddings endpoint
 	// Use the configured model if set, otherwise fall back to Ada
 	embeddingModel := openai.EmbeddingModel(o.model)
 	if o.model == "" || o.model == "openrouter/auto" {
 		embeddingModel = openai.AdaEmbeddingV2
+	} else if o.model == OPENROUTER_MODEL_AUTO {
+		embeddingModel = openrouterAutoEmbeddingModel
 	}
 
 	req := openai.EmbeddingRequest{
@@ -341,6 +733,7 @@ func (o *openrouterImplementation) GenerateEmbedding(text string) ([]float32, er
 
 	resp, err := o.client.CreateEmbeddings(ctx, req)
 	if err != nil {
+		err = providerAPIError(ProviderOpenRouter, err)
 		if o.logger != nil {
 			o.logger.Error("OpenRouter embedding generation error",
 				slog.String("error", err.Error()))
@@ -356,3 +749,99 @@ func (o *openrouterImplementation) GenerateEmbedding(text string) ([]float32, er
 
 	return resp.Data[0].Embedding, nil
 }
+
+// ListModels implements ModelListInterface, fetching the models listed by
+// OpenRouter's /models endpoint with their context length and pricing
+func (o *openrouterImplementation) ListModels() ([]ModelInfo, error) {
+	ctx, cancel := requestContext(o.options)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, o.baseURL+"/models", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+o.apiKey)
+
+	resp, err := o.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 100<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	o.recordHTTP(ProviderOpenRouter, resp, body)
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(ProviderOpenRouter, resp.StatusCode, body)
+	}
+
+	var modelsResp struct {
+		Data []struct {
+			ID            string `json:"id"`
+			Name          string `json:"name"`
+			ContextLength int    `json:"context_length"`
+			Pricing       struct {
+				Prompt     string `json:"prompt"`
+				Completion string `json:"completion"`
+			} `json:"pricing"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &modelsResp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	models := make([]ModelInfo, 0, len(modelsResp.Data))
+	for _, model := range modelsResp.Data {
+		models = append(models, ModelInfo{
+			ID:            model.ID,
+			Name:          model.Name,
+			ContextLength: model.ContextLength,
+			InputPrice:    openrouterPricePer1M(model.Pricing.Prompt),
+			OutputPrice:   openrouterPricePer1M(model.Pricing.Completion),
+		})
+	}
+
+	if o.logger != nil {
+		o.logger.Debug("OpenRouter models listed", slog.Int("models", len(models)))
+	} else if o.verbose {
+		fmt.Printf("OpenRouter models listed: %d\n", len(models))
+	}
+
+	return models, nil
+}
+
+// openrouterPricePer1M converts an OpenRouter price, a string of USD per
+// token, to USD per million tokens. Invalid and negative prices, such as
+// the "-1" of routers with variable pricing, return 0.
+func openrouterPricePer1M(price string) float64 {
+	perToken, err := strconv.ParseFloat(price, 64)
+	if err != nil || perToken < 0 {
+		return 0
+	}
+	return perToken * 1_000_000
+}
+
+// openrouterModelNotFound reports whether an OpenRouter API error rejects
+// the model. OpenRouter uses numeric codes, returning 404 when no endpoint
+// serves the model and 400 for an unknown model ID.
+func openrouterModelNotFound(err error) bool {
+	var apiErr *openai.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	if apiErr.HTTPStatusCode == http.StatusNotFound {
+		return true
+	}
+	return apiErr.HTTPStatusCode == http.StatusBadRequest &&
+		strings.Contains(apiErr.Message, "not a valid model ID")
+}
+
+// Close implements io.Closer. It closes the idle connections of the
+// HTTP client.
+func (o *openrouterImplementation) Close() error {
+	closeIdleConnections(o.httpClient)
+	return nil
+}
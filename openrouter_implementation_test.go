@@ -0,0 +1,206 @@
+package llm
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestOpenrouterAttributionHeaders(t *testing.T) {
+	var headers http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header.Clone()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"hi"}}]}`))
+	}))
+	defer server.Close()
+	target, _ := url.Parse(server.URL)
+
+	newEngine := func(providerOptions map[string]any) LlmInterface {
+		engine, err := NewLLM(LlmOptions{
+			Provider:        ProviderOpenRouter,
+			ApiKey:          "test-key",
+			ProviderOptions: providerOptions,
+			HTTPClient:      &http.Client{Transport: redirectTransport{target: target}},
+		})
+		if err != nil {
+			t.Fatalf("failed to create OpenRouter LLM: %v", err)
+		}
+		return engine
+	}
+
+	engine := newEngine(map[string]any{"referer": "https://example.com", "title": "Example App"})
+	if _, err := engine.GenerateText("system", "hello"); err != nil {
+		t.Fatalf("GenerateText failed: %v", err)
+	}
+	if headers.Get("HTTP-Referer") != "https://example.com" {
+		t.Errorf("expected HTTP-Referer header, got %q", headers.Get("HTTP-Referer"))
+	}
+	if headers.Get("X-Title") != "Example App" {
+		t.Errorf("expected X-Title header, got %q", headers.Get("X-Title"))
+	}
+	if headers.Get("Authorization") != "Bearer test-key" {
+		t.Errorf("expected the API key to still be sent, got %q", headers.Get("Authorization"))
+	}
+
+	// Nothing is sent when unset
+	engine = newEngine(nil)
+	if _, err := engine.GenerateText("system", "hello"); err != nil {
+		t.Fatalf("GenerateText failed: %v", err)
+	}
+	if _, ok := headers["Http-Referer"]; ok {
+		t.Errorf("expected no HTTP-Referer header, got %q", headers.Get("HTTP-Referer"))
+	}
+	if _, ok := headers["X-Title"]; ok {
+		t.Errorf("expected no X-Title header, got %q", headers.Get("X-Title"))
+	}
+}
+
+func TestOpenrouterFallbackModels(t *testing.T) {
+	var captured map[string]any
+	server := captureServer(t, `{"model":"anthropic/claude-3.5-sonnet","choices":[{"message":{"role":"assistant","content":"hi"}}]}`, &captured)
+	defer server.Close()
+
+	engine := &openrouterImplementation{
+		model:                "openai/gpt-4o",
+		apiKey:               "test-key",
+		baseURL:              server.URL,
+		httpClient:           http.DefaultClient,
+		lastResponseRecorder: newLastResponseRecorder(nil),
+	}
+
+	response, err := engine.GenerateText("system", "hello", LlmOptions{ProviderOptions: map[string]any{
+		"fallback_models":      []string{"anthropic/claude-3.5-sonnet", "google/gemini-2.5-flash"},
+		"provider_preferences": map[string]any{"order": []string{"Anthropic"}, "allow_fallbacks": true},
+	}})
+	if err != nil {
+		t.Fatalf("GenerateText failed: %v", err)
+	}
+	if response != "hi" {
+		t.Errorf("expected hi, got %q", response)
+	}
+
+	if captured["model"] != "openai/gpt-4o" {
+		t.Errorf("expected the primary model, got %v", captured["model"])
+	}
+	if !reflect.DeepEqual(captured["models"], []any{"anthropic/claude-3.5-sonnet", "google/gemini-2.5-flash"}) {
+		t.Errorf("expected fallback models, got %v", captured["models"])
+	}
+	if captured["route"] != "fallback" {
+		t.Errorf("expected route fallback, got %v", captured["route"])
+	}
+	provider, _ := captured["provider"].(map[string]any)
+	if provider["allow_fallbacks"] != true || !reflect.DeepEqual(provider["order"], []any{"Anthropic"}) {
+		t.Errorf("expected provider preferences, got %v", captured["provider"])
+	}
+	messages, _ := captured["messages"].([]any)
+	if len(messages) != 2 {
+		t.Errorf("expected the chat messages to be kept, got %v", captured["messages"])
+	}
+}
+
+func TestOpenrouterRoutedErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":{"code":400,"message":"foo/bar is not a valid model ID"}}`))
+	}))
+	defer server.Close()
+
+	engine := &openrouterImplementation{
+		model:                "foo/bar",
+		apiKey:               "test-key",
+		baseURL:              server.URL,
+		httpClient:           http.DefaultClient,
+		lastResponseRecorder: newLastResponseRecorder(nil),
+	}
+
+	_, err := engine.GenerateText("system", "hello", LlmOptions{ProviderOptions: map[string]any{
+		"fallback_models": []any{"openai/gpt-4o"},
+	}})
+	if !errors.Is(err, ErrModelNotFound) {
+		t.Errorf("expected ErrModelNotFound, got %v", err)
+	}
+}
+
+func TestOpenrouterBaseURL(t *testing.T) {
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"hi"}}]}`))
+	}))
+	defer server.Close()
+
+	engine, err := NewLLM(LlmOptions{
+		Provider:        ProviderOpenRouter,
+		ApiKey:          "test-key",
+		Model:           OPENROUTER_MODEL_GPT_5_NANO,
+		ProviderOptions: map[string]any{"base_url": server.URL + "/proxy/api/v1"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create OpenRouter LLM: %v", err)
+	}
+
+	if _, err := engine.GenerateText("system", "hello"); err != nil {
+		t.Fatalf("GenerateText failed: %v", err)
+	}
+	if path != "/proxy/api/v1/chat/completions" {
+		t.Errorf("expected the request to go through the proxy, got path %s", path)
+	}
+}
+
+func TestOpenrouterListModels(t *testing.T) {
+	var request *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request = r
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data":[
+			{"id":"anthropic/claude-sonnet-4","name":"Anthropic: Claude Sonnet 4","context_length":200000,"pricing":{"prompt":"0.000003","completion":"0.000015"}},
+			{"id":"openrouter/auto","name":"Auto Router","context_length":2000000,"pricing":{"prompt":"-1","completion":"-1"}}
+		]}`))
+	}))
+	defer server.Close()
+
+	engine, err := NewLLM(LlmOptions{
+		Provider:        ProviderOpenRouter,
+		ApiKey:          "test-key",
+		ProviderOptions: map[string]any{"base_url": server.URL},
+	})
+	if err != nil {
+		t.Fatalf("failed to create OpenRouter LLM: %v", err)
+	}
+
+	models, err := ListModels(engine)
+	if err != nil {
+		t.Fatalf("ListModels failed: %v", err)
+	}
+	if request.Method != http.MethodGet || request.URL.Path != "/models" {
+		t.Errorf("expected GET /models, got %s %s", request.Method, request.URL.Path)
+	}
+	if request.Header.Get("Authorization") != "Bearer test-key" {
+		t.Errorf("expected the API key to be sent, got %q", request.Header.Get("Authorization"))
+	}
+
+	if len(models) != 2 {
+		t.Fatalf("expected 2 models, got %d", len(models))
+	}
+	sonnet := models[0]
+	if sonnet.ID != "anthropic/claude-sonnet-4" || sonnet.Name != "Anthropic: Claude Sonnet 4" || sonnet.ContextLength != 200000 {
+		t.Errorf("unexpected model: %+v", sonnet)
+	}
+	if math.Abs(sonnet.InputPrice-3) > 1e-9 || math.Abs(sonnet.OutputPrice-15) > 1e-9 {
+		t.Errorf("expected prices 3 and 15 per million tokens, got %v and %v", sonnet.InputPrice, sonnet.OutputPrice)
+	}
+	if models[1].InputPrice != 0 || models[1].OutputPrice != 0 {
+		t.Errorf("expected variable pricing as 0, got %+v", models[1])
+	}
+
+	mock, _ := NewLLM(LlmOptions{Provider: ProviderMock})
+	if _, err := ListModels(mock); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported for the mock, got %v", err)
+	}
+}
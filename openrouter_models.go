@@ -243,3 +243,36 @@ const OPENROUTER_MODEL_TEXT_EMBEDDING_3_LARGE = "openai/text-embedding-3-large"
 // OpenAI Text Embedding 3 Small
 // Input $0.02/M Output $0.00/M
 const OPENROUTER_MODEL_TEXT_EMBEDDING_3_SMALL = "openai/text-embedding-3-small"
+
+// ===========================================================================//
+// Automatic Model Selection
+// ===========================================================================//
+
+// OPENROUTER_MODEL_AUTO picks a concrete default model for each task:
+// a cheap text model, a JSON-capable model, an image model or an embedding
+// model. Unlike "openrouter/auto" it is resolved by this package, not by
+// OpenRouter.
+const OPENROUTER_MODEL_AUTO = "auto"
+
+// openrouterAutoModels maps output formats to the model used for OPENROUTER_MODEL_AUTO
+var openrouterAutoModels = map[OutputFormat]string{
+	OutputFormatText:     OPENROUTER_MODEL_GEMINI_2_5_FLASH_LITE,
+	OutputFormatJSON:     OPENROUTER_MODEL_GPT_4_1_NANO,
+	OutputFormatImagePNG: OPENROUTER_MODEL_GEMINI_2_5_FLASH_IMAGE,
+	OutputFormatImageJPG: OPENROUTER_MODEL_GEMINI_2_5_FLASH_IMAGE,
+}
+
+// openrouterAutoEmbeddingModel is the embedding model used for OPENROUTER_MODEL_AUTO
+const openrouterAutoEmbeddingModel = OPENROUTER_MODEL_TEXT_EMBEDDING_3_SMALL
+
+// openrouterModelFor resolves OPENROUTER_MODEL_AUTO to the default model for
+// the output format. Other models are returned unchanged.
+func openrouterModelFor(model string, format OutputFormat) string {
+	if model != OPENROUTER_MODEL_AUTO {
+		return model
+	}
+	if autoModel, ok := openrouterAutoModels[format]; ok {
+		return autoModel
+	}
+	return openrouterAutoModels[OutputFormatText]
+}
@@ -0,0 +1,35 @@
+package llm
+
+import "testing"
+
+func TestOpenrouterModelFor(t *testing.T) {
+	testCases := []struct {
+		model  string
+		format OutputFormat
+		want   string
+	}{
+		{OPENROUTER_MODEL_AUTO, OutputFormatText, OPENROUTER_MODEL_GEMINI_2_5_FLASH_LITE},
+		{OPENROUTER_MODEL_AUTO, "", OPENROUTER_MODEL_GEMINI_2_5_FLASH_LITE},
+		{OPENROUTER_MODEL_AUTO, OutputFormatJSON, OPENROUTER_MODEL_GPT_4_1_NANO},
+		{OPENROUTER_MODEL_AUTO, OutputFormatImagePNG, OPENROUTER_MODEL_GEMINI_2_5_FLASH_IMAGE},
+		{OPENROUTER_MODEL_AUTO, OutputFormatImageJPG, OPENROUTER_MODEL_GEMINI_2_5_FLASH_IMAGE},
+		{"openrouter/auto", OutputFormatJSON, "openrouter/auto"},
+		{OPENROUTER_MODEL_GPT_5_NANO, OutputFormatJSON, OPENROUTER_MODEL_GPT_5_NANO},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.model+"/"+string(tc.format), func(t *testing.T) {
+			if got := openrouterModelFor(tc.model, tc.format); got != tc.want {
+				t.Errorf("openrouterModelFor(%q, %q) = %q, want %q", tc.model, tc.format, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestOpenrouterAutoModelsArePriced(t *testing.T) {
+	for format, model := range openrouterAutoModels {
+		if _, ok := lookupModelPrice(model); !ok {
+			t.Errorf("auto model %s for %s has no pricing", model, format)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package llm
+
+// GenerateWithProvider generates a text response with another provider
+// than the client's, e.g. to escalate a call from a cheap model to a
+// stronger one. It is Generate with the per-call Provider option set: the
+// alternate provider is constructed on demand from the client's options
+// merged with the given options.
+//
+// The client's ApiKey, Model, ProjectID, Region and ProviderOptions belong
+// to its own provider and are not carried over: the options must set the
+// ApiKey (or credentials) and Model of the alternate provider.
+func GenerateWithProvider(llm LlmInterface, provider Provider, systemPrompt string, userPrompt string, options ...LlmOptions) (string, error) {
+	perCall := LlmOptions{}
+	if len(options) > 0 {
+		perCall = options[0]
+	}
+	perCall.Provider = provider
+	perCall.OutputFormat = OutputFormatText
+	return llm.Generate(systemPrompt, userPrompt, perCall)
+}
+
+// overrideProvider returns a client for the per-call Provider option when
+// it names another provider than the client's, and nil otherwise. The
+// client is built from the merged options, with the provider specific
+// settings (credentials, model, project, region and provider options)
+// taken from the per-call options only. The client is built for the call:
+// the caller closes it with Close once the call returns.
+func overrideProvider(client Provider, merged LlmOptions, perCall LlmOptions) (LlmInterface, error) {
+	if perCall.Provider == "" || perCall.Provider == client {
+		return nil, nil
+	}
+
+	options := merged
+	options.Provider = perCall.Provider
+	options.ApiKey = perCall.ApiKey
+	options.Model = perCall.Model
+	options.ProjectID = perCall.ProjectID
+	options.Region = perCall.Region
+	options.ProviderOptions = perCall.ProviderOptions
+	// The client's own spend tracker and rate limiter, if any, already apply
+	// to the call
+	options.SpendTracker = nil
+	options.RequestsPerMinute = 0
+
+	return NewLLM(options)
+}
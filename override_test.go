@@ -0,0 +1,115 @@
+package llm
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestGenerateWithProvider(t *testing.T) {
+	var captured map[string]any
+	server := captureServer(t, `{"choices":[{"message":{"role":"assistant","content":"escalated"}}]}`, &captured)
+	defer server.Close()
+	target, _ := url.Parse(server.URL)
+
+	engine, err := NewLLM(LlmOptions{Provider: ProviderMock, Model: "cheap-model", MockResponse: "cheap"})
+	if err != nil {
+		t.Fatalf("Failed to create mock LLM: %v", err)
+	}
+
+	response, err := GenerateWithProvider(engine, ProviderOpenAI, "system", "hard question", LlmOptions{
+		ApiKey:     "test-key",
+		Model:      "gpt-4.1",
+		HTTPClient: &http.Client{Transport: redirectTransport{target: target}},
+	})
+	if err != nil {
+		t.Fatalf("GenerateWithProvider failed: %v", err)
+	}
+	if response != "escalated" {
+		t.Errorf("expected the OpenAI response, got %q", response)
+	}
+	if captured["model"] != "gpt-4.1" {
+		t.Errorf("expected the override model, got %v", captured["model"])
+	}
+
+	// The client's own provider is used without an override
+	if response, err := engine.GenerateText("system", "easy question", LlmOptions{Provider: ProviderMock}); err != nil || response != "cheap" {
+		t.Errorf("expected the mock response, got %q (err=%v)", response, err)
+	}
+}
+
+func TestOverrideClientIsClosed(t *testing.T) {
+	override := &closeCountingLLM{}
+	provider := Provider("closing-test")
+	RegisterProvider(provider, func(options LlmOptions) (LlmInterface, error) {
+		return override, nil
+	})
+	t.Cleanup(func() {
+		providerMu.Lock()
+		delete(providerFactories, provider)
+		providerMu.Unlock()
+	})
+
+	engine, err := NewLLM(LlmOptions{Provider: ProviderMock, MockResponse: "cheap"})
+	if err != nil {
+		t.Fatalf("Failed to create mock LLM: %v", err)
+	}
+
+	for range 2 {
+		if _, err := GenerateWithProvider(engine, provider, "system", "hard question"); err != nil {
+			t.Fatalf("GenerateWithProvider failed: %v", err)
+		}
+	}
+	if override.closed != 2 {
+		t.Errorf("expected each override client to be closed, got %d closes", override.closed)
+	}
+}
+
+// toolTestLLM answers tool calls with a fixed text
+type toolTestLLM struct {
+	CustomTestLLM
+}
+
+func (c *toolTestLLM) GenerateWithTools(systemPrompt string, userPrompt string, tools []ToolDefinition, opts ...LlmOptions) (ToolResult, error) {
+	return ToolResult{Text: "escalated"}, nil
+}
+
+func TestOverrideProviderStreamAndTools(t *testing.T) {
+	toolProvider := Provider("tool-test")
+	RegisterProvider(toolProvider, func(options LlmOptions) (LlmInterface, error) {
+		return &toolTestLLM{}, nil
+	})
+	t.Cleanup(func() {
+		providerMu.Lock()
+		delete(providerFactories, toolProvider)
+		providerMu.Unlock()
+	})
+
+	for _, provider := range []Provider{ProviderOpenAI, ProviderOpenRouter} {
+		engine, err := NewLLM(LlmOptions{Provider: provider, ApiKey: "test-key", Model: "test-model"})
+		if err != nil {
+			t.Fatalf("%s: failed to create LLM: %v", provider, err)
+		}
+
+		var response string
+		err = GenerateStream(engine, "system", "hard question", func(chunk string) error {
+			response += chunk
+			return nil
+		}, LlmOptions{Provider: ProviderMock, MockResponse: "streamed"})
+		if err != nil || response != "streamed" {
+			t.Errorf("%s: expected the stream from the override provider, got %q (err=%v)", provider, response, err)
+		}
+	}
+
+	for _, provider := range []Provider{ProviderOpenAI, ProviderAnthropic, ProviderGemini} {
+		engine, err := NewLLM(LlmOptions{Provider: provider, ApiKey: "test-key", Model: "test-model"})
+		if err != nil {
+			t.Fatalf("%s: failed to create LLM: %v", provider, err)
+		}
+
+		result, err := GenerateWithTools(engine, "system", "hard question", []ToolDefinition{{Name: "lookup"}}, LlmOptions{Provider: toolProvider})
+		if err != nil || result.Text != "escalated" {
+			t.Errorf("%s: expected the tool result from the override provider, got %q (err=%v)", provider, result.Text, err)
+		}
+	}
+}
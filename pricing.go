@@ -0,0 +1,132 @@
+package llm
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// modelPrice holds the USD price per million tokens of a model
+type modelPrice struct {
+	InputPer1M  float64
+	OutputPer1M float64
+}
+
+// modelPricing is the pricing catalog, built from the prices listed
+// alongside the model constants in openrouter_models.go
+var modelPricing = map[string]modelPrice{
+	OPENROUTER_MODEL_GPT_OSS_20B:                    {InputPer1M: 0.04, OutputPer1M: 0.15},
+	OPENROUTER_MODEL_GPT_OSS_120B:                   {InputPer1M: 0.072, OutputPer1M: 0.28},
+	OPENROUTER_MODEL_O4_MINI:                        {InputPer1M: 1.10, OutputPer1M: 4.40},
+	OPENROUTER_MODEL_GPT_4_1_NANO:                   {InputPer1M: 0.10, OutputPer1M: 0.40},
+	OPENROUTER_MODEL_GPT_5_NANO:                     {InputPer1M: 0.05, OutputPer1M: 0.40},
+	OPENROUTER_MODEL_GPT_5_1:                        {InputPer1M: 1.25, OutputPer1M: 10.00},
+	OPENROUTER_MODEL_GPT_5_2:                        {InputPer1M: 1.75, OutputPer1M: 14.00},
+	OPENROUTER_MODEL_GPT_5_2_CHAT:                   {InputPer1M: 1.75, OutputPer1M: 14.00},
+	OPENROUTER_MODEL_GPT_5_2_PRO:                    {InputPer1M: 21.00, OutputPer1M: 168.00},
+	OPENROUTER_MODEL_GPT_5_2_CODEX:                  {InputPer1M: 1.75, OutputPer1M: 14.00},
+	OPENROUTER_MODEL_CLAUDE_SONNET_4:                {InputPer1M: 3.00, OutputPer1M: 15.00},
+	OPENROUTER_MODEL_CLAUDE_SONNET_4_5:              {InputPer1M: 3.00, OutputPer1M: 15.00},
+	OPENROUTER_MODEL_CLAUDE_HAIKU_4_5:               {InputPer1M: 0.80, OutputPer1M: 4.00},
+	OPENROUTER_MODEL_CLAUDE_OPUS_4_5:                {InputPer1M: 5.00, OutputPer1M: 25.00},
+	OPENROUTER_MODEL_CLAUDE_OPUS_4_6:                {InputPer1M: 5.00, OutputPer1M: 25.00},
+	OPENROUTER_MODEL_GEMMA_3_12B_IT:                 {InputPer1M: 0.048, OutputPer1M: 0.193},
+	OPENROUTER_MODEL_GEMMA_3_27B_IT:                 {InputPer1M: 0.067, OutputPer1M: 0.267},
+	OPENROUTER_MODEL_GEMINI_2_5_FLASH_LITE:          {InputPer1M: 0.10, OutputPer1M: 0.40},
+	OPENROUTER_MODEL_GEMINI_2_5_FLASH:               {InputPer1M: 0.30, OutputPer1M: 2.50},
+	OPENROUTER_MODEL_GEMINI_2_5_PRO:                 {InputPer1M: 1.25, OutputPer1M: 10},
+	OPENROUTER_MODEL_GEMINI_3_FLASH_PREVIEW:         {InputPer1M: 0.50, OutputPer1M: 3.00},
+	OPENROUTER_MODEL_GEMINI_3_PRO_PREVIEW:           {InputPer1M: 2, OutputPer1M: 12},
+	OPENROUTER_MODEL_MISTRAL_NEMO:                   {InputPer1M: 0.02, OutputPer1M: 0.04},
+	OPENROUTER_MODEL_MISTRAL_MEDIUM_3_1:             {InputPer1M: 0.40, OutputPer1M: 2},
+	OPENROUTER_MODEL_DEVSTRAL_2512:                  {InputPer1M: 0.05, OutputPer1M: 0.22},
+	OPENROUTER_MODEL_QWEN_3_235B_A22B_INSTRUCT_2507: {InputPer1M: 0.078, OutputPer1M: 0.312},
+	OPENROUTER_MODEL_QWEN_3_30B_A3B:                 {InputPer1M: 0.02, OutputPer1M: 0.08},
+	OPENROUTER_MODEL_QWEN_3_MAX_THINKING:            {InputPer1M: 1.20, OutputPer1M: 6.00},
+	OPENROUTER_MODEL_QWEN_3_CODER_NEXT:              {InputPer1M: 0.07, OutputPer1M: 0.30},
+	OPENROUTER_MODEL_DEEPSEEK_V3_1:                  {InputPer1M: 0.20, OutputPer1M: 0.80},
+	OPENROUTER_MODEL_GROK_3:                         {InputPer1M: 3.00, OutputPer1M: 15.00},
+	OPENROUTER_MODEL_GROK_3_MINI:                    {InputPer1M: 0.30, OutputPer1M: 0.50},
+	OPENROUTER_MODEL_GROK_4:                         {InputPer1M: 3.00, OutputPer1M: 15.00},
+	OPENROUTER_MODEL_KIMI_K2_5:                      {InputPer1M: 0.45, OutputPer1M: 2.25},
+	OPENROUTER_MODEL_MINIMAX_M2_1:                   {InputPer1M: 0.27, OutputPer1M: 0.95},
+	OPENROUTER_MODEL_SEED_1_6:                       {InputPer1M: 0.25, OutputPer1M: 2.00},
+	OPENROUTER_MODEL_SEED_1_6_FLASH:                 {InputPer1M: 0.075, OutputPer1M: 0.30},
+	OPENROUTER_MODEL_MIMO_V2_FLASH:                  {InputPer1M: 0.09, OutputPer1M: 0.29},
+	OPENROUTER_MODEL_GLM_4_7:                        {InputPer1M: 0.40, OutputPer1M: 1.50},
+	OPENROUTER_MODEL_GLM_4_7_FLASH:                  {InputPer1M: 0.06, OutputPer1M: 0.40},
+	OPENROUTER_MODEL_STEP_3_5_FLASH:                 {InputPer1M: 0.10, OutputPer1M: 0.30},
+	OPENROUTER_MODEL_GEMINI_2_5_FLASH_IMAGE:         {InputPer1M: 0.30, OutputPer1M: 2.50},
+	OPENROUTER_MODEL_GPT_5_IMAGE_MINI:               {InputPer1M: 2.50, OutputPer1M: 2},
+	OPENROUTER_MODEL_GPT_5_IMAGE:                    {InputPer1M: 10.00, OutputPer1M: 10},
+	OPENROUTER_MODEL_QWEN_3_EMBEDDING_0_6B:          {InputPer1M: 0.01, OutputPer1M: 0.00},
+	OPENROUTER_MODEL_MISTRAL_EMBED_2312:             {InputPer1M: 0.10, OutputPer1M: 0.00},
+	OPENROUTER_MODEL_GEMINI_EMBED_001:               {InputPer1M: 0.15, OutputPer1M: 0.00},
+	OPENROUTER_MODEL_TEXT_EMBEDDING_ADA_002:         {InputPer1M: 0.10, OutputPer1M: 0.00},
+	OPENROUTER_MODEL_CODESTRAL_EMBED_2505:           {InputPer1M: 0.15, OutputPer1M: 0.00},
+	OPENROUTER_MODEL_TEXT_EMBEDDING_3_LARGE:         {InputPer1M: 0.13, OutputPer1M: 0.00},
+	OPENROUTER_MODEL_TEXT_EMBEDDING_3_SMALL:         {InputPer1M: 0.02, OutputPer1M: 0.00},
+}
+
+// lookupModelPrice finds the price of a model. Models are matched exactly
+// first, then by the name without the vendor prefix, so "gpt-5-nano"
+// resolves to the "openai/gpt-5-nano" entry.
+func lookupModelPrice(model string) (modelPrice, bool) {
+	model = strings.TrimSpace(model)
+	if price, ok := modelPricing[model]; ok {
+		return price, true
+	}
+
+	for id, price := range modelPricing {
+		if _, name, found := strings.Cut(id, "/"); found && name == model {
+			return price, true
+		}
+	}
+
+	return modelPrice{}, false
+}
+
+// CostEstimate returns the USD cost of a call to the model with the given
+// token counts, using the pricing catalog. Models are matched with or
+// without their vendor prefix. Unknown models return an error.
+func CostEstimate(model string, promptTokens int, completionTokens int) (float64, error) {
+	price, ok := lookupModelPrice(model)
+	if !ok {
+		return 0, fmt.Errorf("no pricing for model %s", model)
+	}
+	return price.cost(promptTokens, completionTokens), nil
+}
+
+// cost returns the USD cost of the token counts
+func (p modelPrice) cost(promptTokens int, completionTokens int) float64 {
+	return (float64(promptTokens)*p.InputPer1M + float64(completionTokens)*p.OutputPer1M) / 1_000_000
+}
+
+// checkCostBudget estimates the worst-case cost of a call (the prompt at
+// the input price plus MaxTokens at the output price) and returns
+// ErrCostExceeded when it is above options.MaxCostUSD. Without MaxTokens
+// the output is unbounded, so the budget cannot be enforced and an error
+// is returned. It does nothing when no budget is set.
+func checkCostBudget(options LlmOptions, systemPrompt string, userMessage string) error {
+	if options.MaxCostUSD <= 0 {
+		return nil
+	}
+
+	if options.MaxTokens <= 0 {
+		return errors.New("cannot enforce cost budget: MaxTokens must be set to bound the output")
+	}
+
+	price, ok := lookupModelPrice(options.Model)
+	if !ok {
+		return fmt.Errorf("cannot enforce cost budget: no pricing for model %s", options.Model)
+	}
+
+	promptTokens := CountTokensForModel(systemPrompt, options.Model) + CountTokensForModel(userMessage, options.Model)
+	estimated := price.cost(promptTokens, options.MaxTokens)
+
+	if estimated > options.MaxCostUSD {
+		return fmt.Errorf("%w: estimated $%.6f exceeds budget of $%.6f", ErrCostExceeded, estimated, options.MaxCostUSD)
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package llm
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestLookupModelPrice(t *testing.T) {
+	price, ok := lookupModelPrice(OPENROUTER_MODEL_GPT_5_NANO)
+	if !ok || price.InputPer1M != 0.05 || price.OutputPer1M != 0.40 {
+		t.Errorf("unexpected price for %s: %+v (found=%v)", OPENROUTER_MODEL_GPT_5_NANO, price, ok)
+	}
+
+	if _, ok := lookupModelPrice("gpt-5-nano"); !ok {
+		t.Error("expected model without vendor prefix to resolve")
+	}
+
+	if _, ok := lookupModelPrice("unknown-model"); ok {
+		t.Error("expected unknown model not to resolve")
+	}
+}
+
+func TestCostEstimate(t *testing.T) {
+	// Claude Sonnet 4.5 is $3 in / $15 out per 1M tokens
+	cost, err := CostEstimate(OPENROUTER_MODEL_CLAUDE_SONNET_4_5, 1_000_000, 100_000)
+	if err != nil {
+		t.Fatalf("CostEstimate failed: %v", err)
+	}
+	if math.Abs(cost-4.5) > 1e-9 {
+		t.Errorf("expected $4.50, got $%f", cost)
+	}
+
+	if cost, err := CostEstimate("gpt-5-nano", 0, 0); err != nil || cost != 0 {
+		t.Errorf("expected zero cost for zero tokens, got %f (err=%v)", cost, err)
+	}
+
+	if _, err := CostEstimate("unknown-model", 10, 10); err == nil {
+		t.Error("expected an error for an unknown model")
+	}
+}
+
+func TestMaxCostUSD(t *testing.T) {
+	mock, _ := newMockImplementation(LlmOptions{MockResponse: "ok"})
+
+	// 100 output tokens of gpt-5-nano cost well under a cent
+	response, err := mock.GenerateText("system", "hello", LlmOptions{
+		Model:      OPENROUTER_MODEL_GPT_5_NANO,
+		MaxTokens:  100,
+		MaxCostUSD: 0.01,
+	})
+	if err != nil {
+		t.Fatalf("cheap call should pass the budget: %v", err)
+	}
+	if response != "ok" {
+		t.Errorf("unexpected response: %s", response)
+	}
+
+	// 100k output tokens of gpt-5.2-pro cost $16.80
+	_, err = mock.GenerateText("system", "hello", LlmOptions{
+		Model:      OPENROUTER_MODEL_GPT_5_2_PRO,
+		MaxTokens:  100000,
+		MaxCostUSD: 1,
+	})
+	if !errors.Is(err, ErrCostExceeded) {
+		t.Errorf("expected ErrCostExceeded, got %v", err)
+	}
+
+	// The budget cannot be enforced without pricing
+	_, err = mock.GenerateText("system", "hello", LlmOptions{
+		Model:      "unknown-model",
+		MaxTokens:  100,
+		MaxCostUSD: 1,
+	})
+	if err == nil {
+		t.Error("expected error for model without pricing")
+	}
+
+	// Nor without MaxTokens, which leaves the output unbounded
+	for _, maxTokens := range []int{0, -1} {
+		_, err = mock.GenerateText("system", "hello", LlmOptions{
+			Model:      OPENROUTER_MODEL_GPT_5_NANO,
+			MaxTokens:  maxTokens,
+			MaxCostUSD: 1,
+		})
+		if err == nil || errors.Is(err, ErrCostExceeded) {
+			t.Errorf("MaxTokens %d: expected an error for an unbounded output, got %v", maxTokens, err)
+		}
+	}
+}
+
+func TestUsageEstimatedCost(t *testing.T) {
+	price, _ := lookupModelPrice("gpt-5-nano")
+
+	usage := estimateUsage("system\nhello", "hi there", "gpt-5-nano")
+	if expected := price.cost(usage.PromptTokens, usage.CompletionTokens); usage.EstimatedCost != expected {
+		t.Errorf("expected the estimated usage to cost $%f, got $%f", expected, usage.EstimatedCost)
+	}
+	if usage := estimateUsage("system\nhello", "hi there", "unknown-model"); usage.EstimatedCost != 0 {
+		t.Errorf("expected no cost for an unpriced model, got $%f", usage.EstimatedCost)
+	}
+
+	streamed := streamUsage(&openai.Usage{PromptTokens: 12, CompletionTokens: 3, TotalTokens: 15}, "", "", "gpt-5-nano")
+	if expected := price.cost(12, 3); streamed.EstimatedCost != expected {
+		t.Errorf("expected the streamed usage to cost $%f, got $%f", expected, streamed.EstimatedCost)
+	}
+
+	raw := RawResponse{Provider: ProviderOpenAI, Body: []byte(`{"model":"gpt-5-nano","usage":{"prompt_tokens":100,"completion_tokens":20,"total_tokens":120}}`)}
+	parsed, ok := raw.Usage()
+	if expected := price.cost(100, 20); !ok || parsed.EstimatedCost != expected {
+		t.Errorf("expected the raw usage to cost $%f, got %+v", expected, parsed)
+	}
+
+	raw = RawResponse{Provider: ProviderAnthropic, Body: []byte(`{"model":"anthropic/claude-sonnet-4.5","usage":{"input_tokens":10,"cache_read_input_tokens":90,"output_tokens":20}}`)}
+	parsed, _ = raw.Usage()
+	anthropicPrice, _ := lookupModelPrice(OPENROUTER_MODEL_CLAUDE_SONNET_4_5)
+	if parsed.EstimatedCost == 0 || parsed.EstimatedCost != anthropicPrice.cost(100, 20) {
+		t.Errorf("expected the cached input tokens to be priced, got $%f", parsed.EstimatedCost)
+	}
+}
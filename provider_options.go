@@ -0,0 +1,216 @@
+package llm
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/spf13/cast"
+)
+
+// ErrInvalidProviderOption is matched by errors.Is for each problem
+// reported by ValidateProviderOptions
+var ErrInvalidProviderOption = errors.New("invalid provider option")
+
+// providerOptionString returns the provider option with the key as a
+// string, converting []byte, numbers and booleans. It returns false when
+// the option is not set or cannot be converted.
+func providerOptionString(providerOptions map[string]any, key string) (string, bool) {
+	raw, ok := providerOptions[key]
+	if !ok || raw == nil {
+		return "", false
+	}
+	v, err := cast.ToStringE(raw)
+	return v, err == nil
+}
+
+// providerOptionInt returns the provider option with the key as an int,
+// converting other numeric types and numeric strings. It returns false
+// when the option is not set or cannot be converted.
+func providerOptionInt(providerOptions map[string]any, key string) (int, bool) {
+	raw, ok := providerOptions[key]
+	if !ok || raw == nil {
+		return 0, false
+	}
+	v, err := cast.ToIntE(raw)
+	return v, err == nil
+}
+
+// providerOptionBool returns the provider option with the key as a bool,
+// converting strings such as "true" and numbers. It returns false when
+// the option is not set or cannot be converted.
+func providerOptionBool(providerOptions map[string]any, key string) (bool, bool) {
+	raw, ok := providerOptions[key]
+	if !ok || raw == nil {
+		return false, false
+	}
+	v, err := cast.ToBoolE(raw)
+	return v, err == nil
+}
+
+// providerOptionKind is the type a provider option is converted to
+type providerOptionKind string
+
+const (
+	optionString      providerOptionKind = "a string"
+	optionInt         providerOptionKind = "an integer"
+	optionBool        providerOptionKind = "a boolean"
+	optionStringSlice providerOptionKind = "a []string"
+	optionStringMap   providerOptionKind = "a map[string]string"
+	optionMap         providerOptionKind = "a map[string]any"
+)
+
+// convertible reports whether a provider option value can be converted
+// to the kind
+func (k providerOptionKind) convertible(raw any) bool {
+	var err error
+	switch k {
+	case optionString:
+		_, err = cast.ToStringE(raw)
+	case optionInt:
+		_, err = cast.ToIntE(raw)
+	case optionBool:
+		_, err = cast.ToBoolE(raw)
+	case optionStringSlice:
+		_, err = cast.ToStringSliceE(raw)
+	case optionStringMap:
+		_, err = cast.ToStringMapStringE(raw)
+	case optionMap:
+		_, err = cast.ToStringMapE(raw)
+	}
+	return err == nil
+}
+
+// commonProviderOptions are accepted by every built-in provider
+var commonProviderOptions = map[string]providerOptionKind{
+	"timeout_ms":           optionInt,
+	"record_last_response": optionBool,
+	"json_repair_attempts": optionInt,
+}
+
+// secureHTTPProviderOptions are accepted by the providers using
+// secureHTTPClient
+var secureHTTPProviderOptions = map[string]providerOptionKind{
+	"root_ca_file": optionString,
+	"root_ca_pem":  optionString,
+	"spki_hash":    optionString,
+}
+
+// apiKeyProviderOptions are accepted by the providers using
+// apiKeyHTTPClient or apiKeyHTTPClientWith
+var apiKeyProviderOptions = map[string]providerOptionKind{
+	"api_keys":            optionStringSlice,
+	"api_key_cooldown_ms": optionInt,
+}
+
+// providerOptionKinds maps each built-in provider to the provider options
+// it reads, in addition to commonProviderOptions
+var providerOptionKinds = map[Provider]map[string]providerOptionKind{
+	ProviderOpenAI: joinProviderOptionKinds(secureHTTPProviderOptions, apiKeyProviderOptions, map[string]providerOptionKind{
+		"base_url":         optionString,
+		"organization":     optionString,
+		"reasoning_effort": optionString,
+		"model":            optionString,
+		"size":             optionString,
+		"image_size":       optionString,
+		"quality":          optionString,
+		"style":            optionString,
+		"background":       optionString,
+	}),
+	ProviderGemini: {
+		"thinking_budget": optionInt,
+	},
+	ProviderVertex: {
+		"thinking_budget":  optionInt,
+		"embedding_model":  optionString,
+		"credentials_json": optionString,
+		"credentials_file": optionString,
+	},
+	ProviderMock: {},
+	ProviderAnthropic: joinProviderOptionKinds(secureHTTPProviderOptions, apiKeyProviderOptions, map[string]providerOptionKind{
+		"anthropic_root_ca_file": optionString,
+		"anthropic_root_ca_pem":  optionString,
+		"anthropic_spki_hash":    optionString,
+		"enable_prompt_cache":    optionBool,
+		"prompt_cache_messages":  optionBool,
+	}),
+	ProviderOpenRouter: joinProviderOptionKinds(secureHTTPProviderOptions, apiKeyProviderOptions, map[string]providerOptionKind{
+		"base_url":             optionString,
+		"reasoning_effort":     optionString,
+		"referer":              optionString,
+		"title":                optionString,
+		"aspect_ratio":         optionString,
+		"fallback_models":      optionStringSlice,
+		"provider_preferences": optionMap,
+	}),
+	ProviderCustom: joinProviderOptionKinds(secureHTTPProviderOptions, apiKeyProviderOptions, map[string]providerOptionKind{
+		"url":              optionString,
+		"endpoint_url":     optionString,
+		"base_url":         optionString,
+		"response_path":    optionString,
+		"auth_header":      optionString,
+		"auth_scheme":      optionString,
+		"auth_query_param": optionString,
+		"headers":          optionStringMap,
+	}),
+	ProviderCohere: joinProviderOptionKinds(secureHTTPProviderOptions, apiKeyProviderOptions, map[string]providerOptionKind{
+		"base_url": optionString,
+	}),
+	ProviderMistral: joinProviderOptionKinds(secureHTTPProviderOptions, apiKeyProviderOptions, map[string]providerOptionKind{
+		"base_url":        optionString,
+		"embedding_model": optionString,
+	}),
+	ProviderGroq: joinProviderOptionKinds(secureHTTPProviderOptions, apiKeyProviderOptions, map[string]providerOptionKind{
+		"base_url": optionString,
+	}),
+	ProviderDeepSeek: joinProviderOptionKinds(secureHTTPProviderOptions, apiKeyProviderOptions, map[string]providerOptionKind{
+		"base_url": optionString,
+	}),
+	ProviderHuggingFace: joinProviderOptionKinds(secureHTTPProviderOptions, apiKeyProviderOptions, map[string]providerOptionKind{
+		"base_url":        optionString,
+		"embedding_model": optionString,
+	}),
+}
+
+// joinProviderOptionKinds merges sets of provider options into one
+func joinProviderOptionKinds(sets ...map[string]providerOptionKind) map[string]providerOptionKind {
+	joined := map[string]providerOptionKind{}
+	for _, set := range sets {
+		maps.Copy(joined, set)
+	}
+	return joined
+}
+
+// ValidateProviderOptions reports the provider options the built-in
+// provider does not read, e.g. a misspelled key, and those with a value
+// that cannot be converted to the expected type, e.g. a list for
+// "timeout_ms". Each problem is an error matching
+// ErrInvalidProviderOption, joined in key order. Options of providers
+// registered with RegisterProvider are not checked.
+//
+// NewLLM does not call it, as unknown options are ignored by the providers.
+func ValidateProviderOptions(provider Provider, providerOptions map[string]any) error {
+	kinds, ok := providerOptionKinds[provider]
+	if !ok {
+		return nil
+	}
+
+	errs := []error{}
+	for _, key := range slices.Sorted(maps.Keys(providerOptions)) {
+		kind, known := kinds[key]
+		if !known {
+			kind, known = commonProviderOptions[key]
+		}
+		if !known {
+			errs = append(errs, fmt.Errorf("%w: %s does not read %q", ErrInvalidProviderOption, provider, key))
+			continue
+		}
+
+		if raw := providerOptions[key]; raw != nil && !kind.convertible(raw) {
+			errs = append(errs, fmt.Errorf("%w: %q must be %s, got %T", ErrInvalidProviderOption, key, kind, raw))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,103 @@
+package llm
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestProviderOptionAccessors(t *testing.T) {
+	providerOptions := map[string]any{
+		"size":      1024,
+		"pem":       []byte("pem"),
+		"timeout":   "1500",
+		"attempts":  3.0,
+		"record":    "true",
+		"cache":     true,
+		"invalid":   []int{1},
+		"nil_value": nil,
+	}
+
+	if v, ok := providerOptionString(providerOptions, "size"); !ok || v != "1024" {
+		t.Errorf("expected size 1024, got %q, %v", v, ok)
+	}
+	if v, ok := providerOptionString(providerOptions, "pem"); !ok || v != "pem" {
+		t.Errorf("expected pem from []byte, got %q, %v", v, ok)
+	}
+	if v, ok := providerOptionInt(providerOptions, "timeout"); !ok || v != 1500 {
+		t.Errorf("expected timeout 1500, got %d, %v", v, ok)
+	}
+	if v, ok := providerOptionInt(providerOptions, "attempts"); !ok || v != 3 {
+		t.Errorf("expected attempts 3, got %d, %v", v, ok)
+	}
+	if v, ok := providerOptionBool(providerOptions, "record"); !ok || !v {
+		t.Errorf("expected record true, got %v, %v", v, ok)
+	}
+	if v, ok := providerOptionBool(providerOptions, "cache"); !ok || !v {
+		t.Errorf("expected cache true, got %v, %v", v, ok)
+	}
+
+	for _, key := range []string{"invalid", "nil_value", "missing"} {
+		if _, ok := providerOptionString(providerOptions, key); ok {
+			t.Errorf("expected %s not to be a string", key)
+		}
+		if _, ok := providerOptionInt(providerOptions, key); ok {
+			t.Errorf("expected %s not to be an int", key)
+		}
+	}
+	if _, ok := providerOptionString(nil, "size"); ok {
+		t.Error("expected no option in a nil map")
+	}
+}
+
+func TestValidateProviderOptions(t *testing.T) {
+	valid := map[string]any{
+		"base_url":             "https://proxy.example.com/v1",
+		"api_keys":             []string{"a", "b"},
+		"api_key_cooldown_ms":  "30000",
+		"timeout_ms":           5000,
+		"record_last_response": true,
+		"size":                 1024,
+		"spki_hash":            nil,
+	}
+	if err := ValidateProviderOptions(ProviderOpenAI, valid); err != nil {
+		t.Errorf("expected valid options, got %v", err)
+	}
+
+	err := ValidateProviderOptions(ProviderOpenAI, map[string]any{
+		"base_ulr":            "https://proxy.example.com/v1",
+		"timeout_ms":          []string{"5s"},
+		"api_key_cooldown_ms": "soon",
+	})
+	if !errors.Is(err, ErrInvalidProviderOption) {
+		t.Fatalf("expected ErrInvalidProviderOption, got %v", err)
+	}
+	lines := strings.Split(err.Error(), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 problems, got %v", lines)
+	}
+	for i, expected := range []string{`"api_key_cooldown_ms" must be an integer, got string`, `openai does not read "base_ulr"`, `"timeout_ms" must be an integer, got []string`} {
+		if !strings.Contains(lines[i], expected) {
+			t.Errorf("expected %q in %q", expected, lines[i])
+		}
+	}
+
+	// Options of another provider are reported
+	if err := ValidateProviderOptions(ProviderGroq, map[string]any{"headers": map[string]string{"X-Tenant": "a"}}); err == nil {
+		t.Error("expected headers to be unknown to Groq")
+	}
+	if err := ValidateProviderOptions(ProviderCustom, map[string]any{"headers": map[string]string{"X-Tenant": "a"}}); err != nil {
+		t.Errorf("expected headers to be valid for Custom, got %v", err)
+	}
+
+	// Registered providers are not checked
+	if err := ValidateProviderOptions("my-provider", map[string]any{"anything": 1}); err != nil {
+		t.Errorf("expected no validation of registered providers, got %v", err)
+	}
+
+	for provider := range providerCapabilities {
+		if _, ok := providerOptionKinds[provider]; !ok {
+			t.Errorf("no provider options listed for %s", provider)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package llm
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RateLimitInfo holds the rate limit state reported by a provider in its
+// response headers. Counts that were not reported are -1, reset times that
+// were not reported are zero.
+type RateLimitInfo struct {
+	// LimitRequests is the maximum number of requests in the current window
+	LimitRequests int
+
+	// RemainingRequests is the number of requests left in the current window
+	RemainingRequests int
+
+	// LimitTokens is the maximum number of tokens in the current window
+	LimitTokens int
+
+	// RemainingTokens is the number of tokens left in the current window
+	RemainingTokens int
+
+	// ResetRequests is when the request limit resets
+	ResetRequests time.Time
+
+	// ResetTokens is when the token limit resets
+	ResetTokens time.Time
+}
+
+// RateLimit parses the rate limit headers of the response. It supports the
+// OpenAI style x-ratelimit-* headers (also used by OpenRouter and most
+// OpenAI-compatible servers) and Anthropic's anthropic-ratelimit-* headers.
+// It returns false if the response has no rate limit headers.
+func (r RawResponse) RateLimit() (RateLimitInfo, bool) {
+	return parseRateLimitHeaders(r.Header, time.Now())
+}
+
+// parseRateLimitHeaders parses rate limit headers, resolving relative
+// reset durations against now
+func parseRateLimitHeaders(header http.Header, now time.Time) (RateLimitInfo, bool) {
+	info := RateLimitInfo{
+		LimitRequests:     -1,
+		RemainingRequests: -1,
+		LimitTokens:       -1,
+		RemainingTokens:   -1,
+	}
+	if header == nil {
+		return info, false
+	}
+
+	found := false
+	count := func(target *int, keys ...string) {
+		for _, key := range keys {
+			if v, err := strconv.Atoi(strings.TrimSpace(header.Get(key))); err == nil {
+				*target = v
+				found = true
+				return
+			}
+		}
+	}
+	reset := func(target *time.Time, keys ...string) {
+		for _, key := range keys {
+			if t, ok := parseRateLimitReset(header.Get(key), now); ok {
+				*target = t
+				found = true
+				return
+			}
+		}
+	}
+
+	count(&info.LimitRequests, "x-ratelimit-limit-requests", "anthropic-ratelimit-requests-limit")
+	count(&info.RemainingRequests, "x-ratelimit-remaining-requests", "anthropic-ratelimit-requests-remaining")
+	count(&info.LimitTokens, "x-ratelimit-limit-tokens", "anthropic-ratelimit-tokens-limit")
+	count(&info.RemainingTokens, "x-ratelimit-remaining-tokens", "anthropic-ratelimit-tokens-remaining")
+	reset(&info.ResetRequests, "x-ratelimit-reset-requests", "anthropic-ratelimit-requests-reset")
+	reset(&info.ResetTokens, "x-ratelimit-reset-tokens", "anthropic-ratelimit-tokens-reset")
+
+	return info, found
+}
+
+// parseRateLimitReset parses a reset value, which is either an RFC 3339
+// timestamp (Anthropic) or a duration such as "1s" or "6m0s" (OpenAI)
+func parseRateLimitReset(value string, now time.Time) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, false
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, true
+	}
+
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(d), true
+	}
+
+	return time.Time{}, false
+}
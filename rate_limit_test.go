@@ -0,0 +1,95 @@
+package llm
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseRateLimitHeadersOpenAI(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	header := http.Header{}
+	header.Set("x-ratelimit-limit-requests", "500")
+	header.Set("x-ratelimit-remaining-requests", "499")
+	header.Set("x-ratelimit-limit-tokens", "30000")
+	header.Set("x-ratelimit-remaining-tokens", "29950")
+	header.Set("x-ratelimit-reset-requests", "120ms")
+	header.Set("x-ratelimit-reset-tokens", "6m0s")
+
+	info, ok := parseRateLimitHeaders(header, now)
+	if !ok {
+		t.Fatal("expected rate limit headers to be found")
+	}
+	if info.LimitRequests != 500 || info.RemainingRequests != 499 {
+		t.Errorf("unexpected request limits: %+v", info)
+	}
+	if info.LimitTokens != 30000 || info.RemainingTokens != 29950 {
+		t.Errorf("unexpected token limits: %+v", info)
+	}
+	if !info.ResetRequests.Equal(now.Add(120 * time.Millisecond)) {
+		t.Errorf("unexpected requests reset: %v", info.ResetRequests)
+	}
+	if !info.ResetTokens.Equal(now.Add(6 * time.Minute)) {
+		t.Errorf("unexpected tokens reset: %v", info.ResetTokens)
+	}
+}
+
+func TestParseRateLimitHeadersAnthropic(t *testing.T) {
+	header := http.Header{}
+	header.Set("anthropic-ratelimit-requests-remaining", "49")
+	header.Set("anthropic-ratelimit-tokens-remaining", "39000")
+	header.Set("anthropic-ratelimit-tokens-reset", "2025-01-01T12:01:00Z")
+
+	info, ok := parseRateLimitHeaders(header, time.Now())
+	if !ok {
+		t.Fatal("expected rate limit headers to be found")
+	}
+	if info.RemainingRequests != 49 || info.RemainingTokens != 39000 {
+		t.Errorf("unexpected remaining counts: %+v", info)
+	}
+	if info.LimitRequests != -1 || info.LimitTokens != -1 {
+		t.Errorf("expected unreported limits to be -1: %+v", info)
+	}
+	if !info.ResetTokens.Equal(time.Date(2025, 1, 1, 12, 1, 0, 0, time.UTC)) {
+		t.Errorf("unexpected tokens reset: %v", info.ResetTokens)
+	}
+	if !info.ResetRequests.IsZero() {
+		t.Errorf("expected unreported reset to be zero: %v", info.ResetRequests)
+	}
+}
+
+func TestRawResponseRateLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("x-ratelimit-remaining-requests", "7")
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"hi"}}]}`))
+	}))
+	defer server.Close()
+
+	llm, err := NewLLM(LlmOptions{
+		Provider: ProviderCustom,
+		ProviderOptions: map[string]any{
+			"url":                  server.URL,
+			"record_last_response": true,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create custom LLM: %v", err)
+	}
+	if _, err := llm.GenerateText("system", "hello"); err != nil {
+		t.Fatalf("GenerateText failed: %v", err)
+	}
+
+	raw, ok := llm.(RawResponseRecorderInterface).LastRawResponse()
+	if !ok {
+		t.Fatal("expected last raw response to be recorded")
+	}
+	info, ok := raw.RateLimit()
+	if !ok || info.RemainingRequests != 7 {
+		t.Errorf("unexpected rate limit info: %+v, %v", info, ok)
+	}
+
+	if _, ok := (RawResponse{Header: http.Header{}}).RateLimit(); ok {
+		t.Error("expected no rate limit info without headers")
+	}
+}
@@ -0,0 +1,211 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// withRateLimiter wraps llm to limit its calls to
+// options.RequestsPerMinute, if set. The limiter is shared by all the calls
+// made through the returned client.
+func withRateLimiter(llm LlmInterface, options LlmOptions) LlmInterface {
+	if options.RequestsPerMinute <= 0 {
+		return llm
+	}
+
+	limiter := rate.NewLimiter(rate.Every(time.Minute/time.Duration(options.RequestsPerMinute)), 1)
+	return &rateLimitedLLM{llm: llm, ctx: options.Context, limiter: limiter}
+}
+
+// rateLimitedLLM spaces the calls of the wrapped client evenly so that no
+// more than the configured number of requests is sent per minute. A call
+// blocks until the limiter allows it, or fails when its context is done.
+// The calls that send nothing (DebugMessages, EffectiveOptions and
+// LastRawResponse) are not limited.
+type rateLimitedLLM struct {
+	llm     LlmInterface
+	ctx     context.Context
+	limiter *rate.Limiter
+}
+
+// wait blocks until the limiter allows a call, or the context of the call
+// (the per-call Context, else the client's) is done
+func (r *rateLimitedLLM) wait(opts []LlmOptions) error {
+	ctx := r.ctx
+	if len(opts) > 0 && opts[0].Context != nil {
+		ctx = opts[0].Context
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if err := r.limiter.Wait(ctx); err != nil {
+		return fmt.Errorf("rate limiter: %w", err)
+	}
+	return nil
+}
+
+// GenerateText implements LlmInterface
+func (r *rateLimitedLLM) GenerateText(systemPrompt string, userPrompt string, opts ...LlmOptions) (string, error) {
+	if err := r.wait(opts); err != nil {
+		return "", err
+	}
+	return r.llm.GenerateText(systemPrompt, userPrompt, opts...)
+}
+
+// GenerateJSON implements LlmInterface
+func (r *rateLimitedLLM) GenerateJSON(systemPrompt string, userPrompt string, opts ...LlmOptions) (string, error) {
+	if err := r.wait(opts); err != nil {
+		return "", err
+	}
+	return r.llm.GenerateJSON(systemPrompt, userPrompt, opts...)
+}
+
+// GenerateXML implements LlmInterface
+func (r *rateLimitedLLM) GenerateXML(systemPrompt string, userPrompt string, opts ...LlmOptions) (string, error) {
+	if err := r.wait(opts); err != nil {
+		return "", err
+	}
+	return r.llm.GenerateXML(systemPrompt, userPrompt, opts...)
+}
+
+// GenerateYAML implements LlmInterface
+func (r *rateLimitedLLM) GenerateYAML(systemPrompt string, userPrompt string, opts ...LlmOptions) (string, error) {
+	if err := r.wait(opts); err != nil {
+		return "", err
+	}
+	return r.llm.GenerateYAML(systemPrompt, userPrompt, opts...)
+}
+
+// Generate implements LlmInterface
+func (r *rateLimitedLLM) Generate(systemPrompt string, userMessage string, opts ...LlmOptions) (string, error) {
+	if err := r.wait(opts); err != nil {
+		return "", err
+	}
+	return r.llm.Generate(systemPrompt, userMessage, opts...)
+}
+
+// GenerateChat implements ChatInterface
+func (r *rateLimitedLLM) GenerateChat(messages []Message, opts ...LlmOptions) (string, error) {
+	if err := r.wait(opts); err != nil {
+		return "", err
+	}
+	return GenerateChat(r.llm, messages, opts...)
+}
+
+// GenerateImage implements LlmInterface
+func (r *rateLimitedLLM) GenerateImage(prompt string, opts ...LlmOptions) ([]byte, error) {
+	if err := r.wait(opts); err != nil {
+		return nil, err
+	}
+	return r.llm.GenerateImage(prompt, opts...)
+}
+
+// GenerateEmbedding implements LlmInterface
+func (r *rateLimitedLLM) GenerateEmbedding(text string) ([]float32, error) {
+	if err := r.wait(nil); err != nil {
+		return nil, err
+	}
+	return r.llm.GenerateEmbedding(text)
+}
+
+// GenerateStream implements StreamInterface
+func (r *rateLimitedLLM) GenerateStream(systemPrompt string, userMessage string, onChunk func(chunk string) error, opts ...LlmOptions) error {
+	if err := r.wait(opts); err != nil {
+		return err
+	}
+	return GenerateStream(r.llm, systemPrompt, userMessage, onChunk, opts...)
+}
+
+// GenerateStreamWithUsage implements StreamUsageInterface
+func (r *rateLimitedLLM) GenerateStreamWithUsage(systemPrompt string, userMessage string, onChunk func(chunk string) error, onDone func(usage Usage), opts ...LlmOptions) error {
+	if err := r.wait(opts); err != nil {
+		return err
+	}
+	return GenerateStreamWithUsage(r.llm, systemPrompt, userMessage, onChunk, onDone, opts...)
+}
+
+// GenerateWithImages implements VisionInterface
+func (r *rateLimitedLLM) GenerateWithImages(systemPrompt string, userPrompt string, images [][]byte, opts ...LlmOptions) (string, error) {
+	if err := r.wait(opts); err != nil {
+		return "", err
+	}
+	return GenerateWithImages(r.llm, systemPrompt, userPrompt, images, opts...)
+}
+
+// GenerateWithTools implements ToolInterface
+func (r *rateLimitedLLM) GenerateWithTools(systemPrompt string, userPrompt string, tools []ToolDefinition, opts ...LlmOptions) (ToolResult, error) {
+	if err := r.wait(opts); err != nil {
+		return ToolResult{}, err
+	}
+	return GenerateWithTools(r.llm, systemPrompt, userPrompt, tools, opts...)
+}
+
+// GenerateN implements CandidatesInterface
+func (r *rateLimitedLLM) GenerateN(systemPrompt string, userPrompt string, opts ...LlmOptions) ([]string, error) {
+	if err := r.wait(opts); err != nil {
+		return nil, err
+	}
+	return GenerateN(r.llm, systemPrompt, userPrompt, opts...)
+}
+
+// GenerateStructured implements StructuredOutputInterface
+func (r *rateLimitedLLM) GenerateStructured(systemPrompt string, userPrompt string, schema json.RawMessage, opts ...LlmOptions) (json.RawMessage, error) {
+	if err := r.wait(opts); err != nil {
+		return nil, err
+	}
+	return generateStructuredWith(r.llm, systemPrompt, userPrompt, schema, opts...)
+}
+
+// GenerateImageSize implements ImageSizeInterface
+func (r *rateLimitedLLM) GenerateImageSize(prompt string, width int, height int, opts ...LlmOptions) ([]byte, error) {
+	if err := r.wait(opts); err != nil {
+		return nil, err
+	}
+	return generateImageSizeWith(r.llm, prompt, width, height, opts...)
+}
+
+// GenerateImageWithText implements ImageTextInterface
+func (r *rateLimitedLLM) GenerateImageWithText(prompt string, opts ...LlmOptions) ([]byte, string, error) {
+	if err := r.wait(opts); err != nil {
+		return nil, "", err
+	}
+	return GenerateImageWithText(r.llm, prompt, opts...)
+}
+
+// ListModels implements ModelListInterface
+func (r *rateLimitedLLM) ListModels() ([]ModelInfo, error) {
+	if err := r.wait(nil); err != nil {
+		return nil, err
+	}
+	return ListModels(r.llm)
+}
+
+// DebugMessages implements DebugMessagesInterface
+func (r *rateLimitedLLM) DebugMessages(systemPrompt string, userMessage string, opts ...LlmOptions) []Message {
+	return DebugMessages(r.llm, systemPrompt, userMessage, opts...)
+}
+
+// EffectiveOptions implements EffectiveOptionsInterface
+func (r *rateLimitedLLM) EffectiveOptions(opts ...LlmOptions) LlmOptions {
+	return EffectiveOptions(r.llm, opts...)
+}
+
+// LastRawResponse implements RawResponseRecorderInterface
+func (r *rateLimitedLLM) LastRawResponse() (RawResponse, bool) {
+	return lastRawResponse(r.llm)
+}
+
+// Close implements io.Closer, closing the wrapped client
+func (r *rateLimitedLLM) Close() error {
+	return Close(r.llm)
+}
+
+// unwrap implements wrapperInterface
+func (r *rateLimitedLLM) unwrap() LlmInterface {
+	return r.llm
+}
@@ -0,0 +1,88 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	engine, err := NewLLM(LlmOptions{Provider: ProviderMock, MockResponse: "ok", RequestsPerMinute: 600})
+	if err != nil {
+		t.Fatalf("Failed to create mock LLM: %v", err)
+	}
+
+	start := time.Now()
+	for range 3 {
+		if _, err := engine.GenerateText("system", "Hello"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if _, err := engine.GenerateImage("a cat"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// 600 requests per minute is one every 100ms; the first call is immediate
+	if elapsed := time.Since(start); elapsed < 250*time.Millisecond {
+		t.Errorf("expected calls to be spaced by the limiter, took %v", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := engine.GenerateJSON("system", "Hello", LlmOptions{Context: ctx}); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled while waiting, got %v", err)
+	}
+}
+
+func TestRateLimiterDisabled(t *testing.T) {
+	engine, err := NewLLM(LlmOptions{Provider: ProviderMock})
+	if err != nil {
+		t.Fatalf("Failed to create mock LLM: %v", err)
+	}
+	if _, ok := engine.(*rateLimitedLLM); ok {
+		t.Error("expected no rate limiter without RequestsPerMinute")
+	}
+}
+
+func TestRateLimiterForwardsOptionalInterfaces(t *testing.T) {
+	var path string
+	server := usageStreamServer(t, &path)
+	defer server.Close()
+
+	engine, err := NewLLM(LlmOptions{
+		Provider:          ProviderOpenAI,
+		ApiKey:            "test-key",
+		Model:             "gpt-4.1",
+		RequestsPerMinute: 600,
+		ProviderOptions:   map[string]any{"base_url": server.URL},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create LLM: %v", err)
+	}
+
+	var chunks []string
+	var usage Usage
+	err = GenerateStreamWithUsage(engine, "system", "hello", func(chunk string) error {
+		chunks = append(chunks, chunk)
+		return nil
+	}, func(u Usage) {
+		usage = u
+	})
+	if err != nil {
+		t.Fatalf("GenerateStreamWithUsage failed: %v", err)
+	}
+	if len(chunks) != 3 || usage.PromptTokens != 12 {
+		t.Errorf("expected the provider's stream and usage, got chunks %q and %+v", chunks, usage)
+	}
+
+	mock, err := NewLLM(LlmOptions{Provider: ProviderMock, MockResponse: "ok", RequestsPerMinute: 600})
+	if err != nil {
+		t.Fatalf("Failed to create mock LLM: %v", err)
+	}
+	if _, err := GenerateWithTools(mock, "system", "hello", nil); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported for tools on the wrapped mock, got %v", err)
+	}
+	if _, _, err := GenerateRaw(mock, "system", "hello"); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported for raw responses on the wrapped mock, got %v", err)
+	}
+}
@@ -0,0 +1,155 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// RawResponse holds the undecoded response of the most recent provider call.
+// For HTTP providers Body is the raw response body, for SDK providers it is
+// the decoded response object marshaled back to JSON.
+type RawResponse struct {
+	// Provider that produced the response
+	Provider Provider
+
+	// StatusCode is the HTTP status code (0 when not available)
+	StatusCode int
+
+	// Header holds the HTTP response headers (nil when not available)
+	Header http.Header
+
+	// Body is the raw response payload
+	Body []byte
+}
+
+// RawResponseRecorderInterface is implemented by providers that can keep the
+// last raw response for debugging. Recording is opt-in via the
+// ProviderOptions["record_last_response"] = true option.
+type RawResponseRecorderInterface interface {
+	// LastRawResponse returns the most recent raw response, and false
+	// if recording is disabled or no call has been made yet
+	LastRawResponse() (RawResponse, bool)
+}
+
+// GenerateRaw generates a response like Generate, and also returns the raw
+// provider response of the call, e.g. to log its finish reason, usage or
+// warnings. It does not need the "record_last_response" option and is safe
+// to use concurrently. It returns an error wrapping ErrNotSupported if the
+// provider does not implement RawResponseRecorderInterface. The raw
+// response is returned with the error when the provider responded.
+func GenerateRaw(llm LlmInterface, systemPrompt string, userPrompt string, options ...LlmOptions) (string, RawResponse, error) {
+	if _, ok := unwrapLLM(llm).(RawResponseRecorderInterface); !ok {
+		return "", RawResponse{}, fmt.Errorf("%w: raw responses", ErrNotSupported)
+	}
+
+	perCall := LlmOptions{}
+	if len(options) > 0 {
+		perCall = options[0]
+	}
+	capture := &RawResponse{}
+	perCall.rawCapture = capture
+
+	text, err := llm.Generate(systemPrompt, userPrompt, perCall)
+	return text, *capture, err
+}
+
+// lastRawResponse returns the most recent raw response of llm, and false
+// if it does not implement RawResponseRecorderInterface
+func lastRawResponse(llm LlmInterface) (RawResponse, bool) {
+	if recorder, ok := llm.(RawResponseRecorderInterface); ok {
+		return recorder.LastRawResponse()
+	}
+	return RawResponse{}, false
+}
+
+// rawCaptureKey is the context key of the RawResponse a GenerateRaw call
+// captures into
+type rawCaptureKey struct{}
+
+// withRawCapture returns ctx carrying capture, if set
+func withRawCapture(ctx context.Context, capture *RawResponse) context.Context {
+	if capture == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, rawCaptureKey{}, capture)
+}
+
+// lastResponseRecorder stores the most recent raw response of a provider.
+// It is embedded in the provider implementations and is safe for concurrent use.
+type lastResponseRecorder struct {
+	mu      sync.Mutex
+	enabled bool
+	last    *RawResponse
+}
+
+// newLastResponseRecorder creates a recorder, enabled only when the
+// "record_last_response" provider option is set to true
+func newLastResponseRecorder(providerOptions map[string]any) *lastResponseRecorder {
+	enabled, _ := providerOptionBool(providerOptions, "record_last_response")
+	return &lastResponseRecorder{enabled: enabled}
+}
+
+// record stores the given response if recording is enabled, and in the
+// RawResponse carried by ctx for GenerateRaw
+func (r *lastResponseRecorder) record(ctx context.Context, raw RawResponse) {
+	if capture, ok := ctx.Value(rawCaptureKey{}).(*RawResponse); ok {
+		*capture = raw
+	}
+	if r == nil || !r.enabled {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.last = &raw
+}
+
+// recordHTTP stores an HTTP response and its already read body
+func (r *lastResponseRecorder) recordHTTP(provider Provider, resp *http.Response, body []byte) {
+	if resp == nil {
+		return
+	}
+	ctx := context.Background()
+	if resp.Request != nil {
+		ctx = resp.Request.Context()
+	}
+	r.record(ctx, RawResponse{
+		Provider:   provider,
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header.Clone(),
+		Body:       body,
+	})
+}
+
+// recordObject stores an SDK response object by marshaling it to JSON
+func (r *lastResponseRecorder) recordObject(ctx context.Context, provider Provider, statusCode int, header http.Header, object any) {
+	_, capturing := ctx.Value(rawCaptureKey{}).(*RawResponse)
+	if !capturing && (r == nil || !r.enabled) {
+		return
+	}
+	body, err := json.Marshal(object)
+	if err != nil {
+		return
+	}
+	r.record(ctx, RawResponse{
+		Provider:   provider,
+		StatusCode: statusCode,
+		Header:     header.Clone(),
+		Body:       body,
+	})
+}
+
+// LastRawResponse implements RawResponseRecorderInterface
+func (r *lastResponseRecorder) LastRawResponse() (RawResponse, bool) {
+	if r == nil {
+		return RawResponse{}, false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.last == nil {
+		return RawResponse{}, false
+	}
+	return *r.last, true
+}
@@ -0,0 +1,147 @@
+package llm
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestLastRawResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "req-123")
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"hi"}}]}`))
+	}))
+	defer server.Close()
+
+	newCustom := func(record bool) RawResponseRecorderInterface {
+		llm, err := NewLLM(LlmOptions{
+			Provider: ProviderCustom,
+			ProviderOptions: map[string]any{
+				"url":                  server.URL,
+				"record_last_response": record,
+			},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create custom LLM: %v", err)
+		}
+		if _, err := llm.GenerateText("system", "hello"); err != nil {
+			t.Fatalf("GenerateText failed: %v", err)
+		}
+		recorder, ok := llm.(RawResponseRecorderInterface)
+		if !ok {
+			t.Fatalf("custom provider does not implement RawResponseRecorderInterface")
+		}
+		return recorder
+	}
+
+	raw, ok := newCustom(true).LastRawResponse()
+	if !ok {
+		t.Fatal("expected last raw response to be recorded")
+	}
+	if raw.Provider != ProviderCustom || raw.StatusCode != http.StatusOK {
+		t.Errorf("unexpected raw response metadata: %+v", raw)
+	}
+	if raw.Header.Get("X-Request-Id") != "req-123" {
+		t.Errorf("expected response headers to be recorded, got %v", raw.Header)
+	}
+	if string(raw.Body) != `{"choices":[{"message":{"role":"assistant","content":"hi"}}]}` {
+		t.Errorf("unexpected raw body: %s", raw.Body)
+	}
+
+	if _, ok := newCustom(false).LastRawResponse(); ok {
+		t.Error("expected no raw response when recording is disabled")
+	}
+}
+
+func TestGenerateRaw(t *testing.T) {
+	body := `{"choices":[{"message":{"role":"assistant","content":"hi"},"finish_reason":"stop"}],"usage":{"total_tokens":7}}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	// Recording is not enabled: GenerateRaw captures the response anyway
+	engine, err := NewLLM(LlmOptions{Provider: ProviderCustom, ProviderOptions: map[string]any{"url": server.URL}})
+	if err != nil {
+		t.Fatalf("Failed to create custom LLM: %v", err)
+	}
+
+	text, raw, err := GenerateRaw(engine, "system", "hello")
+	if err != nil {
+		t.Fatalf("GenerateRaw failed: %v", err)
+	}
+	if text != "hi" {
+		t.Errorf("expected text hi, got %q", text)
+	}
+	if raw.Provider != ProviderCustom || raw.StatusCode != http.StatusOK || string(raw.Body) != body {
+		t.Errorf("unexpected raw response: %+v", raw)
+	}
+	if _, ok := engine.(RawResponseRecorderInterface).LastRawResponse(); ok {
+		t.Error("expected GenerateRaw not to enable last response recording")
+	}
+
+	mock, _ := NewLLM(LlmOptions{Provider: ProviderMock})
+	if _, _, err := GenerateRaw(mock, "system", "hello"); !errors.Is(err, ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported, got %v", err)
+	}
+}
+
+func TestGenerateRawSDKProvider(t *testing.T) {
+	var captured map[string]any
+	server := captureServer(t, `{"choices":[{"message":{"role":"assistant","content":"hi"},"finish_reason":"length"}]}`, &captured)
+	defer server.Close()
+
+	config := openai.DefaultConfig("test-key")
+	config.BaseURL = server.URL
+	engine := &openaiImplementation{
+		client:               openai.NewClientWithConfig(config),
+		model:                "gpt-4o-mini",
+		lastResponseRecorder: newLastResponseRecorder(nil),
+	}
+
+	_, raw, err := GenerateRaw(engine, "system", "hello")
+	if err != nil {
+		t.Fatalf("GenerateRaw failed: %v", err)
+	}
+	if raw.Provider != ProviderOpenAI || !strings.Contains(string(raw.Body), `"finish_reason":"length"`) {
+		t.Errorf("expected the decoded response marshaled to JSON, got %+v", raw)
+	}
+}
+
+func TestRawResponseUsage(t *testing.T) {
+	raw := RawResponse{
+		Provider: ProviderOpenAI,
+		Body:     []byte(`{"usage":{"prompt_tokens":100,"completion_tokens":20,"total_tokens":120,"prompt_tokens_details":{"cached_tokens":64}}}`),
+	}
+	usage, ok := raw.Usage()
+	if !ok {
+		t.Fatal("expected usage in the response")
+	}
+	if usage != (Usage{PromptTokens: 100, CompletionTokens: 20, TotalTokens: 120, CacheReadTokens: 64}) {
+		t.Errorf("unexpected usage: %+v", usage)
+	}
+
+	if _, ok := (RawResponse{Body: []byte(`{"choices":[]}`)}).Usage(); ok {
+		t.Error("expected no usage")
+	}
+}
+
+func TestRawResponseStopReason(t *testing.T) {
+	tests := map[string]string{
+		`{"stop_reason":"end_turn","content":[]}`:               "end_turn",
+		`{"choices":[{"finish_reason":"length"}]}`:              "length",
+		`{"choices":[{"message":{"content":"hi"}}],"usage":{}}`: "",
+		`not json`: "",
+	}
+
+	for body, expected := range tests {
+		stopReason, ok := (RawResponse{Body: []byte(body)}).StopReason()
+		if stopReason != expected || ok != (expected != "") {
+			t.Errorf("StopReason(%s) = %q, %v, expected %q", body, stopReason, ok, expected)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package llm
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// reasoningEfforts are the accepted values of ProviderOptions["reasoning_effort"].
+// "minimal" is supported by the GPT-5 models only.
+var reasoningEfforts = []string{"minimal", "low", "medium", "high"}
+
+// isReasoningModel reports whether the model is an OpenAI reasoning model
+// accepting reasoning_effort: the o-series and the GPT-5 models, also with
+// OpenRouter's "openai/" prefix. The GPT-5 chat models do not reason.
+func isReasoningModel(model string) bool {
+	model = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(model)), "openai/")
+
+	if strings.HasPrefix(model, "gpt-5") {
+		return !strings.Contains(model, "-chat")
+	}
+	for _, prefix := range []string{"o1", "o3", "o4"} {
+		if model == prefix || strings.HasPrefix(model, prefix+"-") {
+			return true
+		}
+	}
+	return false
+}
+
+// reasoningEffort returns the reasoning_effort to send for the options:
+// ProviderOptions["reasoning_effort"] when the model is a reasoning model,
+// and an empty string otherwise. An unknown effort is an error.
+func reasoningEffort(options LlmOptions) (string, error) {
+	effort, ok := providerOptionString(options.ProviderOptions, "reasoning_effort")
+	if !ok || strings.TrimSpace(effort) == "" {
+		return "", nil
+	}
+
+	effort = strings.ToLower(strings.TrimSpace(effort))
+	if !slices.Contains(reasoningEfforts, effort) {
+		return "", fmt.Errorf("invalid reasoning_effort %q: must be one of %s", effort, strings.Join(reasoningEfforts, ", "))
+	}
+
+	if !isReasoningModel(options.Model) {
+		return "", nil
+	}
+	return effort, nil
+}
@@ -0,0 +1,89 @@
+package llm
+
+import (
+	"testing"
+)
+
+func TestIsReasoningModel(t *testing.T) {
+	for model, expected := range map[string]bool{
+		"o4-mini":                   true,
+		"o1":                        true,
+		"o3-mini-2025-01-31":        true,
+		"gpt-5":                     true,
+		OPENROUTER_MODEL_GPT_5_NANO: true,
+		"gpt-5-chat-latest":         false,
+		"gpt-4.1":                   false,
+		"openai/gpt-4o":             false,
+		"o1x":                       false,
+	} {
+		if got := isReasoningModel(model); got != expected {
+			t.Errorf("isReasoningModel(%q) = %v, expected %v", model, got, expected)
+		}
+	}
+}
+
+func TestOpenaiReasoningEffort(t *testing.T) {
+	var captured map[string]any
+	server := captureServer(t, `{"choices":[{"message":{"role":"assistant","content":"hi"}}]}`, &captured)
+	defer server.Close()
+
+	engine, err := TextModel(ProviderOpenAI, LlmOptions{
+		ApiKey:          "test-key",
+		Model:           "o4-mini",
+		ProviderOptions: map[string]any{"base_url": server.URL, "reasoning_effort": "high"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create OpenAI LLM: %v", err)
+	}
+
+	if _, err := engine.GenerateText("system", "hello"); err != nil {
+		t.Fatalf("GenerateText failed: %v", err)
+	}
+	if captured["reasoning_effort"] != "high" {
+		t.Errorf("expected reasoning_effort high, got %v", captured["reasoning_effort"])
+	}
+	if captured["max_completion_tokens"] != float64(4096) || captured["max_tokens"] != nil || captured["temperature"] != nil {
+		t.Errorf("expected max_completion_tokens without max_tokens and temperature, got %v", captured)
+	}
+
+	// Non-reasoning models omit the field
+	captured = nil
+	if _, err := engine.GenerateText("system", "hello", LlmOptions{Model: "gpt-4.1"}); err != nil {
+		t.Fatalf("GenerateText failed: %v", err)
+	}
+	if _, ok := captured["reasoning_effort"]; ok {
+		t.Errorf("expected no reasoning_effort for gpt-4.1, got %v", captured["reasoning_effort"])
+	}
+
+	captured = nil
+	_, err = engine.GenerateText("system", "hello", LlmOptions{ProviderOptions: map[string]any{"base_url": server.URL, "reasoning_effort": "extreme"}})
+	if err == nil {
+		t.Error("expected an error for an invalid reasoning_effort")
+	}
+	if captured != nil {
+		t.Error("expected no request for an invalid reasoning_effort")
+	}
+}
+
+func TestOpenrouterReasoningEffort(t *testing.T) {
+	var captured map[string]any
+	server := captureServer(t, `{"choices":[{"message":{"role":"assistant","content":"hi"}}]}`, &captured)
+	defer server.Close()
+
+	engine, err := NewLLM(LlmOptions{
+		Provider:        ProviderOpenRouter,
+		ApiKey:          "test-key",
+		Model:           OPENROUTER_MODEL_GPT_5_NANO,
+		ProviderOptions: map[string]any{"base_url": server.URL, "reasoning_effort": "low"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create OpenRouter LLM: %v", err)
+	}
+
+	if _, err := engine.GenerateText("system", "hello"); err != nil {
+		t.Fatalf("GenerateText failed: %v", err)
+	}
+	if captured["reasoning_effort"] != "low" {
+		t.Errorf("expected reasoning_effort low, got %v", captured["reasoning_effort"])
+	}
+}
@@ -0,0 +1,86 @@
+package llm
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// MultiConfig configures several providers at once, for NewRegistry
+type MultiConfig struct {
+	// Providers maps each provider to its options. The Provider field of
+	// the options is set from the key.
+	Providers map[Provider]LlmOptions
+}
+
+// Registry holds an LlmInterface for each configured provider
+type Registry struct {
+	llms map[Provider]LlmInterface
+}
+
+// NewRegistry creates and validates all the providers of the config, so
+// misconfiguration is reported at startup. The errors of all failing
+// providers are returned together.
+func NewRegistry(config MultiConfig) (*Registry, error) {
+	if len(config.Providers) == 0 {
+		return nil, errors.New("no providers configured")
+	}
+
+	registry := &Registry{llms: make(map[Provider]LlmInterface, len(config.Providers))}
+
+	var errs []error
+	for provider, options := range config.Providers {
+		options.Provider = provider
+
+		if err := validateCredentials(provider, options); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", provider, err))
+			continue
+		}
+
+		llm, err := NewLLM(options)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", provider, err))
+			continue
+		}
+
+		registry.llms[provider] = llm
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	return registry, nil
+}
+
+// Get returns the LlmInterface of the provider, or an error if the
+// provider was not configured
+func (r *Registry) Get(provider Provider) (LlmInterface, error) {
+	llm, ok := r.llms[provider]
+	if !ok {
+		return nil, fmt.Errorf("provider %s is not configured", provider)
+	}
+	return llm, nil
+}
+
+// Providers returns the configured providers, sorted by name
+func (r *Registry) Providers() []Provider {
+	providers := make([]Provider, 0, len(r.llms))
+	for provider := range r.llms {
+		providers = append(providers, provider)
+	}
+	slices.Sort(providers)
+	return providers
+}
+
+// Close closes the clients of all the configured providers, returning
+// their errors together
+func (r *Registry) Close() error {
+	var errs []error
+	for _, provider := range r.Providers() {
+		if err := Close(r.llms[provider]); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", provider, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,64 @@
+package llm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewRegistry(t *testing.T) {
+	registry, err := NewRegistry(MultiConfig{
+		Providers: map[Provider]LlmOptions{
+			ProviderMock:   {MockResponse: "mock response"},
+			ProviderOpenAI: {ApiKey: "test-key", Model: "gpt-4o-mini"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewRegistry failed: %v", err)
+	}
+
+	mock, err := registry.Get(ProviderMock)
+	if err != nil {
+		t.Fatalf("Get(mock) failed: %v", err)
+	}
+	if response, err := mock.GenerateText("system", "hello"); err != nil || response != "mock response" {
+		t.Errorf("expected mock response, got %q (err=%v)", response, err)
+	}
+
+	openaiLLM, err := registry.Get(ProviderOpenAI)
+	if err != nil {
+		t.Fatalf("Get(openai) failed: %v", err)
+	}
+	if _, ok := openaiLLM.(*openaiImplementation); !ok {
+		t.Errorf("expected the OpenAI implementation, got %T", openaiLLM)
+	}
+
+	if _, err := registry.Get(ProviderAnthropic); err == nil {
+		t.Error("expected an error for a provider that is not configured")
+	}
+
+	if providers := registry.Providers(); len(providers) != 2 || providers[0] != ProviderMock || providers[1] != ProviderOpenAI {
+		t.Errorf("unexpected providers: %v", providers)
+	}
+}
+
+func TestNewRegistryValidation(t *testing.T) {
+	if _, err := NewRegistry(MultiConfig{}); err == nil {
+		t.Error("expected an error for an empty config")
+	}
+
+	_, err := NewRegistry(MultiConfig{
+		Providers: map[Provider]LlmOptions{
+			ProviderMock:      {},
+			ProviderOpenAI:    {},
+			ProviderAnthropic: {},
+		},
+	})
+	if err == nil {
+		t.Fatal("expected missing API keys to be rejected")
+	}
+	for _, want := range []string{"openai api key is required", "anthropic api key is required"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error to contain %q, got %v", want, err)
+		}
+	}
+}
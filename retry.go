@@ -0,0 +1,94 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+	"google.golang.org/genai"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// retryBaseDelay is the delay before the first retry, doubled for each
+// following retry up to retryMaxDelay
+var retryBaseDelay = 500 * time.Millisecond
+
+// retryMaxDelay caps the delay between retries
+const retryMaxDelay = 30 * time.Second
+
+// withRetry calls call, retrying transient failures up to
+// options.MaxRetries times with exponential backoff. options.OnRetry is
+// invoked before each retry sleep. The sleep is cut short, returning the
+// last error, when ctx is done.
+func withRetry(ctx context.Context, options LlmOptions, call func() error) error {
+	delay := retryBaseDelay
+	for attempt := 1; ; attempt++ {
+		err := call()
+		if err == nil || attempt > options.MaxRetries || !isRetryable(err) {
+			return err
+		}
+
+		if options.OnRetry != nil {
+			options.OnRetry(attempt, err, delay)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+
+		delay = min(delay*2, retryMaxDelay)
+	}
+}
+
+// isRetryable reports whether err is a transient provider failure: a rate
+// limit (429 or gRPC RESOURCE_EXHAUSTED), a server error (5xx or gRPC
+// UNAVAILABLE) or a network timeout
+func isRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var llmErr *APIError
+	if errors.As(err, &llmErr) {
+		return llmErr.Retryable
+	}
+
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) {
+		return retryableStatus(apiErr.HTTPStatusCode)
+	}
+
+	var requestErr *openai.RequestError
+	if errors.As(err, &requestErr) {
+		return retryableStatus(requestErr.HTTPStatusCode)
+	}
+
+	var genaiErr genai.APIError
+	if errors.As(err, &genaiErr) {
+		return genaiErr.Status == "RESOURCE_EXHAUSTED" || retryableStatus(genaiErr.Code)
+	}
+
+	// Vertex AI reports quota and availability failures as gRPC statuses
+	if grpcStatus, ok := status.FromError(err); ok {
+		switch grpcStatus.Code() {
+		case codes.ResourceExhausted, codes.Unavailable:
+			return true
+		}
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// retryableStatus reports whether an HTTP status code is worth retrying
+func retryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= http.StatusInternalServerError
+}
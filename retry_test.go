@@ -0,0 +1,140 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"google.golang.org/genai"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// failingServer responds with the given status to the first failures
+// requests, then with a chat completion
+func failingServer(failures int, status int) (*httptest.Server, *int) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests <= failures {
+			w.WriteHeader(status)
+			w.Write([]byte(`{"error":"try again"}`))
+			return
+		}
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"hi"}}]}`))
+	}))
+	return server, &requests
+}
+
+func TestOnRetry(t *testing.T) {
+	originalDelay := retryBaseDelay
+	retryBaseDelay = time.Millisecond
+	defer func() { retryBaseDelay = originalDelay }()
+
+	server, requests := failingServer(2, http.StatusServiceUnavailable)
+	defer server.Close()
+
+	var attempts []int
+	var delays []time.Duration
+	engine, err := NewLLM(LlmOptions{
+		Provider:        ProviderCustom,
+		ProviderOptions: map[string]any{"url": server.URL},
+		MaxRetries:      3,
+		OnRetry: func(attempt int, err error, delay time.Duration) {
+			attempts = append(attempts, attempt)
+			delays = append(delays, delay)
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create custom LLM: %v", err)
+	}
+
+	response, err := engine.GenerateText("system", "hello")
+	if err != nil {
+		t.Fatalf("GenerateText failed: %v", err)
+	}
+	if response != "hi" {
+		t.Errorf("expected hi, got %q", response)
+	}
+	if *requests != 3 {
+		t.Errorf("expected 3 requests, got %d", *requests)
+	}
+	if len(attempts) != 2 || attempts[0] != 1 || attempts[1] != 2 {
+		t.Errorf("expected OnRetry for attempts 1 and 2, got %v", attempts)
+	}
+	if len(delays) == 2 && delays[1] != 2*delays[0] {
+		t.Errorf("expected exponential backoff, got %v", delays)
+	}
+}
+
+func TestRetryLimits(t *testing.T) {
+	originalDelay := retryBaseDelay
+	retryBaseDelay = time.Millisecond
+	defer func() { retryBaseDelay = originalDelay }()
+
+	// Retries stop after MaxRetries
+	server, requests := failingServer(5, http.StatusTooManyRequests)
+	defer server.Close()
+	engine, _ := NewLLM(LlmOptions{
+		Provider:        ProviderCustom,
+		ProviderOptions: map[string]any{"url": server.URL},
+		MaxRetries:      2,
+	})
+	_, err := engine.GenerateText("system", "hello")
+	if !IsRateLimited(err) {
+		t.Errorf("expected the 429 error, got %v", err)
+	}
+	if *requests != 3 {
+		t.Errorf("expected 3 requests, got %d", *requests)
+	}
+
+	// Client errors are not retried
+	badRequest, requests := failingServer(1, http.StatusBadRequest)
+	defer badRequest.Close()
+	engine, _ = NewLLM(LlmOptions{
+		Provider:        ProviderCustom,
+		ProviderOptions: map[string]any{"url": badRequest.URL},
+		MaxRetries:      2,
+	})
+	if _, err := engine.GenerateText("system", "hello"); err == nil {
+		t.Error("expected the 400 error")
+	}
+	if *requests != 1 {
+		t.Errorf("expected no retry for 400, got %d requests", *requests)
+	}
+}
+
+func TestRetryResourceExhausted(t *testing.T) {
+	originalDelay := retryBaseDelay
+	retryBaseDelay = time.Millisecond
+	defer func() { retryBaseDelay = originalDelay }()
+
+	// Vertex quota errors are gRPC statuses, possibly wrapped
+	calls := 0
+	err := withRetry(context.Background(), LlmOptions{MaxRetries: 2}, func() error {
+		calls++
+		if calls == 1 {
+			return fmt.Errorf("generate: %w", status.Error(codes.ResourceExhausted, "quota exceeded"))
+		}
+		return nil
+	})
+	if err != nil || calls != 2 {
+		t.Errorf("expected success on the second call, got %d calls (err=%v)", calls, err)
+	}
+
+	// Gemini reports them as RESOURCE_EXHAUSTED API errors
+	if !isRetryable(genai.APIError{Code: http.StatusTooManyRequests, Status: "RESOURCE_EXHAUSTED"}) {
+		t.Error("expected Gemini RESOURCE_EXHAUSTED to be retryable")
+	}
+
+	if isRetryable(status.Error(codes.InvalidArgument, "bad request")) {
+		t.Error("expected gRPC InvalidArgument not to be retryable")
+	}
+	if isRetryable(errors.New("plain error")) {
+		t.Error("expected a plain error not to be retryable")
+	}
+}
@@ -0,0 +1,449 @@
+package llm
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// captureServer records the JSON body of the last request and responds
+// with the given body
+func captureServer(t *testing.T, response string, captured *map[string]any) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, captured); err != nil {
+			t.Errorf("request body is not JSON: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(response))
+	}))
+}
+
+func TestOpenaiStopAndTopP(t *testing.T) {
+	var captured map[string]any
+	server := captureServer(t, `{"choices":[{"message":{"role":"assistant","content":"hi"}}]}`, &captured)
+	defer server.Close()
+
+	config := openai.DefaultConfig("test-key")
+	config.BaseURL = server.URL
+	engine := &openaiImplementation{
+		client:               openai.NewClientWithConfig(config),
+		model:                "gpt-4o-mini",
+		lastResponseRecorder: newLastResponseRecorder(nil),
+	}
+
+	if _, err := engine.GenerateText("system", "hello", LlmOptions{Stop: []string{"END"}, TopP: PtrFloat64(0.5)}); err != nil {
+		t.Fatalf("GenerateText failed: %v", err)
+	}
+	if !reflect.DeepEqual(captured["stop"], []any{"END"}) {
+		t.Errorf("expected stop [END], got %v", captured["stop"])
+	}
+	if captured["top_p"] != 0.5 {
+		t.Errorf("expected top_p 0.5, got %v", captured["top_p"])
+	}
+
+	// Unset values are not sent
+	captured = nil
+	if _, err := engine.GenerateText("system", "hello"); err != nil {
+		t.Fatalf("GenerateText failed: %v", err)
+	}
+	if _, ok := captured["stop"]; ok {
+		t.Error("expected no stop when unset")
+	}
+	if _, ok := captured["top_p"]; ok {
+		t.Error("expected no top_p when unset")
+	}
+}
+
+func TestGeminiStopAndTopP(t *testing.T) {
+	var captured map[string]any
+	server := captureServer(t, `{"candidates":[{"content":{"role":"model","parts":[{"text":"hi"}]}}]}`, &captured)
+	defer server.Close()
+
+	engine := newTestGemini(t, server.URL)
+	if _, err := engine.GenerateText("system", "hello", LlmOptions{Stop: []string{"END"}, TopP: PtrFloat64(0.5), TopK: PtrInt(20)}); err != nil {
+		t.Fatalf("GenerateText failed: %v", err)
+	}
+	generationConfig, _ := captured["generationConfig"].(map[string]any)
+	if generationConfig["topK"] != 20.0 {
+		t.Errorf("expected topK 20, got %v", generationConfig["topK"])
+	}
+	if !reflect.DeepEqual(generationConfig["stopSequences"], []any{"END"}) {
+		t.Errorf("expected stopSequences [END], got %v", generationConfig["stopSequences"])
+	}
+	if generationConfig["topP"] != 0.5 {
+		t.Errorf("expected topP 0.5, got %v", generationConfig["topP"])
+	}
+}
+
+func TestVertexGenerationConfigStopAndTopP(t *testing.T) {
+	config, err := vertexGenerationConfig(LlmOptions{Stop: []string{"END"}, TopP: PtrFloat64(0.5), TopK: PtrInt(20)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(config.StopSequences, []string{"END"}) {
+		t.Errorf("expected stop sequences [END], got %v", config.StopSequences)
+	}
+	if *config.TopP != 0.5 {
+		t.Errorf("expected top_p 0.5, got %v", *config.TopP)
+	}
+	if *config.TopK != 20 {
+		t.Errorf("expected top_k 20, got %v", *config.TopK)
+	}
+
+	// Vertex defaults are used when unset
+	config, _ = vertexGenerationConfig(LlmOptions{})
+	if config.StopSequences != nil || config.TopP != nil || config.TopK != nil {
+		t.Errorf("expected default config, got stop=%v topP=%v topK=%v", config.StopSequences, config.TopP, config.TopK)
+	}
+}
+
+func TestMaxTokensOmittedWhenNotPositive(t *testing.T) {
+	chatResponse := `{"choices":[{"message":{"role":"assistant","content":"hi"}}]}`
+
+	for _, maxTokens := range []int{0, -1} {
+		options := LlmOptions{MaxTokens: maxTokens}
+
+		var captured map[string]any
+		server := captureServer(t, chatResponse, &captured)
+		config := openai.DefaultConfig("test-key")
+		config.BaseURL = server.URL
+		openaiEngine := &openaiImplementation{
+			client:               openai.NewClientWithConfig(config),
+			model:                "gpt-4o-mini",
+			maxTokens:            maxTokens,
+			lastResponseRecorder: newLastResponseRecorder(nil),
+		}
+		if _, err := openaiEngine.GenerateText("system", "hello", options); err != nil {
+			t.Fatalf("OpenAI GenerateText failed: %v", err)
+		}
+		if _, ok := captured["max_tokens"]; ok {
+			t.Errorf("OpenAI: expected max_tokens to be omitted for %d, got %v", maxTokens, captured["max_tokens"])
+		}
+
+		captured = nil
+		openrouterEngine := &openrouterImplementation{
+			client:               openai.NewClientWithConfig(config),
+			model:                OPENROUTER_MODEL_GPT_5_NANO,
+			maxTokens:            maxTokens,
+			lastResponseRecorder: newLastResponseRecorder(nil),
+		}
+		if _, err := openrouterEngine.GenerateText("system", "hello", options); err != nil {
+			t.Fatalf("OpenRouter GenerateText failed: %v", err)
+		}
+		if _, ok := captured["max_tokens"]; ok {
+			t.Errorf("OpenRouter: expected max_tokens to be omitted for %d, got %v", maxTokens, captured["max_tokens"])
+		}
+
+		captured = nil
+		customEngine, err := NewLLM(LlmOptions{
+			Provider:        ProviderCustom,
+			MaxTokens:       maxTokens,
+			ProviderOptions: map[string]any{"url": server.URL},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create custom LLM: %v", err)
+		}
+		if _, err := customEngine.GenerateText("system", "hello"); err != nil {
+			t.Fatalf("Custom GenerateText failed: %v", err)
+		}
+		if _, ok := captured["max_tokens"]; ok {
+			t.Errorf("Custom: expected max_tokens to be omitted for %d, got %v", maxTokens, captured["max_tokens"])
+		}
+		server.Close()
+
+		captured = nil
+		geminiServer := captureServer(t, `{"candidates":[{"content":{"role":"model","parts":[{"text":"hi"}]}}]}`, &captured)
+		geminiEngine := newTestGemini(t, geminiServer.URL)
+		if _, err := geminiEngine.GenerateText("system", "hello", options); err != nil {
+			t.Fatalf("Gemini GenerateText failed: %v", err)
+		}
+		generationConfig, _ := captured["generationConfig"].(map[string]any)
+		if _, ok := generationConfig["maxOutputTokens"]; ok {
+			t.Errorf("Gemini: expected maxOutputTokens to be omitted for %d, got %v", maxTokens, generationConfig["maxOutputTokens"])
+		}
+		geminiServer.Close()
+
+		vertexConfig, err := vertexGenerationConfig(options)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if vertexConfig.MaxOutputTokens != nil {
+			t.Errorf("Vertex: expected MaxOutputTokens to be omitted for %d, got %d", maxTokens, *vertexConfig.MaxOutputTokens)
+		}
+	}
+
+	// Positive values are still sent
+	vertexConfig, _ := vertexGenerationConfig(LlmOptions{MaxTokens: 100})
+	if vertexConfig.MaxOutputTokens == nil || *vertexConfig.MaxOutputTokens != 100 {
+		t.Errorf("Vertex: expected MaxOutputTokens 100, got %v", vertexConfig.MaxOutputTokens)
+	}
+}
+
+func TestAnthropicStopSequences(t *testing.T) {
+	var captured map[string]any
+	server := captureServer(t, `{"content":[{"type":"text","text":"hi"}]}`, &captured)
+	defer server.Close()
+
+	target, _ := url.Parse(server.URL)
+	engine, err := NewLLM(LlmOptions{Provider: ProviderAnthropic, ApiKey: "test-key", Model: "claude-sonnet-4"})
+	if err != nil {
+		t.Fatalf("failed to create Anthropic LLM: %v", err)
+	}
+	engine.(*anthropicImplementation).httpClient = &http.Client{Transport: redirectTransport{target: target}}
+
+	if _, err := engine.GenerateText("system", "hello", LlmOptions{Stop: []string{"END"}}); err != nil {
+		t.Fatalf("GenerateText failed: %v", err)
+	}
+	if !reflect.DeepEqual(captured["stop_sequences"], []any{"END"}) {
+		t.Errorf("expected stop_sequences [END], got %v", captured["stop_sequences"])
+	}
+
+	// An empty slice is omitted
+	captured = nil
+	if _, err := engine.GenerateText("system", "hello", LlmOptions{Stop: []string{}}); err != nil {
+		t.Fatalf("GenerateText failed: %v", err)
+	}
+	if _, ok := captured["stop_sequences"]; ok {
+		t.Error("expected no stop_sequences for an empty slice")
+	}
+}
+
+func TestMockStopSequences(t *testing.T) {
+	engine, err := NewLLM(LlmOptions{Provider: ProviderMock, MockResponse: "line one\nEND\nline two"})
+	if err != nil {
+		t.Fatalf("Failed to create mock LLM: %v", err)
+	}
+
+	response, _ := engine.GenerateText("system", "hello", LlmOptions{Stop: []string{"two", "END"}})
+	if response != "line one\n" {
+		t.Errorf("expected response truncated at the first stop sequence, got %q", response)
+	}
+
+	response, _ = engine.GenerateText("system", "hello", LlmOptions{Stop: []string{}})
+	if response != "line one\nEND\nline two" {
+		t.Errorf("expected full response without stop sequences, got %q", response)
+	}
+}
+
+func TestJSONInstructionInjectedOnce(t *testing.T) {
+	var captured map[string]any
+	server := captureServer(t, `{"candidates":[{"content":{"role":"model","parts":[{"text":"{}"}]}}]}`, &captured)
+	defer server.Close()
+
+	geminiSystemPrompt := func() string {
+		instruction, _ := captured["systemInstruction"].(map[string]any)
+		parts, _ := instruction["parts"].([]any)
+		if len(parts) == 0 {
+			return ""
+		}
+		part, _ := parts[0].(map[string]any)
+		text, _ := part["text"].(string)
+		return text
+	}
+
+	gemini := newTestGemini(t, server.URL)
+	if _, err := gemini.GenerateJSON("system", "hello"); err != nil {
+		t.Fatalf("Gemini GenerateJSON failed: %v", err)
+	}
+	if n := strings.Count(geminiSystemPrompt(), jsonInstruction); n != 1 {
+		t.Errorf("Gemini: expected the JSON instruction once, got %d in %q", n, geminiSystemPrompt())
+	}
+
+	// A prompt that already carries the instruction is left as is
+	captured = nil
+	if _, err := gemini.GenerateJSON("system\n"+jsonInstruction, "hello"); err != nil {
+		t.Fatalf("Gemini GenerateJSON failed: %v", err)
+	}
+	if n := strings.Count(geminiSystemPrompt(), jsonInstruction); n != 1 {
+		t.Errorf("Gemini: expected the JSON instruction once, got %d in %q", n, geminiSystemPrompt())
+	}
+
+	captured = nil
+	if _, err := gemini.GenerateJSON("Reply with JSON", "hello", LlmOptions{DisableJSONInstruction: true}); err != nil {
+		t.Fatalf("Gemini GenerateJSON failed: %v", err)
+	}
+	if got := geminiSystemPrompt(); got != "Reply with JSON" {
+		t.Errorf("Gemini: expected the system prompt untouched, got %q", got)
+	}
+
+	anthropicServer := captureServer(t, `{"content":[{"type":"text","text":"{}"}]}`, &captured)
+	defer anthropicServer.Close()
+
+	target, _ := url.Parse(anthropicServer.URL)
+	anthropic, err := NewLLM(LlmOptions{Provider: ProviderAnthropic, ApiKey: "test-key", Model: "claude-sonnet-4"})
+	if err != nil {
+		t.Fatalf("failed to create Anthropic LLM: %v", err)
+	}
+	anthropic.(*anthropicImplementation).httpClient = &http.Client{Transport: redirectTransport{target: target}}
+
+	captured = nil
+	if _, err := anthropic.GenerateJSON("system", "hello"); err != nil {
+		t.Fatalf("Anthropic GenerateJSON failed: %v", err)
+	}
+	system, _ := captured["system"].(string)
+	if n := strings.Count(system, jsonInstruction); n != 1 {
+		t.Errorf("Anthropic: expected the JSON instruction once, got %d in %q", n, system)
+	}
+
+	captured = nil
+	if _, err := anthropic.GenerateJSON("Reply with JSON", "hello", LlmOptions{DisableJSONInstruction: true}); err != nil {
+		t.Fatalf("Anthropic GenerateJSON failed: %v", err)
+	}
+	if system, _ := captured["system"].(string); system != "Reply with JSON" {
+		t.Errorf("Anthropic: expected the system prompt untouched, got %q", system)
+	}
+}
+
+func TestSeed(t *testing.T) {
+	var captured map[string]any
+	server := captureServer(t, `{"choices":[{"message":{"role":"assistant","content":"hi"}}]}`, &captured)
+	defer server.Close()
+
+	config := openai.DefaultConfig("test-key")
+	config.BaseURL = server.URL
+	openaiEngine := &openaiImplementation{
+		client:               openai.NewClientWithConfig(config),
+		model:                "gpt-4o-mini",
+		lastResponseRecorder: newLastResponseRecorder(nil),
+	}
+	if _, err := openaiEngine.GenerateText("system", "hello", LlmOptions{Seed: PtrInt(42)}); err != nil {
+		t.Fatalf("OpenAI GenerateText failed: %v", err)
+	}
+	if captured["seed"] != 42.0 {
+		t.Errorf("OpenAI: expected seed 42, got %v", captured["seed"])
+	}
+
+	captured = nil
+	openrouterEngine := &openrouterImplementation{
+		client:               openai.NewClientWithConfig(config),
+		model:                OPENROUTER_MODEL_GPT_5_NANO,
+		lastResponseRecorder: newLastResponseRecorder(nil),
+	}
+	if _, err := openrouterEngine.GenerateText("system", "hello", LlmOptions{Seed: PtrInt(42)}); err != nil {
+		t.Fatalf("OpenRouter GenerateText failed: %v", err)
+	}
+	if captured["seed"] != 42.0 {
+		t.Errorf("OpenRouter: expected seed 42, got %v", captured["seed"])
+	}
+
+	// Unset seeds are not sent
+	captured = nil
+	if _, err := openaiEngine.GenerateText("system", "hello"); err != nil {
+		t.Fatalf("OpenAI GenerateText failed: %v", err)
+	}
+	if _, ok := captured["seed"]; ok {
+		t.Error("expected no seed when unset")
+	}
+
+	// The mock response is deterministic in the seed
+	mock, err := NewLLM(LlmOptions{Provider: ProviderMock})
+	if err != nil {
+		t.Fatalf("Failed to create mock LLM: %v", err)
+	}
+	first, _ := mock.GenerateText("system", "hello", LlmOptions{Seed: PtrInt(7)})
+	second, _ := mock.GenerateText("system", "hello", LlmOptions{Seed: PtrInt(7)})
+	if first != "mock response (seed 7)" || first != second {
+		t.Errorf("expected deterministic mock response with the seed, got %q and %q", first, second)
+	}
+}
+
+func TestGenerateXMLAndYAML(t *testing.T) {
+	var captured map[string]any
+	server := captureServer(t, `{"choices":[{"message":{"role":"assistant","content":"`+"```xml\\n<colors><color>red</color></colors>\\n```"+`"}}]}`, &captured)
+	defer server.Close()
+
+	config := openai.DefaultConfig("test-key")
+	config.BaseURL = server.URL
+	engine := &openaiImplementation{
+		client:               openai.NewClientWithConfig(config),
+		model:                "gpt-4o-mini",
+		lastResponseRecorder: newLastResponseRecorder(nil),
+	}
+
+	response, err := engine.GenerateXML("List colors", "one color")
+	if err != nil {
+		t.Fatalf("GenerateXML failed: %v", err)
+	}
+	if response != "<colors><color>red</color></colors>" {
+		t.Errorf("expected the code fence stripped, got %q", response)
+	}
+
+	messages, _ := captured["messages"].([]any)
+	system, _ := messages[0].(map[string]any)
+	if system["role"] != "system" || system["content"] != "List colors\n"+markupInstructions[OutputFormatXML] {
+		t.Errorf("expected the XML instruction in the system message, got %v", system)
+	}
+
+	mock, _ := NewLLM(LlmOptions{Provider: ProviderMock, MockResponse: "```yaml\ncolors:\n  - red\n```"})
+	response, err = mock.GenerateYAML("List colors", "one color")
+	if err != nil {
+		t.Fatalf("GenerateYAML failed: %v", err)
+	}
+	if response != "colors:\n  - red" {
+		t.Errorf("expected the code fence stripped, got %q", response)
+	}
+
+	// Without a system prompt the instruction becomes the system message
+	debug := DebugMessages(engine, "", "one color", LlmOptions{OutputFormat: OutputFormatYAML})
+	if len(debug) == 0 || debug[0].Content != markupInstructions[OutputFormatYAML] {
+		t.Errorf("expected the YAML instruction as system message, got %+v", debug)
+	}
+	anthropic, _ := NewLLM(LlmOptions{Provider: ProviderAnthropic, ApiKey: "test-key", Model: "claude-sonnet-4"})
+	if got := DebugMessages(anthropic, "system", "hello", LlmOptions{OutputFormat: OutputFormatXML}); got[0].Content != "system\n"+markupInstructions[OutputFormatXML] {
+		t.Errorf("Anthropic: expected the XML instruction, got %q", got[0].Content)
+	}
+}
+
+func TestPresenceAndFrequencyPenalty(t *testing.T) {
+	for _, provider := range []Provider{ProviderOpenAI, ProviderOpenRouter, ProviderMistral, ProviderGroq, ProviderDeepSeek} {
+		t.Run(string(provider), func(t *testing.T) {
+			var captured map[string]any
+			server := captureServer(t, `{"choices":[{"message":{"role":"assistant","content":"hi"}}]}`, &captured)
+			defer server.Close()
+
+			engine, err := NewLLM(LlmOptions{
+				Provider:        provider,
+				ApiKey:          "test-key",
+				Model:           "test-model",
+				PresencePenalty: PtrFloat64(0.5),
+				ProviderOptions: map[string]any{"base_url": server.URL},
+			})
+			if err != nil {
+				t.Fatalf("failed to create %s LLM: %v", provider, err)
+			}
+
+			if _, err := engine.GenerateText("system", "hello", LlmOptions{FrequencyPenalty: PtrFloat64(-1)}); err != nil {
+				t.Fatalf("GenerateText failed: %v", err)
+			}
+			if captured["presence_penalty"] != 0.5 || captured["frequency_penalty"] != float64(-1) {
+				t.Errorf("expected presence_penalty 0.5 and frequency_penalty -1, got %v and %v", captured["presence_penalty"], captured["frequency_penalty"])
+			}
+		})
+	}
+
+	// Unset values are not sent
+	var captured map[string]any
+	server := captureServer(t, `{"choices":[{"message":{"role":"assistant","content":"hi"}}]}`, &captured)
+	defer server.Close()
+	engine, err := NewLLM(LlmOptions{Provider: ProviderOpenAI, ApiKey: "test-key", ProviderOptions: map[string]any{"base_url": server.URL}})
+	if err != nil {
+		t.Fatalf("failed to create OpenAI LLM: %v", err)
+	}
+	if _, err := engine.GenerateText("system", "hello"); err != nil {
+		t.Fatalf("GenerateText failed: %v", err)
+	}
+	for _, key := range []string{"presence_penalty", "frequency_penalty"} {
+		if _, ok := captured[key]; ok {
+			t.Errorf("expected no %s when unset", key)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// sanitizeJSONResponse extracts the JSON from a model response that wraps it
+// in markdown code fences or surrounds it with prose. If no valid JSON can be
+// extracted the response is returned unchanged, so the raw text is kept for
+// error reporting.
+func sanitizeJSONResponse(s string) string {
+	trimmed := strings.TrimSpace(s)
+	if json.Valid([]byte(trimmed)) {
+		return trimmed
+	}
+
+	unfenced := stripCodeFences(extractCodeFence(trimmed))
+	if json.Valid([]byte(unfenced)) {
+		return unfenced
+	}
+
+	// Leading or trailing prose: take the first object or array
+	if extracted, ok := extractJSON(unfenced); ok {
+		return extracted
+	}
+
+	return s
+}
+
+// sanitizeJSONModeResponse returns the JSON of a JSON mode response like
+// sanitizeJSONResponse, or an error wrapping ErrInvalidJSON with the raw
+// response if it holds no valid JSON object or array
+func sanitizeJSONModeResponse(provider Provider, response string) (string, error) {
+	sanitized := sanitizeJSONResponse(response)
+	if !json.Valid([]byte(sanitized)) {
+		return "", fmt.Errorf("%w: no JSON object or array in the %s response: %s", ErrInvalidJSON, provider, response)
+	}
+	return sanitized, nil
+}
+
+// extractJSON returns the first balanced JSON object or array in text that
+// is valid JSON. Brackets inside JSON strings are skipped.
+func extractJSON(text string) (string, bool) {
+	for start := 0; start < len(text); start++ {
+		if text[start] != '{' && text[start] != '[' {
+			continue
+		}
+
+		if end := balancedJSONEnd(text, start); end > start && json.Valid([]byte(text[start:end+1])) {
+			return text[start : end+1], true
+		}
+	}
+	return "", false
+}
+
+// balancedJSONEnd returns the index of the bracket closing the one at
+// start, or -1 if it is not closed
+func balancedJSONEnd(text string, start int) int {
+	depth := 0
+	inString := false
+	escaped := false
+	for i := start; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case escaped:
+			escaped = false
+		case inString && c == '\\':
+			escaped = true
+		case c == '"':
+			inString = !inString
+		case inString:
+		case c == '{' || c == '[':
+			depth++
+		case c == '}' || c == ']':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// sanitizeMarkupResponse extracts an XML or YAML response from the
+// markdown code fences models often wrap it in
+func sanitizeMarkupResponse(s string) string {
+	return stripCodeFences(extractCodeFence(strings.TrimSpace(s)))
+}
+
+// extractCodeFence returns the first fenced code block in text, including
+// its fences, or text itself if it has no complete code block
+func extractCodeFence(text string) string {
+	start := strings.Index(text, "```")
+	if start < 0 {
+		return text
+	}
+	end := strings.Index(text[start+3:], "```")
+	if end < 0 {
+		return text
+	}
+	return text[start : start+3+end+3]
+}
+
+// stripCodeFences removes a surrounding markdown code fence, with or
+// without a language tag, from text
+func stripCodeFences(text string) string {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "```") {
+		return text
+	}
+
+	// Drop the opening fence line, including any language tag
+	if i := strings.Index(text, "\n"); i >= 0 {
+		text = text[i+1:]
+	} else {
+		text = strings.TrimPrefix(text, "```")
+	}
+
+	text = strings.TrimSpace(text)
+	text = strings.TrimSuffix(text, "```")
+
+	return strings.TrimSpace(text)
+}
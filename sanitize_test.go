@@ -0,0 +1,89 @@
+package llm
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSanitizeJSONResponse(t *testing.T) {
+	testCases := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"plain object", `{"a": 1}`, `{"a": 1}`},
+		{"plain array", ` [1, 2] `, `[1, 2]`},
+		{"json fence", "```json\n{\"a\": 1}\n```", `{"a": 1}`},
+		{"bare fence", "```\n[1, 2]\n```", `[1, 2]`},
+		{"prose before fence", "Here is the JSON:\n```json\n{\"a\": 1}\n```\nLet me know!", `{"a": 1}`},
+		{"prose without fence", `Sure! {"a": {"b": 2}} Hope this helps.`, `{"a": {"b": 2}}`},
+		{"prose before array", "The list: [\"x\", \"y\"]", `["x", "y"]`},
+		{"first of two objects", `Here: {"a": 1} and also {"b": 2}.`, `{"a": 1}`},
+		{"brackets in strings", `Result: {"a": "}", "b": "[\"x"} done`, `{"a": "}", "b": "[\"x"}`},
+		{"invalid braces skipped", `Use {placeholder} like {"a": 1}`, `{"a": 1}`},
+		{"invalid kept raw", "I cannot answer that.", "I cannot answer that."},
+		{"invalid fenced kept raw", "```json\n{not json}\n```", "```json\n{not json}\n```"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := sanitizeJSONResponse(tc.input); got != tc.want {
+				t.Errorf("sanitizeJSONResponse(%q) = %q, want %q", tc.input, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGenerateJSONSanitizesResponse(t *testing.T) {
+	engine, err := NewLLM(LlmOptions{
+		Provider:     ProviderMock,
+		MockResponse: "```json\n{\"name\": \"John\"}\n```",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create mock LLM: %v", err)
+	}
+
+	got, err := engine.GenerateJSON("system", "user")
+	if err != nil {
+		t.Fatalf("GenerateJSON failed: %v", err)
+	}
+	if got != `{"name": "John"}` {
+		t.Errorf("GenerateJSON() = %q", got)
+	}
+
+	text, err := engine.GenerateText("system", "user")
+	if err != nil {
+		t.Fatalf("GenerateText failed: %v", err)
+	}
+	if text != "```json\n{\"name\": \"John\"}\n```" {
+		t.Errorf("expected GenerateText to be left unchanged, got %q", text)
+	}
+}
+
+func TestSanitizeJSONModeResponse(t *testing.T) {
+	got, err := sanitizeJSONModeResponse(ProviderGemini, "Sure, here it is:\n{\"a\": [1, 2]}\nAnything else?")
+	if err != nil || got != `{"a": [1, 2]}` {
+		t.Errorf("expected the extracted object, got %q, %v", got, err)
+	}
+
+	if _, err := sanitizeJSONModeResponse(ProviderGemini, "I cannot answer that."); !errors.Is(err, ErrInvalidJSON) {
+		t.Errorf("expected ErrInvalidJSON without JSON, got %v", err)
+	}
+}
+
+func TestGeminiGenerateJSONExtractsJSON(t *testing.T) {
+	var captured map[string]any
+	server := captureServer(t, `{"candidates":[{"content":{"role":"model","parts":[{"text":"Here is the JSON you asked for: {\"name\": \"John\"} Let me know!"}]},"finishReason":"STOP"}]}`, &captured)
+	defer server.Close()
+
+	got, err := newTestGemini(t, server.URL).GenerateJSON("system", "user")
+	if err != nil || got != `{"name": "John"}` {
+		t.Errorf("expected the extracted JSON, got %q, %v", got, err)
+	}
+
+	prose := captureServer(t, `{"candidates":[{"content":{"role":"model","parts":[{"text":"No JSON today."}]},"finishReason":"STOP"}]}`, &captured)
+	defer prose.Close()
+	if _, err := newTestGemini(t, prose.URL).GenerateJSON("system", "user"); !errors.Is(err, ErrInvalidJSON) {
+		t.Errorf("expected ErrInvalidJSON for a response without JSON, got %v", err)
+	}
+}
@@ -0,0 +1,153 @@
+package llm
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// anthropicTLSEnv maps the TLS provider options to the environment
+// variables Anthropic also reads them from
+var anthropicTLSEnv = map[string]string{
+	"root_ca_file": "ANTHROPIC_ROOT_CA_FILE",
+	"root_ca_pem":  "ANTHROPIC_ROOT_CA_PEM",
+	"spki_hash":    "ANTHROPIC_EXPECTED_SPKI_HASH",
+}
+
+// secureHTTPOption returns the TLS provider option with the key. For
+// Anthropic it falls back to the "anthropic_" prefixed option and to the
+// ANTHROPIC_* environment variable.
+func secureHTTPOption(provider Provider, providerOptions map[string]any, key string) string {
+	if value := valueFromProviderOrEnv(providerOptions, key, ""); value != "" {
+		return value
+	}
+	if provider == ProviderAnthropic {
+		return valueFromProviderOrEnv(providerOptions, "anthropic_"+key, anthropicTLSEnv[key])
+	}
+	return ""
+}
+
+// hasSecureHTTPOptions reports whether a root CA or a certificate pin is
+// configured for the provider
+func hasSecureHTTPOptions(provider Provider, providerOptions map[string]any) bool {
+	for key := range anthropicTLSEnv {
+		if secureHTTPOption(provider, providerOptions, key) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// secureHTTPClient returns the HTTP client of an HTTP-based provider: a
+// client with the configured root CAs and certificate pin, or else the
+// caller's HTTPClient or a client with the configured timeout. The TLS
+// options cannot be combined with HTTPClient.
+func secureHTTPClient(provider Provider, options LlmOptions) (*http.Client, error) {
+	if !hasSecureHTTPOptions(provider, options.ProviderOptions) {
+		return providerHTTPClient(options), nil
+	}
+	if options.HTTPClient != nil {
+		return nil, fmt.Errorf("%s: the root CA and SPKI pinning options cannot be combined with HTTPClient", provider)
+	}
+	return buildSecureHTTPClient(provider, options.ProviderOptions, httpTimeout(options))
+}
+
+// buildSecureHTTPClient creates an HTTP client trusting the root CAs of
+// ProviderOptions["root_ca_pem"] and ["root_ca_file"] in addition to the
+// system ones, and pinning the server certificate to the base64 SHA-256
+// hash of its public key in ProviderOptions["spki_hash"]
+func buildSecureHTTPClient(provider Provider, providerOptions map[string]any, timeout time.Duration) (*http.Client, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	rootCAFile := secureHTTPOption(provider, providerOptions, "root_ca_file")
+	rootCAPEM := secureHTTPOption(provider, providerOptions, "root_ca_pem")
+	spkiHash := secureHTTPOption(provider, providerOptions, "spki_hash")
+
+	customRootCA := false
+	if rootCAFile != "" || rootCAPEM != "" {
+		rootPool, err := x509.SystemCertPool()
+		if err != nil || rootPool == nil {
+			rootPool = x509.NewCertPool()
+		}
+
+		if rootCAPEM != "" {
+			if ok := rootPool.AppendCertsFromPEM([]byte(rootCAPEM)); !ok {
+				return nil, fmt.Errorf("%s: invalid root CA PEM", provider)
+			}
+			customRootCA = true
+		}
+
+		if rootCAFile != "" {
+			pemBytes, err := os.ReadFile(rootCAFile)
+			if err != nil {
+				return nil, fmt.Errorf("%s: unable to read root CA file %s: %w", provider, rootCAFile, err)
+			}
+			if ok := rootPool.AppendCertsFromPEM(pemBytes); !ok {
+				return nil, fmt.Errorf("%s: invalid root CA file %s", provider, rootCAFile)
+			}
+			customRootCA = true
+		}
+
+		if customRootCA {
+			tlsConfig.RootCAs = rootPool
+		}
+	}
+
+	spkiHash = strings.TrimSpace(spkiHash)
+	spkiHash = strings.TrimPrefix(spkiHash, "sha256/")
+
+	if spkiHash != "" {
+		expectedPin, err := base64.StdEncoding.DecodeString(spkiHash)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid SPKI hash: %w", provider, err)
+		}
+
+		tlsConfig.VerifyConnection = func(state tls.ConnectionState) error {
+			if len(state.PeerCertificates) == 0 {
+				return fmt.Errorf("%s: no peer certificates for pinning", provider)
+			}
+
+			leaf := state.PeerCertificates[0]
+			hash := sha256.Sum256(leaf.RawSubjectPublicKeyInfo)
+			if subtle.ConstantTimeCompare(hash[:], expectedPin) != 1 {
+				return fmt.Errorf("%s: certificate pin mismatch", provider)
+			}
+
+			return nil
+		}
+	}
+
+	transport := &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		TLSClientConfig:     tlsConfig,
+		ForceAttemptHTTP2:   true,
+		TLSHandshakeTimeout: 10 * time.Second,
+	}
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+	}, nil
+}
+
+// valueFromProviderOrEnv returns the trimmed string or []byte provider
+// option with the key, or else the environment variable envKey, if set
+func valueFromProviderOrEnv(providerOptions map[string]any, key string, envKey string) string {
+	if v, ok := providerOptionString(providerOptions, key); ok && strings.TrimSpace(v) != "" {
+		return strings.TrimSpace(v)
+	}
+
+	if envKey == "" {
+		return ""
+	}
+	return strings.TrimSpace(os.Getenv(envKey))
+}
@@ -0,0 +1,77 @@
+package llm
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSecureHTTPClientPinning(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"hi"}}]}`))
+	}))
+	defer server.Close()
+
+	cert := server.Certificate()
+	rootCAPEM := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+	hash := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	pin := base64.StdEncoding.EncodeToString(hash[:])
+
+	newEngine := func(spkiHash string) LlmInterface {
+		engine, err := NewLLM(LlmOptions{
+			Provider: ProviderOpenAI,
+			ApiKey:   "test-key",
+			Model:    "gpt-4.1",
+			ProviderOptions: map[string]any{
+				"base_url":    server.URL,
+				"root_ca_pem": rootCAPEM,
+				"spki_hash":   spkiHash,
+			},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create OpenAI LLM: %v", err)
+		}
+		return engine
+	}
+
+	if _, err := newEngine("sha256/"+pin).GenerateText("system", "hello"); err != nil {
+		t.Errorf("expected the pinned certificate to be accepted, got %v", err)
+	}
+
+	otherHash := sha256.Sum256([]byte("another key"))
+	_, err := newEngine(base64.StdEncoding.EncodeToString(otherHash[:])).GenerateText("system", "hello")
+	if err == nil || !strings.Contains(err.Error(), "certificate pin mismatch") {
+		t.Errorf("expected a pin mismatch, got %v", err)
+	}
+}
+
+func TestSecureHTTPClientOptions(t *testing.T) {
+	_, err := NewLLM(LlmOptions{
+		Provider:        ProviderCustom,
+		ProviderOptions: map[string]any{"url": "http://localhost", "spki_hash": "not base64!"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "invalid SPKI hash") {
+		t.Errorf("expected an invalid SPKI hash error, got %v", err)
+	}
+
+	_, err = NewLLM(LlmOptions{
+		Provider:        ProviderGroq,
+		ApiKey:          "test-key",
+		HTTPClient:      &http.Client{},
+		ProviderOptions: map[string]any{"root_ca_file": "ca.pem"},
+	})
+	if err == nil {
+		t.Error("expected an error combining HTTPClient with the TLS options")
+	}
+
+	// Anthropic keeps reading its anthropic_ prefixed options
+	legacy := map[string]any{"anthropic_spki_hash": "abc"}
+	if !hasSecureHTTPOptions(ProviderAnthropic, legacy) || hasSecureHTTPOptions(ProviderOpenAI, legacy) {
+		t.Error("expected the anthropic_ prefixed options to apply to Anthropic only")
+	}
+}
@@ -0,0 +1,371 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SpendTracker accumulates the estimated cost of the calls made through
+// the clients it is attached to (with LlmOptions.SpendTracker), and stops
+// them with ErrBudgetExhausted once the ceiling is reached. A tracker can
+// be shared by several clients and is safe for concurrent use.
+type SpendTracker struct {
+	mu      sync.Mutex
+	ceiling float64
+	spent   float64
+	monthly bool
+	month   time.Time
+	now     func() time.Time
+}
+
+// NewSpendTracker creates a tracker with a ceiling in USD for its whole
+// lifetime (e.g. a session). A ceiling of zero or less only tracks spend.
+func NewSpendTracker(ceilingUSD float64) *SpendTracker {
+	return &SpendTracker{ceiling: ceilingUSD, now: time.Now}
+}
+
+// NewMonthlySpendTracker creates a tracker with a ceiling in USD per
+// calendar month (UTC). The spend is reset when a new month starts.
+func NewMonthlySpendTracker(ceilingUSD float64) *SpendTracker {
+	tracker := NewSpendTracker(ceilingUSD)
+	tracker.monthly = true
+	return tracker
+}
+
+// Spent returns the estimated spend in USD of the current period
+func (t *SpendTracker) Spent() float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.rollOver()
+	return t.spent
+}
+
+// Ceiling returns the configured ceiling in USD
+func (t *SpendTracker) Ceiling() float64 {
+	return t.ceiling
+}
+
+// Reset sets the spend back to zero
+func (t *SpendTracker) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.spent = 0
+}
+
+// check returns ErrBudgetExhausted when the ceiling has been reached
+func (t *SpendTracker) check() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.rollOver()
+	if t.ceiling > 0 && t.spent >= t.ceiling {
+		return fmt.Errorf("%w: spent $%.6f of $%.6f", ErrBudgetExhausted, t.spent, t.ceiling)
+	}
+	return nil
+}
+
+// add records the cost of a call
+func (t *SpendTracker) add(cost float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.rollOver()
+	t.spent += cost
+}
+
+// rollOver resets the spend of a monthly tracker when a new month has
+// started. The caller must hold t.mu.
+func (t *SpendTracker) rollOver() {
+	if !t.monthly {
+		return
+	}
+
+	now := t.now().UTC()
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if !month.Equal(t.month) {
+		t.month = month
+		t.spent = 0
+	}
+}
+
+// withSpendTracker wraps llm to record its calls in options.SpendTracker,
+// if one is set
+func withSpendTracker(llm LlmInterface, options LlmOptions) LlmInterface {
+	if options.SpendTracker == nil {
+		return llm
+	}
+	return &spendTrackingLLM{
+		llm:     llm,
+		model:   options.Model,
+		tracker: options.SpendTracker,
+		logger:  options.Logger,
+		verbose: options.Verbose,
+	}
+}
+
+// spendTrackingLLM records the estimated cost of the text generation calls
+// of the wrapped client, computed from the pricing catalog with the prompt
+// and response token counts, or the usage reported by
+// GenerateStreamWithUsage. Image and embedding calls, and the images sent
+// to GenerateWithImages, are stopped once the budget is exhausted but not
+// priced. Calls to a model missing from
+// the catalog are not priced either: a warning is logged once per model,
+// as the per-call Model may differ from the one known at construction.
+type spendTrackingLLM struct {
+	llm      LlmInterface
+	model    string
+	tracker  *SpendTracker
+	logger   *slog.Logger
+	verbose  bool
+	unpriced sync.Map
+}
+
+// track runs the call when the budget allows it, and records its cost.
+// A call returning text is billed even when it fails, e.g. with a response
+// truncated by ErrMaxTokensReached.
+func (s *spendTrackingLLM) track(prompt string, opts []LlmOptions, call func() (string, error)) (string, error) {
+	if err := s.tracker.check(); err != nil {
+		return "", err
+	}
+
+	response, err := call()
+	s.billText(opts, prompt, response)
+	return response, err
+}
+
+// billText bills a call with the token counts of its prompt and response,
+// if it returned text
+func (s *spendTrackingLLM) billText(opts []LlmOptions, prompt string, response string) {
+	if response == "" {
+		return
+	}
+	model := s.callModel(opts)
+	s.bill(model, CountTokensForModel(prompt, model), CountTokensForModel(response, model))
+}
+
+// callModel returns the model a call uses, after the provider defaults and
+// the per-call options are applied. The construction Model is the fallback
+// for clients not implementing EffectiveOptionsInterface.
+func (s *spendTrackingLLM) callModel(opts []LlmOptions) string {
+	if model := EffectiveOptions(s.llm, opts...).Model; model != "" {
+		return model
+	}
+	return s.model
+}
+
+// bill adds the cost of a call's tokens to the tracker, or warns when the
+// model has no pricing
+func (s *spendTrackingLLM) bill(model string, promptTokens int, completionTokens int) {
+	price, ok := lookupModelPrice(model)
+	if !ok {
+		if _, warned := s.unpriced.LoadOrStore(model, true); warned {
+			return
+		}
+		if s.logger != nil {
+			s.logger.Warn("spend not tracked: no pricing for model", slog.String("model", model))
+		} else if s.verbose {
+			fmt.Printf("spend not tracked: no pricing for model %s\n", model)
+		}
+		return
+	}
+
+	s.tracker.add(price.cost(promptTokens, completionTokens))
+}
+
+// GenerateText implements LlmInterface
+func (s *spendTrackingLLM) GenerateText(systemPrompt string, userPrompt string, opts ...LlmOptions) (string, error) {
+	return s.track(systemPrompt+"\n"+userPrompt, opts, func() (string, error) {
+		return s.llm.GenerateText(systemPrompt, userPrompt, opts...)
+	})
+}
+
+// GenerateJSON implements LlmInterface
+func (s *spendTrackingLLM) GenerateJSON(systemPrompt string, userPrompt string, opts ...LlmOptions) (string, error) {
+	return s.track(systemPrompt+"\n"+userPrompt, opts, func() (string, error) {
+		return s.llm.GenerateJSON(systemPrompt, userPrompt, opts...)
+	})
+}
+
+// GenerateXML implements LlmInterface
+func (s *spendTrackingLLM) GenerateXML(systemPrompt string, userPrompt string, opts ...LlmOptions) (string, error) {
+	return s.track(systemPrompt+"\n"+userPrompt, opts, func() (string, error) {
+		return s.llm.GenerateXML(systemPrompt, userPrompt, opts...)
+	})
+}
+
+// GenerateYAML implements LlmInterface
+func (s *spendTrackingLLM) GenerateYAML(systemPrompt string, userPrompt string, opts ...LlmOptions) (string, error) {
+	return s.track(systemPrompt+"\n"+userPrompt, opts, func() (string, error) {
+		return s.llm.GenerateYAML(systemPrompt, userPrompt, opts...)
+	})
+}
+
+// Generate implements LlmInterface
+func (s *spendTrackingLLM) Generate(systemPrompt string, userMessage string, opts ...LlmOptions) (string, error) {
+	return s.track(systemPrompt+"\n"+userMessage, opts, func() (string, error) {
+		return s.llm.Generate(systemPrompt, userMessage, opts...)
+	})
+}
+
+// GenerateChat implements ChatInterface
+func (s *spendTrackingLLM) GenerateChat(messages []Message, opts ...LlmOptions) (string, error) {
+	return s.track(messagesContent(messages), opts, func() (string, error) {
+		return GenerateChat(s.llm, messages, opts...)
+	})
+}
+
+// GenerateImage implements LlmInterface
+func (s *spendTrackingLLM) GenerateImage(prompt string, opts ...LlmOptions) ([]byte, error) {
+	if err := s.tracker.check(); err != nil {
+		return nil, err
+	}
+	return s.llm.GenerateImage(prompt, opts...)
+}
+
+// GenerateEmbedding implements LlmInterface
+func (s *spendTrackingLLM) GenerateEmbedding(text string) ([]float32, error) {
+	if err := s.tracker.check(); err != nil {
+		return nil, err
+	}
+	return s.llm.GenerateEmbedding(text)
+}
+
+// GenerateStream implements StreamInterface, billing the streamed text
+func (s *spendTrackingLLM) GenerateStream(systemPrompt string, userMessage string, onChunk func(chunk string) error, opts ...LlmOptions) error {
+	if err := s.tracker.check(); err != nil {
+		return err
+	}
+
+	var response strings.Builder
+	err := GenerateStream(s.llm, systemPrompt, userMessage, func(chunk string) error {
+		response.WriteString(chunk)
+		return onChunk(chunk)
+	}, opts...)
+	s.billText(opts, systemPrompt+"\n"+userMessage, response.String())
+	return err
+}
+
+// GenerateStreamWithUsage implements StreamUsageInterface, billing the
+// usage passed to onDone, or the streamed text when the stream fails
+func (s *spendTrackingLLM) GenerateStreamWithUsage(systemPrompt string, userMessage string, onChunk func(chunk string) error, onDone func(usage Usage), opts ...LlmOptions) error {
+	if err := s.tracker.check(); err != nil {
+		return err
+	}
+
+	var response strings.Builder
+	billed := false
+	err := GenerateStreamWithUsage(s.llm, systemPrompt, userMessage, func(chunk string) error {
+		response.WriteString(chunk)
+		return onChunk(chunk)
+	}, func(usage Usage) {
+		s.bill(s.callModel(opts), usage.PromptTokens, usage.CompletionTokens)
+		billed = true
+		if onDone != nil {
+			onDone(usage)
+		}
+	}, opts...)
+	if !billed {
+		s.billText(opts, systemPrompt+"\n"+userMessage, response.String())
+	}
+	return err
+}
+
+// GenerateWithImages implements VisionInterface. Only the text of the
+// prompt and response is billed.
+func (s *spendTrackingLLM) GenerateWithImages(systemPrompt string, userPrompt string, images [][]byte, opts ...LlmOptions) (string, error) {
+	return s.track(systemPrompt+"\n"+userPrompt, opts, func() (string, error) {
+		return GenerateWithImages(s.llm, systemPrompt, userPrompt, images, opts...)
+	})
+}
+
+// GenerateWithTools implements ToolInterface, billing the text and the
+// tool calls of the response
+func (s *spendTrackingLLM) GenerateWithTools(systemPrompt string, userPrompt string, tools []ToolDefinition, opts ...LlmOptions) (ToolResult, error) {
+	if err := s.tracker.check(); err != nil {
+		return ToolResult{}, err
+	}
+
+	result, err := GenerateWithTools(s.llm, systemPrompt, userPrompt, tools, opts...)
+	response := []string{result.Text}
+	for _, toolCall := range result.ToolCalls {
+		response = append(response, toolCall.Name, toolCall.Arguments)
+	}
+	s.billText(opts, systemPrompt+"\n"+userPrompt, strings.TrimSpace(strings.Join(response, "\n")))
+	return result, err
+}
+
+// GenerateN implements CandidatesInterface, billing the prompt once and
+// every candidate
+func (s *spendTrackingLLM) GenerateN(systemPrompt string, userPrompt string, opts ...LlmOptions) ([]string, error) {
+	if err := s.tracker.check(); err != nil {
+		return nil, err
+	}
+
+	candidates, err := GenerateN(s.llm, systemPrompt, userPrompt, opts...)
+	s.billText(opts, systemPrompt+"\n"+userPrompt, strings.Join(candidates, "\n"))
+	return candidates, err
+}
+
+// GenerateStructured implements StructuredOutputInterface
+func (s *spendTrackingLLM) GenerateStructured(systemPrompt string, userPrompt string, schema json.RawMessage, opts ...LlmOptions) (json.RawMessage, error) {
+	if err := s.tracker.check(); err != nil {
+		return nil, err
+	}
+
+	response, err := generateStructuredWith(s.llm, systemPrompt, userPrompt, schema, opts...)
+	s.billText(opts, systemPrompt+"\n"+userPrompt, string(response))
+	return response, err
+}
+
+// GenerateImageSize implements ImageSizeInterface
+func (s *spendTrackingLLM) GenerateImageSize(prompt string, width int, height int, opts ...LlmOptions) ([]byte, error) {
+	if err := s.tracker.check(); err != nil {
+		return nil, err
+	}
+	return generateImageSizeWith(s.llm, prompt, width, height, opts...)
+}
+
+// GenerateImageWithText implements ImageTextInterface
+func (s *spendTrackingLLM) GenerateImageWithText(prompt string, opts ...LlmOptions) ([]byte, string, error) {
+	if err := s.tracker.check(); err != nil {
+		return nil, "", err
+	}
+	return GenerateImageWithText(s.llm, prompt, opts...)
+}
+
+// ListModels implements ModelListInterface. Listing is free, so it is not
+// stopped by the budget.
+func (s *spendTrackingLLM) ListModels() ([]ModelInfo, error) {
+	return ListModels(s.llm)
+}
+
+// DebugMessages implements DebugMessagesInterface
+func (s *spendTrackingLLM) DebugMessages(systemPrompt string, userMessage string, opts ...LlmOptions) []Message {
+	return DebugMessages(s.llm, systemPrompt, userMessage, opts...)
+}
+
+// EffectiveOptions implements EffectiveOptionsInterface
+func (s *spendTrackingLLM) EffectiveOptions(opts ...LlmOptions) LlmOptions {
+	return EffectiveOptions(s.llm, opts...)
+}
+
+// LastRawResponse implements RawResponseRecorderInterface
+func (s *spendTrackingLLM) LastRawResponse() (RawResponse, bool) {
+	return lastRawResponse(s.llm)
+}
+
+// Close implements io.Closer, closing the wrapped client
+func (s *spendTrackingLLM) Close() error {
+	return Close(s.llm)
+}
+
+// unwrap implements wrapperInterface
+func (s *spendTrackingLLM) unwrap() LlmInterface {
+	return s.llm
+}
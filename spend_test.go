@@ -0,0 +1,217 @@
+package llm
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSpendTracker(t *testing.T) {
+	tracker := NewSpendTracker(0.01)
+	engine, err := NewLLM(LlmOptions{
+		Provider:     ProviderMock,
+		Model:        OPENROUTER_MODEL_CLAUDE_OPUS_4_6,
+		MockResponse: strings.Repeat("word ", 100),
+		SpendTracker: tracker,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create mock LLM: %v", err)
+	}
+
+	price, _ := lookupModelPrice(OPENROUTER_MODEL_CLAUDE_OPUS_4_6)
+	previous := 0.0
+	calls := 0
+	for ; calls < 100; calls++ {
+		_, err = engine.GenerateText("system", "Hello")
+		if err != nil {
+			break
+		}
+		if spent := tracker.Spent(); spent <= previous {
+			t.Fatalf("expected spend to grow after call %d, got %f", calls+1, spent)
+		}
+		previous = tracker.Spent()
+	}
+
+	if !errors.Is(err, ErrBudgetExhausted) {
+		t.Fatalf("expected ErrBudgetExhausted, got %v", err)
+	}
+	if calls < 2 {
+		t.Errorf("expected several calls before the ceiling, got %d", calls)
+	}
+	if tracker.Spent() < tracker.Ceiling() || tracker.Spent() > tracker.Ceiling()+price.cost(1000, 1000) {
+		t.Errorf("expected spend just above the ceiling, got %f", tracker.Spent())
+	}
+
+	if _, err := GenerateChat(engine, []Message{{Role: MessageRoleUser, Content: "Hello"}}); !errors.Is(err, ErrBudgetExhausted) {
+		t.Errorf("expected ErrBudgetExhausted from GenerateChat, got %v", err)
+	}
+
+	tracker.Reset()
+	if _, err := engine.GenerateText("system", "Hello"); err != nil {
+		t.Errorf("expected call to succeed after Reset, got %v", err)
+	}
+}
+
+func TestSpendTrackerUnknownModel(t *testing.T) {
+	var logs bytes.Buffer
+	tracker := NewSpendTracker(1)
+	engine, err := NewLLM(LlmOptions{
+		Provider:     ProviderMock,
+		Model:        "unknown-model",
+		MockResponse: "ok",
+		SpendTracker: tracker,
+		Logger:       slog.New(slog.NewTextHandler(&logs, nil)),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create mock LLM: %v", err)
+	}
+
+	for range 2 {
+		if _, err := engine.GenerateText("system", "Hello"); err != nil {
+			t.Fatalf("expected a model without pricing to be called, got %v", err)
+		}
+	}
+	if tracker.Spent() != 0 {
+		t.Errorf("expected no spend for a model without pricing, got %f", tracker.Spent())
+	}
+	if count := strings.Count(logs.String(), "no pricing for model"); count != 1 {
+		t.Errorf("expected one warning for the model, got %d in %q", count, logs.String())
+	}
+}
+
+func TestSpendTrackerBillsTruncatedResponse(t *testing.T) {
+	tracker := NewSpendTracker(1)
+	engine := withSpendTracker(&CustomTestLLM{
+		generateFunc: func(string, string, LlmOptions) (string, error) {
+			return "partial response", ErrMaxTokensReached
+		},
+	}, LlmOptions{Model: OPENROUTER_MODEL_CLAUDE_OPUS_4_6, SpendTracker: tracker})
+
+	response, err := engine.GenerateText("system", "Hello")
+	if !errors.Is(err, ErrMaxTokensReached) || response != "partial response" {
+		t.Fatalf("expected the truncated response with ErrMaxTokensReached, got %q, %v", response, err)
+	}
+	if tracker.Spent() <= 0 {
+		t.Error("expected the truncated response to be billed")
+	}
+}
+
+func TestMonthlySpendTracker(t *testing.T) {
+	now := time.Date(2025, time.January, 31, 23, 0, 0, 0, time.UTC)
+	tracker := NewMonthlySpendTracker(1)
+	tracker.now = func() time.Time { return now }
+
+	tracker.add(1)
+	if !errors.Is(tracker.check(), ErrBudgetExhausted) {
+		t.Error("expected the ceiling to be reached")
+	}
+
+	now = now.Add(2 * time.Hour)
+	if spent := tracker.Spent(); spent != 0 {
+		t.Errorf("expected spend to reset in a new month, got %f", spent)
+	}
+	if err := tracker.check(); err != nil {
+		t.Errorf("expected budget in a new month, got %v", err)
+	}
+}
+
+func TestSpendTrackerForwardsOptionalInterfaces(t *testing.T) {
+	var path string
+	server := usageStreamServer(t, &path)
+	defer server.Close()
+
+	tracker := NewSpendTracker(1)
+	engine, err := NewLLM(LlmOptions{
+		Provider:        ProviderOpenAI,
+		ApiKey:          "test-key",
+		Model:           "gpt-5-nano",
+		SpendTracker:    tracker,
+		ProviderOptions: map[string]any{"base_url": server.URL},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create LLM: %v", err)
+	}
+
+	var chunks []string
+	err = GenerateStreamWithUsage(engine, "system", "hello", func(chunk string) error {
+		chunks = append(chunks, chunk)
+		return nil
+	}, func(Usage) {})
+	if err != nil {
+		t.Fatalf("GenerateStreamWithUsage failed: %v", err)
+	}
+	if len(chunks) != 3 {
+		t.Errorf("expected the provider's stream, got chunks %q", chunks)
+	}
+
+	price, _ := lookupModelPrice("gpt-5-nano")
+	if expected := price.cost(12, 3); tracker.Spent() != expected {
+		t.Errorf("expected the reported usage to be billed ($%f), got $%f", expected, tracker.Spent())
+	}
+
+	exhausted := NewSpendTracker(0.000001)
+	exhausted.add(1)
+	mock, err := NewLLM(LlmOptions{Provider: ProviderMock, MockResponse: "ok", SpendTracker: exhausted})
+	if err != nil {
+		t.Fatalf("Failed to create mock LLM: %v", err)
+	}
+	if _, err := GenerateN(mock, "system", "hello", LlmOptions{N: 2}); !errors.Is(err, ErrBudgetExhausted) {
+		t.Errorf("expected ErrBudgetExhausted from GenerateN, got %v", err)
+	}
+}
+
+func TestSpendTrackerStreamWithoutOnDone(t *testing.T) {
+	var path string
+	server := usageStreamServer(t, &path)
+	defer server.Close()
+
+	tracker := NewSpendTracker(1)
+	engine, err := NewLLM(LlmOptions{
+		Provider:        ProviderOpenAI,
+		ApiKey:          "test-key",
+		Model:           "gpt-5-nano",
+		SpendTracker:    tracker,
+		ProviderOptions: map[string]any{"base_url": server.URL},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create LLM: %v", err)
+	}
+
+	err = GenerateStreamWithUsage(engine, "system", "hello", func(string) error { return nil }, nil)
+	if err != nil {
+		t.Fatalf("GenerateStreamWithUsage failed: %v", err)
+	}
+	if tracker.Spent() <= 0 {
+		t.Error("expected the stream to be billed without onDone")
+	}
+}
+
+func TestSpendTrackerDefaultModel(t *testing.T) {
+	SetDefaultModel(ProviderOpenAI, "gpt-5-nano")
+	defer SetDefaultModel(ProviderOpenAI, "")
+
+	var captured map[string]any
+	server := captureServer(t, `{"choices":[{"message":{"role":"assistant","content":"hi"}}]}`, &captured)
+	defer server.Close()
+
+	tracker := NewSpendTracker(1)
+	engine, err := NewLLM(LlmOptions{
+		Provider:        ProviderOpenAI,
+		ApiKey:          "test-key",
+		SpendTracker:    tracker,
+		ProviderOptions: map[string]any{"base_url": server.URL},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create LLM: %v", err)
+	}
+
+	if _, err := engine.GenerateText("system", "hello"); err != nil {
+		t.Fatalf("GenerateText failed: %v", err)
+	}
+	if captured["model"] != "gpt-5-nano" || tracker.Spent() <= 0 {
+		t.Errorf("expected the call to the default model %v to be billed, spent %f", captured["model"], tracker.Spent())
+	}
+}
@@ -0,0 +1,33 @@
+package llm
+
+import (
+	"encoding/json"
+)
+
+// anthropicStopMaxTokens is Anthropic's stop_reason for a response cut off
+// at max_tokens
+const anthropicStopMaxTokens = "max_tokens"
+
+// StopReason parses why the response ended: Anthropic's stop_reason (e.g.
+// "end_turn", "max_tokens", "stop_sequence", "tool_use") or the
+// finish_reason of the first choice of an OpenAI style response (e.g.
+// "stop", "length"). It returns false if the response reports none.
+func (r RawResponse) StopReason() (string, bool) {
+	var body struct {
+		StopReason string `json:"stop_reason"`
+		Choices    []struct {
+			FinishReason string `json:"finish_reason"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(r.Body, &body); err != nil {
+		return "", false
+	}
+
+	if body.StopReason != "" {
+		return body.StopReason, true
+	}
+	if len(body.Choices) > 0 && body.Choices[0].FinishReason != "" {
+		return body.Choices[0].FinishReason, true
+	}
+	return "", false
+}
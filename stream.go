@@ -0,0 +1,138 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// StreamInterface is implemented by providers that can stream the response
+// text as it is generated (currently OpenAI and OpenRouter)
+type StreamInterface interface {
+	// GenerateStream calls onChunk with each piece of the response text as
+	// it arrives. An error returned by onChunk stops the stream and is
+	// returned.
+	GenerateStream(systemPrompt string, userMessage string, onChunk func(chunk string) error, options ...LlmOptions) error
+}
+
+// StreamUsageInterface is implemented by providers that report the token
+// usage of a streamed response (currently OpenAI and OpenRouter, which ask
+// for it with the include_usage stream option)
+type StreamUsageInterface interface {
+	// GenerateStreamWithUsage streams the response like GenerateStream, then
+	// calls onDone with the token usage once the stream has completed.
+	// onDone may be nil.
+	GenerateStreamWithUsage(systemPrompt string, userMessage string, onChunk func(chunk string) error, onDone func(usage Usage), options ...LlmOptions) error
+}
+
+// GenerateStream streams the response when the llm implements
+// StreamInterface, otherwise onChunk is called once with the full response
+// of Generate
+func GenerateStream(llm LlmInterface, systemPrompt string, userMessage string, onChunk func(chunk string) error, options ...LlmOptions) error {
+	if stream, ok := llm.(StreamInterface); ok {
+		return stream.GenerateStream(systemPrompt, userMessage, onChunk, options...)
+	}
+
+	response, err := llm.Generate(systemPrompt, userMessage, options...)
+	if err != nil {
+		return err
+	}
+	return onChunk(response)
+}
+
+// GenerateStreamWithUsage streams the response like GenerateStream, then
+// calls onDone with the token usage, e.g. to bill by tokens. Providers that
+// do not implement StreamUsageInterface get the usage estimated with
+// CountTokensForModel from the prompt and the streamed response. onDone is
+// not called when the stream fails, and may be nil to only stream.
+func GenerateStreamWithUsage(llm LlmInterface, systemPrompt string, userMessage string, onChunk func(chunk string) error, onDone func(usage Usage), options ...LlmOptions) error {
+	if stream, ok := llm.(StreamUsageInterface); ok {
+		return stream.GenerateStreamWithUsage(systemPrompt, userMessage, onChunk, onDone, options...)
+	}
+
+	var response strings.Builder
+	err := GenerateStream(llm, systemPrompt, userMessage, func(chunk string) error {
+		response.WriteString(chunk)
+		return onChunk(chunk)
+	}, options...)
+	if err != nil {
+		return err
+	}
+
+	if onDone == nil {
+		return nil
+	}
+
+	model := ""
+	if len(options) > 0 {
+		model = options[0].Model
+	}
+	onDone(estimateUsage(systemPrompt+"\n"+userMessage, response.String(), model))
+	return nil
+}
+
+// GenerateStreamTo streams the response, appending each chunk to sb.
+// Chunks received before an error are kept in sb.
+func GenerateStreamTo(llm LlmInterface, sb *strings.Builder, systemPrompt string, userMessage string, options ...LlmOptions) error {
+	return GenerateStream(llm, systemPrompt, userMessage, func(chunk string) error {
+		sb.WriteString(chunk)
+		return nil
+	}, options...)
+}
+
+// openaiChatStream sends the request as a streaming chat completion and
+// calls onChunk with each non-empty content delta. With includeUsage, the
+// include_usage stream option is set and the usage reported in the last
+// chunk is returned (nil if the server did not report it).
+func openaiChatStream(ctx context.Context, client *openai.Client, req openai.ChatCompletionRequest, includeUsage bool, onChunk func(chunk string) error) (*openai.Usage, error) {
+	req.Stream = true
+	if includeUsage {
+		req.StreamOptions = &openai.StreamOptions{IncludeUsage: true}
+	}
+	stream, err := client.CreateChatCompletionStream(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+
+	var usage *openai.Usage
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return usage, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if resp.Usage != nil {
+			usage = resp.Usage
+		}
+		if len(resp.Choices) == 0 || resp.Choices[0].Delta.Content == "" {
+			continue
+		}
+		if err := onChunk(resp.Choices[0].Delta.Content); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// streamUsage converts the usage reported by an OpenAI-style stream, or
+// estimates it from the prompt and response when it was not reported
+func streamUsage(usage *openai.Usage, prompt string, response string, model string) Usage {
+	if usage == nil {
+		return estimateUsage(prompt, response, model)
+	}
+
+	result := Usage{
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		TotalTokens:      usage.TotalTokens,
+	}
+	if usage.PromptTokensDetails != nil {
+		result.CacheReadTokens = usage.PromptTokensDetails.CachedTokens
+	}
+	return result.withEstimatedCost(model)
+}
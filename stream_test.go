@@ -0,0 +1,175 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestGenerateStreamToOpenai(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range []string{"Hello", ", ", "world"} {
+			fmt.Fprintf(w, "data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":%q}}]}\n\n", chunk)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	config := openai.DefaultConfig("test-key")
+	config.BaseURL = server.URL
+	engine := &openaiImplementation{
+		client:               openai.NewClientWithConfig(config),
+		model:                "gpt-4o",
+		lastResponseRecorder: newLastResponseRecorder(nil),
+	}
+
+	var sb strings.Builder
+	if err := GenerateStreamTo(engine, &sb, "system", "hello"); err != nil {
+		t.Fatalf("GenerateStreamTo failed: %v", err)
+	}
+	if sb.String() != "Hello, world" {
+		t.Errorf("expected full response in builder, got %q", sb.String())
+	}
+}
+
+func TestGenerateStreamSplitRuneIsValidUTF8(t *testing.T) {
+	// "é" is 0xC3 0xA9, sent split across two events
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range []string{"caf\xc3", "\xa9 ok"} {
+			fmt.Fprintf(w, "data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"%s\"}}]}\n\n", chunk)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	config := openai.DefaultConfig("test-key")
+	config.BaseURL = server.URL
+	engine := &openaiImplementation{
+		client:               openai.NewClientWithConfig(config),
+		model:                "gpt-4o",
+		lastResponseRecorder: newLastResponseRecorder(nil),
+	}
+
+	chunks := 0
+	err := engine.GenerateStream("system", "hello", func(chunk string) error {
+		chunks++
+		if !utf8.ValidString(chunk) {
+			t.Errorf("chunk is not valid UTF-8: %q", chunk)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("GenerateStream failed: %v", err)
+	}
+	if chunks != 2 {
+		t.Errorf("expected 2 chunks, got %d", chunks)
+	}
+}
+
+func TestGenerateStreamToFallback(t *testing.T) {
+	engine, err := NewLLM(LlmOptions{Provider: ProviderMock, MockResponse: "mock response"})
+	if err != nil {
+		t.Fatalf("Failed to create mock LLM: %v", err)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("> ")
+	if err := GenerateStreamTo(engine, &sb, "system", "hello"); err != nil {
+		t.Fatalf("GenerateStreamTo failed: %v", err)
+	}
+	if sb.String() != "> mock response" {
+		t.Errorf("expected response appended to builder, got %q", sb.String())
+	}
+}
+
+// usageStreamServer streams "Hello, world" and, when the request asks for
+// it with include_usage, a last chunk with the usage
+func usageStreamServer(t *testing.T, path *string) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*path = r.URL.Path
+		var body struct {
+			StreamOptions struct {
+				IncludeUsage bool `json:"include_usage"`
+			} `json:"stream_options"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range []string{"Hello", ", ", "world"} {
+			fmt.Fprintf(w, "data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":%q}}]}\n\n", chunk)
+		}
+		if body.StreamOptions.IncludeUsage {
+			fmt.Fprint(w, "data: {\"choices\":[],\"usage\":{\"prompt_tokens\":12,\"completion_tokens\":3,\"total_tokens\":15,\"prompt_tokens_details\":{\"cached_tokens\":4}}}\n\n")
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+}
+
+func TestGenerateStreamWithUsage(t *testing.T) {
+	var path string
+	server := usageStreamServer(t, &path)
+	defer server.Close()
+
+	for _, provider := range []Provider{ProviderOpenAI, ProviderOpenRouter} {
+		engine, err := NewLLM(LlmOptions{
+			Provider:        provider,
+			ApiKey:          "test-key",
+			Model:           "gpt-4.1",
+			ProviderOptions: map[string]any{"base_url": server.URL},
+		})
+		if err != nil {
+			t.Fatalf("%s: failed to create LLM: %v", provider, err)
+		}
+
+		var sb strings.Builder
+		var usage Usage
+		done := false
+		err = GenerateStreamWithUsage(engine, "system", "hello", func(chunk string) error {
+			sb.WriteString(chunk)
+			return nil
+		}, func(u Usage) {
+			usage = u
+			done = true
+		})
+		if err != nil {
+			t.Fatalf("%s: GenerateStreamWithUsage failed: %v", provider, err)
+		}
+		if sb.String() != "Hello, world" || path != "/chat/completions" {
+			t.Errorf("%s: expected the streamed response, got %q from %s", provider, sb.String(), path)
+		}
+		expected := Usage{PromptTokens: 12, CompletionTokens: 3, TotalTokens: 15, CacheReadTokens: 4}
+		if !done || usage != expected {
+			t.Errorf("%s: expected the reported usage %+v, got %+v", provider, expected, usage)
+		}
+	}
+}
+
+func TestGenerateStreamWithUsageFallback(t *testing.T) {
+	engine, err := NewLLM(LlmOptions{Provider: ProviderMock, MockResponse: "mock response"})
+	if err != nil {
+		t.Fatalf("Failed to create mock LLM: %v", err)
+	}
+
+	var usage Usage
+	err = GenerateStreamWithUsage(engine, "system", "hello", func(string) error { return nil }, func(u Usage) { usage = u })
+	if err != nil {
+		t.Fatalf("GenerateStreamWithUsage failed: %v", err)
+	}
+	if usage.PromptTokens != CountTokens("system\nhello") || usage.CompletionTokens != CountTokens("mock response") ||
+		usage.TotalTokens != usage.PromptTokens+usage.CompletionTokens {
+		t.Errorf("expected the usage estimated with CountTokens, got %+v", usage)
+	}
+
+	if err := GenerateStreamWithUsage(engine, "system", "hello", func(string) error { return nil }, nil); err != nil {
+		t.Errorf("expected a nil onDone to only stream, got %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+package llm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// StructuredOutputInterface is implemented by providers that can enforce a
+// JSON schema on the response (currently OpenAI, OpenRouter, Gemini and Vertex)
+type StructuredOutputInterface interface {
+	// GenerateStructured generates a JSON response conforming to schema.
+	// The schema is passed to the provider's native structured output
+	// support, and the response is validated against it before returning.
+	// A non-conforming response returns an error wrapping ErrSchemaMismatch.
+	GenerateStructured(systemPrompt string, userPrompt string, schema json.RawMessage, options ...LlmOptions) (json.RawMessage, error)
+}
+
+// generateStructuredWith calls the GenerateStructured of llm, returning an
+// error wrapping ErrNotSupported if it does not implement
+// StructuredOutputInterface
+func generateStructuredWith(llm LlmInterface, systemPrompt string, userPrompt string, schema json.RawMessage, options ...LlmOptions) (json.RawMessage, error) {
+	structured, ok := llm.(StructuredOutputInterface)
+	if !ok {
+		return nil, fmt.Errorf("%w: structured output", ErrNotSupported)
+	}
+	return structured.GenerateStructured(systemPrompt, userPrompt, schema, options...)
+}
+
+// structuredSchemaURL is the resource name the schema is compiled under
+const structuredSchemaURL = "schema.json"
+
+// generateStructured implements GenerateStructured on top of a provider's
+// GenerateJSON, which reads the schema from LlmOptions.responseSchema
+func generateStructured(
+	generateJSON func(systemPrompt string, userPrompt string, options ...LlmOptions) (string, error),
+	systemPrompt string,
+	userPrompt string,
+	schema json.RawMessage,
+	opts ...LlmOptions,
+) (json.RawMessage, error) {
+	compiled, err := compileSchema(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	perCall := LlmOptions{}
+	if len(opts) > 0 {
+		perCall = opts[0]
+	}
+	perCall.responseSchema = schema
+
+	response, err := generateJSON(systemPrompt, userPrompt, perCall)
+	if err != nil {
+		return nil, err
+	}
+
+	return validateStructuredResponse(compiled, response)
+}
+
+// compileSchema compiles a JSON schema document
+func compileSchema(schema json.RawMessage) (*jsonschema.Schema, error) {
+	document, err := jsonschema.UnmarshalJSON(bytes.NewReader(schema))
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(structuredSchemaURL, document); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+
+	compiled, err := compiler.Compile(structuredSchemaURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+
+	return compiled, nil
+}
+
+// validateStructuredResponse checks that response is JSON conforming to schema
+func validateStructuredResponse(schema *jsonschema.Schema, response string) (json.RawMessage, error) {
+	response = strings.TrimSpace(response)
+
+	value, err := jsonschema.UnmarshalJSON(strings.NewReader(response))
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid JSON: %v", ErrSchemaMismatch, err)
+	}
+
+	if err := schema.Validate(value); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSchemaMismatch, err)
+	}
+
+	return json.RawMessage(response), nil
+}
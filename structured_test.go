@@ -0,0 +1,127 @@
+package llm
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+const testPersonSchema = `{
+	"type": "object",
+	"properties": {
+		"name": {"type": "string"},
+		"age": {"type": "integer"}
+	},
+	"required": ["name", "age"],
+	"additionalProperties": false
+}`
+
+// newTestOpenAI returns an OpenAI implementation talking to a test server
+// that replies with content and records the request's response_format
+func newTestOpenAI(t *testing.T, content string, responseFormat *map[string]any) *openaiImplementation {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			ResponseFormat map[string]any `json:"response_format"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		*responseFormat = body.ResponseFormat
+
+		reply, _ := json.Marshal(map[string]any{
+			"choices": []map[string]any{
+				{"message": map[string]any{"role": "assistant", "content": content}},
+			},
+		})
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(reply)
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := openai.DefaultConfig("test-key")
+	cfg.BaseURL = server.URL
+	return &openaiImplementation{
+		client:      openai.NewClientWithConfig(cfg),
+		model:       openai.GPT4oMini,
+		temperature: 0.7,
+	}
+}
+
+func TestGenerateStructured(t *testing.T) {
+	var responseFormat map[string]any
+	impl := newTestOpenAI(t, `{"name": "John", "age": 30}`, &responseFormat)
+
+	result, err := impl.GenerateStructured("system", "Extract the person", json.RawMessage(testPersonSchema))
+	if err != nil {
+		t.Fatalf("GenerateStructured failed: %v", err)
+	}
+	if string(result) != `{"name": "John", "age": 30}` {
+		t.Errorf("unexpected result: %s", result)
+	}
+
+	if responseFormat["type"] != "json_schema" {
+		t.Fatalf("expected json_schema response format, got %v", responseFormat)
+	}
+	jsonSchema, _ := responseFormat["json_schema"].(map[string]any)
+	if jsonSchema["strict"] != true || jsonSchema["schema"] == nil {
+		t.Errorf("expected strict schema to be sent, got %v", jsonSchema)
+	}
+}
+
+func TestGenerateStructuredSchemaMismatch(t *testing.T) {
+	var responseFormat map[string]any
+	impl := newTestOpenAI(t, `{"name": "John", "age": "thirty"}`, &responseFormat)
+
+	_, err := impl.GenerateStructured("system", "Extract the person", json.RawMessage(testPersonSchema))
+	if !errors.Is(err, ErrSchemaMismatch) {
+		t.Fatalf("expected ErrSchemaMismatch, got %v", err)
+	}
+
+	impl = newTestOpenAI(t, `not json`, &responseFormat)
+	_, err = impl.GenerateStructured("system", "Extract the person", json.RawMessage(testPersonSchema))
+	if !errors.Is(err, ErrSchemaMismatch) {
+		t.Fatalf("expected ErrSchemaMismatch for invalid JSON, got %v", err)
+	}
+}
+
+func TestGenerateStructuredInvalidSchema(t *testing.T) {
+	var responseFormat map[string]any
+	impl := newTestOpenAI(t, `{}`, &responseFormat)
+
+	if _, err := impl.GenerateStructured("system", "user", json.RawMessage(`{"type": 5}`)); err == nil {
+		t.Fatal("expected error for invalid schema")
+	}
+	if responseFormat != nil {
+		t.Error("expected no request to be sent for an invalid schema")
+	}
+}
+
+func TestVertexSchemaFromJSON(t *testing.T) {
+	schema, err := vertexSchemaFromJSON(json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"name": {"type": ["string", "null"]},
+			"tags": {"type": "array", "items": {"type": "string", "enum": ["a", "b"]}}
+		},
+		"required": ["name"]
+	}`))
+	if err != nil {
+		t.Fatalf("vertexSchemaFromJSON failed: %v", err)
+	}
+
+	if len(schema.Required) != 1 || schema.Required[0] != "name" {
+		t.Errorf("unexpected required: %v", schema.Required)
+	}
+	name := schema.Properties["name"]
+	if name == nil || !name.Nullable {
+		t.Errorf("expected nullable name property, got %+v", name)
+	}
+	tags := schema.Properties["tags"]
+	if tags == nil || tags.Items == nil || len(tags.Items.Enum) != 2 {
+		t.Errorf("expected tags array of enum strings, got %+v", tags)
+	}
+}
@@ -0,0 +1,39 @@
+package llm
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// RenderPrompt renders a prompt from a text/template template and its
+// variables, e.g. "Summarize in {{.Language}}". A variable missing from
+// vars is an error rather than "<no value>".
+func RenderPrompt(tmpl string, vars map[string]any) (string, error) {
+	parsed, err := template.New("prompt").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse prompt template: %w", err)
+	}
+
+	var prompt strings.Builder
+	if err := parsed.Execute(&prompt, vars); err != nil {
+		return "", fmt.Errorf("failed to render prompt template: %w", err)
+	}
+	return prompt.String(), nil
+}
+
+// GenerateTemplate renders the system and user prompt templates with the
+// variables, as RenderPrompt does, and generates a response with Generate
+func GenerateTemplate(llm LlmInterface, systemTmpl string, userTmpl string, vars map[string]any, options ...LlmOptions) (string, error) {
+	systemPrompt, err := RenderPrompt(systemTmpl, vars)
+	if err != nil {
+		return "", fmt.Errorf("system prompt: %w", err)
+	}
+
+	userPrompt, err := RenderPrompt(userTmpl, vars)
+	if err != nil {
+		return "", fmt.Errorf("user prompt: %w", err)
+	}
+
+	return llm.Generate(systemPrompt, userPrompt, options...)
+}
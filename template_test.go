@@ -0,0 +1,47 @@
+package llm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderPrompt(t *testing.T) {
+	prompt, err := RenderPrompt("Translate to {{.Language}}: {{.Text}}", map[string]any{"Language": "French", "Text": "hello"})
+	if err != nil {
+		t.Fatalf("RenderPrompt failed: %v", err)
+	}
+	if prompt != "Translate to French: hello" {
+		t.Errorf("unexpected prompt: %q", prompt)
+	}
+
+	if _, err := RenderPrompt("Translate to {{.Language}}", map[string]any{}); err == nil {
+		t.Error("expected an error for a missing variable")
+	}
+	if _, err := RenderPrompt("{{.Language", nil); err == nil {
+		t.Error("expected an error for an invalid template")
+	}
+}
+
+func TestGenerateTemplate(t *testing.T) {
+	mock := NewRecordingMock(LlmOptions{MockResponse: "bonjour"})
+
+	response, err := GenerateTemplate(mock, "You translate to {{.Language}}.", "{{.Text}}", map[string]any{"Language": "French", "Text": "hello"})
+	if err != nil {
+		t.Fatalf("GenerateTemplate failed: %v", err)
+	}
+	if response != "bonjour" {
+		t.Errorf("expected the mock response, got %q", response)
+	}
+
+	call, _ := mock.LastCall()
+	if call.SystemPrompt != "You translate to French." || call.UserPrompt != "hello" {
+		t.Errorf("expected the rendered prompts, got %q and %q", call.SystemPrompt, call.UserPrompt)
+	}
+
+	if _, err := GenerateTemplate(mock, "{{.Missing}}", "hello", nil); err == nil || !strings.Contains(err.Error(), "system prompt") {
+		t.Errorf("expected a system prompt error, got %v", err)
+	}
+	if len(mock.Calls()) != 1 {
+		t.Errorf("expected no call after a render error, got %d calls", len(mock.Calls()))
+	}
+}
@@ -0,0 +1,61 @@
+package llm
+
+import (
+	"maps"
+	"sync"
+)
+
+var (
+	// testModeMu protects testModeResponses
+	testModeMu sync.RWMutex
+	// testModeResponses holds the scripted responses while test mode is on,
+	// nil otherwise
+	testModeResponses map[string]string
+)
+
+// SetTestMode makes NewLLM, TextModel, JSONModel, ImageModel and
+// NewRegistry return mock engines, whatever the requested provider, until
+// ClearTestMode is called. The mocks answer with the response whose key
+// is the user message, or else the response with the "" key. No
+// credentials are required in test mode.
+func SetTestMode(responses map[string]string) {
+	testModeMu.Lock()
+	defer testModeMu.Unlock()
+
+	testModeResponses = maps.Clone(responses)
+	if testModeResponses == nil {
+		testModeResponses = map[string]string{}
+	}
+}
+
+// ClearTestMode turns test mode off, so real providers are created again
+func ClearTestMode() {
+	testModeMu.Lock()
+	defer testModeMu.Unlock()
+
+	testModeResponses = nil
+}
+
+// testModeLLM returns a mock serving the scripted responses when test mode
+// is on
+func testModeLLM(options LlmOptions) (LlmInterface, bool) {
+	testModeMu.RLock()
+	defer testModeMu.RUnlock()
+
+	if testModeResponses == nil {
+		return nil, false
+	}
+
+	return &mockImplementation{
+		options:   options,
+		responses: maps.Clone(testModeResponses),
+	}, true
+}
+
+// isTestMode reports whether test mode is on
+func isTestMode() bool {
+	testModeMu.RLock()
+	defer testModeMu.RUnlock()
+
+	return testModeResponses != nil
+}
@@ -0,0 +1,44 @@
+package llm
+
+import "testing"
+
+func TestSetTestMode(t *testing.T) {
+	SetTestMode(map[string]string{
+		"What is 2+2?": "4",
+		"":             "default answer",
+	})
+	defer ClearTestMode()
+
+	// A real provider without credentials returns the mock
+	engine, err := TextModel(ProviderOpenAI, LlmOptions{})
+	if err != nil {
+		t.Fatalf("expected a mock in test mode, got error: %v", err)
+	}
+	if _, ok := engine.(*mockImplementation); !ok {
+		t.Fatalf("expected a mock in test mode, got %T", engine)
+	}
+
+	response, err := engine.GenerateText("system", "What is 2+2?")
+	if err != nil || response != "4" {
+		t.Errorf("expected scripted response 4, got %q (err=%v)", response, err)
+	}
+
+	response, err = engine.GenerateText("system", "anything else")
+	if err != nil || response != "default answer" {
+		t.Errorf("expected default response, got %q (err=%v)", response, err)
+	}
+
+	engine, err = NewLLM(LlmOptions{Provider: ProviderAnthropic, Model: "claude-sonnet-4"})
+	if err != nil {
+		t.Fatalf("expected a mock from NewLLM in test mode, got error: %v", err)
+	}
+	if response, _ := engine.GenerateText("system", "What is 2+2?"); response != "4" {
+		t.Errorf("expected scripted response from NewLLM mock, got %q", response)
+	}
+
+	ClearTestMode()
+
+	if _, err := TextModel(ProviderOpenAI, LlmOptions{}); err == nil {
+		t.Error("expected real provider validation after ClearTestMode")
+	}
+}
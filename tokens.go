@@ -1,17 +1,128 @@
 package llm
 
 import (
+	"math"
 	"strings"
+	"sync"
+
+	"github.com/pkoukk/tiktoken-go"
+	tiktoken_loader "github.com/pkoukk/tiktoken-go-loader"
+)
+
+var (
+	// tokenizerMu protects tokenizers from concurrent access
+	tokenizerMu sync.Mutex
+	// tokenizers caches the tiktoken encoders by encoding name
+	tokenizers = make(map[string]*tiktoken.Tiktoken)
 )
 
-// CountTokens provides a simple approximation of token counting
-// Note: This is a basic implementation and not accurate for all models
-// Production code should use model-specific tokenizers
+func init() {
+	// Use the embedded BPE files instead of downloading them at runtime
+	tiktoken.SetBpeLoader(tiktoken_loader.NewOfflineLoader())
+}
+
+// CountTokens counts the tokens in text using the cl100k_base encoding.
+// Use CountTokensForModel when the target model is known.
 func CountTokens(text string) int {
+	return CountTokensForModel(text, "")
+}
+
+// CountTokensForModel counts the tokens in text using the tiktoken encoding
+// of the given model. Vendor prefixes such as "openai/" are ignored, and
+// models without a known encoding (including non-OpenAI models) fall back
+// to cl100k_base, which is a reasonable estimate for most modern tokenizers.
+func CountTokensForModel(text string, model string) int {
 	if text == "" {
 		return 0
 	}
 
+	encoder, err := tokenizerForEncoding(encodingNameForModel(model))
+	if err != nil {
+		return countTokensApprox(text)
+	}
+
+	return len(encoder.Encode(text, nil, nil))
+}
+
+// Chat format overhead of OpenAI's models: each message is framed as
+// <|start|>{role}<|message|>{content}<|end|>, and the reply is primed with
+// <|start|>assistant<|message|>
+const (
+	chatTokensPerMessage = 3
+	chatTokensPerReply   = 3
+)
+
+// CountChatTokens counts the prompt tokens of a chat request with the
+// messages, including the framing of each message and the priming of the
+// reply in OpenAI's chat format, using the tiktoken encoding of the model
+// (see CountTokensForModel). Images attached to the messages are not
+// counted, see ImageTokenCost. It returns 0 for no messages.
+func CountChatTokens(messages []Message, model string) int {
+	if len(messages) == 0 {
+		return 0
+	}
+
+	tokens := chatTokensPerReply
+	for _, message := range messages {
+		tokens += chatTokensPerMessage
+		tokens += CountTokensForModel(message.Role, model)
+		tokens += CountTokensForModel(message.Content, model)
+	}
+	return tokens
+}
+
+// encodingNameForModel returns the tiktoken encoding name for a model
+func encodingNameForModel(model string) string {
+	model = strings.ToLower(strings.TrimSpace(model))
+	if i := strings.LastIndex(model, "/"); i >= 0 {
+		model = model[i+1:]
+	}
+
+	if model == "" {
+		return tiktoken.MODEL_CL100K_BASE
+	}
+
+	if encoding, ok := tiktoken.MODEL_TO_ENCODING[model]; ok {
+		return encoding
+	}
+
+	for prefix, encoding := range tiktoken.MODEL_PREFIX_TO_ENCODING {
+		if strings.HasPrefix(model, prefix) {
+			return encoding
+		}
+	}
+
+	// Newer OpenAI models not yet known to tiktoken-go
+	for _, prefix := range []string{"gpt-5", "gpt-oss", "o1", "o3", "o4"} {
+		if strings.HasPrefix(model, prefix) {
+			return tiktoken.MODEL_O200K_BASE
+		}
+	}
+
+	return tiktoken.MODEL_CL100K_BASE
+}
+
+// tokenizerForEncoding returns a cached encoder for the encoding name
+func tokenizerForEncoding(encoding string) (*tiktoken.Tiktoken, error) {
+	tokenizerMu.Lock()
+	defer tokenizerMu.Unlock()
+
+	if encoder, ok := tokenizers[encoding]; ok {
+		return encoder, nil
+	}
+
+	encoder, err := tiktoken.GetEncoding(encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenizers[encoding] = encoder
+	return encoder, nil
+}
+
+// countTokensApprox approximates the token count as words plus punctuation.
+// It is only used if the tokenizer cannot be loaded.
+func countTokensApprox(text string) int {
 	// Count words
 	words := strings.Fields(text)
 	tokenCount := len(words)
@@ -35,3 +146,32 @@ func EstimateMaxTokens(promptTokens, contextWindowSize int) int {
 
 	return contextWindowSize - promptTokens
 }
+
+// ImageTokenCost estimates the input tokens of an image in a vision request
+// with OpenAI's tile-based formula. "low" detail costs a flat 85 tokens.
+// Other details ("high", "auto" or empty) scale the image to fit within
+// 2048x2048, then its shortest side down to 768, and cost 170 tokens per
+// 512px tile plus 85.
+func ImageTokenCost(width int, height int, detail string) int {
+	const baseTokens = 85
+	const tileTokens = 170
+
+	if width <= 0 || height <= 0 {
+		return 0
+	}
+
+	if strings.EqualFold(strings.TrimSpace(detail), "low") {
+		return baseTokens
+	}
+
+	w, h := float64(width), float64(height)
+	if longest := math.Max(w, h); longest > 2048 {
+		w, h = w*2048/longest, h*2048/longest
+	}
+	if shortest := math.Min(w, h); shortest > 768 {
+		w, h = w*768/shortest, h*768/shortest
+	}
+
+	tiles := int(math.Ceil(w/512)) * int(math.Ceil(h/512))
+	return tileTokens*tiles + baseTokens
+}
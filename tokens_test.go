@@ -1,6 +1,12 @@
 package llm
 
-import "testing"
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
 
 func TestCountTokens(t *testing.T) {
 	tests := []struct {
@@ -26,12 +32,27 @@ func TestCountTokens(t *testing.T) {
 		{
 			name:     "with punctuation",
 			text:     "hello, world!",
-			expected: 4, // "hello", ",", "world", "!"
+			expected: 4, // "hello", ",", " world", "!"
 		},
 		{
 			name:     "complex sentence",
 			text:     "This is a test. It has multiple sentences, with various punctuation marks!",
-			expected: 15, // 12 words + 3 punctuation marks (. , !)
+			expected: 15,
+		},
+		{
+			name:     "code",
+			text:     "func main() {\n\tfmt.Println(\"hi\")\n}",
+			expected: 10,
+		},
+		{
+			name:     "single long word",
+			text:     "internationalization",
+			expected: 2,
+		},
+		{
+			name:     "non-latin text",
+			text:     "Привет, как дела сегодня?",
+			expected: 12,
 		},
 	}
 
@@ -45,6 +66,65 @@ func TestCountTokens(t *testing.T) {
 	}
 }
 
+func TestCountTokensForModel(t *testing.T) {
+	text := "Привет, как дела сегодня?"
+
+	// gpt-4o uses o200k_base, which encodes Cyrillic more compactly
+	if got := CountTokensForModel(text, "gpt-4o"); got != 7 {
+		t.Errorf("CountTokensForModel(gpt-4o) = %d, expected 7", got)
+	}
+
+	// Vendor prefixes are ignored
+	if got := CountTokensForModel(text, OPENROUTER_MODEL_GPT_5_NANO); got != 7 {
+		t.Errorf("CountTokensForModel(%s) = %d, expected 7", OPENROUTER_MODEL_GPT_5_NANO, got)
+	}
+
+	// Unknown models fall back to cl100k_base
+	if got := CountTokensForModel(text, "claude-sonnet-4"); got != CountTokens(text) {
+		t.Errorf("CountTokensForModel(claude-sonnet-4) = %d, expected %d", got, CountTokens(text))
+	}
+}
+
+func TestCountChatTokens(t *testing.T) {
+	messages := []Message{
+		{Role: MessageRoleSystem, Content: "You are a helpful assistant."},
+		{Role: MessageRoleUser, Content: "Hello!"},
+	}
+
+	// The prompt_tokens OpenAI reports for this request
+	if got := CountChatTokens(messages, "gpt-4"); got != 19 {
+		t.Errorf("CountChatTokens(gpt-4) = %d, expected 19", got)
+	}
+
+	// Each message adds its content and a fixed framing overhead
+	messages = append(messages, Message{Role: MessageRoleAssistant, Content: "Hi"})
+	if got := CountChatTokens(messages, "gpt-4"); got != 19+3+1+1 {
+		t.Errorf("CountChatTokens(gpt-4) = %d, expected %d", got, 19+3+1+1)
+	}
+
+	if got := CountChatTokens(nil, "gpt-4"); got != 0 {
+		t.Errorf("CountChatTokens(nil) = %d, expected 0", got)
+	}
+}
+
+func TestEncodingNameForModel(t *testing.T) {
+	tests := map[string]string{
+		"":                  "cl100k_base",
+		"gpt-4":             "cl100k_base",
+		"gpt-4o-mini":       "o200k_base",
+		"openai/gpt-4.1":    "o200k_base",
+		"o4-mini":           "o200k_base",
+		"gemini-2.5-flash":  "cl100k_base",
+		"anthropic/claude-": "cl100k_base",
+	}
+
+	for model, expected := range tests {
+		if got := encodingNameForModel(model); got != expected {
+			t.Errorf("encodingNameForModel(%q) = %s, expected %s", model, got, expected)
+		}
+	}
+}
+
 func TestEstimateMaxTokens(t *testing.T) {
 	tests := []struct {
 		name              string
@@ -94,3 +174,81 @@ func TestEstimateMaxTokens(t *testing.T) {
 		})
 	}
 }
+
+func TestImageTokenCost(t *testing.T) {
+	// Examples from OpenAI's vision pricing documentation
+	cases := []struct {
+		width  int
+		height int
+		detail string
+		want   int
+	}{
+		{1024, 1024, "high", 765},
+		{2048, 4096, "high", 1105},
+		{4096, 8192, "low", 85},
+		{1024, 1024, "auto", 765},
+		{512, 512, "", 255},
+		{0, 512, "high", 0},
+	}
+
+	for _, tc := range cases {
+		if got := ImageTokenCost(tc.width, tc.height, tc.detail); got != tc.want {
+			t.Errorf("ImageTokenCost(%d, %d, %q) = %d, want %d", tc.width, tc.height, tc.detail, got, tc.want)
+		}
+	}
+}
+
+func TestCountTokensAnthropic(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/messages/count_tokens" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if r.Header.Get("x-api-key") != "test-key" {
+			t.Errorf("unexpected x-api-key header: %s", r.Header.Get("x-api-key"))
+		}
+
+		var body struct {
+			Model    string `json:"model"`
+			Messages []struct {
+				Role    string `json:"role"`
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("failed to decode request: %v", err)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if body.Model != "claude-sonnet-4-5" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"type":"error","error":{"type":"not_found_error","message":"model not found"}}`))
+			return
+		}
+		if len(body.Messages) != 1 || body.Messages[0].Role != "user" || body.Messages[0].Content != "Hello, Claude" {
+			t.Errorf("unexpected messages: %+v", body.Messages)
+		}
+		w.Write([]byte(`{"input_tokens":14}`))
+	}))
+	defer server.Close()
+
+	originalURL := anthropicAPIURL
+	anthropicAPIURL = server.URL
+	defer func() { anthropicAPIURL = originalURL }()
+
+	tokens, err := CountTokensAnthropic("Hello, Claude", "claude-sonnet-4-5", LlmOptions{ApiKey: "test-key"})
+	if err != nil {
+		t.Fatalf("CountTokensAnthropic failed: %v", err)
+	}
+	if tokens != 14 {
+		t.Errorf("expected 14 tokens, got %d", tokens)
+	}
+
+	_, err = CountTokensAnthropic("Hello, Claude", "claude-unknown", LlmOptions{ApiKey: "test-key"})
+	if !errors.Is(err, ErrModelNotFound) {
+		t.Errorf("expected ErrModelNotFound, got %v", err)
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound || apiErr.Provider != ProviderAnthropic {
+		t.Errorf("expected an Anthropic APIError with the status, got %v", err)
+	}
+}
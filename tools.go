@@ -0,0 +1,64 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ToolDefinition describes a tool (function) the model may call
+type ToolDefinition struct {
+	// Name identifies the tool in the model's tool calls
+	Name string
+
+	// Description tells the model what the tool does and when to use it
+	Description string
+
+	// Parameters is the JSON schema of the tool's arguments object. If
+	// empty, the tool takes no arguments.
+	Parameters json.RawMessage
+}
+
+// ToolResult is the outcome of GenerateWithTools: either the final text
+// response, or the tool calls the model requested instead
+type ToolResult struct {
+	// Text is the text response. It may accompany tool calls.
+	Text string
+
+	// ToolCalls are the tool calls requested by the model, with their
+	// arguments as a JSON object
+	ToolCalls []ToolCall
+}
+
+// HasToolCalls reports whether the model requested any tool calls
+func (r ToolResult) HasToolCalls() bool {
+	return len(r.ToolCalls) > 0
+}
+
+// ToolInterface is implemented by providers that support tool calling
+// (currently OpenAI, Anthropic and Gemini)
+type ToolInterface interface {
+	// GenerateWithTools generates a response to the prompt, letting the
+	// model call the given tools. The tools are not executed: the requested
+	// calls are returned in the ToolResult.
+	GenerateWithTools(systemPrompt string, userPrompt string, tools []ToolDefinition, options ...LlmOptions) (ToolResult, error)
+}
+
+// GenerateWithTools generates a response to the prompt, letting the model
+// call the given tools, returning an error wrapping ErrNotSupported if the
+// provider does not implement ToolInterface
+func GenerateWithTools(llm LlmInterface, systemPrompt string, userPrompt string, tools []ToolDefinition, options ...LlmOptions) (ToolResult, error) {
+	toolLlm, ok := llm.(ToolInterface)
+	if !ok {
+		return ToolResult{}, fmt.Errorf("%w: tool calling", ErrNotSupported)
+	}
+	return toolLlm.GenerateWithTools(systemPrompt, userPrompt, tools, options...)
+}
+
+// toolParameters returns the tool's parameters schema, defaulting to an
+// empty object schema, as the providers require one
+func toolParameters(tool ToolDefinition) json.RawMessage {
+	if len(tool.Parameters) == 0 {
+		return json.RawMessage(`{"type":"object","properties":{}}`)
+	}
+	return tool.Parameters
+}
@@ -0,0 +1,139 @@
+package llm
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+var testWeatherTool = ToolDefinition{
+	Name:        "get_weather",
+	Description: "Get the current weather for a city",
+	Parameters:  json.RawMessage(`{"type":"object","properties":{"city":{"type":"string"}},"required":["city"]}`),
+}
+
+// assertWeatherCall checks that result requests get_weather for Paris
+func assertWeatherCall(t *testing.T, result ToolResult) {
+	t.Helper()
+	if !result.HasToolCalls() || len(result.ToolCalls) != 1 {
+		t.Fatalf("expected one tool call, got %+v", result)
+	}
+	call := result.ToolCalls[0]
+	if call.Name != "get_weather" {
+		t.Errorf("expected get_weather, got %s", call.Name)
+	}
+	var arguments map[string]any
+	if err := json.Unmarshal([]byte(call.Arguments), &arguments); err != nil || arguments["city"] != "Paris" {
+		t.Errorf("expected city Paris arguments, got %s (err=%v)", call.Arguments, err)
+	}
+}
+
+func TestOpenaiGenerateWithTools(t *testing.T) {
+	var captured map[string]any
+	server := captureServer(t, `{"choices":[{"message":{"role":"assistant","tool_calls":[
+		{"id":"call_1","type":"function","function":{"name":"get_weather","arguments":"{\"city\":\"Paris\"}"}}
+	]}}]}`, &captured)
+	defer server.Close()
+
+	config := openai.DefaultConfig("test-key")
+	config.BaseURL = server.URL
+	engine := &openaiImplementation{
+		client:               openai.NewClientWithConfig(config),
+		model:                "gpt-4o-mini",
+		lastResponseRecorder: newLastResponseRecorder(nil),
+	}
+
+	result, err := GenerateWithTools(engine, "system", "Weather in Paris?", []ToolDefinition{testWeatherTool})
+	if err != nil {
+		t.Fatalf("GenerateWithTools failed: %v", err)
+	}
+	assertWeatherCall(t, result)
+	if result.ToolCalls[0].ID != "call_1" {
+		t.Errorf("expected call_1, got %s", result.ToolCalls[0].ID)
+	}
+
+	if captured["tool_choice"] != "auto" {
+		t.Errorf("expected tool_choice auto, got %v", captured["tool_choice"])
+	}
+	tools, _ := captured["tools"].([]any)
+	if len(tools) != 1 {
+		t.Fatalf("expected one tool, got %v", captured["tools"])
+	}
+	function, _ := tools[0].(map[string]any)["function"].(map[string]any)
+	if function["name"] != "get_weather" || function["parameters"] == nil {
+		t.Errorf("unexpected function definition: %v", function)
+	}
+}
+
+func TestAnthropicGenerateWithTools(t *testing.T) {
+	var captured map[string]any
+	server := captureServer(t, `{"content":[
+		{"type":"text","text":"Let me check."},
+		{"type":"tool_use","id":"toolu_1","name":"get_weather","input":{"city":"Paris"}}
+	]}`, &captured)
+	defer server.Close()
+
+	target, _ := url.Parse(server.URL)
+	engine, err := NewLLM(LlmOptions{Provider: ProviderAnthropic, ApiKey: "test-key", Model: "claude-sonnet-4"})
+	if err != nil {
+		t.Fatalf("failed to create Anthropic LLM: %v", err)
+	}
+	engine.(*anthropicImplementation).httpClient = &http.Client{Transport: redirectTransport{target: target}}
+
+	result, err := GenerateWithTools(engine, "system", "Weather in Paris?", []ToolDefinition{testWeatherTool})
+	if err != nil {
+		t.Fatalf("GenerateWithTools failed: %v", err)
+	}
+	assertWeatherCall(t, result)
+	if result.Text != "Let me check." {
+		t.Errorf("expected text alongside the tool call, got %q", result.Text)
+	}
+
+	tools, _ := captured["tools"].([]any)
+	if len(tools) != 1 {
+		t.Fatalf("expected one tool, got %v", captured["tools"])
+	}
+	tool, _ := tools[0].(map[string]any)
+	if tool["name"] != "get_weather" || tool["input_schema"] == nil {
+		t.Errorf("unexpected tool definition: %v", tool)
+	}
+}
+
+func TestGeminiGenerateWithTools(t *testing.T) {
+	var captured map[string]any
+	server := captureServer(t, `{"candidates":[{"content":{"role":"model","parts":[
+		{"functionCall":{"name":"get_weather","args":{"city":"Paris"}}}
+	]}}]}`, &captured)
+	defer server.Close()
+
+	result, err := GenerateWithTools(newTestGemini(t, server.URL), "system", "Weather in Paris?", []ToolDefinition{testWeatherTool})
+	if err != nil {
+		t.Fatalf("GenerateWithTools failed: %v", err)
+	}
+	assertWeatherCall(t, result)
+
+	tools, _ := captured["tools"].([]any)
+	if len(tools) != 1 {
+		t.Fatalf("expected one tool, got %v", captured["tools"])
+	}
+	declarations, _ := tools[0].(map[string]any)["functionDeclarations"].([]any)
+	if len(declarations) != 1 || declarations[0].(map[string]any)["name"] != "get_weather" {
+		t.Errorf("unexpected function declarations: %v", tools[0])
+	}
+}
+
+func TestGenerateWithToolsNotSupported(t *testing.T) {
+	engine, err := NewLLM(LlmOptions{Provider: ProviderMock})
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+
+	_, err = GenerateWithTools(engine, "", "Weather in Paris?", []ToolDefinition{testWeatherTool})
+	if !errors.Is(err, ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported, got %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+package llm
+
+import (
+	"encoding/json"
+)
+
+// Usage holds the token counts reported by a provider for a call
+type Usage struct {
+	// PromptTokens is the number of input tokens. For Anthropic it excludes
+	// the tokens written to or read from the prompt cache.
+	PromptTokens int
+
+	// CompletionTokens is the number of generated tokens
+	CompletionTokens int
+
+	// TotalTokens is the sum of all the input and generated tokens
+	TotalTokens int
+
+	// CacheCreationTokens is the number of input tokens written to the
+	// prompt cache (Anthropic)
+	CacheCreationTokens int
+
+	// CacheReadTokens is the number of input tokens read from the prompt
+	// cache
+	CacheReadTokens int
+
+	// EstimatedCost is the USD cost of the tokens from the pricing catalog
+	// (see CostEstimate), or 0 if the model is not priced. All the input
+	// tokens, including the cached ones, are priced at the input price.
+	EstimatedCost float64
+}
+
+// withEstimatedCost returns the usage with its EstimatedCost for the
+// model, if the model is priced
+func (u Usage) withEstimatedCost(model string) Usage {
+	price, ok := lookupModelPrice(model)
+	if !ok {
+		return u
+	}
+	inputTokens := max(u.TotalTokens-u.CompletionTokens, u.PromptTokens)
+	u.EstimatedCost = price.cost(inputTokens, u.CompletionTokens)
+	return u
+}
+
+// estimateUsage estimates the usage of a call by counting the tokens of the
+// prompt and the response for the model
+func estimateUsage(prompt string, response string, model string) Usage {
+	promptTokens := CountTokensForModel(prompt, model)
+	completionTokens := CountTokensForModel(response, model)
+	usage := Usage{
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+		TotalTokens:      promptTokens + completionTokens,
+	}
+	return usage.withEstimatedCost(model)
+}
+
+// Usage parses the token usage of the response body. It supports
+// Anthropic's usage object and the OpenAI style usage object (also used by
+// OpenRouter and most OpenAI-compatible servers). The EstimatedCost is set
+// when the body's model is priced. It returns false if the response
+// reports no usage.
+func (r RawResponse) Usage() (Usage, bool) {
+	var body struct {
+		Model string `json:"model"`
+		Usage *struct {
+			// OpenAI style
+			PromptTokens        int `json:"prompt_tokens"`
+			CompletionTokens    int `json:"completion_tokens"`
+			TotalTokens         int `json:"total_tokens"`
+			PromptTokensDetails *struct {
+				CachedTokens int `json:"cached_tokens"`
+			} `json:"prompt_tokens_details"`
+
+			// Anthropic
+			InputTokens              int `json:"input_tokens"`
+			OutputTokens             int `json:"output_tokens"`
+			CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+			CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.Unmarshal(r.Body, &body); err != nil || body.Usage == nil {
+		return Usage{}, false
+	}
+
+	u := body.Usage
+	if r.Provider == ProviderAnthropic {
+		usage := Usage{
+			PromptTokens:        u.InputTokens,
+			CompletionTokens:    u.OutputTokens,
+			TotalTokens:         u.InputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens + u.OutputTokens,
+			CacheCreationTokens: u.CacheCreationInputTokens,
+			CacheReadTokens:     u.CacheReadInputTokens,
+		}
+		return usage.withEstimatedCost(body.Model), true
+	}
+
+	usage := Usage{
+		PromptTokens:     u.PromptTokens,
+		CompletionTokens: u.CompletionTokens,
+		TotalTokens:      u.TotalTokens,
+	}
+	if u.PromptTokensDetails != nil {
+		usage.CacheReadTokens = u.PromptTokensDetails.CachedTokens
+	}
+	return usage.withEstimatedCost(body.Model), true
+}
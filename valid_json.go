@@ -0,0 +1,61 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// defaultJSONRepairAttempts is the number of re-prompts of GenerateValidJSON
+// when ProviderOptions["json_repair_attempts"] is not set
+const defaultJSONRepairAttempts = 2
+
+// GenerateValidJSON generates a JSON response like GenerateJSON, and
+// re-prompts the model when the response is not valid JSON, e.g. truncated
+// or malformed. Each repair prompt carries the invalid response and the
+// parse error. The number of re-prompts is read from the per-call
+// ProviderOptions["json_repair_attempts"] (default 2, 0 disables them).
+//
+// It returns the first valid JSON, with markdown code fences and prose
+// around it removed, or an error wrapping ErrInvalidJSON once the attempts
+// are exhausted.
+func GenerateValidJSON(llm LlmInterface, systemPrompt string, userPrompt string, options ...LlmOptions) (string, error) {
+	perCall := LlmOptions{}
+	if len(options) > 0 {
+		perCall = options[0]
+	}
+
+	attempts := defaultJSONRepairAttempts
+	if v, ok := providerOptionInt(perCall.ProviderOptions, "json_repair_attempts"); ok {
+		attempts = max(v, 0)
+	}
+
+	prompt := userPrompt
+	for attempt := 0; ; attempt++ {
+		response, err := llm.GenerateJSON(systemPrompt, prompt, perCall)
+		if err != nil {
+			return "", err
+		}
+
+		sanitized := sanitizeJSONResponse(response)
+		var value any
+		parseErr := json.Unmarshal([]byte(sanitized), &value)
+		if parseErr == nil {
+			return sanitized, nil
+		}
+
+		if attempt >= attempts {
+			return "", fmt.Errorf("%w after %d attempt(s): %v; raw response: %s", ErrInvalidJSON, attempt+1, parseErr, response)
+		}
+
+		prompt = jsonRepairPrompt(userPrompt, response, parseErr)
+	}
+}
+
+// jsonRepairPrompt returns the user prompt asking the model to correct its
+// invalid JSON response
+func jsonRepairPrompt(userPrompt string, response string, parseErr error) string {
+	return userPrompt +
+		"\n\nThe previous response was invalid JSON because " + parseErr.Error() + ":\n" +
+		response +
+		"\n\nReturn the corrected JSON only."
+}
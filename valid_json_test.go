@@ -0,0 +1,60 @@
+package llm
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestGenerateValidJSON(t *testing.T) {
+	responses := []string{`{"name": "Ada"`, "```json\n{\"name\": \"Ada\"}\n```"}
+	var prompts []string
+	engine := &CustomTestLLM{generateFunc: func(systemPrompt string, userPrompt string, options LlmOptions) (string, error) {
+		prompts = append(prompts, userPrompt)
+		response := responses[0]
+		responses = responses[1:]
+		return response, nil
+	}}
+
+	response, err := GenerateValidJSON(engine, "system", "Who wrote the first program?")
+	if err != nil {
+		t.Fatalf("GenerateValidJSON failed: %v", err)
+	}
+	if response != `{"name": "Ada"}` {
+		t.Errorf("expected the repaired JSON, got %q", response)
+	}
+	if len(prompts) != 2 {
+		t.Fatalf("expected one repair prompt, got %d calls", len(prompts))
+	}
+	if !strings.HasPrefix(prompts[1], "Who wrote the first program?") ||
+		!strings.Contains(prompts[1], "invalid JSON because") ||
+		!strings.Contains(prompts[1], `{"name": "Ada"`) {
+		t.Errorf("expected the repair prompt to carry the invalid response and error, got %q", prompts[1])
+	}
+}
+
+func TestGenerateValidJSONExhausted(t *testing.T) {
+	calls := 0
+	engine := &CustomTestLLM{generateFunc: func(string, string, LlmOptions) (string, error) {
+		calls++
+		return "not json", nil
+	}}
+
+	_, err := GenerateValidJSON(engine, "system", "hello", LlmOptions{
+		ProviderOptions: map[string]any{"json_repair_attempts": 3},
+	})
+	if !errors.Is(err, ErrInvalidJSON) {
+		t.Fatalf("expected ErrInvalidJSON, got %v", err)
+	}
+	if calls != 4 {
+		t.Errorf("expected 1 call and 3 repair attempts, got %d calls", calls)
+	}
+
+	calls = 0
+	_, err = GenerateValidJSON(engine, "system", "hello", LlmOptions{
+		ProviderOptions: map[string]any{"json_repair_attempts": 0},
+	})
+	if !errors.Is(err, ErrInvalidJSON) || calls != 1 {
+		t.Errorf("expected no repair attempt, got %d calls and %v", calls, err)
+	}
+}
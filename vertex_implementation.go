@@ -2,15 +2,24 @@ package llm
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"regexp"
 	"strings"
+	"sync"
 
+	aiplatform "cloud.google.com/go/aiplatform/apiv1"
+	"cloud.google.com/go/aiplatform/apiv1/aiplatformpb"
 	"cloud.google.com/go/vertexai/genai"
 	"github.com/spf13/cast"
 	"google.golang.org/api/option"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
 // const GEMINI_MODEL_2_0_FLASH = "gemini-2.0-flash-001"
@@ -25,16 +34,149 @@ const GEMINI_MODEL_1_5_PRO = "gemini-1.5-pro"             // supported but now o
 const GEMINI_MODEL_1_5_FLASH = "gemini-1.5-flash"         // supported but now old
 const GEMINI_MODEL_3_0_IMAGEN = "imagen-3.0-generate-002" // not supported
 
+const VERTEX_MODEL_TEXT_EMBEDDING_004 = "text-embedding-004"
+const VERTEX_MODEL_TEXTEMBEDDING_GECKO = "textembedding-gecko@003"
+
 func newVertexImplementation(options LlmOptions) (LlmInterface, error) {
 	o := options
-	// Add checks for required options if needed, e.g. API key
+	if o.Model == "" {
+		o.Model = DefaultModel(ProviderVertex)
+	}
 	return &vertexLlmImpl{
 		options: o,
+
+		lastResponseRecorder: newLastResponseRecorder(o.ProviderOptions),
 	}, nil
 }
 
 type vertexLlmImpl struct {
 	options LlmOptions
+	*lastResponseRecorder
+
+	// mu guards the clients, created on first use and reused by the calls
+	// until Close
+	mu               sync.Mutex
+	client           *genai.Client
+	predictionClient *aiplatform.PredictionClient
+}
+
+// genaiClient returns the genai client for the call and a function to
+// release it. Calls with the client's own project, region and credentials
+// share one client, whatever their other options. Calls changing them get
+// a client of their own, closed on release.
+func (c *vertexLlmImpl) genaiClient(options LlmOptions, perCall LlmOptions) (*genai.Client, func(), error) {
+	clientOptions, err := buildVertexClientOptions(options)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if vertexClientChanged(c.options, perCall) {
+		// Like the shared client, the client is used after this function
+		// returns, so it is not bound to a context cancelled here
+		client, err := genai.NewClient(context.Background(), options.ProjectID, options.Region, clientOptions...)
+		if err != nil {
+			return nil, nil, err
+		}
+		return client, func() { closeVertexClient(options, "vertex client", client) }, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.client == nil {
+		// The shared client outlives the call, so it is not bound to the
+		// call's context
+		client, err := genai.NewClient(context.Background(), options.ProjectID, options.Region, clientOptions...)
+		if err != nil {
+			return nil, nil, err
+		}
+		c.client = client
+	}
+	return c.client, func() {}, nil
+}
+
+// vertexCredentialOptions are the provider options selecting the
+// credentials of the genai client
+var vertexCredentialOptions = []string{"credentials_json", "credentials_file"}
+
+// vertexClientChanged reports whether the per-call options change the
+// project, region or credentials of the client's options, which need a
+// genai client of their own
+func vertexClientChanged(options LlmOptions, perCall LlmOptions) bool {
+	if perCall.ProjectID != "" && perCall.ProjectID != options.ProjectID {
+		return true
+	}
+	if perCall.Region != "" && perCall.Region != options.Region {
+		return true
+	}
+	if apiKey := strings.TrimSpace(perCall.ApiKey); apiKey != "" && apiKey != strings.TrimSpace(options.ApiKey) {
+		return true
+	}
+	for _, key := range vertexCredentialOptions {
+		if _, set := perCall.ProviderOptions[key]; !set {
+			continue
+		}
+		value, _ := providerOptionString(perCall.ProviderOptions, key)
+		current, _ := providerOptionString(options.ProviderOptions, key)
+		if value != current {
+			return true
+		}
+	}
+	return false
+}
+
+// sharedPredictionClient returns the prediction client used for
+// embeddings, creating it on first use
+func (c *vertexLlmImpl) sharedPredictionClient(options LlmOptions) (*aiplatform.PredictionClient, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.predictionClient != nil {
+		return c.predictionClient, nil
+	}
+
+	clientOptions, err := buildVertexClientOptions(options)
+	if err != nil {
+		return nil, err
+	}
+	clientOptions = append(clientOptions, option.WithEndpoint(fmt.Sprintf("%s-aiplatform.googleapis.com:443", options.Region)))
+
+	client, err := aiplatform.NewPredictionClient(context.Background(), clientOptions...)
+	if err != nil {
+		return nil, err
+	}
+	c.predictionClient = client
+	return client, nil
+}
+
+// Close releases the clients reused across calls. The implementation can
+// still be used afterwards: new clients are created on the next call.
+func (c *vertexLlmImpl) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var errs []error
+	if c.client != nil {
+		errs = append(errs, c.client.Close())
+		c.client = nil
+	}
+	if c.predictionClient != nil {
+		errs = append(errs, c.predictionClient.Close())
+		c.predictionClient = nil
+	}
+	return errors.Join(errs...)
+}
+
+// closeVertexClient closes a client, logging the error if it fails
+func closeVertexClient(options LlmOptions, name string, client io.Closer) {
+	if err := client.Close(); err != nil {
+		if options.Logger != nil {
+			options.Logger.Warn("failed to close "+name,
+				slog.String("error", err.Error()))
+		} else if options.Verbose {
+			fmt.Printf("failed to close %s: %v\n", name, err)
+		}
+	}
 }
 
 // Generate generates a response from the LLM based on the provided system prompt and user message.
@@ -47,40 +189,69 @@ func (c *vertexLlmImpl) Generate(systemPrompt string, userMessage string, opts .
 	}
 	options := mergeOptions(c.options, perCall)
 
-	if options.ProjectID == "" {
-		return "", errors.New("project id is required")
+	if override, err := overrideProvider(c.options.Provider, options, perCall); err != nil {
+		return "", err
+	} else if override != nil {
+		defer Close(override)
+		return override.Generate(systemPrompt, userMessage, perCall)
 	}
 
-	if options.Region == "" {
-		return "", errors.New("region is required")
+	resp, err := c.generateContent(systemPrompt, userMessage, options, perCall, 1)
+	if err != nil {
+		return "", err
 	}
 
-	ctx := context.Background()
-	clientOptions, err := buildVertexClientOptions(options)
+	return vertexResponseText(resp)
+}
+
+// GenerateN implements CandidatesInterface, requesting options.N candidates
+func (c *vertexLlmImpl) GenerateN(systemPrompt string, userPrompt string, opts ...LlmOptions) ([]string, error) {
+	perCall := firstOptions(opts)
+	options := mergeOptions(c.options, perCall)
+
+	if override, err := overrideProvider(c.options.Provider, options, perCall); err != nil {
+		return nil, err
+	} else if override != nil {
+		defer Close(override)
+		return GenerateN(override, systemPrompt, userPrompt, perCall)
+	}
+
+	resp, err := c.generateContent(systemPrompt, userPrompt, options, perCall, candidateCount(options))
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	return vertexResponseTexts(resp)
+}
+
+// generateContent sends the prompt to Vertex AI, asking for the number of
+// candidates, and records the response
+func (c *vertexLlmImpl) generateContent(systemPrompt string, userMessage string, options LlmOptions, perCall LlmOptions, candidates int) (*genai.GenerateContentResponse, error) {
+	options.Model = findVertexModelName(options.Model)
+
+	if err := checkCostBudget(options, systemPrompt, userMessage); err != nil {
+		return nil, err
 	}
 
-	client, err := genai.NewClient(ctx, options.ProjectID, options.Region, clientOptions...)
+	if options.ProjectID == "" {
+		return nil, errors.New("project id is required")
+	}
+
+	if options.Region == "" {
+		return nil, errors.New("region is required")
+	}
+
+	ctx, cancel := requestContext(options)
+	defer cancel()
+
+	client, release, err := c.genaiClient(options, perCall)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	defer func() {
-		if cerr := client.Close(); cerr != nil {
-			if options.Logger != nil {
-				options.Logger.Warn("failed to close vertex client",
-					slog.String("error", cerr.Error()))
-			} else if options.Verbose {
-				fmt.Printf("failed to close vertex client: %v\n", cerr)
-			}
-		}
-	}()
+	defer release()
 
 	// Prepare system instruction
-	effectiveSystemPrompt := systemPrompt
-	if options.OutputFormat == OutputFormatJSON {
-		effectiveSystemPrompt += "\nYou must respond with a JSON object only. Do not include any text outside the JSON."
-	}
+	effectiveSystemPrompt := vertexSystemPrompt(systemPrompt, options)
 
 	if options.Logger != nil {
 		options.Logger.Debug("Vertex AI request",
@@ -93,40 +264,19 @@ func (c *vertexLlmImpl) Generate(systemPrompt string, userMessage string, opts .
 	}
 
 	// For text-only input, use the gemini-pro model
-	model := client.GenerativeModel(findVertexModelName(options.Model))
+	model := client.GenerativeModel(options.Model)
 
 	// Set system instruction separately from user content
 	model.SystemInstruction = &genai.Content{
 		Parts: []genai.Part{genai.Text(effectiveSystemPrompt)},
 	}
 
-	// Convert values to pointers for generation config
-	temp := float32(derefFloat64(options.Temperature, 0.7))
-	maxTokens := int32(options.MaxTokens)
-	candidateCount := int32(1)
-	topP := float32(0.8)
-	topK := int32(40)
-
-	// Configure generation parameters
-	generationConfig := &genai.GenerationConfig{
-		Temperature:     &temp,
-		MaxOutputTokens: &maxTokens,
-		CandidateCount:  &candidateCount,
-		TopP:            &topP,
-		TopK:            &topK,
+	generationConfig, err := vertexGenerationConfig(options)
+	if err != nil {
+		return nil, err
 	}
-
-	switch options.OutputFormat {
-	case OutputFormatJSON:
-		generationConfig.ResponseMIMEType = "application/json"
-	case OutputFormatXML:
-		generationConfig.ResponseMIMEType = "application/xml"
-	case OutputFormatYAML:
-		generationConfig.ResponseMIMEType = "application/yaml"
-	case OutputFormatEnum:
-		generationConfig.ResponseMIMEType = "text/x.enum"
-	default:
-		generationConfig.ResponseMIMEType = "text/plain"
+	if candidates > 1 {
+		generationConfig.SetCandidateCount(int32(candidates))
 	}
 	model.GenerationConfig = *generationConfig
 
@@ -153,23 +303,145 @@ func (c *vertexLlmImpl) Generate(systemPrompt string, userMessage string, opts .
 		model.SafetySettings = safetySettings
 	}
 
-	resp, err := model.GenerateContent(ctx, genai.Text(userMessage))
+	var resp *genai.GenerateContentResponse
+	err = withRetry(ctx, options, func() error {
+		var err error
+		resp, err = model.GenerateContent(ctx, genai.Text(userMessage))
+		return err
+	})
 	if err != nil {
-		return "", err
+		if status.Code(err) == codes.NotFound {
+			return nil, &ModelNotFoundError{Provider: ProviderVertex, Model: options.Model, Err: err}
+		}
+		// The SDK reports safety blocks as a *genai.BlockedError
+		var blockedErr *genai.BlockedError
+		if errors.As(err, &blockedErr) {
+			return nil, fmt.Errorf("%w: %w", ErrContentBlocked, err)
+		}
+		return nil, err
+	}
+	c.recordObject(ctx, ProviderVertex, 0, nil, resp)
+
+	return resp, nil
+}
+
+// EffectiveOptions implements EffectiveOptionsInterface
+func (c *vertexLlmImpl) EffectiveOptions(opts ...LlmOptions) LlmOptions {
+	return mergeOptions(c.options, firstOptions(opts))
+}
+
+// DebugMessages implements DebugMessagesInterface. The system message is
+// sent as Vertex's system instruction.
+func (c *vertexLlmImpl) DebugMessages(systemPrompt string, userMessage string, opts ...LlmOptions) []Message {
+	perCall := LlmOptions{}
+	if len(opts) > 0 {
+		perCall = opts[0]
+	}
+	options := mergeOptions(c.options, perCall)
+
+	return promptMessages(vertexSystemPrompt(systemPrompt, options), userMessage)
+}
+
+// vertexSystemPrompt returns the system instruction for the prompt, asking
+// for a JSON object for JSON output unless DisableJSONInstruction is set.
+// The response MIME type enforces JSON either way.
+func vertexSystemPrompt(systemPrompt string, options LlmOptions) string {
+	if options.OutputFormat == OutputFormatJSON && !options.DisableJSONInstruction {
+		return systemPrompt + "\nYou must respond with a JSON object only. Do not include any text outside the JSON."
+	}
+	return systemPrompt
+}
+
+// vertexResponseText concatenates the text parts of the first candidate.
+// Gemini 2.5 often splits a response across several parts, and may mix in
+// non-text parts, which are skipped. A response without text is an error.
+func vertexResponseText(resp *genai.GenerateContentResponse) (string, error) {
+	finishErr := vertexFinishError(resp)
+	if errors.Is(finishErr, ErrContentBlocked) {
+		return "", finishErr
 	}
 
 	// Parse response
-	if len(resp.Candidates) == 0 || len(resp.Candidates[0].Content.Parts) == 0 {
-		return "", fmt.Errorf("unexpected vertex response: no candidates or empty parts")
+	if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil || len(resp.Candidates[0].Content.Parts) == 0 {
+		if finishErr != nil {
+			return "", finishErr
+		}
+		return "", fmt.Errorf("%w: no candidates or empty parts from vertex", ErrEmptyResponse)
 	}
 
-	// Iterate over all parts and concatenate text parts
-	var result string
-	for _, part := range resp.Candidates[0].Content.Parts {
-		result += cast.ToString(part)
+	text := vertexCandidateText(resp.Candidates[0])
+	if text == "" && finishErr == nil {
+		return "", fmt.Errorf("%w: no text in %d part(s) from vertex", ErrEmptyResponse, len(resp.Candidates[0].Content.Parts))
+	}
+
+	// Return the truncated text along with ErrMaxTokensReached
+	return text, finishErr
+}
+
+// vertexResponseTexts returns the text of every candidate with text. Without
+// any, it returns the error of vertexResponseText.
+func vertexResponseTexts(resp *genai.GenerateContentResponse) ([]string, error) {
+	texts := []string{}
+	for _, candidate := range resp.Candidates {
+		if text := vertexCandidateText(candidate); text != "" {
+			texts = append(texts, text)
+		}
 	}
 
-	return strings.TrimSpace(result), nil
+	if len(texts) == 0 {
+		_, err := vertexResponseText(resp)
+		return nil, err
+	}
+	return texts, nil
+}
+
+// vertexCandidateText concatenates the text parts of the candidate,
+// skipping the other parts, and trims the result
+func vertexCandidateText(candidate *genai.Candidate) string {
+	if candidate.Content == nil {
+		return ""
+	}
+
+	var result strings.Builder
+	for _, part := range candidate.Content.Parts {
+		if text, ok := part.(genai.Text); ok {
+			result.WriteString(string(text))
+		}
+	}
+	return strings.TrimSpace(result.String())
+}
+
+// vertexFinishError returns an error wrapping ErrContentBlocked if the
+// first candidate was blocked for a reason the SDK does not report as a
+// *genai.BlockedError, or ErrMaxTokensReached if it was cut off at the
+// token limit
+func vertexFinishError(resp *genai.GenerateContentResponse) error {
+	if len(resp.Candidates) == 0 {
+		return nil
+	}
+
+	candidate := resp.Candidates[0]
+	switch candidate.FinishReason {
+	case genai.FinishReasonSafety,
+		genai.FinishReasonRecitation,
+		genai.FinishReasonBlocklist,
+		genai.FinishReasonProhibitedContent,
+		genai.FinishReasonSpii:
+		categories := []string{}
+		for _, rating := range candidate.SafetyRatings {
+			if rating != nil && rating.Blocked {
+				categories = append(categories, rating.Category.String())
+			}
+		}
+		if len(categories) > 0 {
+			return fmt.Errorf("%w: response blocked with reason %s, blocked categories: %s", ErrContentBlocked, candidate.FinishReason, strings.Join(categories, ", "))
+		}
+		return fmt.Errorf("%w: response blocked with reason %s", ErrContentBlocked, candidate.FinishReason)
+	case genai.FinishReasonMaxTokens:
+		return fmt.Errorf("%w: vertex finish reason %s", ErrMaxTokensReached, candidate.FinishReason)
+	}
+
+	return nil
 }
 
 func (l *vertexLlmImpl) GenerateText(systemPrompt string, userPrompt string, opts ...LlmOptions) (string, error) {
@@ -177,9 +449,10 @@ func (l *vertexLlmImpl) GenerateText(systemPrompt string, userPrompt string, opt
 	if len(opts) > 0 {
 		perCall = opts[0]
 	}
-	options := mergeOptions(l.options, perCall)
-	options.OutputFormat = OutputFormatText
-	return l.Generate(systemPrompt, userPrompt, options)
+	// Only the caller's options are forwarded, so that calls without
+	// overrides share the client
+	perCall.OutputFormat = OutputFormatText
+	return l.Generate(systemPrompt, userPrompt, perCall)
 }
 
 func (l *vertexLlmImpl) GenerateJSON(systemPrompt string, userPrompt string, opts ...LlmOptions) (string, error) {
@@ -187,12 +460,38 @@ func (l *vertexLlmImpl) GenerateJSON(systemPrompt string, userPrompt string, opt
 	if len(opts) > 0 {
 		perCall = opts[0]
 	}
-	options := mergeOptions(l.options, perCall)
-	options.OutputFormat = OutputFormatJSON
-	return l.Generate(systemPrompt, userPrompt, options)
+	perCall.OutputFormat = OutputFormatJSON
+	response, err := l.Generate(systemPrompt, userPrompt, perCall)
+	if err != nil {
+		return "", err
+	}
+	return sanitizeJSONModeResponse(ProviderVertex, response)
+}
+
+// GenerateXML implements LlmInterface
+func (l *vertexLlmImpl) GenerateXML(systemPrompt string, userPrompt string, opts ...LlmOptions) (string, error) {
+	return generateMarkup(l, OutputFormatXML, systemPrompt, userPrompt, opts...)
+}
+
+// GenerateYAML implements LlmInterface
+func (l *vertexLlmImpl) GenerateYAML(systemPrompt string, userPrompt string, opts ...LlmOptions) (string, error) {
+	return generateMarkup(l, OutputFormatYAML, systemPrompt, userPrompt, opts...)
+}
+
+// GenerateStructured implements StructuredOutputInterface
+func (l *vertexLlmImpl) GenerateStructured(systemPrompt string, userPrompt string, schema json.RawMessage, opts ...LlmOptions) (json.RawMessage, error) {
+	return generateStructured(l.GenerateJSON, systemPrompt, userPrompt, schema, opts...)
 }
 
+// GenerateImage implements LlmInterface
 func (l *vertexLlmImpl) GenerateImage(prompt string, opts ...LlmOptions) ([]byte, error) {
+	image, _, err := l.GenerateImageWithText(prompt, opts...)
+	return image, err
+}
+
+// GenerateImageWithText implements ImageTextInterface. The text is the
+// concatenated text parts of the response.
+func (l *vertexLlmImpl) GenerateImageWithText(prompt string, opts ...LlmOptions) ([]byte, string, error) {
 	perCall := LlmOptions{}
 	if len(opts) > 0 {
 		perCall = opts[0]
@@ -200,33 +499,21 @@ func (l *vertexLlmImpl) GenerateImage(prompt string, opts ...LlmOptions) ([]byte
 	options := mergeOptions(l.options, perCall)
 
 	if options.ProjectID == "" {
-		return nil, errors.New("project id is required")
+		return nil, "", errors.New("project id is required")
 	}
 
 	if options.Region == "" {
-		return nil, errors.New("region is required")
+		return nil, "", errors.New("region is required")
 	}
 
-	ctx := context.Background()
-	clientOptions, err := buildVertexClientOptions(options)
-	if err != nil {
-		return nil, err
-	}
+	ctx, cancel := requestContext(options)
+	defer cancel()
 
-	client, err := genai.NewClient(ctx, options.ProjectID, options.Region, clientOptions...)
+	client, release, err := l.genaiClient(options, perCall)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create genai client: %w", err)
-	}
-	defer func() {
-		if cerr := client.Close(); cerr != nil {
-			if options.Logger != nil {
-				options.Logger.Warn("failed to close vertex image client",
-					slog.String("error", cerr.Error()))
-			} else if options.Verbose {
-				fmt.Printf("failed to close vertex image client: %v\n", cerr)
-			}
-		}
-	}()
+		return nil, "", fmt.Errorf("failed to create genai client: %w", err)
+	}
+	defer release()
 
 	if options.Logger != nil {
 		options.Logger.Debug("Using experimental image generation model",
@@ -239,159 +526,269 @@ func (l *vertexLlmImpl) GenerateImage(prompt string, opts ...LlmOptions) ([]byte
 
 	// Convert values to pointers for generation config
 	temp := float32(derefFloat64(options.Temperature, 0.7))
-	maxTokens := int32(options.MaxTokens)
 	candidateCount := int32(1)
-	topP := float32(0.8)
-	topK := int32(40)
 
 	// Configure generation parameters
 	generationConfig := &genai.GenerationConfig{
-		Temperature:     &temp,
-		MaxOutputTokens: &maxTokens,
-		CandidateCount:  &candidateCount,
-		TopP:            &topP,
-		TopK:            &topK,
+		Temperature:    &temp,
+		CandidateCount: &candidateCount,
 	}
-	switch options.OutputFormat {
-	case OutputFormatImagePNG:
-		generationConfig.ResponseMIMEType = string(OutputFormatImagePNG)
-	case OutputFormatImageJPG:
-		generationConfig.ResponseMIMEType = "image/jpg"
-	default:
-		generationConfig.ResponseMIMEType = string(OutputFormatImagePNG)
+	if maxTokens := requestMaxTokens(options); maxTokens > 0 {
+		generationConfig.SetMaxOutputTokens(int32(maxTokens))
 	}
+	setVertexSampling(generationConfig, options)
+	format := imageOutputFormat(options)
+	generationConfig.ResponseMIMEType = string(format)
 	model.GenerationConfig = *generationConfig
 	resp, err := model.GenerateContent(ctx,
 		genai.Text(prompt),
 	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate image: %w", err)
+		return nil, "", fmt.Errorf("failed to generate image: %w", err)
 	}
 
-	if len(resp.Candidates) == 0 || len(resp.Candidates[0].Content.Parts) == 0 {
-		return nil, errors.New("no image generated")
+	return vertexImageAndText(resp, format)
+}
+
+// vertexImageAndText returns the image of the response in the requested
+// format, or else the first image converted to it, and the text parts
+// accompanying it
+func vertexImageAndText(resp *genai.GenerateContentResponse, format OutputFormat) ([]byte, string, error) {
+	if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil || len(resp.Candidates[0].Content.Parts) == 0 {
+		return nil, "", errors.New("no image generated")
 	}
 
+	var image []byte
+	exact := false
+	var text strings.Builder
 	for _, part := range resp.Candidates[0].Content.Parts {
-		if blob, ok := part.(genai.Blob); ok && blob.MIMEType == "image/png" {
-			return blob.Data, nil
+		switch part := part.(type) {
+		case genai.Blob:
+			if exact || !strings.HasPrefix(part.MIMEType, "image/") {
+				continue
+			}
+			if part.MIMEType == string(format) {
+				image, exact = part.Data, true
+			} else if image == nil {
+				image = part.Data
+			}
+		case genai.Text:
+			text.WriteString(string(part))
 		}
 	}
 
-	return nil, errors.New("no image found in response")
+	if image == nil {
+		return nil, "", errors.New("no image found in response")
+	}
+
+	converted, err := convertImage(image, format)
+	if err != nil {
+		return nil, "", err
+	}
+	return converted, text.String(), nil
 }
 
+// GenerateEmbedding generates embeddings for the given text with the
+// Vertex AI text embedding model, text-embedding-004 unless overridden by
+// ProviderOptions["embedding_model"]
 func (l *vertexLlmImpl) GenerateEmbedding(text string) ([]float32, error) {
-	return nil, errors.New("not supported. change to openrouter")
-	// options := l.options
-
-	// if options.ProjectID == "" {
-	// 	return nil, errors.New("project id is required")
-	// }
-
-	// if options.Region == "" {
-	// 	return nil, errors.New("region is required")
-	// }
-
-	// ctx := context.Background()
-	// clientOptions, err := buildVertexClientOptions(options)
-	// if err != nil {
-	// 	return nil, err
-	// }
-
-	// client, err := genai.NewClient(ctx, options.ProjectID, options.Region, clientOptions...)
-	// if err != nil {
-	// 	return nil, fmt.Errorf("failed to create genai client: %w", err)
-	// }
-	// defer func() {
-	// 	if cerr := client.Close(); cerr != nil && options.Verbose {
-	// 		fmt.Printf("failed to close vertex client: %v\n", cerr)
-	// 	}
-	// }()
-
-	// // Use text embedding model
-	// model := client.GenerativeModel("models/embedding-001")
-
-	// // Generate embeddings
-	// resp, err := model.EmbedContent(ctx, genai.Text(text))
-	// if err != nil {
-	// 	return nil, fmt.Errorf("failed to generate embeddings: %w", err)
-	// }
-
-	// if len(resp.Embedding.Values) == 0 {
-	// 	return nil, fmt.Errorf("no embeddings generated")
-	// }
-
-	// // Convert float64 to float32
-	// embeddings := make([]float32, len(resp.Embedding.Values))
-	// for i, v := range resp.Embedding.Values {
-	// 	embeddings[i] = float32(v)
-	// }
-
-	// return embeddings, nil
+	if l.options.EmbeddingLlm != nil {
+		return l.options.EmbeddingLlm.GenerateEmbedding(text)
+	}
+
+	options := l.options
+
+	if options.ProjectID == "" {
+		return nil, errors.New("project id is required")
+	}
+
+	if options.Region == "" {
+		return nil, errors.New("region is required")
+	}
+
+	ctx, cancel := requestContext(options)
+	defer cancel()
+
+	client, err := l.sharedPredictionClient(options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create prediction client: %w", err)
+	}
+
+	instance, err := structpb.NewValue(map[string]any{"content": text})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build embedding instance: %w", err)
+	}
+
+	embeddingModel := vertexEmbeddingModel(options)
+	resp, err := client.Predict(ctx, &aiplatformpb.PredictRequest{
+		Endpoint:  fmt.Sprintf("projects/%s/locations/%s/publishers/google/models/%s", options.ProjectID, options.Region, embeddingModel),
+		Instances: []*structpb.Value{instance},
+	})
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, &ModelNotFoundError{Provider: ProviderVertex, Model: embeddingModel, Err: err}
+		}
+		return nil, fmt.Errorf("failed to generate embeddings: %w", err)
+	}
+	l.recordObject(ctx, ProviderVertex, 0, nil, resp)
+
+	return vertexEmbeddingValues(resp)
+}
+
+// vertexEmbeddingModel returns the embedding model from
+// ProviderOptions["embedding_model"], defaulting to text-embedding-004
+func vertexEmbeddingModel(options LlmOptions) string {
+	if v, ok := providerOptionString(options.ProviderOptions, "embedding_model"); ok && strings.TrimSpace(v) != "" {
+		return strings.TrimSpace(v)
+	}
+	return VERTEX_MODEL_TEXT_EMBEDDING_004
+}
+
+// vertexEmbeddingValues extracts the embeddings.values of the first
+// prediction, converting them to float32
+func vertexEmbeddingValues(resp *aiplatformpb.PredictResponse) ([]float32, error) {
+	if len(resp.GetPredictions()) == 0 {
+		return nil, errors.New("no embeddings generated")
+	}
+
+	embeddings := resp.GetPredictions()[0].GetStructValue().GetFields()["embeddings"]
+	values := embeddings.GetStructValue().GetFields()["values"].GetListValue().GetValues()
+	if len(values) == 0 {
+		return nil, errors.New("no embeddings generated")
+	}
+
+	result := make([]float32, len(values))
+	for i, v := range values {
+		result[i] = float32(v.GetNumberValue())
+	}
+
+	return result, nil
 }
 
+// setVertexSampling sets TopP and TopK on the generation config when they
+// are configured, leaving the Vertex defaults otherwise
+func setVertexSampling(generationConfig *genai.GenerationConfig, options LlmOptions) {
+	if options.TopP != nil {
+		generationConfig.SetTopP(float32(*options.TopP))
+	}
+	if options.TopK != nil {
+		generationConfig.SetTopK(int32(*options.TopK))
+	}
+}
+
+// vertexGenerationConfig returns the generation config of a text request
+func vertexGenerationConfig(options LlmOptions) (*genai.GenerationConfig, error) {
+	// Convert values to pointers for generation config
+	temp := float32(derefFloat64(options.Temperature, 0.7))
+	candidateCount := int32(1)
+
+	// Configure generation parameters
+	generationConfig := &genai.GenerationConfig{
+		Temperature:    &temp,
+		CandidateCount: &candidateCount,
+		StopSequences:  options.Stop,
+	}
+	if maxTokens := requestMaxTokens(options); maxTokens > 0 {
+		generationConfig.SetMaxOutputTokens(int32(maxTokens))
+	}
+	setVertexSampling(generationConfig, options)
+	if budget, ok := geminiThinkingBudget(options.ProviderOptions); ok {
+		generationConfig.ThinkingConfig = &genai.ThinkingConfig{ThinkingBudget: &budget}
+	}
+
+	switch options.OutputFormat {
+	case OutputFormatJSON:
+		generationConfig.ResponseMIMEType = "application/json"
+	case OutputFormatXML:
+		generationConfig.ResponseMIMEType = "application/xml"
+	case OutputFormatYAML:
+		generationConfig.ResponseMIMEType = "application/yaml"
+	case OutputFormatEnum:
+		generationConfig.ResponseMIMEType = "text/x.enum"
+	default:
+		generationConfig.ResponseMIMEType = "text/plain"
+	}
+	if options.OutputFormat == OutputFormatJSON && len(options.responseSchema) > 0 {
+		responseSchema, err := vertexSchemaFromJSON(options.responseSchema)
+		if err != nil {
+			return nil, err
+		}
+		generationConfig.ResponseSchema = responseSchema
+	}
+
+	return generationConfig, nil
+}
+
+// vertexVersionedModel matches the versioned Gemini model names, e.g.
+// "gemini-2.5-pro" or "gemini-3-pro-preview"
+var vertexVersionedModel = regexp.MustCompile(`^gemini-\d`)
+
 // findVertexModelName returns the name of the gemini model to use
 // based on the model name.
 //
-// Supported models by gemini are:
-// - gemini-1.5-flash is the default model
-// - gemini-1.5-pro is the pro model, it is used if the model name contains "pro"
-// - gemini-2.0-flash is the flash model, it is used if the model name contains "flash"
-// - gemini-2.0-flash-lite is the flash-lite model, it is used if the model name contains "flash-lite"
-// - gemini-2.5-pro-preview-03-25 is the pro preview model, it is used if the model name contains "pro-preview"
+// Versioned Gemini model names (e.g. gemini-2.5-pro, gemini-2.5-flash-lite)
+// and resource paths (e.g. publishers/google/models/gemini-2.5-flash or a
+// tuned model endpoint) are used verbatim. Other names fall back by keyword:
+// - gemini-2.5-flash-lite if the model name contains "flash-lite"
+// - gemini-2.5-pro if the model name contains "pro"
+// - gemini-2.5-flash otherwise, including for an empty name
 //
 // See https://cloud.google.com/vertex-ai/generative-ai/docs/learn/model-versioning
 // for details on model naming and versioning
-//
-// Parameters:
-// - model: the name of the model
-//
-// Returns:
-// - the name of the model
 func findVertexModelName(modelName string) string {
-	if strings.Contains(modelName, "pro") {
-		//return GEMINI_MODEL_2_0_FLASH
-		return GEMINI_MODEL_2_5_PRO
+	name := strings.TrimSpace(modelName)
+	if vertexVersionedModel.MatchString(name) || strings.Contains(name, "/") {
+		return name
 	}
 
-	// return GEMINI_MODEL_2_0_FLASH_LITE
+	switch {
+	case strings.Contains(name, "flash-lite"):
+		return GEMINI_MODEL_2_5_FLASH_LITE
+	case strings.Contains(name, "pro"):
+		return GEMINI_MODEL_2_5_PRO
+	}
 	return GEMINI_MODEL_2_5_FLASH
 }
 
+// buildVertexClientOptions returns the client options authenticating the
+// Vertex AI clients, from the first credential source set, in order:
+//  1. ProviderOptions["credentials_json"]
+//  2. ProviderOptions["credentials_file"]
+//  3. ApiKey
+//  4. VERTEXAI_CREDENTIALS_JSON
+//  5. VERTEXAI_CREDENTIALS_FILE
+//  6. GOOGLE_APPLICATION_CREDENTIALS
+//
+// Without any, no option is returned and the clients use Application
+// Default Credentials.
 func buildVertexClientOptions(options LlmOptions) ([]option.ClientOption, error) {
-	if options.ProviderOptions != nil {
-		if raw, ok := options.ProviderOptions["credentials_json"]; ok {
-			switch value := raw.(type) {
-			case string:
-				if trimmed := strings.TrimSpace(value); trimmed != "" {
-					return []option.ClientOption{option.WithCredentialsJSON([]byte(trimmed))}, nil
-				}
-			case []byte:
-				if len(value) > 0 {
-					return []option.ClientOption{option.WithCredentialsJSON(value)}, nil
-				}
-			default:
-				return nil, fmt.Errorf("credentials_json provider option must be string or []byte")
-			}
+	if _, set := options.ProviderOptions["credentials_json"]; set {
+		value, ok := providerOptionString(options.ProviderOptions, "credentials_json")
+		if !ok {
+			return nil, fmt.Errorf("credentials_json provider option must be string or []byte")
+		}
+		if trimmed := strings.TrimSpace(value); trimmed != "" {
+			return []option.ClientOption{option.WithCredentialsJSON([]byte(trimmed))}, nil
 		}
+	}
 
-		if raw, ok := options.ProviderOptions["credentials_file"]; ok {
-			switch value := raw.(type) {
-			case string:
-				trimmed := strings.TrimSpace(value)
-				if trimmed != "" {
-					if _, err := os.Stat(trimmed); err != nil {
-						return nil, fmt.Errorf("unable to access credentials file %s: %w", trimmed, err)
-					}
-					return []option.ClientOption{option.WithCredentialsFile(trimmed)}, nil
-				}
-			default:
-				return nil, fmt.Errorf("credentials_file provider option must be string")
+	if _, set := options.ProviderOptions["credentials_file"]; set {
+		value, ok := providerOptionString(options.ProviderOptions, "credentials_file")
+		if !ok {
+			return nil, fmt.Errorf("credentials_file provider option must be string")
+		}
+		if trimmed := strings.TrimSpace(value); trimmed != "" {
+			if _, err := os.Stat(trimmed); err != nil {
+				return nil, fmt.Errorf("unable to access credentials file %s: %w", trimmed, err)
 			}
+			return []option.ClientOption{option.WithCredentialsFile(trimmed)}, nil
 		}
 	}
 
+	if apiKey := strings.TrimSpace(options.ApiKey); apiKey != "" {
+		return []option.ClientOption{option.WithAPIKey(apiKey)}, nil
+	}
+
 	if jsonEnv := strings.TrimSpace(os.Getenv("VERTEXAI_CREDENTIALS_JSON")); jsonEnv != "" {
 		return []option.ClientOption{option.WithCredentialsJSON([]byte(jsonEnv))}, nil
 	}
@@ -412,3 +809,72 @@ func buildVertexClientOptions(options LlmOptions) ([]option.ClientOption, error)
 
 	return nil, nil
 }
+
+// vertexSchemaFromJSON converts a JSON schema to the OpenAPI subset
+// supported by Vertex AI's responseSchema
+func vertexSchemaFromJSON(schema json.RawMessage) (*genai.Schema, error) {
+	var document map[string]any
+	if err := json.Unmarshal(schema, &document); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	return vertexSchemaFromMap(document), nil
+}
+
+// vertexSchemaFromMap converts a decoded JSON schema node
+func vertexSchemaFromMap(node map[string]any) *genai.Schema {
+	schema := &genai.Schema{
+		Format:        cast.ToString(node["format"]),
+		Title:         cast.ToString(node["title"]),
+		Description:   cast.ToString(node["description"]),
+		Enum:          cast.ToStringSlice(node["enum"]),
+		Required:      cast.ToStringSlice(node["required"]),
+		MinItems:      cast.ToInt64(node["minItems"]),
+		MaxItems:      cast.ToInt64(node["maxItems"]),
+		MinProperties: cast.ToInt64(node["minProperties"]),
+		MaxProperties: cast.ToInt64(node["maxProperties"]),
+		Minimum:       cast.ToFloat64(node["minimum"]),
+		Maximum:       cast.ToFloat64(node["maximum"]),
+	}
+
+	// "type" is either a single type or a list such as ["string", "null"]
+	types := []string{}
+	switch t := node["type"].(type) {
+	case string:
+		types = append(types, t)
+	case []any:
+		types = cast.ToStringSlice(t)
+	}
+	for _, t := range types {
+		switch t {
+		case "string":
+			schema.Type = genai.TypeString
+		case "number":
+			schema.Type = genai.TypeNumber
+		case "integer":
+			schema.Type = genai.TypeInteger
+		case "boolean":
+			schema.Type = genai.TypeBoolean
+		case "array":
+			schema.Type = genai.TypeArray
+		case "object":
+			schema.Type = genai.TypeObject
+		case "null":
+			schema.Nullable = true
+		}
+	}
+
+	if items, ok := node["items"].(map[string]any); ok {
+		schema.Items = vertexSchemaFromMap(items)
+	}
+
+	if properties, ok := node["properties"].(map[string]any); ok {
+		schema.Properties = make(map[string]*genai.Schema, len(properties))
+		for name, property := range properties {
+			if propertyNode, ok := property.(map[string]any); ok {
+				schema.Properties[name] = vertexSchemaFromMap(propertyNode)
+			}
+		}
+	}
+
+	return schema
+}
@@ -0,0 +1,297 @@
+package llm
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/aiplatform/apiv1/aiplatformpb"
+	vertexgenai "cloud.google.com/go/vertexai/genai"
+	"google.golang.org/api/option"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestVertexEmbeddingModel(t *testing.T) {
+	if model := vertexEmbeddingModel(LlmOptions{}); model != VERTEX_MODEL_TEXT_EMBEDDING_004 {
+		t.Errorf("expected default %s, got %s", VERTEX_MODEL_TEXT_EMBEDDING_004, model)
+	}
+
+	options := LlmOptions{ProviderOptions: map[string]any{"embedding_model": VERTEX_MODEL_TEXTEMBEDDING_GECKO}}
+	if model := vertexEmbeddingModel(options); model != VERTEX_MODEL_TEXTEMBEDDING_GECKO {
+		t.Errorf("expected override %s, got %s", VERTEX_MODEL_TEXTEMBEDDING_GECKO, model)
+	}
+}
+
+func TestVertexEmbeddingValues(t *testing.T) {
+	prediction, err := structpb.NewValue(map[string]any{
+		"embeddings": map[string]any{
+			"values": []any{0.25, -0.5, 1.0},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to build prediction: %v", err)
+	}
+
+	values, err := vertexEmbeddingValues(&aiplatformpb.PredictResponse{
+		Predictions: []*structpb.Value{prediction},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(values) != 3 || values[0] != 0.25 || values[1] != -0.5 || values[2] != 1.0 {
+		t.Errorf("unexpected embedding values: %v", values)
+	}
+
+	if _, err := vertexEmbeddingValues(&aiplatformpb.PredictResponse{}); err == nil {
+		t.Error("expected an error for an empty response")
+	}
+}
+
+func TestVertexGenerateEmbeddingRequiresProjectAndRegion(t *testing.T) {
+	engine, err := newVertexImplementation(LlmOptions{Region: "europe-west1"})
+	if err != nil {
+		t.Fatalf("failed to create vertex implementation: %v", err)
+	}
+	if _, err := engine.GenerateEmbedding("hello"); err == nil || err.Error() != "project id is required" {
+		t.Errorf("expected project id error, got %v", err)
+	}
+}
+
+func TestVertexResponseText(t *testing.T) {
+	response := func(finishReason vertexgenai.FinishReason, parts ...vertexgenai.Part) *vertexgenai.GenerateContentResponse {
+		return &vertexgenai.GenerateContentResponse{Candidates: []*vertexgenai.Candidate{{
+			Content:      &vertexgenai.Content{Role: "model", Parts: parts},
+			FinishReason: finishReason,
+		}}}
+	}
+
+	// Multi-part responses are concatenated, skipping non-text parts
+	text, err := vertexResponseText(response(vertexgenai.FinishReasonStop,
+		vertexgenai.Text("Hello, "),
+		vertexgenai.FunctionCall{Name: "lookup"},
+		vertexgenai.Text("world!"),
+	))
+	if err != nil || text != "Hello, world!" {
+		t.Errorf("expected concatenated text, got %q (err=%v)", text, err)
+	}
+
+	if _, err := vertexResponseText(&vertexgenai.GenerateContentResponse{}); !errors.Is(err, ErrEmptyResponse) {
+		t.Errorf("expected ErrEmptyResponse for no candidates, got %v", err)
+	}
+
+	if _, err := vertexResponseText(response(vertexgenai.FinishReasonStop)); !errors.Is(err, ErrEmptyResponse) {
+		t.Errorf("expected ErrEmptyResponse for no parts, got %v", err)
+	}
+
+	if _, err := vertexResponseText(response(vertexgenai.FinishReasonStop, vertexgenai.FunctionCall{Name: "lookup"})); !errors.Is(err, ErrEmptyResponse) {
+		t.Errorf("expected ErrEmptyResponse for no text parts, got %v", err)
+	}
+
+	if _, err := vertexResponseText(response(vertexgenai.FinishReasonStop, vertexgenai.Text("  "))); !errors.Is(err, ErrEmptyResponse) {
+		t.Errorf("expected ErrEmptyResponse for blank text, got %v", err)
+	}
+
+	// Truncated text is returned with ErrMaxTokensReached
+	text, err = vertexResponseText(response(vertexgenai.FinishReasonMaxTokens, vertexgenai.Text("Hello")))
+	if text != "Hello" || !errors.Is(err, ErrMaxTokensReached) {
+		t.Errorf("expected truncated text with ErrMaxTokensReached, got %q (err=%v)", text, err)
+	}
+}
+
+func TestVertexClientReuse(t *testing.T) {
+	// Offline credentials: creating a client does not contact Google
+	impl := &vertexLlmImpl{options: LlmOptions{
+		ProjectID: "test-project",
+		Region:    "europe-west1",
+		ProviderOptions: map[string]any{
+			"credentials_json": `{"type":"authorized_user","client_id":"id","client_secret":"secret","refresh_token":"token"}`,
+		},
+	}}
+
+	first, release, err := impl.genaiClient(impl.options, LlmOptions{})
+	if err != nil {
+		t.Fatalf("genaiClient failed: %v", err)
+	}
+	release()
+	second, release, err := impl.genaiClient(impl.options, LlmOptions{})
+	if err != nil {
+		t.Fatalf("genaiClient failed: %v", err)
+	}
+	release()
+	if first != second {
+		t.Error("expected the client to be reused across calls")
+	}
+
+	// Per-call options other than the project, region and credentials
+	// share the client
+	perCall := LlmOptions{
+		Region:          "europe-west1",
+		ProviderOptions: map[string]any{"thinking_budget": 1024, "credentials_json": impl.options.ProviderOptions["credentials_json"]},
+	}
+	shared, release, err := impl.genaiClient(mergeOptions(impl.options, perCall), perCall)
+	if err != nil {
+		t.Fatalf("genaiClient failed: %v", err)
+	}
+	release()
+	if shared != first {
+		t.Error("expected the client to be reused with unchanged credentials")
+	}
+
+	// A call overriding the region gets a client of its own
+	perCall = LlmOptions{Region: "us-central1"}
+	other, release, err := impl.genaiClient(mergeOptions(impl.options, perCall), perCall)
+	if err != nil {
+		t.Fatalf("genaiClient failed: %v", err)
+	}
+	release()
+	if other == first {
+		t.Error("expected a separate client for another region")
+	}
+
+	if err := impl.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	third, release, err := impl.genaiClient(impl.options, LlmOptions{})
+	if err != nil {
+		t.Fatalf("genaiClient failed: %v", err)
+	}
+	release()
+	if third == first {
+		t.Error("expected a new client after Close")
+	}
+	impl.Close()
+}
+
+func TestVertexClientReuseThroughGenerateText(t *testing.T) {
+	// Offline credentials and a short timeout: the calls fail without
+	// contacting Google, after getting their client
+	impl := &vertexLlmImpl{options: LlmOptions{
+		ProjectID: "test-project",
+		Region:    "europe-west1",
+		Timeout:   time.Millisecond,
+		ProviderOptions: map[string]any{
+			"credentials_json": `{"type":"authorized_user","client_id":"id","client_secret":"secret","refresh_token":"token"}`,
+		},
+	}}
+	defer impl.Close()
+
+	impl.GenerateText("system", "hello")
+	shared := impl.client
+	if shared == nil {
+		t.Fatal("expected GenerateText to use the shared client")
+	}
+
+	impl.GenerateJSON("system", "hello")
+	if impl.client != shared {
+		t.Error("expected GenerateJSON to reuse the shared client")
+	}
+}
+
+func TestVertexThinkingBudget(t *testing.T) {
+	config, err := vertexGenerationConfig(LlmOptions{})
+	if err != nil {
+		t.Fatalf("vertexGenerationConfig failed: %v", err)
+	}
+	if config.ThinkingConfig != nil {
+		t.Errorf("expected no thinking config by default, got %+v", config.ThinkingConfig)
+	}
+
+	config, err = vertexGenerationConfig(LlmOptions{ProviderOptions: map[string]any{"thinking_budget": "-1"}})
+	if err != nil {
+		t.Fatalf("vertexGenerationConfig failed: %v", err)
+	}
+	if config.ThinkingConfig == nil || config.ThinkingConfig.ThinkingBudget == nil || *config.ThinkingConfig.ThinkingBudget != -1 {
+		t.Errorf("expected a dynamic thinking budget, got %+v", config.ThinkingConfig)
+	}
+}
+
+func TestFindVertexModelName(t *testing.T) {
+	testCases := map[string]string{
+		GEMINI_MODEL_2_5_PRO:                       GEMINI_MODEL_2_5_PRO,
+		GEMINI_MODEL_2_5_FLASH_LITE:                GEMINI_MODEL_2_5_FLASH_LITE,
+		GEMINI_MODEL_1_5_PRO:                       GEMINI_MODEL_1_5_PRO,
+		"gemini-3-pro-preview":                     "gemini-3-pro-preview",
+		"gemini-2.0-flash-001":                     "gemini-2.0-flash-001",
+		"publishers/google/models/gemini-2.5-pro":  "publishers/google/models/gemini-2.5-pro",
+		"projects/p/locations/l/endpoints/1234567": "projects/p/locations/l/endpoints/1234567",
+		"pro":        GEMINI_MODEL_2_5_PRO,
+		"flash-lite": GEMINI_MODEL_2_5_FLASH_LITE,
+		"flash":      GEMINI_MODEL_2_5_FLASH,
+		"":           GEMINI_MODEL_2_5_FLASH,
+	}
+
+	for model, expected := range testCases {
+		if got := findVertexModelName(model); got != expected {
+			t.Errorf("findVertexModelName(%q) = %q, want %q", model, got, expected)
+		}
+	}
+}
+
+func TestBuildVertexClientOptions(t *testing.T) {
+	for _, name := range []string{"VERTEXAI_CREDENTIALS_JSON", "VERTEXAI_CREDENTIALS_FILE", "GOOGLE_APPLICATION_CREDENTIALS"} {
+		t.Setenv(name, "")
+	}
+	credentialsFile := filepath.Join(t.TempDir(), "credentials.json")
+	if err := os.WriteFile(credentialsFile, []byte(`{"type":"service_account"}`), 0o600); err != nil {
+		t.Fatalf("failed to write credentials file: %v", err)
+	}
+
+	build := func(options LlmOptions) []option.ClientOption {
+		t.Helper()
+		clientOptions, err := buildVertexClientOptions(options)
+		if err != nil {
+			t.Fatalf("buildVertexClientOptions failed: %v", err)
+		}
+		return clientOptions
+	}
+	expect := func(source string, got []option.ClientOption, expected ...option.ClientOption) {
+		t.Helper()
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("%s: expected %v, got %v", source, expected, got)
+		}
+	}
+
+	// Application Default Credentials without any source
+	expect("adc", build(LlmOptions{}))
+
+	// Environment variables, in order
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", credentialsFile)
+	expect("GOOGLE_APPLICATION_CREDENTIALS", build(LlmOptions{}), option.WithCredentialsFile(credentialsFile))
+	t.Setenv("VERTEXAI_CREDENTIALS_FILE", credentialsFile)
+	expect("VERTEXAI_CREDENTIALS_FILE", build(LlmOptions{}), option.WithCredentialsFile(credentialsFile))
+	t.Setenv("VERTEXAI_CREDENTIALS_JSON", `{"env":true}`)
+	expect("VERTEXAI_CREDENTIALS_JSON", build(LlmOptions{}), option.WithCredentialsJSON([]byte(`{"env":true}`)))
+
+	// Options take precedence over the environment
+	options := LlmOptions{ApiKey: " test-key "}
+	expect("api key", build(options), option.WithAPIKey("test-key"))
+	options.ProviderOptions = map[string]any{"credentials_file": credentialsFile}
+	expect("credentials_file", build(options), option.WithCredentialsFile(credentialsFile))
+	options.ProviderOptions["credentials_json"] = []byte(`{"option":true}`)
+	expect("credentials_json", build(options), option.WithCredentialsJSON([]byte(`{"option":true}`)))
+
+	// Invalid sources are reported
+	if _, err := buildVertexClientOptions(LlmOptions{ProviderOptions: map[string]any{"credentials_file": filepath.Join(t.TempDir(), "missing.json")}}); err == nil {
+		t.Error("expected an error for a missing credentials file")
+	}
+	if _, err := buildVertexClientOptions(LlmOptions{ProviderOptions: map[string]any{"credentials_json": []int{1}}}); err == nil {
+		t.Error("expected an error for mistyped credentials_json")
+	}
+}
+
+func TestVertexCostBudgetResolvesModel(t *testing.T) {
+	engine, err := newVertexImplementation(LlmOptions{ProjectID: "test-project", Region: "europe-west1", Model: "pro"})
+	if err != nil {
+		t.Fatalf("failed to create Vertex LLM: %v", err)
+	}
+
+	// The "pro" keyword resolves to gemini-2.5-pro, whose million output
+	// tokens cost more than a cent
+	_, err = engine.GenerateText("system", "hello", LlmOptions{MaxTokens: 1_000_000, MaxCostUSD: 0.01})
+	if !errors.Is(err, ErrCostExceeded) {
+		t.Errorf("expected ErrCostExceeded for the resolved model, got %v", err)
+	}
+}
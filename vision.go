@@ -0,0 +1,55 @@
+package llm
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+)
+
+// VisionInterface is implemented by providers that accept images as input
+// (currently OpenAI, OpenRouter, Anthropic and Gemini)
+type VisionInterface interface {
+	// GenerateWithImages generates a text response to a prompt about the
+	// given images. The image format (PNG, JPEG, GIF or WebP) is detected
+	// from the image data.
+	GenerateWithImages(systemPrompt string, userPrompt string, images [][]byte, options ...LlmOptions) (string, error)
+}
+
+// GenerateWithImages generates a text response to a prompt about the given
+// images, returning an error wrapping ErrNotSupported if the provider does
+// not implement VisionInterface
+func GenerateWithImages(llm LlmInterface, systemPrompt string, userPrompt string, images [][]byte, options ...LlmOptions) (string, error) {
+	vision, ok := llm.(VisionInterface)
+	if !ok {
+		return "", fmt.Errorf("%w: image input", ErrNotSupported)
+	}
+	return vision.GenerateWithImages(systemPrompt, userPrompt, images, options...)
+}
+
+// visionMessages returns the system and user messages for GenerateWithImages
+func visionMessages(systemPrompt string, userPrompt string, images [][]byte) []Message {
+	return []Message{
+		{Role: MessageRoleSystem, Content: systemPrompt},
+		{Role: MessageRoleUser, Content: userPrompt, Images: images},
+	}
+}
+
+// imageMediaType detects the media type of image data, accepting only the
+// formats supported as input by the vision providers
+func imageMediaType(image []byte) (string, error) {
+	mediaType := http.DetectContentType(image)
+	switch mediaType {
+	case "image/png", "image/jpeg", "image/gif", "image/webp":
+		return mediaType, nil
+	}
+	return "", fmt.Errorf("unsupported image type %s", mediaType)
+}
+
+// imageDataURI encodes image data as a base64 data URI
+func imageDataURI(image []byte) (string, error) {
+	mediaType, err := imageMediaType(image)
+	if err != nil {
+		return "", err
+	}
+	return "data:" + mediaType + ";base64," + base64.StdEncoding.EncodeToString(image), nil
+}
@@ -0,0 +1,71 @@
+package llm
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// pngHeader is the signature of a PNG file
+var pngHeader = []byte("\x89PNG\r\n\x1a\n")
+
+func TestImageMediaType(t *testing.T) {
+	mediaType, err := imageMediaType(pngHeader)
+	if err != nil || mediaType != "image/png" {
+		t.Errorf("expected image/png, got %s (err=%v)", mediaType, err)
+	}
+
+	if _, err := imageMediaType([]byte("plain text")); err == nil {
+		t.Error("expected non-image data to be rejected")
+	}
+}
+
+func TestAnthropicMessagesWithImages(t *testing.T) {
+	messages, err := anthropicMessages([]Message{
+		{Role: MessageRoleUser, Content: "What is this?", Images: [][]byte{pngHeader}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	blocks, ok := messages[0]["content"].([]map[string]any)
+	if !ok || len(blocks) != 2 {
+		t.Fatalf("expected image and text blocks, got %#v", messages[0]["content"])
+	}
+	source := blocks[0]["source"].(map[string]any)
+	if source["media_type"] != "image/png" {
+		t.Errorf("expected image/png media type, got %v", source["media_type"])
+	}
+	if blocks[1]["text"] != "What is this?" {
+		t.Errorf("expected text block last, got %v", blocks[1])
+	}
+}
+
+func TestOpenaiChatMessagesWithImages(t *testing.T) {
+	messages, err := openaiChatMessages([]Message{
+		{Role: MessageRoleUser, Content: "What is this?", Images: [][]byte{pngHeader}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	parts := messages[0].MultiContent
+	if len(parts) != 2 || parts[1].ImageURL == nil {
+		t.Fatalf("expected text and image parts, got %#v", parts)
+	}
+	if !strings.HasPrefix(parts[1].ImageURL.URL, "data:image/png;base64,") {
+		t.Errorf("expected PNG data URI, got %s", parts[1].ImageURL.URL)
+	}
+}
+
+func TestGenerateWithImagesNotSupported(t *testing.T) {
+	engine, err := NewLLM(LlmOptions{Provider: ProviderMock})
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+
+	_, err = GenerateWithImages(engine, "", "What is this?", [][]byte{pngHeader})
+	if !errors.Is(err, ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported, got %v", err)
+	}
+}
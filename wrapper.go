@@ -0,0 +1,24 @@
+package llm
+
+// wrapperInterface is implemented by the clients NewLLM wraps around a
+// provider (rate limiter, spend tracker and middlewares). They implement
+// all the optional interfaces, forwarding them to the wrapped client
+// through the package-level helpers, so a call the provider does not
+// support falls back or returns an error wrapping ErrNotSupported as it
+// would on the provider.
+type wrapperInterface interface {
+	// unwrap returns the wrapped client
+	unwrap() LlmInterface
+}
+
+// unwrapLLM returns the provider client under the wrappers of llm, or llm
+// itself if it is not wrapped
+func unwrapLLM(llm LlmInterface) LlmInterface {
+	for {
+		wrapper, ok := llm.(wrapperInterface)
+		if !ok {
+			return llm
+		}
+		llm = wrapper.unwrap()
+	}
+}